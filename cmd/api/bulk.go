@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"easylist/internal/data"
+	"easylist/internal/events"
+	"easylist/internal/validator"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/jsonapi"
+	"net/http"
+	"strconv"
+)
+
+const maxBulkOperations = 500
+
+type BulkResult struct {
+	Data *jsonapi.Node `json:"data,omitempty"`
+}
+
+// bulkItemsHandler applies a batch of add, update and remove operations to the items of a list.
+// All operations are validated first and applied in one transaction, so either every operation is saved or none.
+func (app *application) bulkItemsHandler(w http.ResponseWriter, r *http.Request) {
+	listId, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var userModel = app.contextGetUser(r)
+
+	list, err := app.models.Lists.Get(r.Context(), listId, userModel.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if !list.CanEdit() {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	var input BulkInput
+	err = decodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, "bulkItemsHandler", err)
+		return
+	}
+
+	var v = validator.New()
+	v.Check(len(input.Operations) > 0, "atomic:operations", "must be provided")
+	v.Check(len(input.Operations) <= maxBulkOperations, "atomic:operations", "must contain no more than 500 operations")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	var operations = make([]data.BulkItemOperation, 0, len(input.Operations))
+	var touched = make(map[int64]bool)
+	for index, operation := range input.Operations {
+		item, err := app.prepareBulkOperation(r.Context(), operation, list, userModel, bulkValidator{v, index})
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		if item == nil {
+			continue
+		}
+		if item.ID > 0 {
+			if touched[item.ID] {
+				v.AddError(bulkErrorKey(index, "data.id"), "item is already changed by another operation")
+				continue
+			}
+			touched[item.ID] = true
+		}
+		operations = append(operations, data.BulkItemOperation{Op: operation.Op, Item: item})
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Items.ApplyBulk(r.Context(), list.ID, userModel.ID, operations)
+	if err != nil {
+		var operationError *data.BulkOperationError
+		switch {
+		case errors.As(err, &operationError) && errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r, bulkErrorKey(operationError.Index, "data"))
+		case errors.As(err, &operationError) && errors.Is(err, data.ErrRecordNotFound):
+			v.AddError(bulkErrorKey(operationError.Index, "ref.id"), "item does not exist in this list")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	app.publishListEvent(events.ItemsUpdated, list.ID)
+
+	var results = make([]BulkResult, 0, len(operations))
+	for _, operation := range operations {
+		if operation.Op == data.BulkOpRemove {
+			results = append(results, BulkResult{})
+			continue
+		}
+		results = append(results, BulkResult{Data: app.syncNode(operation.Item)})
+	}
+
+	writeHeaders(w, http.StatusOK, nil)
+	err = json.NewEncoder(w).Encode(map[string]any{
+		"atomic:results": results,
+	})
+	if err != nil {
+		app.logError(r, err)
+	}
+}
+
+// bulkValidator collects the errors of a single operation under its index, e.g. atomic:operations[3].data.attributes.name.
+type bulkValidator struct {
+	*validator.Validator
+	index int
+}
+
+func (bv bulkValidator) addError(field string, message string) {
+	bv.AddError(bulkErrorKey(bv.index, field), message)
+}
+
+func (bv bulkValidator) merge(errors map[string]string) {
+	for field, message := range errors {
+		bv.addError(field, message)
+	}
+}
+
+func bulkErrorKey(index int, field string) string {
+	return fmt.Sprintf("atomic:operations[%d].%s", index, field)
+}
+
+// prepareBulkOperation turns the operation into the item to save. Validation errors are added to bv and a nil item is
+// returned, the error is only set when the database fails.
+func (app *application) prepareBulkOperation(ctx context.Context, operation BulkOperation, list *data.List, user *data.User, bv bulkValidator) (*data.Item, error) {
+	switch operation.Op {
+	case data.BulkOpAdd:
+		if operation.Data == nil || operation.Data.Type != ItemType {
+			bv.addError("data.type", "Wrong type provided, accepted type is items")
+			return nil, nil
+		}
+		var item = &data.Item{ListId: list.ID, UserId: user.ID, Version: 1, Order: 1}
+		if !checkBulkAttributes(operation.Data.Attributes, list, bv) {
+			return nil, nil
+		}
+		operation.Data.Attributes.applyTo(item)
+		return app.validateBulkItem(ctx, item, operation.Data.Attributes, user, bv)
+
+	case data.BulkOpUpdate, data.BulkOpRemove:
+		var id string
+		var field = "ref.id"
+		if operation.Ref != nil {
+			id = operation.Ref.Id
+			bv.Check(operation.Ref.Type == ItemType, bulkErrorKey(bv.index, "ref.type"), "Wrong type provided, accepted type is items")
+		} else if operation.Data != nil {
+			id = operation.Data.Id
+			field = "data.id"
+			bv.Check(operation.Data.Type == ItemType, bulkErrorKey(bv.index, "data.type"), "Wrong type provided, accepted type is items")
+		}
+		itemId, err := strconv.ParseInt(id, 10, 64)
+		if err != nil || itemId < 1 {
+			bv.addError(field, "must be provided for update and remove operations")
+			return nil, nil
+		}
+
+		item, err := app.models.Items.Get(ctx, itemId, user.ID)
+		if err != nil {
+			if errors.Is(err, data.ErrRecordNotFound) {
+				bv.addError(field, "item does not exist in this list")
+				return nil, nil
+			}
+			return nil, err
+		}
+		if item.ListId != list.ID {
+			bv.addError(field, "item does not exist in this list")
+			return nil, nil
+		}
+
+		if operation.Op == data.BulkOpUpdate {
+			if operation.Data == nil {
+				bv.addError("data", "must be provided for update operations")
+				return nil, nil
+			}
+			if !checkBulkAttributes(operation.Data.Attributes, list, bv) {
+				return nil, nil
+			}
+			operation.Data.Attributes.applyTo(item)
+			return app.validateBulkItem(ctx, item, operation.Data.Attributes, user, bv)
+		}
+		return item, nil
+
+	default:
+		bv.addError("op", "must be one of add, update or remove")
+		return nil, nil
+	}
+}
+
+// checkBulkAttributes rejects the attributes which can not be changed in bulk, items stay in the list of the url
+// and files are uploaded one item at a time.
+func checkBulkAttributes(attributes ItemAttributes, list *data.List, bv bulkValidator) bool {
+	var ok = true
+	if attributes.ListId != nil && *attributes.ListId != list.ID {
+		bv.addError("data.attributes.list_id", "items can not be moved to another list in bulk")
+		ok = false
+	}
+	if attributes.File != nil {
+		bv.addError("data.attributes.file", "files can not be changed in bulk")
+		ok = false
+	}
+	return ok
+}
+
+// validateBulkItem sets the category of the item like updateItemHandler does and validates the item.
+func (app *application) validateBulkItem(ctx context.Context, item *data.Item, attributes ItemAttributes, user *data.User, bv bulkValidator) (*data.Item, error) {
+	if attributes.CategoryId != nil && *attributes.CategoryId != item.CategoryId {
+		found, err := app.checkCategory(ctx, *attributes.CategoryId, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			bv.addError("data.attributes.category_id", "this category does not exists")
+			return nil, nil
+		}
+		item.CategoryId = *attributes.CategoryId
+	}
+	var v = validator.New()
+	if data.ValidateItem(v, item); !v.Valid() {
+		bv.merge(v.Errors)
+		return nil, nil
+	}
+	return item, nil
+}
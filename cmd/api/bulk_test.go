@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"easylist/internal/data"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func TestBulkItemsAppliesAllOperations(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	item, token := createItem(app, t)
+	var id = strconv.Itoa(int(item.ID))
+	category, err := createTestCategory(app, item.UserId, "Dairy")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var operations = []byte(`{
+	  "atomic:operations": [
+		{"op": "add", "data": {"type": "items", "attributes": {"name": "Milk", "quantity": 2, "is_done": true, "category_id": ` + strconv.Itoa(int(category.ID)) + `}}},
+		{"op": "add", "data": {"type": "items", "attributes": {"name": "Bread", "quantity": 1}}},
+		{"op": "update", "data": {"type": "items", "id": "` + id + `", "attributes": {"is_done": true}}}
+	  ]
+	}`)
+	req := generateRequestWithToken(ts.URL+"/api/v1/lists/"+strconv.Itoa(int(item.ListId))+"/items/bulk", token.Plaintext, "POST", bytes.NewBuffer(operations))
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("want %d status code; got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	var check struct {
+		Results []BulkResult `json:"atomic:results"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&check)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(check.Results) != 3 {
+		t.Fatalf("want 3 results, got %d", len(check.Results))
+	}
+	if check.Results[0].Data == nil || check.Results[0].Data.ID == "" {
+		t.Error("want the added item to be returned with its id")
+	}
+
+	updated, err := app.models.Items.Get(context.Background(), item.ID, item.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !updated.IsDone {
+		t.Error("want the item to be marked as done")
+	}
+
+	addedId, _ := strconv.ParseInt(check.Results[0].Data.ID, 10, 64)
+	added, err := app.models.Items.Get(context.Background(), addedId, item.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !added.IsDone || added.CategoryId != category.ID {
+		t.Errorf("want the added item to be done in the category %d; got %v %d", category.ID, added.IsDone, added.CategoryId)
+	}
+	suggestions, err := app.models.Suggestions.GetAll(context.Background(), item.UserId, "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(suggestions) != 2 {
+		t.Errorf("want both done items in the purchase history; got %d", len(suggestions))
+	}
+}
+
+func TestBulkItemsRejectsAllOnValidationError(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	item, token := createItem(app, t)
+
+	var operations = []byte(`{
+	  "atomic:operations": [
+		{"op": "add", "data": {"type": "items", "attributes": {"name": "Milk", "quantity": 2}}},
+		{"op": "add", "data": {"type": "items", "attributes": {"name": ""}}},
+		{"op": "remove", "ref": {"type": "items", "id": "999999"}}
+	  ]
+	}`)
+	req := generateRequestWithToken(ts.URL+"/api/v1/lists/"+strconv.Itoa(int(item.ListId))+"/items/bulk", token.Plaintext, "POST", bytes.NewBuffer(operations))
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Fatalf("want %d status code; got %d", http.StatusUnprocessableEntity, resp.StatusCode)
+	}
+
+	var check struct {
+		Errors []struct {
+			Meta struct {
+				Field string `json:"field"`
+			} `json:"meta"`
+		} `json:"errors"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&check)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields = make(map[string]bool)
+	for _, e := range check.Errors {
+		fields[e.Meta.Field] = true
+	}
+	for _, field := range []string{"atomic:operations[1].data.attributes.name", "atomic:operations[2].ref.id"} {
+		if !fields[field] {
+			t.Errorf("want an error for %s, got %v", field, fields)
+		}
+	}
+
+	items, _, err := app.models.Items.GetAll(context.Background(), item.UserId, item.ListId, data.ItemFilters{}, data.Filters{Page: 1, Size: 10, Sort: "order", SortSafelist: []string{"order"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 {
+		t.Errorf("want no item to be added when an operation is invalid, got %d items", len(items))
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"easylist/internal/data"
+	"easylist/internal/validator"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// applyTo copies the attributes which were sent by the client.
+func (attributes CategoryAttributes) applyTo(category *data.Category) {
+	if attributes.Name != nil {
+		category.Name = *attributes.Name
+	}
+	if attributes.Icon != nil {
+		category.Icon = *attributes.Icon
+	}
+}
+
+func (app *application) indexCategoriesHandler(w http.ResponseWriter, r *http.Request) {
+	var userModel = app.contextGetUser(r)
+
+	categories, err := app.models.Categories.GetAll(r.Context(), userModel.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, categories, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	var input = Input[CategoryAttributes]{Data: InputAttributes[CategoryAttributes]{
+		Attributes: CategoryAttributes{},
+	}}
+	var err = readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, "createCategoryHandler", err)
+		return
+	}
+
+	var userModel = app.contextGetUser(r)
+
+	var category = &data.Category{
+		UserId:    userModel.ID,
+		Icon:      "mdi-tag",
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	input.Data.Attributes.applyTo(category)
+
+	var v = validator.New()
+	v.Check(input.Data.Type == data.CategoriesType, "data.type", "Wrong type provided, accepted type is categories")
+	if data.ValidateCategory(v, category); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Categories.Insert(r.Context(), category)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var headers = make(http.Header)
+	headers.Set("Location", fmt.Sprintf("%s/api/v1/categories/%d", app.config.Domain, category.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, category, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	category, ok := app.readCategory(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.writeJSON(w, http.StatusOK, category, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	category, ok := app.readCategory(w, r)
+	if !ok {
+		return
+	}
+
+	var input = Input[CategoryAttributes]{Data: InputAttributes[CategoryAttributes]{
+		Attributes: CategoryAttributes{},
+	}}
+	var err = readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, "updateCategoryHandler", err)
+		return
+	}
+
+	var v = validator.New()
+	v.Check(input.Data.Type == data.CategoriesType, "data.type", "Wrong type provided, accepted type is categories")
+	v.Check(input.Data.Id == strconv.FormatInt(category.ID, 10), "data.id", "Passed json id does not match request id")
+
+	input.Data.Attributes.applyTo(category)
+	if data.ValidateCategory(v, category); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Categories.Update(r.Context(), category)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r, "updateCategoryHandler")
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, category, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var userModel = app.contextGetUser(r)
+
+	err = app.models.Categories.Delete(r.Context(), id, userModel.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// reorderCategoriesHandler changes the order of the categories of the user, see readReorder for the accepted input.
+func (app *application) reorderCategoriesHandler(w http.ResponseWriter, r *http.Request) {
+	var userModel = app.contextGetUser(r)
+
+	reorder, ok := app.readReorder(w, r, "reorderCategoriesHandler")
+	if !ok {
+		return
+	}
+
+	ranks, err := app.models.Categories.Reorder(r.Context(), userModel.ID, reorder)
+	if err != nil {
+		app.reorderErrorResponse(w, r, err, "categories")
+		return
+	}
+	app.writeRanks(w, r, data.CategoriesType, ranks)
+}
+
+// readCategory loads the category from the id in the url, on failure the response is already written.
+func (app *application) readCategory(w http.ResponseWriter, r *http.Request) (*data.Category, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	var userModel = app.contextGetUser(r)
+
+	category, err := app.models.Categories.Get(r.Context(), id, userModel.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+	return category, true
+}
+
+// checkCategory reports whether the user can give the category to an item, zero stands for no category.
+func (app *application) checkCategory(ctx context.Context, categoryId int64, userId int64) (bool, error) {
+	if categoryId == 0 {
+		return true, nil
+	}
+	_, err := app.models.Categories.Get(ctx, categoryId, userId)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"easylist/internal/data"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+type categoryResponse struct {
+	Data struct {
+		Id         string `json:"id"`
+		Attributes struct {
+			Name  string `json:"name"`
+			Icon  string `json:"icon"`
+			Order int32  `json:"order"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+type groupedItemsResponse struct {
+	Data []struct {
+		Id            string `json:"id"`
+		Relationships struct {
+			Category *struct {
+				Data struct {
+					Id string `json:"id"`
+				} `json:"data"`
+			} `json:"category"`
+		} `json:"relationships"`
+	} `json:"data"`
+	Included []struct {
+		Type       string `json:"type"`
+		Id         string `json:"id"`
+		Attributes struct {
+			Name string `json:"name"`
+		} `json:"attributes"`
+	} `json:"included"`
+}
+
+func createTestCategory(app *application, userId int64, name string) (*data.Category, error) {
+	var category = &data.Category{UserId: userId, Name: name, Icon: "mdi-tag"}
+	err := app.models.Categories.Insert(context.Background(), category)
+	if err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+func TestCreateAndUpdateCategory(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	user, token, err := createTestUserWithToken(t, app, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = createTestCategory(app, user.ID, "Bakery")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var body = `{"data": {"type": "categories", "attributes": {"name": "Dairy", "icon": "mdi-cow"}}}`
+	req := generateRequestWithToken(ts.URL+"/api/v1/categories", token.Plaintext, "POST", bytes.NewBufferString(body))
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("want %d status code; got %d", http.StatusCreated, resp.StatusCode)
+	}
+	var check categoryResponse
+	err = json.NewDecoder(resp.Body).Decode(&check)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if check.Data.Attributes.Name != "Dairy" || check.Data.Attributes.Order != 2 {
+		t.Errorf("want the new category after the existing one; got %+v", check.Data.Attributes)
+	}
+
+	body = `{"data": {"type": "categories", "id": "` + check.Data.Id + `", "attributes": {"name": "Milk and cheese"}}}`
+	req = generateRequestWithToken(ts.URL+"/api/v1/categories/"+check.Data.Id, token.Plaintext, "PATCH", bytes.NewBufferString(body))
+	resp, err = ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("want %d status code; got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	categories, err := app.models.Categories.GetAll(context.Background(), user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(categories) != 2 || categories[1].Name != "Milk and cheese" || categories[1].Icon != "mdi-cow" {
+		t.Errorf("want the renamed category to keep its icon; got %+v", categories)
+	}
+
+	body = `{"data": {"type": "categories", "attributes": {"name": ""}}}`
+	req = generateRequestWithToken(ts.URL+"/api/v1/categories", token.Plaintext, "POST", bytes.NewBufferString(body))
+	resp, err = ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("want %d status code for a category without a name; got %d", http.StatusUnprocessableEntity, resp.StatusCode)
+	}
+}
+
+func TestCreateItemSuggestsCategory(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	item, token := createItem(app, t)
+	dairy, err := createTestCategory(app, item.UserId, "Dairy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var milk = data.Item{ListId: item.ListId, UserId: item.UserId, Name: "Milk", CategoryId: dairy.ID}
+	err = createTestItem(app, &milk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var create = func(name string, categoryId int64) *http.Response {
+		var body = `{"data": {"type": "items", "attributes": {"name": "` + name + `", "list_id": ` + strconv.FormatInt(item.ListId, 10) + `, "category_id": ` + strconv.FormatInt(categoryId, 10) + `}}}`
+		req := generateRequestWithToken(ts.URL+"/api/v1/items", token.Plaintext, "POST", bytes.NewBufferString(body))
+		resp, err := ts.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp := create("milk", 0)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("want %d status code; got %d", http.StatusCreated, resp.StatusCode)
+	}
+	var check struct {
+		Data struct {
+			Attributes struct {
+				CategoryId int64 `json:"category_id"`
+			} `json:"attributes"`
+		} `json:"data"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&check)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if check.Data.Attributes.CategoryId != dairy.ID {
+		t.Errorf("want the category of the earlier milk %d; got %d", dairy.ID, check.Data.Attributes.CategoryId)
+	}
+
+	other, _, err := createTestUserWithToken(t, app, "other@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	foreign, err := createTestCategory(app, other.ID, "Foreign")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp = create("Bread", foreign.ID)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("want %d status code for the category of another user; got %d", http.StatusUnprocessableEntity, resp.StatusCode)
+	}
+}
+
+func TestIndexItemsGroupedByCategory(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	item, token := createItem(app, t)
+	dairy, err := createTestCategory(app, item.UserId, "Dairy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bakery, err := createTestCategory(app, item.UserId, "Bakery")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the bakery comes first in the store
+	_, err = app.models.Categories.Reorder(context.Background(), item.UserId, data.Reorder{ID: bakery.ID, Before: dairy.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var milk = data.Item{ListId: item.ListId, UserId: item.UserId, Name: "Milk", CategoryId: dairy.ID}
+	var bread = data.Item{ListId: item.ListId, UserId: item.UserId, Name: "Bread", CategoryId: bakery.ID}
+	for _, next := range []*data.Item{&milk, &bread} {
+		err = createTestItem(app, next)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	req := generateRequestWithToken(ts.URL+"/api/v1/lists/"+strconv.FormatInt(item.ListId, 10)+"/items?group=category", token.Plaintext, "GET", nil)
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("want %d status code; got %d", http.StatusOK, resp.StatusCode)
+	}
+	var check groupedItemsResponse
+	err = json.NewDecoder(resp.Body).Decode(&check)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var want = []string{strconv.FormatInt(bread.ID, 10), strconv.FormatInt(milk.ID, 10), strconv.FormatInt(item.ID, 10)}
+	if len(check.Data) != len(want) {
+		t.Fatalf("want %d items; got %d", len(want), len(check.Data))
+	}
+	for i, id := range want {
+		if check.Data[i].Id != id {
+			t.Errorf("want item %s at position %d; got %s", id, i, check.Data[i].Id)
+		}
+	}
+	if check.Data[0].Relationships.Category == nil || check.Data[0].Relationships.Category.Data.Id != strconv.FormatInt(bakery.ID, 10) {
+		t.Errorf("want the bread related to the bakery; got %+v", check.Data[0].Relationships.Category)
+	}
+	if len(check.Included) != 2 || check.Included[0].Type != data.CategoriesType {
+		t.Errorf("want both categories included; got %+v", check.Included)
+	}
+
+	req = generateRequestWithToken(ts.URL+"/api/v1/lists/"+strconv.FormatInt(item.ListId, 10)+"/items?group=aisle", token.Plaintext, "GET", nil)
+	resp, err = ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("want %d status code for an unknown group; got %d", http.StatusUnprocessableEntity, resp.StatusCode)
+	}
+}
+
+func TestDeleteCategoryKeepsItems(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	item, token := createItem(app, t)
+	dairy, err := createTestCategory(app, item.UserId, "Dairy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var milk = data.Item{ListId: item.ListId, UserId: item.UserId, Name: "Milk", CategoryId: dairy.ID}
+	err = createTestItem(app, &milk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := generateRequestWithToken(ts.URL+"/api/v1/categories/"+strconv.FormatInt(dairy.ID, 10), token.Plaintext, "DELETE", nil)
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("want %d status code; got %d", http.StatusNoContent, resp.StatusCode)
+	}
+
+	check, err := app.models.Items.Get(context.Background(), milk.ID, item.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if check.CategoryId != 0 || check.Version != milk.Version+1 {
+		t.Errorf("want the item without a category and a new version; got category %d, version %d", check.CategoryId, check.Version)
+	}
+}
+
+func TestPublicPageRendersCategories(t *testing.T) {
+	ts, err := template.ParseFiles("../../ui/html/public.page.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var items = data.Items{
+		{ID: 1, Name: "Bread", CategoryId: 2, Category: &data.Category{ID: 2, Name: "Bakery"}},
+		{ID: 2, Name: "Milk", CategoryId: 1, Category: &data.Category{ID: 1, Name: "Dairy"}},
+		{ID: 3, Name: "Candles"},
+	}
+	var page bytes.Buffer
+	err = ts.Execute(&page, EmailData{List: &data.List{Name: "Groceries"}, Items: items, Groups: data.GroupItems(items)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var html = page.String()
+	var bakery, dairy, other = strings.Index(html, ">Bakery<"), strings.Index(html, ">Dairy<"), strings.Index(html, ">Other<")
+	if bakery < 0 || dairy < bakery || other < dairy || strings.Index(html, "Candles") < other {
+		t.Errorf("want the items under their categories in order, uncategorized last; got headings at %d, %d, %d", bakery, dairy, other)
+	}
+}
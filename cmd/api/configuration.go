@@ -1,10 +1,16 @@
 package main
 
 import (
+	"context"
 	"easylist/internal/data"
+	"easylist/internal/events"
 	"easylist/internal/jsonlog"
 	"easylist/internal/mailer"
+	"easylist/internal/oidc"
+	"easylist/internal/storage"
+	"net/url"
 	"sync"
+	"time"
 )
 
 var version string
@@ -25,9 +31,32 @@ type config struct {
 	Cors         struct {
 		TrustedOrigins []string `yaml:"trustedOrigins"`
 	}
+	Trash struct {
+		Retention string `yaml:"retention"`
+	}
+	Sync struct {
+		Retention string `yaml:"retention"`
+	}
+	Auth struct {
+		AccessTtl  string `yaml:"accessTtl"`
+		RefreshTtl string `yaml:"refreshTtl"`
+		Lockout    struct {
+			Attempts int    `yaml:"attempts"`
+			Duration string `yaml:"duration"`
+		} `yaml:"lockout"`
+	}
+	Oidc struct {
+		Providers map[string]oidcProvider `yaml:"providers"`
+	}
+	Currency struct {
+		Default   string `yaml:"default"`
+		RatesFile string `yaml:"ratesFile"`
+	}
+	Storage storageConfig `yaml:"storage"`
 }
 
 type database struct {
+	Driver       string
 	Dsn          string
 	Host         string
 	Login        string
@@ -36,6 +65,20 @@ type database struct {
 	MaxOpenConns int    `yaml:"maxOpenConns"`
 	MaxIdleConns int    `yaml:"maxIdleConns"`
 	MaxIdleTime  string `yaml:"maxIdleTime"`
+	QueryTimeout string `yaml:"queryTimeout"`
+	Migrate      string
+}
+
+// dsn builds the data source name for the driver from the separate settings, for SQLite the dbname is the path of the database file.
+func (d *database) dsn(dialect data.Dialect) string {
+	switch dialect {
+	case data.Postgres:
+		var dsn = url.URL{Scheme: "postgres", User: url.UserPassword(d.Login, d.Password), Host: d.Host, Path: "/" + d.Dbname, RawQuery: "sslmode=disable"}
+		return dsn.String()
+	case data.SQLite:
+		return "file:" + d.Dbname + "?_foreign_keys=on&_busy_timeout=5000&_txlock=immediate"
+	}
+	return d.Login + ":" + d.Password + "@" + d.Host + "/" + d.Dbname + "?parseTime=true"
 }
 
 type smtp struct {
@@ -46,6 +89,33 @@ type smtp struct {
 	Sender   string
 }
 
+type storageConfig struct {
+	Driver        string
+	Path          string
+	MaxSize       int64    `yaml:"maxSize"`
+	AllowedTypes  []string `yaml:"allowedTypes"`
+	ThumbnailSize int      `yaml:"thumbnailSize"`
+	UrlSecret     string   `yaml:"urlSecret"`
+	UrlTtl        string   `yaml:"urlTtl"`
+	S3            struct {
+		Endpoint  string
+		Region    string
+		Bucket    string
+		AccessKey string `yaml:"accessKey"`
+		SecretKey string `yaml:"secretKey"`
+	}
+}
+
+// oidcProvider is an OpenID Connect provider the users can sign in with, the name of the provider is its key in the
+// providers section.
+type oidcProvider struct {
+	Issuer       string
+	ClientId     string   `yaml:"clientId"`
+	ClientSecret string   `yaml:"clientSecret"`
+	RedirectUrl  string   `yaml:"redirectUrl"`
+	Scopes       []string `yaml:"scopes"`
+}
+
 type limiter struct {
 	Rps     float64
 	Burst   int
@@ -53,9 +123,34 @@ type limiter struct {
 }
 
 type application struct {
-	config config
-	logger *jsonlog.Logger
-	models data.Models
-	mailer mailer.Mailer
-	wg     sync.WaitGroup
+	config  config
+	logger  *jsonlog.Logger
+	models  data.Models
+	mailer  mailer.Mailer
+	events  *events.Hub
+	storage storage.Store
+	// oidcProviders are the configured OpenID Connect providers by their name
+	oidcProviders map[string]*oidc.Provider
+	loginAttempts *loginAttempts
+	wg            sync.WaitGroup
+	// baseCtx is the context shared by the requests and the jobs of the server, the work in the background uses it
+	baseCtx context.Context
+	// clock is the source of the current time for the one-time codes, the tests replace it with a fixed time
+	clock func() time.Time
+}
+
+// baseContext returns the context of the work which outlives a request, it is cancelled when the server shuts down.
+func (app *application) baseContext() context.Context {
+	if app.baseCtx == nil {
+		return context.Background()
+	}
+	return app.baseCtx
+}
+
+// now returns the current time of the clock of the application.
+func (app *application) now() time.Time {
+	if app.clock == nil {
+		return time.Now()
+	}
+	return app.clock()
 }
@@ -9,6 +9,8 @@ import (
 type contextKey string
 
 const userContextKey = contextKey("user")
+const sessionContextKey = contextKey("session")
+const accessTokenContextKey = contextKey("accessToken")
 
 func (app *application) contextSetUser(r *http.Request, user *data.User) *http.Request {
 	var ctx = context.WithValue(r.Context(), userContextKey, user)
@@ -22,3 +24,26 @@ func (app *application) contextGetUser(r *http.Request) *data.User {
 	}
 	return user
 }
+
+// contextSetSession keeps the session of the authentication token, the tokens created before the sessions have none.
+func (app *application) contextSetSession(r *http.Request, session *data.Session) *http.Request {
+	var ctx = context.WithValue(r.Context(), sessionContextKey, session)
+	return r.WithContext(ctx)
+}
+
+// contextGetSession returns the session of the request or nil when the token has none.
+func (app *application) contextGetSession(r *http.Request) *data.Session {
+	session, _ := r.Context().Value(sessionContextKey).(*data.Session)
+	return session
+}
+
+func (app *application) contextSetAccessToken(r *http.Request, accessToken *data.PersonalAccessToken) *http.Request {
+	var ctx = context.WithValue(r.Context(), accessTokenContextKey, accessToken)
+	return r.WithContext(ctx)
+}
+
+// contextGetAccessToken returns the personal access token of the request or nil when the user signed in otherwise.
+func (app *application) contextGetAccessToken(r *http.Request) *data.PersonalAccessToken {
+	accessToken, _ := r.Context().Value(accessTokenContextKey).(*data.PersonalAccessToken)
+	return accessToken
+}
@@ -4,7 +4,10 @@ import (
 	"fmt"
 	"github.com/google/jsonapi"
 	"log"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 func (app *application) logError(r *http.Request, err error) {
@@ -200,3 +203,21 @@ func (app *application) rateLimitExceededResponse(w http.ResponseWriter, r *http
 	}
 	app.errorResponse(w, r, http.StatusTooManyRequests, jsonapi.ErrorsPayload{Errors: []*jsonapi.ErrorObject{&errorObject}})
 }
+
+// tooManyAttemptsResponse answers a sign in while the email or the ip address has to wait after failed attempts.
+func (app *application) tooManyAttemptsResponse(w http.ResponseWriter, r *http.Request, retryAfter time.Duration) {
+	var seconds = int64(math.Ceil(retryAfter.Seconds()))
+	message := fmt.Sprintf("too many failed attempts, please try again in %d seconds", seconds)
+	m := make(map[string]interface{})
+	m["retry_after"] = seconds
+
+	var errorObject = jsonapi.ErrorObject{
+		Status: "429",
+		Code:   "429",
+		Meta:   &m,
+		Title:  "Too many attempts",
+		Detail: message,
+	}
+	w.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+	app.errorResponse(w, r, http.StatusTooManyRequests, jsonapi.ErrorsPayload{Errors: []*jsonapi.ErrorObject{&errorObject}})
+}
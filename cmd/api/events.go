@@ -0,0 +1,116 @@
+package main
+
+import (
+	"easylist/internal/data"
+	"easylist/internal/events"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/jsonapi"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// eventsHeartbeat keeps idle streams alive through proxies which drop silent connections.
+const eventsHeartbeat = 15 * time.Second
+
+func (app *application) listEventsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var userModel = app.contextGetUser(r)
+
+	list, err := app.models.Lists.Get(r.Context(), id, userModel.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// The stream lives much longer than the server WriteTimeout, so the deadline is lifted for this response only.
+	var rc = http.NewResponseController(w)
+	err = rc.SetWriteDeadline(time.Time{})
+	if err != nil && !errors.Is(err, http.ErrNotSupported) {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var stream, unsubscribe = app.events.Subscribe(list.ID)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err = rc.Flush(); err != nil {
+		app.logger.PrintError(err, nil)
+		return
+	}
+
+	var heartbeat = time.NewTicker(eventsHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-stream:
+			if !ok {
+				return
+			}
+			_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Name, event.Data)
+		case <-heartbeat.C:
+			_, err = fmt.Fprint(w, ": ping\n\n")
+		}
+		if err == nil {
+			err = rc.Flush()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (app *application) publishItemEvent(name string, item *data.Item) {
+	payload, err := jsonapi.Marshal(item)
+	if err != nil {
+		app.logger.PrintError(err, nil)
+		return
+	}
+	app.publishEvent(item.ListId, name, payload)
+}
+
+func (app *application) publishItemDeletedEvent(item *data.Item) {
+	var payload = &jsonapi.OnePayload{Data: &jsonapi.Node{
+		Type: data.ItemsType,
+		ID:   strconv.FormatInt(item.ID, 10),
+	}}
+	app.publishEvent(item.ListId, events.ItemDeleted, payload)
+}
+
+// publishListEvent notifies about changes touching many items at once, clients are expected to refetch the list items.
+func (app *application) publishListEvent(name string, listId int64) {
+	var payload = &jsonapi.OnePayload{Data: &jsonapi.Node{
+		Type: ListType,
+		ID:   strconv.FormatInt(listId, 10),
+	}}
+	app.publishEvent(listId, name, payload)
+}
+
+func (app *application) publishEvent(listId int64, name string, payload jsonapi.Payloader) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		app.logger.PrintError(err, nil)
+		return
+	}
+	app.events.Publish(listId, events.Event{Name: name, Data: body})
+}
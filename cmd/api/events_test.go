@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"easylist/internal/events"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestListEventsStreamReceivesCreatedItem(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	item, token := createItem(app, t)
+
+	req := generateRequestWithToken(ts.URL+"/api/v1/lists/"+strconv.Itoa(int(item.ListId))+"/events", token.Plaintext, "", nil)
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("want %d status code; got %d", http.StatusOK, resp.StatusCode)
+	}
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Errorf("want Content-Type to be text/event-stream, got %s", resp.Header.Get("Content-Type"))
+	}
+
+	var itemData = []byte(`{
+	  "data": {
+		"type": "items",
+		"attributes": {
+		  "name": "Milk",
+		  "quantity": 1,
+		  "list_id": ` + strconv.Itoa(int(item.ListId)) + `
+		}
+	  }
+	}`)
+	createReq := generateRequestWithToken(ts.URL+"/api/v1/items", token.Plaintext, "POST", bytes.NewBuffer(itemData))
+	createResp, err := ts.Client().Do(createReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	createResp.Body.Close()
+
+	var received = make(chan string, 1)
+	go func() {
+		var scanner = bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			if strings.HasPrefix(scanner.Text(), "event: ") {
+				received <- strings.TrimPrefix(scanner.Text(), "event: ")
+				return
+			}
+		}
+	}()
+
+	select {
+	case name := <-received:
+		if name != events.ItemCreated {
+			t.Errorf("want event %s, got %s", events.ItemCreated, name)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("want item.created event, got nothing")
+	}
+}
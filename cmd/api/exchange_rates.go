@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"easylist/internal/money"
+	"easylist/internal/validator"
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+// defaultCurrency is the currency of new users who did not choose one.
+func (app *application) defaultCurrency() string {
+	if money.ValidCurrency(app.config.Currency.Default) {
+		return app.config.Currency.Default
+	}
+	return money.DefaultCurrency
+}
+
+func (app *application) indexExchangeRatesHandler(w http.ResponseWriter, r *http.Request) {
+	rates, err := app.models.ExchangeRates.GetAll(r.Context())
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, rates, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// replaceExchangeRatesHandler swaps all rates for the ones in the body, which uses the same format as the rates file.
+func (app *application) replaceExchangeRatesHandler(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 1_048_576)
+
+	rates, err := money.ParseRates(r.Body)
+	if err != nil {
+		if errors.Is(err, money.ErrInvalidRates) {
+			var v = validator.New()
+			v.AddError("rates", "must contain a valid base currency and positive rates keyed by ISO 4217 codes")
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+		app.badRequestResponse(w, r, "replaceExchangeRatesHandler", err)
+		return
+	}
+
+	err = app.models.ExchangeRates.Replace(r.Context(), rates)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	stored, err := app.models.ExchangeRates.GetAll(r.Context())
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, stored, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// loadExchangeRatesFile replaces the stored rates with the ones from the configured file, nothing happens without one.
+func (app *application) loadExchangeRatesFile(ctx context.Context) error {
+	if app.config.Currency.RatesFile == "" {
+		return nil
+	}
+
+	file, err := os.Open(app.config.Currency.RatesFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	rates, err := money.ParseRates(file)
+	if err != nil {
+		return err
+	}
+
+	err = app.models.ExchangeRates.Replace(ctx, rates)
+	if err != nil {
+		return err
+	}
+	app.logger.PrintInfo("exchange rates loaded", map[string]string{
+		"file":  app.config.Currency.RatesFile,
+		"rates": strconv.Itoa(len(rates)),
+	})
+	return nil
+}
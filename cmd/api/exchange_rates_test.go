@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"easylist/internal/data"
+	"easylist/internal/money"
+	"net/http"
+	"testing"
+)
+
+func TestListTotalsAreConvertedIntoUserCurrency(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	err := app.models.ExchangeRates.Replace(context.Background(), money.Rates{"USD": 1, "EUR": 0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	item, _ := createItem(app, t)
+
+	var euro = data.Item{ListId: item.ListId, UserId: item.UserId, Price: 100, Currency: "EUR"}
+	err = createTestItem(app, &euro)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var unknown = data.Item{ListId: item.ListId, UserId: item.UserId, Price: 500, Currency: "GBP"}
+	err = createTestItem(app, &unknown)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := app.models.Lists.Get(context.Background(), item.ListId, item.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.Currency != "USD" {
+		t.Errorf("want the totals in the currency of the user, got %s", list.Currency)
+	}
+	// 78 USD cents of the default item and 100 EUR cents worth 200 USD cents, GBP has no rate
+	if list.Total != 278 {
+		t.Errorf("want total 278, got %d", list.Total)
+	}
+}
+
+func TestNewItemGetsUserCurrency(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	item, _ := createItem(app, t)
+	if item.Currency != "USD" {
+		t.Errorf("want the item to get the currency of the user, got %q", item.Currency)
+	}
+}
+
+func TestReplaceExchangeRatesRequiresPermission(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	user, token, err := createTestUserWithToken(t, app, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var body = []byte(`{"base": "EUR", "rates": {"USD": 1.08}}`)
+	req := generateRequestWithToken(ts.URL+"/api/v1/exchange-rates", token.Plaintext, "PUT", bytes.NewBuffer(body))
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("want %d status code without rates:write; got %d", http.StatusForbidden, resp.StatusCode)
+	}
+
+	err = app.models.Permissions.AddForUser(context.Background(), user.ID, "rates:write")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req = generateRequestWithToken(ts.URL+"/api/v1/exchange-rates", token.Plaintext, "PUT", bytes.NewBuffer(body))
+	resp, err = ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("want %d status code; got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	rates, err := app.models.ExchangeRates.GetAll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := rates.Rates(); got["EUR"] != 1 || got["USD"] != 1.08 {
+		t.Errorf("want the stored rates to be replaced, got %v", got)
+	}
+}
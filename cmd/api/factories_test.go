@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"easylist/internal/data"
 	"testing"
@@ -26,16 +27,16 @@ func createTestUserWithToken(t *testing.T, app *application, email string) (*dat
 		return user, token, err
 	}
 
-	err = app.models.Users.Insert(user)
+	err = app.models.Users.Insert(context.Background(), user)
 	if err != nil {
 		return user, token, err
 	}
 
-	err = app.models.Permissions.AddForUser(user.ID, "folders:read", "folders:write", "lists:write", "lists:read", "items:read", "items:write")
+	err = app.models.Permissions.AddForUser(context.Background(), user.ID, "folders:read", "folders:write", "lists:write", "lists:read", "items:read", "items:write")
 	if err != nil {
 		return user, token, err
 	}
-	token, err = app.models.Tokens.New(user.ID, 24*time.Hour*90, data.ScopeAuthentication)
+	token, err = app.models.Tokens.New(context.Background(), user.ID, 24*time.Hour*90, data.ScopeAuthentication)
 	if err != nil {
 		return user, token, err
 	}
@@ -61,7 +62,7 @@ func createTestFolder(app *application, userId int64, name string, order int32)
 		CreatedAt: time.Time{},
 		UpdatedAt: time.Time{},
 	}
-	var err = app.models.Folders.Insert(&folder)
+	var err = app.models.Folders.Insert(context.Background(), &folder)
 	if err != nil {
 		return &folder, err
 	}
@@ -87,7 +88,7 @@ func createTestList(app *application, list *data.List) error {
 	if list.UserId == 0 {
 		list.UserId = 1
 	}
-	var err = app.models.Lists.Insert(list)
+	var err = app.models.Lists.Insert(context.Background(), list)
 	if err != nil {
 		return err
 	}
@@ -113,9 +114,26 @@ func createTestItem(app *application, item *data.Item) error {
 	if item.Order == 0 {
 		item.Order = 1
 	}
-	var err = app.models.Items.Insert(item)
+	var err = app.models.Items.Insert(context.Background(), item)
 	if err != nil {
 		return err
 	}
 	return nil
 }
+
+func createTestMember(app *application, listId int64, userId int64, role string, isAccepted bool) (*data.Member, error) {
+	if role == "" {
+		role = data.RoleViewer
+	}
+	var member = data.Member{
+		ListId:     listId,
+		UserId:     userId,
+		Role:       role,
+		IsAccepted: isAccepted,
+	}
+	var err = app.models.Members.Insert(context.Background(), &member)
+	if err != nil {
+		return &member, err
+	}
+	return &member, nil
+}
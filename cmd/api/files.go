@@ -0,0 +1,396 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"easylist/internal/data"
+	"easylist/internal/events"
+	"easylist/internal/images"
+	"easylist/internal/storage"
+	"easylist/internal/validator"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/jsonapi"
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultFileUrlTtl = 15 * time.Minute
+
+// openStorage creates the store configured in the storage section, files are kept in the storage directory by default.
+func openStorage(cfg config) (storage.Store, error) {
+	switch cfg.Storage.Driver {
+	case "", "local":
+		var path = cfg.Storage.Path
+		if path == "" {
+			path = "storage"
+		}
+		var secret = []byte(cfg.Storage.UrlSecret)
+		if len(secret) == 0 {
+			// without a configured secret the signed urls stop working after a restart
+			secret = make([]byte, 32)
+			if _, err := rand.Read(secret); err != nil {
+				return nil, err
+			}
+		}
+		return storage.NewLocal(path, cfg.Domain+"/files", secret), nil
+	case "s3":
+		var s3 = cfg.Storage.S3
+		if s3.Endpoint == "" || s3.Bucket == "" {
+			return nil, errors.New("storage: s3 endpoint and bucket must be configured")
+		}
+		return storage.NewS3(s3.Endpoint, s3.Region, s3.Bucket, s3.AccessKey, s3.SecretKey), nil
+	}
+	return nil, fmt.Errorf("storage: unknown driver %q", cfg.Storage.Driver)
+}
+
+func (app *application) storageLimits() storage.Limits {
+	return storage.Limits{MaxSize: app.config.Storage.MaxSize, AllowedTypes: app.config.Storage.AllowedTypes}
+}
+
+// fileUrlTtl is how long signed download urls stay valid.
+func (app *application) fileUrlTtl() time.Duration {
+	ttl, err := time.ParseDuration(app.config.Storage.UrlTtl)
+	if err != nil || ttl <= 0 {
+		return defaultFileUrlTtl
+	}
+	return ttl
+}
+
+// multipartOverhead is the room left in upload requests for the multipart boundaries and headers.
+const multipartOverhead = 64 << 10
+
+// saveImage checks the uploaded image against the limits, strips its metadata and stores it together with a thumbnail.
+// It returns the key of the image, the key of the thumbnail is derived from it by thumbnailKey.
+func (app *application) saveImage(ctx context.Context, content []byte, userId int64) (string, error) {
+	_, err := app.storageLimits().Check(content)
+	if err != nil {
+		return "", err
+	}
+	original, thumbnail, err := images.Process(content, app.config.Storage.ThumbnailSize)
+	if err != nil {
+		return "", err
+	}
+
+	var key = fmt.Sprintf("covers/%d/%s%s", userId, uuid.NewString(), storage.Extension(original.ContentType))
+	err = app.storage.Put(ctx, key, bytes.NewReader(original.Content), int64(len(original.Content)), original.ContentType)
+	if err != nil {
+		return "", err
+	}
+	err = app.storage.Put(ctx, thumbnailKey(key), bytes.NewReader(thumbnail.Content), int64(len(thumbnail.Content)), thumbnail.ContentType)
+	if err != nil {
+		app.deleteFiles(app.baseContext(), []string{key})
+		return "", err
+	}
+	return key, nil
+}
+
+// thumbnailKey is where the thumbnail of the file is kept, covers/1/photo.jpg has covers/1/photo_thumb.jpg.
+func thumbnailKey(key string) string {
+	var ext = path.Ext(key)
+	return strings.TrimSuffix(key, ext) + "_thumb" + ext
+}
+
+// itemFiles lists the stored files of an item file, which are the file itself and its thumbnail.
+func itemFiles(key string) []string {
+	if key == "" {
+		return nil
+	}
+	return []string{key, thumbnailKey(key)}
+}
+
+// checkFileError turns the errors of saveImage caused by the uploaded content into validation errors of the field,
+// it returns false for the errors which are not the client's fault.
+func (app *application) checkFileError(v *validator.Validator, field string, err error) bool {
+	var corruptInput base64.CorruptInputError
+	var limits = app.storageLimits()
+	switch {
+	case errors.As(err, &corruptInput):
+		v.AddError(field, "must be a base64 encoded file")
+	case errors.Is(err, storage.ErrTooLarge):
+		v.AddError(field, fmt.Sprintf("must not be larger than %d bytes", limits.MaxBytes()))
+	case errors.Is(err, storage.ErrUnsupportedType):
+		v.AddError(field, "must be one of "+strings.Join(limits.Types(), ", "))
+	case errors.Is(err, images.ErrInvalidImage):
+		v.AddError(field, "must be a valid JPEG, PNG or WebP image")
+	case errors.Is(err, images.ErrTooManyPixels):
+		v.AddError(field, fmt.Sprintf("must not have more than %d pixels", images.MaxPixels))
+	default:
+		return false
+	}
+	return true
+}
+
+// itemFile finds the item of the url the current user can read, and makes sure it has a file.
+func (app *application) itemFile(w http.ResponseWriter, r *http.Request) (*data.Item, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+	var userModel = app.contextGetUser(r)
+	item, err := app.models.Items.Get(r.Context(), id, userModel.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+	if item.File == "" {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+	return item, true
+}
+
+// editableItem finds the item of the url, and makes sure the current user can change it.
+func (app *application) editableItem(w http.ResponseWriter, r *http.Request) (*data.Item, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+	var userModel = app.contextGetUser(r)
+	item, err := app.models.Items.Get(r.Context(), id, userModel.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+	list, err := app.models.Lists.Get(r.Context(), item.ListId, userModel.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notPermittedResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+	if !list.CanEdit() {
+		app.notPermittedResponse(w, r)
+		return nil, false
+	}
+	return item, true
+}
+
+// showItemFileHandler sends the file of the item to the authenticated user.
+func (app *application) showItemFileHandler(w http.ResponseWriter, r *http.Request) {
+	item, ok := app.itemFile(w, r)
+	if !ok {
+		return
+	}
+	app.sendFile(w, r, item.File)
+}
+
+// showItemThumbnailHandler sends the thumbnail of the item file, files uploaded before thumbnails existed are sent as they are.
+func (app *application) showItemThumbnailHandler(w http.ResponseWriter, r *http.Request) {
+	item, ok := app.itemFile(w, r)
+	if !ok {
+		return
+	}
+	body, object, err := app.storage.Get(r.Context(), thumbnailKey(item.File))
+	if errors.Is(err, storage.ErrNotFound) {
+		app.sendFile(w, r, item.File)
+		return
+	}
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	app.writeFile(w, r, body, object)
+}
+
+// showItemFileUrlHandler returns signed urls of the file and its thumbnail, which can be used without the token until they expire.
+func (app *application) showItemFileUrlHandler(w http.ResponseWriter, r *http.Request) {
+	item, ok := app.itemFile(w, r)
+	if !ok {
+		return
+	}
+
+	var ttl = app.fileUrlTtl()
+	link, err := app.storage.URL(r.Context(), item.File, ttl)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	thumbnailLink, err := app.storage.URL(r.Context(), thumbnailKey(item.File), ttl)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	writeHeaders(w, http.StatusOK, nil)
+	err = json.NewEncoder(w).Encode(jsonapi.OnePayload{Meta: &jsonapi.Meta{
+		"url":           link,
+		"thumbnail_url": thumbnailLink,
+		"expires_at":    time.Now().Add(ttl).UTC().Format(time.RFC3339),
+	}})
+	if err != nil {
+		app.logError(r, err)
+	}
+}
+
+// uploadItemFileHandler replaces the image of the item with the one sent in the file field of a multipart form.
+func (app *application) uploadItemFileHandler(w http.ResponseWriter, r *http.Request) {
+	item, ok := app.editableItem(w, r)
+	if !ok {
+		return
+	}
+	var v = validator.New()
+	var limits = app.storageLimits()
+
+	r.Body = http.MaxBytesReader(w, r.Body, limits.MaxBytes()+multipartOverhead)
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxBytesError):
+			v.AddError("file", fmt.Sprintf("must not be larger than %d bytes", limits.MaxBytes()))
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, http.ErrMissingFile):
+			v.AddError("file", "must be provided")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.badRequestResponse(w, r, "uploadItemFileHandler", err)
+		}
+		return
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(io.LimitReader(file, limits.MaxBytes()+1))
+	if err != nil {
+		app.badRequestResponse(w, r, "uploadItemFileHandler", err)
+		return
+	}
+
+	var userModel = app.contextGetUser(r)
+	key, err := app.saveImage(r.Context(), content, userModel.ID)
+	if err != nil {
+		if app.checkFileError(v, "file", err) {
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.replaceItemFile(w, r, item, key)
+}
+
+// deleteItemFileHandler removes the image of the item.
+func (app *application) deleteItemFileHandler(w http.ResponseWriter, r *http.Request) {
+	item, ok := app.editableItem(w, r)
+	if !ok {
+		return
+	}
+	if item.File == "" {
+		app.notFoundResponse(w, r)
+		return
+	}
+	app.replaceItemFile(w, r, item, "")
+}
+
+// replaceItemFile saves the new file of the item and removes the old one, the new file is removed if the item can not be saved.
+func (app *application) replaceItemFile(w http.ResponseWriter, r *http.Request, item *data.Item, key string) {
+	var userModel = app.contextGetUser(r)
+	var oldFile = item.File
+	item.File = key
+
+	err := app.models.Items.Update(r.Context(), item, item.Order, userModel.ID)
+	if err != nil {
+		app.deleteFiles(app.baseContext(), itemFiles(key))
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r, "replaceItemFile")
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	app.background(func() {
+		app.deleteFiles(app.baseContext(), itemFiles(oldFile))
+	})
+	item.SetFileUrls()
+	app.publishItemEvent(events.ItemUpdated, item)
+
+	err = app.writeJSON(w, http.StatusOK, item, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// signedFileHandler sends files of the local store to the holders of urls signed by it.
+func (app *application) signedFileHandler(w http.ResponseWriter, r *http.Request) {
+	local, ok := app.storage.(*storage.Local)
+	if !ok {
+		app.notFoundResponse(w, r)
+		return
+	}
+	var params = httprouter.ParamsFromContext(r.Context())
+	var key = strings.TrimPrefix(params.ByName("key"), "/")
+	var qs = r.URL.Query()
+
+	err := local.Verify(key, qs.Get("expires"), qs.Get("signature"))
+	if err != nil {
+		app.notPermittedResponse(w, r)
+		return
+	}
+	app.sendFile(w, r, key)
+}
+
+func (app *application) sendFile(w http.ResponseWriter, r *http.Request, key string) {
+	body, object, err := app.storage.Get(r.Context(), key)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrNotFound), errors.Is(err, storage.ErrInvalidKey):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	app.writeFile(w, r, body, object)
+}
+
+func (app *application) writeFile(w http.ResponseWriter, r *http.Request, body io.ReadCloser, object *storage.Object) {
+	defer body.Close()
+
+	w.Header().Set("Content-Type", object.ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	if object.Size >= 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(object.Size, 10))
+	}
+	w.WriteHeader(http.StatusOK)
+	_, err := io.Copy(w, body)
+	if err != nil {
+		app.logError(r, err)
+	}
+}
+
+// deleteFiles removes the files of purged or replaced items, a failure is only logged as the records are already gone.
+func (app *application) deleteFiles(ctx context.Context, keys []string) {
+	for _, key := range keys {
+		err := app.storage.Delete(ctx, key)
+		if err != nil {
+			app.logger.PrintError(err, map[string]string{"file": key})
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"easylist/internal/storage"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// 1x1 png image
+const testImage = "iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mNkYPhfDwAChwGA60e6kgAAAABJRU5ErkJggg=="
+
+// storedFile reads the file from the storage, uploaded images are stored re-encoded without their metadata.
+func storedFile(t *testing.T, app *application, key string) []byte {
+	body, _, err := app.storage.Get(context.Background(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer body.Close()
+	content, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return content
+}
+
+func TestSignedFileHandler(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	key, err := app.saveFile(context.Background(), testImage, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	link, err := app.storage.URL(context.Background(), key, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := ts.Client().Get(ts.URL + signed.RequestURI())
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("want %d status code; got %d", http.StatusOK, resp.StatusCode)
+	}
+	if resp.Header.Get("Content-Type") != "image/png" {
+		t.Errorf("want image/png content type; got %s", resp.Header.Get("Content-Type"))
+	}
+	if !bytes.Equal(body, storedFile(t, app, key)) {
+		t.Error("downloaded file differs from the stored one")
+	}
+
+	var query = signed.Query()
+	query.Set("signature", "forged")
+	resp, err = ts.Client().Get(ts.URL + signed.Path + "?" + query.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("want %d status code for a forged signature; got %d", http.StatusForbidden, resp.StatusCode)
+	}
+
+	resp, err = ts.Client().Get(ts.URL + "/storage/" + key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("want %d status code for the old public directory; got %d", http.StatusNotFound, resp.StatusCode)
+	}
+}
+
+func TestShowItemFileHandler(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	item, token := createItem(app, t)
+	key, err := app.saveFile(context.Background(), testImage, item.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	item.File = key
+	err = app.models.Items.Update(context.Background(), &item, item.Order, item.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fileUrl = ts.URL + "/api/v1/items/" + strconv.Itoa(int(item.ID)) + "/file"
+
+	resp, err := ts.Client().Do(generateRequestWithToken(fileUrl, "", "", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("want %d status code without a token; got %d", http.StatusUnauthorized, resp.StatusCode)
+	}
+
+	resp, err = ts.Client().Do(generateRequestWithToken(fileUrl, token.Plaintext, "", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !bytes.Equal(body, storedFile(t, app, key)) {
+		t.Errorf("want the file with %d status code; got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	resp, err = ts.Client().Do(generateRequestWithToken(fileUrl+"-url", token.Plaintext, "", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("want %d status code; got %d", http.StatusOK, resp.StatusCode)
+	}
+	var check struct {
+		Meta struct {
+			Url string `json:"url"`
+		} `json:"meta"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&check)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := url.Parse(check.Meta.Url)
+	if err != nil || signed.Query().Get("signature") == "" {
+		t.Errorf("want a signed url; got %q", check.Meta.Url)
+	}
+}
+
+func TestUploadItemFileHandler(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	item, token := createItem(app, t)
+	var fileUrl = ts.URL + "/api/v1/items/" + strconv.Itoa(int(item.ID)) + "/file"
+
+	var upload = func(field string, content []byte) *http.Response {
+		var body bytes.Buffer
+		var form = multipart.NewWriter(&body)
+		part, err := form.CreateFormFile(field, "photo.png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write(content)
+		form.Close()
+
+		req := generateRequestWithToken(fileUrl, token.Plaintext, http.MethodPut, &body)
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		resp, err := ts.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	var image, _ = base64.StdEncoding.DecodeString(testImage)
+	resp := upload("file", image)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("want %d status code; got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	updated, err := app.models.Items.Get(context.Background(), item.ID, item.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.File == "" || updated.ThumbnailUrl == "" {
+		t.Fatalf("want the item to have a file and a thumbnail url, got %+v", updated)
+	}
+	_, _, err = app.storage.Get(context.Background(), thumbnailKey(updated.File))
+	if err != nil {
+		t.Errorf("want a thumbnail to be stored: %v", err)
+	}
+
+	resp = upload("file", []byte("This is a test test file"))
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("want %d status code for a text file; got %d", http.StatusUnprocessableEntity, resp.StatusCode)
+	}
+
+	resp = upload("photo", image)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("want %d status code without the file field; got %d", http.StatusUnprocessableEntity, resp.StatusCode)
+	}
+
+	req := generateRequestWithToken(fileUrl, token.Plaintext, http.MethodDelete, nil)
+	resp, err = ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("want %d status code; got %d", http.StatusOK, resp.StatusCode)
+	}
+	app.wg.Wait()
+	_, _, err = app.storage.Get(context.Background(), updated.File)
+	if !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("want the removed file to be deleted from the storage, got %v", err)
+	}
+}
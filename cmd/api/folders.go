@@ -42,7 +42,7 @@ func (app *application) createFolderHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	var err = app.models.Folders.Insert(folder)
+	var err = app.models.Folders.Insert(r.Context(), folder)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -66,6 +66,8 @@ func (app *application) indexFoldersHandler(w http.ResponseWriter, r *http.Reque
 	input.Name = app.readString(qs, "filter[name]", "")
 	input.Filters.Page = app.readInt(qs, jsonapi.QueryParamPageNumber, 1, v)
 	input.Filters.Size = app.readInt(qs, jsonapi.QueryParamPageSize, 20, v)
+	input.Filters.After = app.readOptionalString(qs, "page[after]")
+	input.Filters.Before = app.readOptionalString(qs, "page[before]")
 	input.Filters.Sort = app.readString(qs, "sort", "order")
 	input.Filters.Includes = app.readCSV(qs, "include", []string{})
 
@@ -76,7 +78,7 @@ func (app *application) indexFoldersHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	folders, metadata, err := app.models.Folders.GetAll(input.Name, userModel.ID, input.Filters)
+	folders, metadata, err := app.models.Folders.GetAll(r.Context(), input.Name, userModel.ID, input.Filters)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -95,7 +97,7 @@ func (app *application) showFolderByIdHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 	var userModel = app.contextGetUser(r)
-	folder, err := app.models.Folders.Get(id, userModel.ID)
+	folder, err := app.models.Folders.Get(r.Context(), id, userModel.ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -109,7 +111,7 @@ func (app *application) showFolderByIdHandler(w http.ResponseWriter, r *http.Req
 	var qs = r.URL.Query()
 	var includes = app.readCSV(qs, "include", []string{})
 	if len(includes) > 0 && data.Contains(includes, "lists") {
-		lists, _, err := app.models.Lists.GetAll(folder.ID, "", userModel.ID, data.GetDefaultFilterInstance())
+		lists, _, err := app.models.Lists.GetAll(r.Context(), folder.ID, userModel.ID, data.ListFilters{}, data.GetDefaultFilterInstance())
 
 		if err != nil {
 			app.serverErrorResponse(w, r, err)
@@ -132,7 +134,7 @@ func (app *application) updateFolderHandler(w http.ResponseWriter, r *http.Reque
 
 	var userModel = app.contextGetUser(r)
 
-	folder, err := app.models.Folders.Get(id, userModel.ID)
+	folder, err := app.models.Folders.Get(r.Context(), id, userModel.ID)
 	var oldOrder = folder.Order
 
 	if err != nil {
@@ -169,7 +171,7 @@ func (app *application) updateFolderHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	err = app.models.Folders.Update(folder, oldOrder)
+	err = app.models.Folders.Update(r.Context(), folder, oldOrder)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrEditConflict):
@@ -202,7 +204,7 @@ func (app *application) deleteFolderHandler(w http.ResponseWriter, r *http.Reque
 
 	var userModel = app.contextGetUser(r)
 
-	err = app.models.Folders.Delete(id, userModel.ID)
+	err = app.models.Folders.Delete(r.Context(), id, userModel.ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"easylist/internal/data"
 	"easylist/internal/validator"
 	"encoding/base64"
@@ -8,18 +9,17 @@ import (
 	"errors"
 	"fmt"
 	"github.com/google/jsonapi"
-	"github.com/google/uuid"
 	"github.com/julienschmidt/httprouter"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
-	"os"
 	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 )
 
-const StoragePath = "storage/"
-
 func (app *application) readIDParam(r *http.Request) (int64, error) {
 	var params = httprouter.ParamsFromContext(r.Context())
 	id, err := strconv.ParseInt(params.ByName("id"), 10, 64)
@@ -60,6 +60,11 @@ func writeAndChangeJson[T data.ComplexModels](w http.ResponseWriter, status int,
 		if metadata.ParentId != 0 {
 			relationPrefix = metadata.ParentName + "/" + strconv.Itoa(int(metadata.ParentId)) + "/"
 		}
+		if metadata.UsesCursor {
+			manyPayload.Links = cursorLinks(domainName+"/api/v1/"+relationPrefix+typeData, metadata)
+			delete(*manyPayload.Meta, "total")
+			return json.NewEncoder(w).Encode(manyPayload)
+		}
 		manyPayload.Links = &jsonapi.Links{
 			jsonapi.KeyFirstPage:    domainName + "/api/v1/" + relationPrefix + typeData + "?" + jsonapi.QueryParamPageNumber + "=" + strconv.Itoa(metadata.FirstPage) + "&" + jsonapi.QueryParamPageSize + "=" + strconv.Itoa(metadata.PageSize),
 			jsonapi.KeyPreviousPage: domainName + "/api/v1/" + relationPrefix + typeData + "?" + jsonapi.QueryParamPageNumber + "=" + strconv.Itoa(metadata.PrevPage) + "&" + jsonapi.QueryParamPageSize + "=" + strconv.Itoa(metadata.PageSize),
@@ -78,7 +83,36 @@ func writeAndChangeJson[T data.ComplexModels](w http.ResponseWriter, status int,
 	return jsonapi.ErrInvalidType
 }
 
+// cursorLinks builds the links of a page selected by a cursor, the cursors are only valid with the same sort.
+// There is no link to the last page, the collection is not counted.
+func cursorLinks(base string, metadata data.Metadata) *jsonapi.Links {
+	var link = func(key string, cursor string) string {
+		var qs = url.Values{}
+		qs.Set(key, cursor)
+		qs.Set(jsonapi.QueryParamPageSize, strconv.Itoa(metadata.PageSize))
+		qs.Set("sort", metadata.Sort)
+		return base + "?" + qs.Encode()
+	}
+	var links = jsonapi.Links{
+		jsonapi.KeyFirstPage:    link("page[after]", ""),
+		jsonapi.KeyPreviousPage: nil,
+		jsonapi.KeyNextPage:     nil,
+	}
+	if metadata.PrevCursor != "" {
+		links[jsonapi.KeyPreviousPage] = link("page[before]", metadata.PrevCursor)
+	}
+	if metadata.NextCursor != "" {
+		links[jsonapi.KeyNextPage] = link("page[after]", metadata.NextCursor)
+	}
+	return &links
+}
+
 func readJSON[T ComplexInputModels](w http.ResponseWriter, r *http.Request, dst *Input[T]) error {
+	return decodeJSON(w, r, dst)
+}
+
+// decodeJSON reads a single JSON value from the request body into dst, it is used for inputs which are not Input[T] envelopes.
+func decodeJSON(w http.ResponseWriter, r *http.Request, dst any) error {
 	var maxBytes = 1_048_576
 	r.Body = http.MaxBytesReader(w, r.Body, int64(maxBytes))
 	var dec = json.NewDecoder(r.Body)
@@ -136,25 +170,15 @@ func (app *application) background(fn func()) {
 	}()
 }
 
-func (app *application) saveFile(file string, userId int64) (string, error) {
+// saveFile stores the base64 encoded image of the user, see saveImage.
+func (app *application) saveFile(ctx context.Context, file string, userId int64) (string, error) {
 	if len(file) > 0 {
 		// we have a photo
 		decoded, err := base64.StdEncoding.DecodeString(file)
 		if err != nil {
 			return "", err
 		}
-
-		var fileUuid = uuid.NewString()
-		var fileName = fmt.Sprintf("%scovers/%d/%s.jpg", StoragePath, userId, fileUuid)
-		err = os.MkdirAll(fmt.Sprintf("%scovers/%d/", StoragePath, userId), os.ModePerm)
-		if err != nil {
-			return "", err
-		}
-		// write image to /storage/covers
-		if err := os.WriteFile(fileName, decoded, 0666); err != nil {
-			return "", err
-		}
-		return fileName, nil
+		return app.saveImage(ctx, decoded, userId)
 	}
 	return "", nil
 }
@@ -215,3 +239,82 @@ func (app *application) readBool(qs url.Values, key string, defaultValue bool, v
 	v.AddError(key, "must be an integer value")
 	return defaultValue
 }
+
+// readDateRange reads the days filter[name_from] and filter[name_to], e.g. filter[created_from]=2024-01-31.
+func (app *application) readDateRange(qs url.Values, name string, v *validator.Validator) data.DateRange {
+	return data.DateRange{
+		From: app.readDate(qs, "filter["+name+"_from]", time.Time{}, v),
+		To:   app.readDate(qs, "filter["+name+"_to]", time.Time{}, v),
+	}
+}
+
+// readOptionalString returns nil when the parameter is missing, unlike readString it keeps an empty value.
+func (app *application) readOptionalString(qs url.Values, key string) *string {
+	if !qs.Has(key) {
+		return nil
+	}
+	var s = qs.Get(key)
+	return &s
+}
+
+// readOptionalBool is readBool for the filters which are not applied when the parameter is missing.
+func (app *application) readOptionalBool(qs url.Values, key string, v *validator.Validator) *bool {
+	if qs.Get(key) == "" {
+		return nil
+	}
+	var b = app.readBool(qs, key, false, v)
+	return &b
+}
+
+// readOptionalInt64 is readInt for the filters which are not applied when the parameter is missing.
+func (app *application) readOptionalInt64(qs url.Values, key string, v *validator.Validator) *int64 {
+	var s = qs.Get(key)
+
+	if s == "" {
+		return nil
+	}
+
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		v.AddError(key, "must be an integer value")
+		return nil
+	}
+
+	return &i
+}
+
+func (app *application) readDate(qs url.Values, key string, defaultValue time.Time, v *validator.Validator) time.Time {
+	var s = qs.Get(key)
+
+	if s == "" {
+		return defaultValue
+	}
+
+	date, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		v.AddError(key, "must be a date in YYYY-MM-DD format")
+		return defaultValue
+	}
+
+	return date
+}
+
+// clientIp returns the address the request came from without the port.
+func clientIp(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
+
+// truncate cuts the string to at most max bytes without splitting a character.
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
+	}
+	return s[:max]
+}
@@ -2,12 +2,16 @@ package main
 
 import (
 	"context"
+	"easylist/internal/storage"
 	"easylist/internal/validator"
+	"errors"
 	"github.com/julienschmidt/httprouter"
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -54,28 +58,33 @@ func TestReadIDParam(t *testing.T) {
 }
 
 func TestSaveFile(t *testing.T) {
-	app := &application{}
+	var root = t.TempDir()
+	app := &application{storage: storage.NewLocal(root, "http://127.0.0.1/files", []byte("secret"))}
 
-	file := "VGhpcyBpcyBhIHRlc3QgdGVzdCBmaWxl"
+	// 1x1 png image
+	file := "iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mNkYPhfDwAChwGA60e6kgAAAABJRU5ErkJggg=="
 	userId := int64(123)
 
 	// Test with valid file
-	actualFileName, err := app.saveFile(file, userId)
+	actualFileName, err := app.saveFile(context.Background(), file, userId)
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
+	if !strings.HasPrefix(actualFileName, "covers/123/") || !strings.HasSuffix(actualFileName, ".png") {
+		t.Errorf("unexpected file name: %s", actualFileName)
+	}
 
-	_, err = os.Stat(actualFileName)
+	_, err = os.Stat(filepath.Join(root, actualFileName))
 	if os.IsNotExist(err) {
 		t.Errorf("file not created: %s", actualFileName)
 	}
-	err = os.Remove(actualFileName)
-	if err != nil {
-		t.Fatal(err)
+	_, err = os.Stat(filepath.Join(root, thumbnailKey(actualFileName)))
+	if os.IsNotExist(err) {
+		t.Errorf("thumbnail not created: %s", thumbnailKey(actualFileName))
 	}
 
 	// Test with empty file
-	actualFileName, err = app.saveFile("", userId)
+	actualFileName, err = app.saveFile(context.Background(), "", userId)
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -84,13 +93,26 @@ func TestSaveFile(t *testing.T) {
 	}
 
 	// Test with invalid base64 string
-	actualFileName, err = app.saveFile("invalid", userId)
+	actualFileName, err = app.saveFile(context.Background(), "invalid", userId)
 	if err == nil {
 		t.Error("expected error, but got nil")
 	}
 	if actualFileName != "" {
 		t.Errorf("unexpected file name: got %s, want \"\"", actualFileName)
 	}
+
+	// Test with a file which is not an image
+	_, err = app.saveFile(context.Background(), "VGhpcyBpcyBhIHRlc3QgdGVzdCBmaWxl", userId)
+	if !errors.Is(err, storage.ErrUnsupportedType) {
+		t.Errorf("expected ErrUnsupportedType, got %v", err)
+	}
+
+	// Test with a file which exceeds the size limit
+	app.config.Storage.MaxSize = 16
+	_, err = app.saveFile(context.Background(), file, userId)
+	if !errors.Is(err, storage.ErrTooLarge) {
+		t.Errorf("expected ErrTooLarge, got %v", err)
+	}
 }
 
 func TestReadString(t *testing.T) {
@@ -2,19 +2,21 @@ package main
 
 import (
 	"easylist/internal/data"
+	"easylist/internal/events"
 	"easylist/internal/validator"
 	"errors"
 	"fmt"
 	"github.com/google/jsonapi"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 )
 
 const ItemType = "items"
 
 type ItemInput struct {
-	Name string
+	data.ItemFilters
 	data.Filters
 }
 
@@ -22,15 +24,58 @@ func (app *application) NewItemInput(r *http.Request, v *validator.Validator) It
 	var input ItemInput
 	qs := r.URL.Query()
 	input.Name = app.readString(qs, "filter[name]", "")
+	input.IsStarred = app.readOptionalBool(qs, "filter[is_starred]", v)
+	input.IsDone = app.readOptionalBool(qs, "filter[is_done]", v)
+	input.HasFile = app.readOptionalBool(qs, "filter[has_file]", v)
+	input.PriceMin = app.readOptionalInt64(qs, "filter[price_min]", v)
+	input.PriceMax = app.readOptionalInt64(qs, "filter[price_max]", v)
+	input.Currency = strings.ToUpper(app.readString(qs, "filter[currency]", ""))
+	input.QuantityTypes = app.readCSV(qs, "filter[quantity_type]", nil)
+	input.Created = app.readDateRange(qs, "created", v)
+	input.Updated = app.readDateRange(qs, "updated", v)
 	input.Filters.Page = app.readInt(qs, jsonapi.QueryParamPageNumber, 1, v)
 	input.Filters.Size = app.readInt(qs, jsonapi.QueryParamPageSize, 20, v)
+	input.Filters.After = app.readOptionalString(qs, "page[after]")
+	input.Filters.Before = app.readOptionalString(qs, "page[before]")
 	input.Filters.Sort = app.readString(qs, "sort", "order")
 	input.Filters.Includes = app.readCSV(qs, "include", []string{})
+	input.Filters.Group = app.readString(qs, "group", "")
 
 	input.Filters.SortSafelist = []string{"id", "name", "order", "created_at", "updated_at", "quantity", "is_starred", "-id", "-name", "-order", "-created_at", "-updated_at", "-quantity", "-is_starred"}
 	return input
 }
 
+// applyTo copies the plain attributes which were sent by the client, list_id and file need extra checks and are handled by the caller.
+func (attributes ItemAttributes) applyTo(item *data.Item) {
+	if attributes.Name != nil {
+		item.Name = *attributes.Name
+	}
+	if attributes.Description != nil {
+		item.Description = *attributes.Description
+	}
+	if attributes.Quantity != nil {
+		item.Quantity = *attributes.Quantity
+	}
+	if attributes.QuantityType != nil {
+		item.QuantityType = *attributes.QuantityType
+	}
+	if attributes.Price != nil {
+		item.Price = *attributes.Price
+	}
+	if attributes.Currency != nil {
+		item.Currency = *attributes.Currency
+	}
+	if attributes.IsStarred != nil {
+		item.IsStarred = *attributes.IsStarred
+	}
+	if attributes.IsDone != nil {
+		item.IsDone = *attributes.IsDone
+	}
+	if attributes.Order != nil {
+		item.Order = *attributes.Order
+	}
+}
+
 func (app *application) createItemsHandler(w http.ResponseWriter, r *http.Request) {
 	var item = new(data.Item)
 	if err := readJsonApi(r, item); err != nil {
@@ -40,10 +85,13 @@ func (app *application) createItemsHandler(w http.ResponseWriter, r *http.Reques
 
 	var userModel = app.contextGetUser(r)
 
-	_, err := app.models.Lists.Get(item.ListId, userModel.ID)
+	list, err := app.models.Lists.Get(r.Context(), item.ListId, userModel.ID)
 	var v = validator.New()
 	if err != nil {
 		v.AddError("data.attributes.list_id", "Can not find current list id")
+	} else if !list.CanEdit() || !app.accessTokenAllowsList(r, list.ID) {
+		app.notPermittedResponse(w, r)
+		return
 	}
 
 	item.Version = 1
@@ -52,23 +100,30 @@ func (app *application) createItemsHandler(w http.ResponseWriter, r *http.Reques
 	item.CreatedAt = time.Now()
 	item.UpdatedAt = time.Now()
 
-	if data.ValidateItem(v, item); !v.Valid() {
-		app.failedValidationResponse(w, r, v.Errors)
-		return
+	// without a category the item gets the one the user gave to the last item with the same name
+	if item.CategoryId == 0 {
+		item.CategoryId, err = app.models.Categories.Suggest(r.Context(), userModel.ID, item.Name)
+	} else {
+		var found bool
+		found, err = app.checkCategory(r.Context(), item.CategoryId, userModel.ID)
+		v.Check(found, "data.attributes.category_id", "this category does not exists")
 	}
-
-	fileName, err := app.saveFile(item.File, userModel.ID)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
-	item.File = fileName
 
-	err = app.models.Items.Insert(item)
+	if data.ValidateItem(v, item); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Items.Insert(r.Context(), item)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
+	app.publishItemEvent(events.ItemCreated, item)
 
 	var headers = make(http.Header)
 	headers.Set("Location", fmt.Sprintf("%s/api/v1/items/%d", app.config.Domain, item.ID))
@@ -87,19 +142,20 @@ func (app *application) indexItemsHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 	v := validator.New()
-	qs := r.URL.Query()
 
 	var input = app.NewItemInput(r, v)
 
 	var userModel = app.contextGetUser(r)
-	var isStarred = app.readBool(qs, "filter[is_starred]", false, v)
+	v.Check(input.Filters.Group == "" || input.Filters.Group == data.GroupByCategory, "group", "must be category")
+	v.Check(input.Filters.Group == "" || !input.Filters.UsesCursor(), "group", "can't be combined with a cursor")
 
+	data.ValidateItemFilters(v, input.ItemFilters)
 	if data.ValidateFilters(v, input.Filters); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	items, metadata, err := app.models.Items.GetAll(input.Name, userModel.ID, listId, isStarred, input.Filters)
+	items, metadata, err := app.models.Items.GetAll(r.Context(), userModel.ID, listId, input.ItemFilters, input.Filters)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -118,7 +174,7 @@ func (app *application) showItemByIdHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 	var userModel = app.contextGetUser(r)
-	item, err := app.models.Items.Get(id, userModel.ID)
+	item, err := app.models.Items.Get(r.Context(), id, userModel.ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -132,7 +188,7 @@ func (app *application) showItemByIdHandler(w http.ResponseWriter, r *http.Reque
 	var qs = r.URL.Query()
 	var includes = app.readCSV(qs, "include", []string{})
 	if len(includes) > 0 && data.Contains(includes, "list") {
-		listModel, err := app.models.Lists.Get(item.ListId, userModel.ID)
+		listModel, err := app.models.Lists.Get(r.Context(), item.ListId, userModel.ID)
 		if err != nil {
 			switch {
 			case errors.Is(err, data.ErrRecordNotFound):
@@ -160,7 +216,7 @@ func (app *application) updateItemHandler(w http.ResponseWriter, r *http.Request
 
 	var userModel = app.contextGetUser(r)
 
-	item, err := app.models.Items.Get(id, userModel.ID)
+	item, err := app.models.Items.Get(r.Context(), id, userModel.ID)
 	var oldOrder = item.Order
 
 	if err != nil {
@@ -173,7 +229,9 @@ func (app *application) updateItemHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	_, err = app.models.Lists.Get(item.ListId, userModel.ID)
+	var oldListId = item.ListId
+
+	list, err := app.models.Lists.Get(r.Context(), item.ListId, userModel.ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -183,6 +241,10 @@ func (app *application) updateItemHandler(w http.ResponseWriter, r *http.Request
 		}
 		return
 	}
+	if !list.CanEdit() {
+		app.notPermittedResponse(w, r)
+		return
+	}
 	var input = Input[ItemAttributes]{Data: InputAttributes[ItemAttributes]{
 		Type:       "tokens",
 		Attributes: ItemAttributes{},
@@ -199,40 +261,34 @@ func (app *application) updateItemHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	if input.Data.Attributes.Name != nil {
-		item.Name = *input.Data.Attributes.Name
-	}
-	if input.Data.Attributes.ListId != nil {
-		item.ListId = *input.Data.Attributes.ListId
-	}
-	if input.Data.Attributes.Description != nil {
-		item.Description = *input.Data.Attributes.Description
-	}
-	if input.Data.Attributes.Quantity != nil {
-		item.Quantity = *input.Data.Attributes.Quantity
-	}
-	if input.Data.Attributes.QuantityType != nil {
-		item.QuantityType = *input.Data.Attributes.QuantityType
-	}
-	if input.Data.Attributes.Price != nil {
-		item.Price = *input.Data.Attributes.Price
-	}
-	if input.Data.Attributes.IsStarred != nil {
-		item.IsStarred = *input.Data.Attributes.IsStarred
-	}
-	if input.Data.Attributes.IsDone != nil {
-		item.IsDone = *input.Data.Attributes.IsDone
-	}
-	if input.Data.Attributes.Order != nil {
-		item.Order = *input.Data.Attributes.Order
+	input.Data.Attributes.applyTo(item)
+	if input.Data.Attributes.ListId != nil && *input.Data.Attributes.ListId != item.ListId {
+		targetList, err := app.models.Lists.Get(r.Context(), *input.Data.Attributes.ListId, userModel.ID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				v.AddError("data.attributes.list_id", "Can not find current list id")
+				app.failedValidationResponse(w, r, v.Errors)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+		if !targetList.CanEdit() || !app.accessTokenAllowsList(r, targetList.ID) {
+			app.notPermittedResponse(w, r)
+			return
+		}
+		item.ListId = targetList.ID
 	}
-	if input.Data.Attributes.File != nil {
-		fileName, err := app.saveFile(*input.Data.Attributes.File, userModel.ID)
+
+	if input.Data.Attributes.CategoryId != nil && *input.Data.Attributes.CategoryId != item.CategoryId {
+		found, err := app.checkCategory(r.Context(), *input.Data.Attributes.CategoryId, userModel.ID)
 		if err != nil {
 			app.serverErrorResponse(w, r, err)
 			return
 		}
-		item.File = fileName
+		v.Check(found, "data.attributes.category_id", "this category does not exists")
+		item.CategoryId = *input.Data.Attributes.CategoryId
 	}
 
 	v.Check(item.Order > 0, "data.attributes.order", "order should be greater then zero")
@@ -242,8 +298,25 @@ func (app *application) updateItemHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	err = app.models.Items.Update(item, oldOrder)
+	var oldFile = item.File
+	if input.Data.Attributes.File != nil {
+		fileName, err := app.saveFile(r.Context(), *input.Data.Attributes.File, userModel.ID)
+		if err != nil {
+			if app.checkFileError(v, "data.attributes.file", err) {
+				app.failedValidationResponse(w, r, v.Errors)
+				return
+			}
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		item.File = fileName
+	}
+
+	err = app.models.Items.Update(r.Context(), item, oldOrder, userModel.ID)
 	if err != nil {
+		if item.File != oldFile {
+			app.deleteFiles(app.baseContext(), itemFiles(item.File))
+		}
 		switch {
 		case errors.Is(err, data.ErrEditConflict):
 			app.editConflictResponse(w, r, "updateItemHandler")
@@ -252,6 +325,21 @@ func (app *application) updateItemHandler(w http.ResponseWriter, r *http.Request
 		}
 		return
 	}
+	if item.File != oldFile {
+		item.SetFileUrls()
+		app.background(func() {
+			app.deleteFiles(app.baseContext(), itemFiles(oldFile))
+		})
+	}
+	if oldListId != item.ListId {
+		app.publishItemDeletedEvent(&data.Item{ID: item.ID, ListId: oldListId})
+		app.publishItemEvent(events.ItemCreated, item)
+	} else {
+		app.publishItemEvent(events.ItemUpdated, item)
+		if oldOrder != item.Order {
+			app.publishListEvent(events.ItemsReordered, item.ListId)
+		}
+	}
 
 	if r.Header.Get("X-Expected-Version") != "" {
 		if strconv.FormatInt(int64(item.Version), 32) != r.Header.Get("X-Expected-Version") {
@@ -275,7 +363,22 @@ func (app *application) uncrossAllItems(w http.ResponseWriter, r *http.Request)
 
 	var userModel = app.contextGetUser(r)
 
-	err = app.models.Items.MarkAllAsUndone(id, userModel.ID)
+	list, err := app.models.Lists.Get(r.Context(), id, userModel.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if !list.CanEdit() {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	err = app.models.Items.MarkAllAsUndone(r.Context(), id, userModel.ID)
 
 	if err != nil {
 		switch {
@@ -286,6 +389,7 @@ func (app *application) uncrossAllItems(w http.ResponseWriter, r *http.Request)
 		}
 		return
 	}
+	app.publishListEvent(events.ItemsUpdated, id)
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -298,7 +402,18 @@ func (app *application) deleteItemHandler(w http.ResponseWriter, r *http.Request
 
 	var userModel = app.contextGetUser(r)
 
-	err = app.models.Items.Delete(id, userModel.ID)
+	item, err := app.models.Items.Get(r.Context(), id, userModel.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.Items.Delete(r.Context(), id, userModel.ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -308,6 +423,7 @@ func (app *application) deleteItemHandler(w http.ResponseWriter, r *http.Request
 		}
 		return
 	}
+	app.publishItemDeletedEvent(item)
 
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -321,7 +437,22 @@ func (app *application) deleteAllItemsFromListHandler(w http.ResponseWriter, r *
 
 	var userModel = app.contextGetUser(r)
 
-	err = app.models.Items.DeleteFromList(userModel.ID, id, false)
+	list, err := app.models.Lists.Get(r.Context(), id, userModel.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if !list.CanEdit() {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	err = app.models.Items.DeleteFromList(r.Context(), userModel.ID, id, false)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -331,6 +462,7 @@ func (app *application) deleteAllItemsFromListHandler(w http.ResponseWriter, r *
 		}
 		return
 	}
+	app.publishListEvent(events.ItemsDeleted, id)
 
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -344,7 +476,22 @@ func (app *application) deleteDoneItemsFromListHandler(w http.ResponseWriter, r
 
 	var userModel = app.contextGetUser(r)
 
-	err = app.models.Items.DeleteFromList(userModel.ID, id, true)
+	list, err := app.models.Lists.Get(r.Context(), id, userModel.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if !list.CanEdit() {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	err = app.models.Items.DeleteFromList(r.Context(), userModel.ID, id, true)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -354,6 +501,7 @@ func (app *application) deleteDoneItemsFromListHandler(w http.ResponseWriter, r
 		}
 		return
 	}
+	app.publishListEvent(events.ItemsDeleted, id)
 
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -2,13 +2,16 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"easylist/internal/data"
 	"encoding/json"
+	"errors"
 	"github.com/google/jsonapi"
 	"io"
 	"net/http"
 	"reflect"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
@@ -303,7 +306,7 @@ func TestItemUpdate(t *testing.T) {
 		t.Errorf("want Order to be 4, got %d", check.Order)
 	}
 	if check.Price != 22 {
-		t.Errorf("want Price to be 22, got %f", check.Price)
+		t.Errorf("want Price to be 22, got %d", check.Price)
 	}
 	if check.Quantity != 3 {
 		t.Errorf("want Quantity to be 3, got %d", check.Quantity)
@@ -369,7 +372,7 @@ func TestUpdateOnlyItemOrder(t *testing.T) {
 		t.Errorf("want Order to be 8, got %d", check.Order)
 	}
 	if check.Price != item.Price {
-		t.Errorf("want Price to be %f, got %f", item.Price, check.Price)
+		t.Errorf("want Price to be %d, got %d", item.Price, check.Price)
 	}
 	if check.Quantity != item.Quantity {
 		t.Errorf("want Quantity to be %d, got %d", item.Quantity, check.Quantity)
@@ -432,7 +435,7 @@ func TestMarkingItemDone(t *testing.T) {
 	}
 
 	if check.Price != item.Price {
-		t.Errorf("want Price to be %f, got %f", item.Price, check.Price)
+		t.Errorf("want Price to be %d, got %d", item.Price, check.Price)
 	}
 	if check.Quantity != item.Quantity {
 		t.Errorf("want Quantity to be %d, got %d", item.Quantity, check.Quantity)
@@ -452,7 +455,7 @@ func TestMarkingItemDone(t *testing.T) {
 	if resp.StatusCode != http.StatusNoContent {
 		t.Errorf("want %d status code; got %d", http.StatusOK, resp.StatusCode)
 	}
-	newItem, err := app.models.Items.Get(item.ID, item.UserId)
+	newItem, err := app.models.Items.Get(context.Background(), item.ID, item.UserId)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -552,7 +555,7 @@ func TestDeleteAllFromList(t *testing.T) {
 	if resp.StatusCode != http.StatusNoContent {
 		t.Errorf("want %d status code; got %d", http.StatusNoContent, resp.StatusCode)
 	}
-	_, err = app.models.Items.Get(item.ID, item.UserId)
+	_, err = app.models.Items.Get(context.Background(), item.ID, item.UserId)
 	if err == nil {
 		t.Error("Expected not found record, got no errors")
 	}
@@ -582,7 +585,7 @@ func TestDeleteDoneItemsFromList(t *testing.T) {
 		CreatedAt:    time.Time{},
 		UpdatedAt:    time.Time{},
 	}
-	err := app.models.Items.Insert(&item2)
+	err := app.models.Items.Insert(context.Background(), &item2)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -616,12 +619,12 @@ func TestDeleteDoneItemsFromList(t *testing.T) {
 	if resp.StatusCode != http.StatusNoContent {
 		t.Errorf("want %d status code; got %d", http.StatusNoContent, resp.StatusCode)
 	}
-	_, err = app.models.Items.Get(item2.ID, item2.UserId)
+	_, err = app.models.Items.Get(context.Background(), item2.ID, item2.UserId)
 	if err != nil {
 		t.Error("Expected to find not done item, got error")
 	}
 
-	_, err = app.models.Items.Get(item.ID, item.UserId)
+	_, err = app.models.Items.Get(context.Background(), item.ID, item.UserId)
 	if err == nil {
 		t.Error("Expected not found record, got no errors")
 	}
@@ -719,3 +722,288 @@ func createItem(app *application, t *testing.T) (data.Item, *data.Token) {
 	}
 	return item, token
 }
+
+func TestFilteringItems(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	// the item of createItem costs 78 USD cents per st
+	item, token := createItem(app, t)
+	var milk = data.Item{ListId: item.ListId, UserId: item.UserId, Name: "Milk", QuantityType: "l", Price: 150, Currency: "EUR", IsStarred: true, IsDone: true}
+	var bread = data.Item{ListId: item.ListId, UserId: item.UserId, Name: "Bread", QuantityType: "piece", Price: 300, Currency: "USD", File: "bread.jpg"}
+	for _, next := range []*data.Item{&milk, &bread} {
+		err := createTestItem(app, next)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var today = time.Now().UTC().Format(time.DateOnly)
+	var yesterday = time.Now().UTC().AddDate(0, 0, -1).Format(time.DateOnly)
+	var tests = []struct {
+		query string
+		want  []int64
+	}{
+		{query: "filter[is_starred]=false", want: []int64{item.ID, bread.ID}},
+		{query: "filter[is_starred]=true", want: []int64{milk.ID}},
+		{query: "filter[is_done]=true", want: []int64{milk.ID}},
+		{query: "filter[has_file]=1", want: []int64{bread.ID}},
+		{query: "filter[price_min]=100&filter[price_max]=300", want: []int64{milk.ID, bread.ID}},
+		{query: "filter[price_max]=200&filter[currency]=usd", want: []int64{item.ID}},
+		{query: "filter[quantity_type]=l,piece", want: []int64{milk.ID, bread.ID}},
+		{query: "filter[created_from]=" + yesterday + "&filter[created_to]=" + today, want: []int64{item.ID, milk.ID, bread.ID}},
+		{query: "filter[updated_to]=" + yesterday, want: []int64{}},
+		{query: "filter[is_done]=false&filter[has_file]=false&filter[quantity_type]=st", want: []int64{item.ID}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			req := generateRequestWithToken(ts.URL+"/api/v1/lists/"+strconv.FormatInt(item.ListId, 10)+"/items?sort=id&"+tt.query, token.Plaintext, "GET", nil)
+			resp, err := ts.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("want %d status code; got %d", http.StatusOK, resp.StatusCode)
+			}
+			check, err := jsonapi.UnmarshalManyPayload(resp.Body, reflect.TypeOf(&data.Item{}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got = []int64{}
+			for _, found := range check {
+				got = append(got, found.(*data.Item).ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("want items %v; got %v", tt.want, got)
+			}
+		})
+	}
+
+	for _, query := range []string{"filter[price_min]=300&filter[price_max]=100", "filter[price_min]=-1", "filter[is_done]=maybe", "filter[currency]=euro", "filter[created_from]=2024-02-30", "filter[updated_from]=2024-02-02&filter[updated_to]=2024-02-01"} {
+		req := generateRequestWithToken(ts.URL+"/api/v1/lists/"+strconv.FormatInt(item.ListId, 10)+"/items?"+query, token.Plaintext, "GET", nil)
+		resp, err := ts.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnprocessableEntity {
+			t.Errorf("want %d status code for %s; got %d", http.StatusUnprocessableEntity, query, resp.StatusCode)
+		}
+	}
+}
+
+type cursorPageResponse struct {
+	Data []struct {
+		Id string `json:"id"`
+	} `json:"data"`
+	Links map[string]*string `json:"links"`
+	Meta  map[string]any     `json:"meta"`
+}
+
+// getCursorPage returns the ids of the page and its links, the url may be a link of the previous page.
+func getCursorPage(t *testing.T, ts *testServer, token string, pageUrl string) ([]int64, cursorPageResponse) {
+	req := generateRequestWithToken(ts.URL+pageUrl[strings.Index(pageUrl, "/api/"):], token, "GET", nil)
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("want %d status code for %s; got %d", http.StatusOK, pageUrl, resp.StatusCode)
+	}
+	var check cursorPageResponse
+	err = json.NewDecoder(resp.Body).Decode(&check)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids = []int64{}
+	for _, found := range check.Data {
+		id, _ := strconv.ParseInt(found.Id, 10, 64)
+		ids = append(ids, id)
+	}
+	return ids, check
+}
+
+func TestItemsCursorPagination(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	item, ids, token := createItems(app, t, 5)
+	var itemsUrl = ts.URL + "/api/v1/lists/" + strconv.FormatInt(item.ListId, 10) + "/items"
+
+	firstPage, check := getCursorPage(t, ts, token.Plaintext, itemsUrl+"?sort=-id&page[size]=2&page[after]=")
+	if !reflect.DeepEqual(firstPage, []int64{ids[4], ids[3]}) {
+		t.Fatalf("want the two newest items; got %v", firstPage)
+	}
+	if check.Links["prev"] != nil || check.Links["next"] == nil || check.Meta["total"] != nil {
+		t.Fatalf("want only a next link and no total; got %+v, %+v", check.Links, check.Meta)
+	}
+
+	// an item added while paging does not move the next pages
+	var added = data.Item{ListId: item.ListId, UserId: item.UserId, Name: "Added"}
+	err := createTestItem(app, &added)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secondPage, check := getCursorPage(t, ts, token.Plaintext, *check.Links["next"])
+	if !reflect.DeepEqual(secondPage, []int64{ids[2], ids[1]}) {
+		t.Fatalf("want the next two items; got %v", secondPage)
+	}
+	var prev = check.Links["prev"]
+
+	lastPage, check := getCursorPage(t, ts, token.Plaintext, *check.Links["next"])
+	if !reflect.DeepEqual(lastPage, []int64{ids[0]}) || check.Links["next"] != nil {
+		t.Fatalf("want the oldest item on the last page; got %v, %+v", lastPage, check.Links)
+	}
+
+	previousPage, check := getCursorPage(t, ts, token.Plaintext, *prev)
+	if !reflect.DeepEqual(previousPage, []int64{ids[4], ids[3]}) || check.Links["prev"] == nil {
+		t.Fatalf("want the first page again with the added item before it; got %v, %+v", previousPage, check.Links)
+	}
+	newestPage, check := getCursorPage(t, ts, token.Plaintext, *check.Links["prev"])
+	if !reflect.DeepEqual(newestPage, []int64{added.ID}) || check.Links["prev"] != nil {
+		t.Fatalf("want the added item alone; got %v, %+v", newestPage, check.Links)
+	}
+
+	// all items have the same quantity, the ties are paged by id
+	var seen []int64
+	var next = itemsUrl + "?sort=quantity&page[size]=4&page[after]="
+	for next != "" {
+		page, check := getCursorPage(t, ts, token.Plaintext, next)
+		seen = append(seen, page...)
+		next = ""
+		if check.Links["next"] != nil {
+			next = *check.Links["next"]
+		}
+	}
+	if !reflect.DeepEqual(seen, append(ids, added.ID)) {
+		t.Errorf("want every item once in the order of ids; got %v", seen)
+	}
+
+	// a cursor made up from the item of another user does not compare with that item
+	otherUser, _, err := createTestUserWithToken(t, app, "other@mail.ru")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var otherList = data.List{FolderId: 1, UserId: otherUser.ID}
+	err = createTestList(app, &otherList)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var other = data.Item{ListId: otherList.ID, UserId: otherUser.ID}
+	err = createTestItem(app, &other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	foreignPage, _ := getCursorPage(t, ts, token.Plaintext, itemsUrl+"?sort=-id&page[after]="+data.EncodePageCursor("-id", other.ID))
+	if len(foreignPage) != 0 {
+		t.Errorf("want no items after the item of another user; got %v", foreignPage)
+	}
+
+	var cursor = data.EncodePageCursor("-id", ids[4])
+	for _, query := range []string{"sort=id&page[after]=" + cursor, "sort=-id&page[after]=bm9wZQ", "sort=-id&page[before]=", "sort=-id&page[after]=&page[before]=" + cursor, "sort=-id&page[number]=2&page[after]=", "group=category&page[after]="} {
+		req := generateRequestWithToken(itemsUrl+"?"+query, token.Plaintext, "GET", nil)
+		resp, err := ts.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnprocessableEntity {
+			t.Errorf("want %d status code for %s; got %d", http.StatusUnprocessableEntity, query, resp.StatusCode)
+		}
+	}
+}
+
+func TestViewerCanNotChangeItemsOfList(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	item, _ := createItem(app, t)
+	viewer, viewerToken, err := createTestUserWithToken(t, app, "viewer@mail.ru")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = createTestMember(app, item.ListId, viewer.ID, data.RoleViewer, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var listPath = "/api/v1/lists/" + strconv.Itoa(int(item.ListId)) + "/items"
+	for _, request := range []struct{ method, path string }{
+		{"PATCH", listPath + "/undone"},
+		{"DELETE", listPath},
+		{"DELETE", listPath + "/done"},
+	} {
+		if status := sendWithToken(t, ts, viewerToken.Plaintext, request.method, request.path, ""); status != http.StatusForbidden {
+			t.Errorf("%s %s: want %d status code; got %d", request.method, request.path, http.StatusForbidden, status)
+		}
+	}
+	if _, err = app.models.Items.Get(context.Background(), item.ID, item.UserId); err != nil {
+		t.Errorf("want the item to stay; got %v", err)
+	}
+
+	// the model refuses the viewer and a stale version without bumping the version
+	for _, userId := range []int64{viewer.ID, item.UserId} {
+		var stale = item
+		if userId == item.UserId {
+			stale.Version--
+		}
+		err = app.models.Items.Update(context.Background(), &stale, stale.Order, userId)
+		if !errors.Is(err, data.ErrEditConflict) || stale.Version > item.Version {
+			t.Errorf("user %d: want %v with the version %d; got %v with %d", userId, data.ErrEditConflict, item.Version, err, stale.Version)
+		}
+	}
+}
+
+func TestEditorItemsInSharedList(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	item, _ := createItem(app, t)
+	editor, editorToken, err := createTestUserWithToken(t, app, "editor@mail.ru")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = createTestMember(app, item.ListId, editor.ID, data.RoleEditor, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the items of the editor are ordered after the items of the owner
+	var added = data.Item{ListId: item.ListId, UserId: editor.ID}
+	err = createTestItem(app, &added)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added.Order <= item.Order {
+		t.Errorf("want the order of the added item greater than %d; got %d", item.Order, added.Order)
+	}
+
+	// deleting the editor keeps the items the editor added to the list and hands them to the owner
+	var path = "/api/v1/users/" + strconv.Itoa(int(editor.ID))
+	if status := sendWithToken(t, ts, editorToken.Plaintext, "DELETE", path, ""); status != http.StatusNoContent {
+		t.Fatalf("want %d status code; got %d", http.StatusNoContent, status)
+	}
+	kept, err := app.models.Items.Get(context.Background(), added.ID, item.UserId)
+	if err != nil {
+		t.Fatalf("want the item of the editor to stay; got %v", err)
+	}
+	if kept.UserId != item.UserId {
+		t.Errorf("want the item to belong to the user %d; got %d", item.UserId, kept.UserId)
+	}
+}
@@ -17,12 +17,13 @@ import (
 const ListType = "lists"
 
 type ListInput struct {
-	Name string
+	data.ListFilters
 	data.Filters
 }
 
 type EmailData struct {
 	Items  data.Items
+	Groups data.ItemGroups
 	List   *data.List
 	User   *data.User
 	Logo   string
@@ -42,7 +43,7 @@ func (app *application) createListsHandler(w http.ResponseWriter, r *http.Reques
 	if folderId == 0 {
 		folderId = 1
 	}
-	_, err := app.models.Folders.Get(folderId, userModel.ID)
+	_, err := app.models.Folders.Get(r.Context(), folderId, userModel.ID)
 	var v = validator.New()
 	if err != nil {
 		switch {
@@ -58,6 +59,9 @@ func (app *application) createListsHandler(w http.ResponseWriter, r *http.Reques
 	list.ID = 1
 	list.UserId = userModel.ID
 	list.Order = 1
+	if list.Budget != nil && *list.Budget == 0 {
+		list.Budget = nil
+	}
 	list.Version = 1
 	list.CreatedAt = time.Now()
 	list.UpdatedAt = time.Now()
@@ -67,7 +71,7 @@ func (app *application) createListsHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	err = app.models.Lists.Insert(list)
+	err = app.models.Lists.Insert(r.Context(), list)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -96,7 +100,7 @@ func (app *application) indexListsHandler(w http.ResponseWriter, r *http.Request
 	var userModel = app.contextGetUser(r)
 
 	if folderId > 0 {
-		_, err = app.models.Folders.Get(folderId, userModel.ID)
+		_, err = app.models.Folders.Get(r.Context(), folderId, userModel.ID)
 		if err != nil {
 			switch {
 			case errors.Is(err, data.ErrRecordNotFound):
@@ -109,18 +113,25 @@ func (app *application) indexListsHandler(w http.ResponseWriter, r *http.Request
 	}
 
 	input.Name = app.readString(qs, "filter[name]", "")
+	input.HasUndone = app.readOptionalBool(qs, "filter[has_undone]", v)
+	input.IsPublic = app.readOptionalBool(qs, "filter[is_public]", v)
+	input.Created = app.readDateRange(qs, "created", v)
+	input.Updated = app.readDateRange(qs, "updated", v)
 	input.Filters.Page = app.readInt(qs, jsonapi.QueryParamPageNumber, 1, v)
 	input.Filters.Size = app.readInt(qs, jsonapi.QueryParamPageSize, 20, v)
+	input.Filters.After = app.readOptionalString(qs, "page[after]")
+	input.Filters.Before = app.readOptionalString(qs, "page[before]")
 	input.Filters.Sort = app.readString(qs, "sort", "order")
 	input.Filters.SortSafelist = []string{"id", "name", "order", "created_at", "updated_at", "folder_id", "-id", "-name", "-order", "-created_at", "-updated_at", "-folder_id"}
 	input.Filters.Includes = app.readCSV(qs, "include", []string{})
 
+	data.ValidateListFilters(v, input.ListFilters)
 	if data.ValidateFilters(v, input.Filters); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	lists, metadata, err := app.models.Lists.GetAll(folderId, input.Name, userModel.ID, input.Filters)
+	lists, metadata, err := app.models.Lists.GetAll(r.Context(), folderId, userModel.ID, input.ListFilters, input.Filters)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -139,7 +150,7 @@ func (app *application) showListByIdHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 	var userModel = app.contextGetUser(r)
-	list, err := app.models.Lists.Get(id, userModel.ID)
+	list, err := app.models.Lists.Get(r.Context(), id, userModel.ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -151,8 +162,8 @@ func (app *application) showListByIdHandler(w http.ResponseWriter, r *http.Reque
 	}
 	var qs = r.URL.Query()
 	var includes = app.readCSV(qs, "include", []string{})
-	if len(includes) > 0 && data.Contains(includes, "folder") {
-		folderModel, err := app.models.Folders.Get(list.FolderId, userModel.ID)
+	if len(includes) > 0 && data.Contains(includes, "folder") && list.Role == data.RoleOwner {
+		folderModel, err := app.models.Folders.Get(r.Context(), list.FolderId, userModel.ID)
 		if err != nil {
 			switch {
 			case errors.Is(err, data.ErrRecordNotFound):
@@ -168,7 +179,7 @@ func (app *application) showListByIdHandler(w http.ResponseWriter, r *http.Reque
 		v := validator.New()
 		var input = app.NewItemInput(r, v)
 
-		items, _, err := app.models.Items.GetAll("", userModel.ID, list.ID, false, input.Filters)
+		items, _, err := app.models.Items.GetAll(r.Context(), userModel.ID, list.ID, data.ItemFilters{}, input.Filters)
 		if err != nil {
 			app.serverErrorResponse(w, r, err)
 			return
@@ -200,7 +211,7 @@ func (app *application) sendListByEmail(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	list, err := app.models.Lists.Get(id, userModel.ID)
+	list, err := app.models.Lists.Get(r.Context(), id, userModel.ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -215,12 +226,14 @@ func (app *application) sendListByEmail(w http.ResponseWriter, r *http.Request)
 	v := validator.New()
 	var input = app.NewItemInput(r, v)
 	input.Filters.Size = 100
-	items, _, err := app.models.Items.GetAll("", userModel.ID, id, false, input.Filters)
+	input.Filters.Group = data.GroupByCategory
+	items, _, err := app.models.Items.GetAll(r.Context(), userModel.ID, id, data.ItemFilters{}, input.Filters)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 	emailList.Items = items
+	emailList.Groups = data.GroupItems(items)
 	app.background(func() {
 		err = app.mailer.Send(emailInput.Email, "list_email.tmpl", emailList)
 		if err != nil {
@@ -235,7 +248,7 @@ func (app *application) showPublicListHandler(w http.ResponseWriter, r *http.Req
 	var params = httprouter.ParamsFromContext(r.Context())
 	var link = params.ByName("link")
 
-	list, err := app.models.Lists.GetPublic(link)
+	list, err := app.models.Lists.GetPublic(r.Context(), link)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -251,7 +264,7 @@ func (app *application) showPublicListHandler(w http.ResponseWriter, r *http.Req
 		v := validator.New()
 		var input = app.NewItemInput(r, v)
 
-		items, _, err := app.models.Items.GetAll("", list.UserId, list.ID, false, input.Filters)
+		items, _, err := app.models.Items.GetAll(r.Context(), list.UserId, list.ID, data.ItemFilters{}, input.Filters)
 		if err != nil {
 			app.serverErrorResponse(w, r, err)
 			return
@@ -274,7 +287,7 @@ func (app *application) updateListHandler(w http.ResponseWriter, r *http.Request
 
 	var userModel = app.contextGetUser(r)
 
-	list, err := app.models.Lists.Get(id, userModel.ID)
+	list, err := app.models.Lists.Get(r.Context(), id, userModel.ID)
 	var oldOrder = list.Order
 
 	if err != nil {
@@ -287,7 +300,12 @@ func (app *application) updateListHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	_, err = app.models.Folders.Get(list.FolderId, userModel.ID)
+	if list.Role != data.RoleOwner {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	_, err = app.models.Folders.Get(r.Context(), list.FolderId, userModel.ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -318,6 +336,16 @@ func (app *application) updateListHandler(w http.ResponseWriter, r *http.Request
 	if inputList.FolderId != 0 {
 		list.FolderId = inputList.FolderId
 	}
+	// a zero budget removes the budget of the list
+	if inputList.Budget != nil {
+		list.Budget = inputList.Budget
+		if *inputList.Budget == 0 {
+			list.Budget = nil
+		}
+	}
+	if inputList.BudgetCurrency != "" {
+		list.BudgetCurrency = inputList.BudgetCurrency
+	}
 
 	if inputList.IsPublic == 2 && !list.Link.Valid {
 		list.Link = data.Link{
@@ -344,7 +372,7 @@ func (app *application) updateListHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	err = app.models.Lists.Update(list, oldOrder)
+	err = app.models.Lists.Update(r.Context(), list, oldOrder)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrEditConflict):
@@ -377,7 +405,7 @@ func (app *application) deleteListHandler(w http.ResponseWriter, r *http.Request
 
 	var userModel = app.contextGetUser(r)
 
-	err = app.models.Lists.Delete(id, userModel.ID)
+	err = app.models.Lists.Delete(r.Context(), id, userModel.ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
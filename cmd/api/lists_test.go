@@ -2,15 +2,18 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"easylist/internal/data"
 	"encoding/json"
+	"errors"
 	"github.com/google/jsonapi"
 	"io"
 	"net/http"
 	"reflect"
 	"strconv"
 	"testing"
+	"time"
 )
 
 func TestShowNewCreatedList(t *testing.T) {
@@ -461,7 +464,7 @@ func TestMakingListPublic(t *testing.T) {
 	if resp.StatusCode != http.StatusOK {
 		t.Errorf("want %d status code; got %d", http.StatusOK, resp.StatusCode)
 	}
-	check, err := app.models.Lists.Get(list.ID, user.ID)
+	check, err := app.models.Lists.Get(context.Background(), list.ID, user.ID)
 	if err != nil {
 		t.Error(err)
 	}
@@ -721,7 +724,7 @@ func TestShowPublicListWithItems(t *testing.T) {
 	list.Link = data.Link{
 		NullString: sql.NullString{String: "111erdde1", Valid: true},
 	}
-	err = app.models.Lists.Update(&list, 1)
+	err = app.models.Lists.Update(context.Background(), &list, 1)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -787,3 +790,120 @@ func TestSendingEmailWithListRoute(t *testing.T) {
 		t.Errorf("want %d status code; got %d", http.StatusNoContent, resp.StatusCode)
 	}
 }
+
+func TestFilteringLists(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	// the list of createItem has an undone item
+	item, token := createItem(app, t)
+	var done = data.List{UserId: item.UserId, Name: "Done list"}
+	var public = data.List{UserId: item.UserId, Name: "Public list"}
+	for _, next := range []*data.List{&done, &public} {
+		err := createTestList(app, next)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	var bought = data.Item{ListId: done.ID, UserId: item.UserId, IsDone: true}
+	err := createTestItem(app, &bought)
+	if err != nil {
+		t.Fatal(err)
+	}
+	public.Link = data.Link{NullString: sql.NullString{String: "public-link", Valid: true}}
+	err = app.models.Lists.Update(context.Background(), &public, public.Order)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		query string
+		want  []int64
+	}{
+		{query: "filter[has_undone]=true", want: []int64{item.ListId}},
+		{query: "filter[has_undone]=false", want: []int64{done.ID, public.ID}},
+		{query: "filter[is_public]=true", want: []int64{public.ID}},
+		{query: "filter[is_public]=false&filter[has_undone]=false", want: []int64{done.ID}},
+		{query: "filter[created_from]=" + time.Now().UTC().Format(time.DateOnly), want: []int64{item.ListId, done.ID, public.ID}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			req := generateRequestWithToken(ts.URL+"/api/v1/lists?sort=id&"+tt.query, token.Plaintext, "GET", nil)
+			resp, err := ts.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("want %d status code; got %d", http.StatusOK, resp.StatusCode)
+			}
+			// the link of a public list can't be unmarshalled into data.List, so only the ids are read
+			var check struct {
+				Data []struct {
+					Id string `json:"id"`
+				} `json:"data"`
+			}
+			err = json.NewDecoder(resp.Body).Decode(&check)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got = []int64{}
+			for _, found := range check.Data {
+				id, _ := strconv.ParseInt(found.Id, 10, 64)
+				got = append(got, id)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("want lists %v; got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestListUpdateConflict(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	item, _ := createItem(app, t)
+	list, err := app.models.Lists.Get(context.Background(), item.ListId, item.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	folder, err := app.models.Folders.Get(context.Background(), list.FolderId, item.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a stale version updates nothing and keeps the version
+	var staleList = *list
+	staleList.Version--
+	err = app.models.Lists.Update(context.Background(), &staleList, staleList.Order)
+	if !errors.Is(err, data.ErrEditConflict) || staleList.Version != list.Version-1 {
+		t.Errorf("want %v with the version %d; got %v with %d", data.ErrEditConflict, list.Version-1, err, staleList.Version)
+	}
+	var staleFolder = *folder
+	staleFolder.Version--
+	err = app.models.Folders.Update(context.Background(), &staleFolder, staleFolder.Order)
+	if !errors.Is(err, data.ErrEditConflict) || staleFolder.Version != folder.Version-1 {
+		t.Errorf("want %v with the version %d; got %v with %d", data.ErrEditConflict, folder.Version-1, err, staleFolder.Version)
+	}
+
+	// a list of another user is not updated either
+	var foreign = *list
+	foreign.UserId++
+	if err = app.models.Lists.Update(context.Background(), &foreign, foreign.Order); !errors.Is(err, data.ErrEditConflict) {
+		t.Errorf("want %v; got %v", data.ErrEditConflict, err)
+	}
+
+	err = app.models.Lists.Update(context.Background(), list, list.Order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = app.models.Folders.Update(context.Background(), folder, folder.Order)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"easylist/internal/data"
+	"easylist/internal/lockout"
+	"expvar"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultLockoutAttempts = 10
+const defaultLockoutDuration = 15 * time.Minute
+
+// ipAttemptsFactor is how many more failures an ip address gets than an email, many users can share an address
+// behind a NAT.
+const ipAttemptsFactor = 5
+
+// maxTwoFactorFailures is how many wrong codes the challenge tokens of a user take, then they are deleted and the
+// password has to be typed again.
+const maxTwoFactorFailures = 5
+
+// loginMetrics counts the failed sign ins, the ones refused because of the back-off and the lockouts.
+var loginMetrics = expvar.NewMap("login_attempts")
+
+// loginAttempts tracks the failed sign ins by email and by ip address, and the wrong codes of the two-factor
+// challenges by user.
+type loginAttempts struct {
+	byEmail     *lockout.Tracker
+	byIp        *lockout.Tracker
+	byChallenge *lockout.Tracker
+}
+
+// newLoginAttempts creates the trackers with the limits of the auth.lockout section and the clock.
+func newLoginAttempts(cfg config, now func() time.Time) *loginAttempts {
+	var attempts = cfg.Auth.Lockout.Attempts
+	if attempts <= 0 {
+		attempts = defaultLockoutAttempts
+	}
+	duration, err := time.ParseDuration(cfg.Auth.Lockout.Duration)
+	if err != nil || duration <= 0 {
+		duration = defaultLockoutDuration
+	}
+
+	var byEmail = lockout.New(lockout.Policy{
+		FreeAttempts:    3,
+		BaseDelay:       time.Second,
+		MaxDelay:        time.Minute,
+		LockoutAttempts: attempts,
+		LockoutDuration: duration,
+		Window:          time.Hour,
+	})
+	var byIp = lockout.New(lockout.Policy{
+		FreeAttempts:    3 * ipAttemptsFactor,
+		BaseDelay:       time.Second,
+		MaxDelay:        time.Minute,
+		LockoutAttempts: attempts * ipAttemptsFactor,
+		LockoutDuration: duration,
+		Window:          time.Hour,
+	})
+	var byChallenge = lockout.New(lockout.Policy{
+		FreeAttempts:    maxTwoFactorFailures,
+		LockoutAttempts: maxTwoFactorFailures,
+		Window:          twoFactorChallengeTtl,
+	})
+	byEmail.Now, byIp.Now, byChallenge.Now = now, now, now
+	return &loginAttempts{byEmail: byEmail, byIp: byIp, byChallenge: byChallenge}
+}
+
+func emailKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// throttleLogin answers with 429 while the ip address of the request or the email has to wait after failed
+// attempts, it reports whether the response has been written. The email may be empty when it is not known yet.
+func (app *application) throttleLogin(w http.ResponseWriter, r *http.Request, email string) bool {
+	var wait = app.loginAttempts.byIp.Check(clientIp(r))
+	if email != "" {
+		if emailWait := app.loginAttempts.byEmail.Check(emailKey(email)); emailWait > wait {
+			wait = emailWait
+		}
+	}
+	if wait <= 0 {
+		return false
+	}
+	loginMetrics.Add("throttled", 1)
+	app.tooManyAttemptsResponse(w, r, wait)
+	return true
+}
+
+// failLogin records the failed attempt of the ip address and of the email. The user, when there is one with the
+// email, gets a mail when the failure locks the account.
+func (app *application) failLogin(r *http.Request, email string, user *data.User) {
+	loginMetrics.Add("failed", 1)
+
+	var ip = clientIp(r)
+	if _, locked := app.loginAttempts.byIp.Fail(ip); locked {
+		loginMetrics.Add("locked_ips", 1)
+		app.logger.PrintInfo("ip address locked after failed sign ins", map[string]string{"ip": ip})
+	}
+	if email == "" {
+		return
+	}
+	wait, locked := app.loginAttempts.byEmail.Fail(emailKey(email))
+	if !locked {
+		return
+	}
+	loginMetrics.Add("locked_accounts", 1)
+	if user == nil {
+		return
+	}
+	app.background(func() {
+		var data = map[string]any{
+			"minutes": int(wait.Minutes()),
+			"ip":      ip,
+			"domain":  app.config.Domain,
+		}
+		err := app.mailer.Send(user.Email, "user_lockout.tmpl", data)
+		if err != nil {
+			app.logger.PrintError(err, nil)
+		}
+	})
+}
+
+// failTwoFactorCode records a wrong code of the challenge, the challenge tokens of the user are deleted once there are
+// maxTwoFactorFailures of them.
+func (app *application) failTwoFactorCode(r *http.Request, user *data.User) error {
+	app.failLogin(r, user.Email, user)
+
+	var key = strconv.FormatInt(user.ID, 10)
+	if _, spent := app.loginAttempts.byChallenge.Fail(key); !spent {
+		return nil
+	}
+	app.loginAttempts.byChallenge.Succeed(key)
+	return app.models.Tokens.DeleteAllForUser(r.Context(), data.ScopeTwoFactor, user.ID)
+}
+
+// succeedLogin forgets the failed attempts of the email and the wrong codes of the user, it runs once the session
+// has been started.
+func (app *application) succeedLogin(user *data.User) {
+	app.loginAttempts.byEmail.Succeed(emailKey(user.Email))
+	app.loginAttempts.byChallenge.Succeed(strconv.FormatInt(user.ID, 10))
+}
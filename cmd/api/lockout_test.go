@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"expvar"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// postLogin sends the attributes to the endpoint and returns the status code with the Retry-After header.
+func postLogin(t *testing.T, ts *testServer, endpoint string, attributes string) (int, string) {
+	t.Helper()
+	var body = []byte(`{"data": {"type": "tokens", "attributes": ` + attributes + `}}`)
+	req := generateRequestWithToken(ts.URL+"/api/v1/tokens/"+endpoint, "", "POST", bytes.NewBuffer(body))
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode, resp.Header.Get("Retry-After")
+}
+
+// loginMetric returns the counter of the login attempts, the counters are shared by the tests of the package.
+func loginMetric(name string) int64 {
+	if counter, ok := loginMetrics.Get(name).(*expvar.Int); ok {
+		return counter.Value()
+	}
+	return 0
+}
+
+func TestLoginLockout(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	var now = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	app.clock = func() time.Time { return now }
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	createItem(app, t)
+	var wrong = `{"email": "test@mail.ru", "password": "wrong-password"}`
+	var right = `{"email": "test@mail.ru", "password": "password123"}`
+	var failed = loginMetric("failed")
+
+	// the free attempts, then the back-off of 1, 2, 4... seconds
+	for i := 1; i <= 3; i++ {
+		if status, _ := postLogin(t, ts, "authentication", wrong); status != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: want %d status code; got %d", i, http.StatusUnauthorized, status)
+		}
+	}
+	for i, wait := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second} {
+		if status, _ := postLogin(t, ts, "authentication", wrong); status != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: want %d status code; got %d", i+4, http.StatusUnauthorized, status)
+		}
+		status, retryAfter := postLogin(t, ts, "authentication", right)
+		if status != http.StatusTooManyRequests || retryAfter != strconv.Itoa(int(wait.Seconds())) {
+			t.Fatalf("want the right password to wait %v too; got %d %q", wait, status, retryAfter)
+		}
+		now = now.Add(wait)
+	}
+	if loginMetric("failed") != failed+6 {
+		t.Errorf("want the failures to be counted; got %d", loginMetric("failed")-failed)
+	}
+
+	// the right password resets the failures of the email
+	if status, _ := postLogin(t, ts, "authentication", right); status != http.StatusCreated {
+		t.Fatalf("want %d status code; got %d", http.StatusCreated, status)
+	}
+
+	for i := 1; i <= defaultLockoutAttempts; i++ {
+		status, _ := postLogin(t, ts, "authentication", wrong)
+		if status != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: want %d status code; got %d", i, http.StatusUnauthorized, status)
+		}
+		now = now.Add(time.Minute)
+	}
+	status, retryAfter := postLogin(t, ts, "authentication", right)
+	if status != http.StatusTooManyRequests || retryAfter != "840" {
+		t.Fatalf("want the account to be locked for the rest of 15 minutes; got %d %q", status, retryAfter)
+	}
+	if status, _ := postLogin(t, ts, "password-reset", `{"email": "test@mail.ru"}`); status != http.StatusTooManyRequests {
+		t.Errorf("want the password reset to wait as well; got %d", status)
+	}
+	if loginMetric("locked_accounts") == 0 {
+		t.Errorf("want the lockout to be counted")
+	}
+
+	now = now.Add(defaultLockoutDuration)
+	if status, _ := postLogin(t, ts, "authentication", right); status != http.StatusCreated {
+		t.Errorf("want the sign in to work after the lockout; got %d", status)
+	}
+}
+
+func TestLoginLockoutByIp(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	var now = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	app.clock = func() time.Time { return now }
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	// guessing emails, every email is new, so only the ip address collects the failures
+	var emails = []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l", "m", "n", "o", "p"}
+	for i, email := range emails {
+		status, _ := postLogin(t, ts, "password-reset", `{"email": "`+email+`@mail.ru"}`)
+		if status != http.StatusUnprocessableEntity {
+			t.Fatalf("attempt %d: want %d status code; got %d", i+1, http.StatusUnprocessableEntity, status)
+		}
+	}
+	if status, _ := postLogin(t, ts, "authentication", `{"email": "z@mail.ru", "password": "password123"}`); status != http.StatusTooManyRequests {
+		t.Errorf("want the ip address to wait; got %d", status)
+	}
+	now = now.Add(time.Second)
+	if status, _ := postLogin(t, ts, "authentication", `{"email": "z@mail.ru", "password": "password123"}`); status != http.StatusUnauthorized {
+		t.Errorf("want %d status code after the wait; got %d", http.StatusUnauthorized, status)
+	}
+}
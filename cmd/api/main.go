@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"easylist/internal/data"
+	"easylist/internal/events"
 	"easylist/internal/jsonlog"
 	"easylist/internal/mailer"
 	"expvar"
@@ -15,6 +16,8 @@ import (
 	"time"
 
 	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
 )
 
 func main() {
@@ -22,7 +25,13 @@ func main() {
 
 	logger := jsonlog.New(os.Stdout, jsonlog.LevelInfo)
 	readConfigFile(&cfg)
-	cfg.Db.Dsn = cfg.Db.Login + ":" + cfg.Db.Password + "@" + cfg.Db.Host + "/" + cfg.Db.Dbname + "?parseTime=true"
+	dialect, err := data.ParseDialect(cfg.Db.Driver)
+	if err != nil {
+		logger.PrintFatal(err, nil)
+	}
+	if cfg.Db.Dsn == "" {
+		cfg.Db.Dsn = cfg.Db.dsn(dialect)
+	}
 	displayVersion := flag.Bool("version", false, "Display version and exit")
 
 	flag.Parse()
@@ -33,12 +42,25 @@ func main() {
 		os.Exit(0)
 	}
 
+	if flag.Arg(0) == "migrate" {
+		migrator, err := openMigrator(cfg, dialect)
+		if err != nil {
+			logger.PrintFatal(err, nil)
+		}
+		err = runMigrations(migrator, flag.Args()[1:], os.Stdout)
+		migrator.DB.Close()
+		if err != nil {
+			logger.PrintFatal(err, nil)
+		}
+		os.Exit(0)
+	}
+
 	data.DomainName = cfg.Domain
-	db, err := openDB(cfg)
+	db, err := openDB(cfg, dialect)
 	if err != nil {
 		logger.PrintFatal(err, nil)
 	}
-	defer func(db *sql.DB) {
+	defer func(db *data.DB) {
 		err := db.Close()
 		if err != nil {
 			logger.PrintError(err, nil)
@@ -56,11 +78,43 @@ func main() {
 		return time.Now().Unix()
 	}))
 
+	store, err := openStorage(cfg)
+	if err != nil {
+		logger.PrintFatal(err, nil)
+	}
+
+	oidcProviders, err := openOidcProviders(cfg)
+	if err != nil {
+		logger.PrintFatal(err, nil)
+	}
+
 	app := &application{
-		config: cfg,
-		logger: logger,
-		models: data.NewModels(db),
-		mailer: mailer.New(cfg.Smtp.Host, cfg.Smtp.Port, cfg.Smtp.Username, cfg.Smtp.Password, cfg.Smtp.Sender),
+		config:        cfg,
+		logger:        logger,
+		models:        data.NewModels(db),
+		mailer:        mailer.New(cfg.Smtp.Host, cfg.Smtp.Port, cfg.Smtp.Username, cfg.Smtp.Password, cfg.Smtp.Sender),
+		events:        events.NewHub(),
+		storage:       store,
+		oidcProviders: oidcProviders,
+	}
+	app.loginAttempts = newLoginAttempts(cfg, app.now)
+	expvar.Publish("login_attempts_tracked", expvar.Func(func() any {
+		return map[string]int{"emails": app.loginAttempts.byEmail.Len(), "ips": app.loginAttempts.byIp.Len()}
+	}))
+
+	migrator, err := openMigrator(cfg, dialect)
+	if err != nil {
+		logger.PrintFatal(err, nil)
+	}
+	err = app.checkSchema(migrator)
+	migrator.DB.Close()
+	if err != nil {
+		logger.PrintFatal(err, nil)
+	}
+
+	err = app.loadExchangeRatesFile(context.Background())
+	if err != nil {
+		logger.PrintFatal(err, nil)
 	}
 
 	err = app.serve()
@@ -85,8 +139,8 @@ func readConfigFile(cfg *config) {
 	}
 }
 
-func openDB(cfg config) (*sql.DB, error) {
-	db, err := sql.Open("mysql", cfg.Db.Dsn)
+func openDB(cfg config, dialect data.Dialect) (*data.DB, error) {
+	db, err := sql.Open(dialect.DriverName(), cfg.Db.Dsn)
 	if err != nil {
 		return nil, err
 	}
@@ -104,5 +158,13 @@ func openDB(cfg config) (*sql.DB, error) {
 	if err = db.PingContext(ctx); err != nil {
 		return nil, err
 	}
-	return db, nil
+
+	var dataDb = data.NewDB(db, dialect)
+	if cfg.Db.QueryTimeout != "" {
+		dataDb.QueryTimeout, err = time.ParseDuration(cfg.Db.QueryTimeout)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return dataDb, nil
 }
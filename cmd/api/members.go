@@ -0,0 +1,315 @@
+package main
+
+import (
+	"easylist/internal/data"
+	"easylist/internal/validator"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+func (app *application) indexMembersHandler(w http.ResponseWriter, r *http.Request) {
+	listId, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var userModel = app.contextGetUser(r)
+
+	_, err = app.models.Lists.Get(r.Context(), listId, userModel.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	members, err := app.models.Members.GetAllForList(r.Context(), listId)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if members == nil {
+		members = data.Members{}
+	}
+
+	err = app.writeJSON(w, http.StatusOK, members, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) inviteMemberHandler(w http.ResponseWriter, r *http.Request) {
+	listId, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var userModel = app.contextGetUser(r)
+
+	list, err := app.models.Lists.Get(r.Context(), listId, userModel.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if list.Role != data.RoleOwner {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	var member = new(data.Member)
+	if err := readJsonApi(r, member); err != nil {
+		app.badRequestResponse(w, r, "inviteMemberHandler", err)
+		return
+	}
+	if member.Role == "" {
+		member.Role = data.RoleViewer
+	}
+
+	var v = validator.New()
+	data.ValidateEmail(v, member.Email)
+	data.ValidateMemberRole(v, member.Role)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	invitee, err := app.models.Users.GetByEmail(r.Context(), member.Email)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("data.attributes.email", "no matching email address found")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if invitee.ID == userModel.ID {
+		v.AddError("data.attributes.email", "you can not invite yourself")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	token, err := app.models.Tokens.New(r.Context(), invitee.ID, 7*24*time.Hour, data.ScopeListInvitation)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	member.ListId = list.ID
+	member.UserId = invitee.ID
+	member.Name = invitee.Name
+	member.IsAccepted = false
+	member.TokenId = token.ID
+	member.ListOwnerId = list.UserId
+
+	err = app.models.Members.Insert(r.Context(), member)
+	if err != nil {
+		if e := app.models.Tokens.Delete(r.Context(), token.ID, invitee.ID); e != nil {
+			app.logger.PrintError(e, nil)
+		}
+		switch {
+		case errors.Is(err, data.ErrDuplicateMember):
+			v.AddError("data.attributes.email", "this user is already a member of the list")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.background(func() {
+		var data = map[string]any{
+			"invitationToken": token.Plaintext,
+			"domain":          app.config.Domain,
+			"listName":        list.Name,
+			"ownerName":       userModel.Name,
+		}
+		err = app.mailer.Send(invitee.Email, "list_invitation.tmpl", data)
+		if err != nil {
+			app.logger.PrintError(err, nil)
+		}
+	})
+
+	var headers = make(http.Header)
+	headers.Set("Location", fmt.Sprintf("%s/api/v1/members/%d", app.config.Domain, member.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, member, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) acceptInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	var input = Input[ActivationAttributes]{Data: InputAttributes[ActivationAttributes]{
+		Type:       "tokens",
+		Attributes: ActivationAttributes{},
+	}}
+
+	var v = validator.New()
+
+	var err = readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, "acceptInvitationHandler", err)
+		return
+	}
+	v.Check(input.Data.Type == "tokens", "data.type", "Wrong type provided, accepted type is tokens")
+
+	if data.ValidateTokenPlaintext(v, input.Data.Attributes.Token); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	var userModel = app.contextGetUser(r)
+
+	member, err := app.models.Members.GetForToken(r.Context(), input.Data.Attributes.Token)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("data.attributes.token", "Invalid or expired invitation token")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if member.UserId != userModel.ID {
+		v.AddError("data.attributes.token", "Invalid or expired invitation token")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	var tokenId = member.TokenId
+	member.IsAccepted = true
+	member.TokenId = 0
+
+	err = app.models.Members.Update(r.Context(), member)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.Tokens.Delete(r.Context(), tokenId, userModel.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, member, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateMemberHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var userModel = app.contextGetUser(r)
+
+	member, err := app.models.Members.Get(r.Context(), id, userModel.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if member.ListOwnerId != userModel.ID {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	var inputMember = new(data.Member)
+	if err := readJsonApi(r, inputMember); err != nil {
+		app.badRequestResponse(w, r, "updateMemberHandler", err)
+		return
+	}
+	if inputMember.Role != "" {
+		member.Role = inputMember.Role
+	}
+
+	var v = validator.New()
+	if data.ValidateMemberRole(v, member.Role); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Members.Update(r.Context(), member)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, member, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteMemberHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var userModel = app.contextGetUser(r)
+
+	member, err := app.models.Members.Get(r.Context(), id, userModel.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.WithTx(r.Context(), func(tx data.Models) error {
+		err := tx.Members.Delete(r.Context(), member.ID)
+		if err != nil || member.TokenId == 0 {
+			return err
+		}
+		return tx.Tokens.Delete(r.Context(), member.TokenId, member.UserId)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"easylist/internal/data"
+	"github.com/google/jsonapi"
+	"io"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestInviteMember(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	item, token := createItem(app, t)
+	invitee, _, err := createTestUserWithToken(t, app, "invitee@mail.ru")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var memberData = []byte(`{
+	  "data": {
+		"type": "members",
+		"attributes": {
+		  "email": "invitee@mail.ru",
+		  "role": "editor"
+		}
+	  }
+	}`)
+	req := generateRequestWithToken(ts.URL+"/api/v1/lists/"+strconv.Itoa(int(item.ListId))+"/members", token.Plaintext, "POST", bytes.NewBuffer(memberData))
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}(resp.Body)
+
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("want %d status code; got %d", http.StatusCreated, resp.StatusCode)
+	}
+
+	check := new(data.Member)
+
+	err = jsonapi.UnmarshalPayload(resp.Body, check)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if check.UserId != invitee.ID {
+		t.Errorf("want UserId to be %d, got %d", invitee.ID, check.UserId)
+	}
+	if check.Role != data.RoleEditor {
+		t.Errorf("want Role to be %s, got %s", data.RoleEditor, check.Role)
+	}
+	if check.IsAccepted {
+		t.Errorf("want IsAccepted to be false, got true")
+	}
+}
+
+func TestAcceptInvitation(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	item, _ := createItem(app, t)
+	invitee, inviteeToken, err := createTestUserWithToken(t, app, "invitee@mail.ru")
+	if err != nil {
+		t.Fatal(err)
+	}
+	invitation, err := app.models.Tokens.New(context.Background(), invitee.ID, 7*24*time.Hour, data.ScopeListInvitation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var member = data.Member{
+		ListId:  item.ListId,
+		UserId:  invitee.ID,
+		Role:    data.RoleViewer,
+		TokenId: invitation.ID,
+	}
+	err = app.models.Members.Insert(context.Background(), &member)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tokenData = []byte(`{
+	  "data": {
+		"type": "tokens",
+		"attributes": {
+		  "token": "` + invitation.Plaintext + `"
+		}
+	  }
+	}`)
+	req := generateRequestWithToken(ts.URL+"/api/v1/members/accepted", inviteeToken.Plaintext, "PUT", bytes.NewBuffer(tokenData))
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("want %d status code; got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	check := new(data.Member)
+
+	err = jsonapi.UnmarshalPayload(resp.Body, check)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !check.IsAccepted {
+		t.Errorf("want IsAccepted to be true, got false")
+	}
+}
+
+func TestSharedListAccessByRole(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	item, _ := createItem(app, t)
+	viewer, viewerToken, err := createTestUserWithToken(t, app, "viewer@mail.ru")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = createTestMember(app, item.ListId, viewer.ID, data.RoleViewer, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := generateRequestWithToken(ts.URL+"/api/v1/lists/"+strconv.Itoa(int(item.ListId)), viewerToken.Plaintext, "", nil)
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("want %d status code; got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	check := new(data.List)
+
+	err = jsonapi.UnmarshalPayload(resp.Body, check)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if check.Role != data.RoleViewer {
+		t.Errorf("want Role to be %s, got %s", data.RoleViewer, check.Role)
+	}
+
+	var itemData = []byte(`{
+	  "data": {
+		"id": "` + strconv.Itoa(int(item.ID)) + `",
+		"type": "items",
+		"attributes": {
+		  "name": "Changed by viewer"
+		}
+	  }
+	}`)
+	req = generateRequestWithToken(ts.URL+"/api/v1/items/"+strconv.Itoa(int(item.ID)), viewerToken.Plaintext, "PATCH", bytes.NewBuffer(itemData))
+	resp2, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp2.Body.Close()
+
+	if resp2.StatusCode != http.StatusForbidden {
+		t.Errorf("want %d status code; got %d", http.StatusForbidden, resp2.StatusCode)
+	}
+}
+
+func TestPendingMemberHasNoAccess(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	item, _ := createItem(app, t)
+	invitee, inviteeToken, err := createTestUserWithToken(t, app, "invitee@mail.ru")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = createTestMember(app, item.ListId, invitee.ID, data.RoleEditor, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := generateRequestWithToken(ts.URL+"/api/v1/items/"+strconv.Itoa(int(item.ID)), inviteeToken.Plaintext, "", nil)
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("want %d status code; got %d", http.StatusNotFound, resp.StatusCode)
+	}
+}
@@ -33,13 +33,35 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 		}
 
 		var token = headerParts[1]
+		var scope = data.ScopeAuthentication
 		v := validator.New()
-		if data.ValidateTokenPlaintext(v, token); !v.Valid() {
+		if strings.HasPrefix(token, data.PersonalAccessTokenPrefix) {
+			scope = data.ScopePersonalAccess
+			data.ValidatePersonalAccessTokenPlaintext(v, token)
+		} else {
+			data.ValidateTokenPlaintext(v, token)
+		}
+		if !v.Valid() {
 			app.invalidAuthenticationTokenResponse(w, r)
 			return
 		}
 
-		user, err := app.models.Users.GetForToken(data.ScopeAuthentication, token)
+		user, err := app.models.Users.GetForToken(r.Context(), scope, token)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				app.invalidAuthenticationTokenResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		if scope == data.ScopePersonalAccess {
+			r, err = app.withAccessToken(r, token)
+		} else {
+			r, err = app.withSession(r, token)
+		}
 		if err != nil {
 			switch {
 			case errors.Is(err, data.ErrRecordNotFound):
@@ -55,6 +77,53 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 	})
 }
 
+// withSession adds the session of the authentication token to the request, the tokens created before the sessions
+// have none and are used without it.
+func (app *application) withSession(r *http.Request, token string) (*http.Request, error) {
+	session, err := app.models.Sessions.GetForToken(r.Context(), token)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			return r, nil
+		}
+		return r, err
+	}
+	err = app.touchSession(r, session)
+	if err != nil {
+		return r, err
+	}
+	return app.contextSetSession(r, session), nil
+}
+
+// withAccessToken adds the personal access token to the request, requirePermission limits the request to its scopes.
+func (app *application) withAccessToken(r *http.Request, token string) (*http.Request, error) {
+	accessToken, err := app.models.PersonalAccessTokens.GetForToken(r.Context(), token)
+	if err != nil {
+		return r, err
+	}
+	if accessToken.LastUsedAt == nil || time.Since(*accessToken.LastUsedAt) >= sessionTouchInterval {
+		err = app.models.PersonalAccessTokens.Touch(r.Context(), accessToken.ID)
+		if err != nil {
+			return r, err
+		}
+	}
+	return app.contextSetAccessToken(r, accessToken), nil
+}
+
+// sessionTouchInterval limits how often the last use of a session or a personal access token is written, the
+// requests of a busy client would otherwise all update the same row.
+const sessionTouchInterval = time.Minute
+
+// touchSession records the ip and the user agent of the request in the session when they change and the time of
+// the last use once in sessionTouchInterval.
+func (app *application) touchSession(r *http.Request, session *data.Session) error {
+	var ip, userAgent = clientIp(r), truncate(r.UserAgent(), 255)
+	if session.Ip == ip && session.UserAgent == userAgent && time.Since(session.LastUsedAt) < sessionTouchInterval {
+		return nil
+	}
+	session.Ip, session.UserAgent, session.LastUsedAt = ip, userAgent, time.Now()
+	return app.models.Sessions.Touch(r.Context(), session)
+}
+
 func (app *application) recoverPanic(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
@@ -99,20 +168,86 @@ func (app *application) requireActivatedUser(next http.HandlerFunc) http.Handler
 func (app *application) requirePermission(code string, next http.HandlerFunc) http.HandlerFunc {
 	fn := func(w http.ResponseWriter, r *http.Request) {
 		var user = app.contextGetUser(r)
-		permissions, err := app.models.Permissions.GetAllForUser(user.ID)
+		permissions, err := app.models.Permissions.GetAllForUser(r.Context(), user.ID)
 		if err != nil {
 			app.serverErrorResponse(w, r, err)
 			return
 		}
+		var accessToken = app.contextGetAccessToken(r)
+		if accessToken != nil {
+			permissions = permissions.Intersect(accessToken.Permissions)
+		}
 		if !permissions.Include(code) {
 			app.notPermittedResponse(w, r)
 			return
 		}
+		if accessToken != nil && accessToken.ListId != 0 {
+			allowed, err := app.accessTokenListAllows(r, accessToken.ListId, user.ID)
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+			if !allowed {
+				app.notPermittedResponse(w, r)
+				return
+			}
+		}
 		next.ServeHTTP(w, r)
 	}
 	return app.requireActivatedUser(fn)
 }
 
+// accessTokenListAllows reports whether the request only reaches the list a personal access token is restricted to:
+// the list itself, the resources under /api/v1/lists/:id and the items of the list. An item is created with the
+// list_id of the body, createItemsHandler checks it with accessTokenAllowsList.
+func (app *application) accessTokenListAllows(r *http.Request, listId int64, userId int64) (bool, error) {
+	var parts = strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/"), "/")
+	if len(parts) == 1 && parts[0] == "items" && r.Method == http.MethodPost {
+		return true, nil
+	}
+	if len(parts) < 2 {
+		return false, nil
+	}
+	id, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return false, nil
+	}
+	switch parts[0] {
+	case "lists":
+		return id == listId, nil
+	case "items":
+		item, err := app.models.Items.Get(r.Context(), id, userId)
+		if err != nil {
+			if errors.Is(err, data.ErrRecordNotFound) {
+				return false, nil
+			}
+			return false, err
+		}
+		return item.ListId == listId, nil
+	}
+	return false, nil
+}
+
+// accessTokenAllowsList reports whether the personal access token of the request may reach the list, it is used for
+// the list ids of the body which accessTokenListAllows can not see. Requests without a token are allowed.
+func (app *application) accessTokenAllowsList(r *http.Request, listId int64) bool {
+	var accessToken = app.contextGetAccessToken(r)
+	return accessToken == nil || accessToken.ListId == 0 || accessToken.ListId == listId
+}
+
+// denyPersonalAccessTokens keeps the account itself, its sessions and tokens out of reach of the personal access
+// tokens, they can only be managed after signing in with the password.
+func (app *application) denyPersonalAccessTokens(next http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if app.contextGetAccessToken(r) != nil {
+			app.notPermittedResponse(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
 func (app *application) rateLimit(next http.Handler) http.Handler {
 	var mu sync.Mutex
 	type client struct {
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"database/sql"
+	"easylist/internal/data"
+	"easylist/internal/migrate"
+	"easylist/migrations"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	MigrateCheck = "check"
+	MigrateAuto  = "auto"
+	MigrateOff   = "off"
+)
+
+var ErrSchemaBehind = errors.New("the database schema is behind, run the migrate up command or set db.migrate to auto")
+
+const migrateUsage = "usage: api migrate up|down [steps]|status|version"
+
+// openMigrator opens a separate connection for the migrations, the MySQL migrations have several statements in one
+// file, which the driver runs only with multiStatements turned on.
+func openMigrator(cfg config, dialect data.Dialect) (*migrate.Migrator, error) {
+	var dir = "."
+	if dialect != data.MySQL {
+		dir = string(dialect)
+	}
+	list, err := migrate.Load(migrations.FS, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var dsn = cfg.Db.Dsn
+	if dialect == data.MySQL && !strings.Contains(dsn, "multiStatements=") {
+		if strings.Contains(dsn, "?") {
+			dsn += "&multiStatements=true"
+		} else {
+			dsn += "?multiStatements=true"
+		}
+	}
+	db, err := sql.Open(dialect.DriverName(), dsn)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	return migrate.New(data.NewDB(db, dialect), list), nil
+}
+
+// runMigrations runs the migrate subcommand with its arguments.
+func runMigrations(migrator *migrate.Migrator, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up()
+		for _, migration := range applied {
+			fmt.Fprintf(out, "applied %06d_%s\n", migration.Version, migration.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Fprintln(out, "no pending migrations")
+		}
+		return err
+	case "down":
+		var steps = 1
+		if len(args) > 1 {
+			var err error
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return errors.New(migrateUsage)
+			}
+		}
+		rolledBack, err := migrator.Down(steps)
+		for _, migration := range rolledBack {
+			fmt.Fprintf(out, "rolled back %06d_%s\n", migration.Version, migration.Name)
+		}
+		return err
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			var state = "pending"
+			if status.Applied {
+				state = "applied"
+			}
+			fmt.Fprintf(out, "%-8s %06d_%s\n", state, status.Version, status.Name)
+		}
+		return nil
+	case "version":
+		version, dirty, err := migrator.Version()
+		if err != nil {
+			return err
+		}
+		if dirty {
+			fmt.Fprintf(out, "%d (dirty)\n", version)
+		} else {
+			fmt.Fprintln(out, version)
+		}
+		return nil
+	}
+	return errors.New(migrateUsage)
+}
+
+// checkSchema makes sure the server doesn't start against a database with pending migrations, depending on db.migrate
+// they are applied or the start is refused.
+func (app *application) checkSchema(migrator *migrate.Migrator) error {
+	switch app.config.Db.Migrate {
+	case MigrateOff:
+		return nil
+	case MigrateAuto:
+		applied, err := migrator.Up()
+		for _, migration := range applied {
+			app.logger.PrintInfo("applied migration", map[string]string{"version": strconv.FormatInt(migration.Version, 10), "name": migration.Name})
+		}
+		return err
+	}
+	pending, err := migrator.Pending()
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: %d pending migrations", ErrSchemaBehind, len(pending))
+	}
+	return nil
+}
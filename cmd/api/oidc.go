@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"easylist/internal/data"
+	"easylist/internal/oidc"
+	"easylist/internal/validator"
+	"errors"
+	"fmt"
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// oidcAuthorizationTtl is how long the user has to sign in at the provider.
+const oidcAuthorizationTtl = 10 * time.Minute
+
+var (
+	errEmailNotVerified   = errors.New("the provider has not verified the email")
+	errRegistrationClosed = errors.New("the registration is closed")
+)
+
+// openOidcProviders creates the OpenID Connect providers configured in the oidc section. The redirect url defaults to
+// the callback page of the frontend, which posts the code and the state to createOidcTokenHandler.
+func openOidcProviders(cfg config) (map[string]*oidc.Provider, error) {
+	var providers = make(map[string]*oidc.Provider, len(cfg.Oidc.Providers))
+	for name, provider := range cfg.Oidc.Providers {
+		if provider.Issuer == "" || provider.ClientId == "" {
+			return nil, fmt.Errorf("oidc: issuer and clientId of the provider %q must be configured", name)
+		}
+		var redirectUrl = provider.RedirectUrl
+		if redirectUrl == "" {
+			var base = cfg.Frontend
+			if base == "" {
+				base = cfg.Domain
+			}
+			redirectUrl = strings.TrimSuffix(base, "/") + "/oidc/" + name + "/callback"
+		}
+		providers[name] = &oidc.Provider{
+			Name:         name,
+			Issuer:       provider.Issuer,
+			ClientId:     provider.ClientId,
+			ClientSecret: provider.ClientSecret,
+			RedirectUrl:  redirectUrl,
+			Scopes:       provider.Scopes,
+		}
+	}
+	return providers, nil
+}
+
+// createOidcAuthorizationHandler starts the sign in with the provider, the frontend sends the user to the
+// authorization url and gets the code and the state back on the redirect url.
+func (app *application) createOidcAuthorizationHandler(w http.ResponseWriter, r *http.Request) {
+	var name = httprouter.ParamsFromContext(r.Context()).ByName("provider")
+	provider, ok := app.oidcProviders[name]
+	if !ok {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var err error
+	var authorization = &data.OidcAuthorization{Provider: name, Expiry: time.Now().Add(oidcAuthorizationTtl)}
+	for _, value := range []*string{&authorization.State, &authorization.Nonce, &authorization.CodeVerifier} {
+		*value, err = oidc.RandomString()
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+	authorization.AuthorizationUrl, err = provider.AuthorizationUrl(r.Context(), authorization.State, authorization.Nonce, authorization.CodeVerifier)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.models.OidcAuthorizations.Insert(r.Context(), authorization)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, authorization, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// createOidcTokenHandler finishes the sign in with the provider, e.g.
+// {"data": {"type": "tokens", "attributes": {"provider": "google", "code": "...", "state": "...", "device_name": "Phone"}}}.
+// The account of the provider is linked to the user with the same verified email, a new user is registered when
+// nobody has it and the registration is open. The response is the same as the one of createAuthenticationTokenHandler.
+func (app *application) createOidcTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var input = Input[TokensAttributes]{Data: InputAttributes[TokensAttributes]{
+		Type:       "tokens",
+		Attributes: TokensAttributes{},
+	}}
+
+	var err = readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, "createOidcTokenHandler", err)
+		return
+	}
+	var attributes = input.Data.Attributes
+	provider, ok := app.oidcProviders[attributes.Provider]
+
+	var v = validator.New()
+	v.Check(input.Data.Type == "tokens", "data.type", "Wrong type provided, accepted type is tokens")
+	v.Check(ok, "provider", "is not configured")
+	v.Check(attributes.Code != "", "code", "must be provided")
+	v.Check(attributes.State != "", "state", "must be provided")
+	v.Check(len(attributes.DeviceName) <= 255, "device_name", "must not be more than 255 bytes long")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	authorization, err := app.models.OidcAuthorizations.Consume(r.Context(), attributes.Provider, attributes.State)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.failedValidationResponse(w, r, map[string]string{"state": "is unknown or has expired"})
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	claims, err := provider.Exchange(r.Context(), attributes.Code, authorization.CodeVerifier, authorization.Nonce, app.now())
+	if err != nil {
+		app.logger.PrintError(err, map[string]string{"provider": attributes.Provider})
+		app.invalidCredentialsResponse(w, r)
+		return
+	}
+
+	user, err := app.oidcUser(r.Context(), attributes.Provider, claims)
+	if err != nil {
+		switch {
+		case errors.Is(err, errEmailNotVerified):
+			app.failedValidationResponse(w, r, map[string]string{"email": "must be verified by the provider"})
+		case errors.Is(err, errRegistrationClosed):
+			app.failedValidationResponse(w, r, map[string]string{"email": "has no account and the registration is closed"})
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if app.challengeTwoFactor(w, r, user) {
+		return
+	}
+	app.startSession(w, r, user, attributes.DeviceName)
+}
+
+// oidcUser returns the user the account of the provider belongs to. An account seen for the first time is linked to
+// the user with its verified email, or to a new user.
+func (app *application) oidcUser(ctx context.Context, provider string, claims *oidc.Claims) (*data.User, error) {
+	user, err := app.models.Identities.GetUser(ctx, provider, claims.Subject)
+	if err == nil || !errors.Is(err, data.ErrRecordNotFound) {
+		return user, err
+	}
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, errEmailNotVerified
+	}
+
+	user, err = app.models.Users.GetByEmail(ctx, claims.Email)
+	switch {
+	case errors.Is(err, data.ErrRecordNotFound):
+		user, err = app.registerOidcUser(ctx, claims)
+		if err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	case !user.IsActive:
+		err = app.takeOverOidcUser(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = app.models.Identities.Insert(ctx, &data.Identity{UserId: user.ID, Provider: provider, Subject: claims.Subject, Email: claims.Email})
+	if errors.Is(err, data.ErrDuplicateIdentity) {
+		// a parallel sign in has linked the account already
+		return app.models.Identities.GetUser(ctx, provider, claims.Subject)
+	}
+	return user, err
+}
+
+// takeOverOidcUser activates the user whose email the provider has confirmed, just like the activation mail would.
+// Whoever registered the account before may not own the email, so the password is replaced with a random one and
+// everything set up with it is revoked in the same transaction.
+func (app *application) takeOverOidcUser(ctx context.Context, user *data.User) error {
+	password, err := oidc.RandomString()
+	if err != nil {
+		return err
+	}
+	err = user.Password.Set(password)
+	if err != nil {
+		return err
+	}
+	user.IsActive = true
+
+	return app.models.WithTx(ctx, func(tx data.Models) error {
+		err := tx.Users.Update(ctx, user)
+		if err != nil {
+			return err
+		}
+		err = tx.Sessions.DeleteAllForUser(ctx, user.ID, 0)
+		if err != nil {
+			return err
+		}
+		err = tx.Tokens.DeleteByUser(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+		return tx.TwoFactor.Disable(ctx, user.ID)
+	})
+}
+
+// registerOidcUser creates an active user for the claims. The user gets a random password, it can be changed with
+// the password reset.
+func (app *application) registerOidcUser(ctx context.Context, claims *oidc.Claims) (*data.User, error) {
+	if !app.config.Registration {
+		return nil, errRegistrationClosed
+	}
+	var name = claims.Name
+	if name == "" {
+		name, _, _ = strings.Cut(claims.Email, "@")
+	}
+	var user = &data.User{
+		Name:     truncate(name, 189),
+		Email:    claims.Email,
+		IsActive: true,
+		Currency: app.defaultCurrency(),
+	}
+	password, err := oidc.RandomString()
+	if err != nil {
+		return nil, err
+	}
+	err = user.Password.Set(password)
+	if err != nil {
+		return nil, err
+	}
+	err = app.models.WithTx(ctx, func(tx data.Models) error {
+		err := tx.Users.Insert(ctx, user)
+		if err != nil {
+			return err
+		}
+		return tx.Permissions.AddForUser(ctx, user.ID, defaultPermissions...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"easylist/internal/data"
+	"easylist/internal/oidc/oidctest"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type oidcAuthorizationResponse struct {
+	Data struct {
+		Attributes struct {
+			State            string `json:"state"`
+			AuthorizationUrl string `json:"authorization_url"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+// startOidc asks the application for the authorization url and signs in at the provider, it returns the state
+// together with the code of the redirect.
+func startOidc(t *testing.T, ts *testServer, provider string) (string, string) {
+	t.Helper()
+	req := generateRequestWithToken(ts.URL+"/api/v1/oidc/"+provider+"/authorization", "", "POST", nil)
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("want %d status code; got %d", http.StatusCreated, resp.StatusCode)
+	}
+	var authorization oidcAuthorizationResponse
+	err = json.NewDecoder(resp.Body).Decode(&authorization)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error { return http.ErrUseLastResponse }}
+	redirect, err := client.Get(authorization.Data.Attributes.AuthorizationUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	redirect.Body.Close()
+	location, err := url.Parse(redirect.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if location.Path != "/oidc/"+provider+"/callback" || location.Query().Get("state") != authorization.Data.Attributes.State {
+		t.Fatalf("want the redirect to the callback with the state; got %s", location)
+	}
+	return authorization.Data.Attributes.State, location.Query().Get("code")
+}
+
+func finishOidc(t *testing.T, ts *testServer, provider string, state string, code string) (int, sessionTokensResponse) {
+	t.Helper()
+	var body = []byte(`{"data": {"type": "tokens", "attributes": {"provider": "` + provider + `", "state": "` + state + `", "code": "` + code + `"}}}`)
+	req := generateRequestWithToken(ts.URL+"/api/v1/tokens/oidc", "", "POST", bytes.NewBuffer(body))
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var check sessionTokensResponse
+	if resp.StatusCode == http.StatusCreated {
+		err = json.NewDecoder(resp.Body).Decode(&check)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp.StatusCode, check
+}
+
+func TestOidcSignIn(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	provider := oidctest.NewServer("easylist", "secret")
+	defer provider.Close()
+	app.config.Registration = true
+	app.config.Oidc.Providers = map[string]oidcProvider{"mock": {Issuer: provider.URL, ClientId: "easylist", ClientSecret: "secret"}}
+	var err error
+	app.oidcProviders, err = openOidcProviders(app.config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	var signIn = func(user oidctest.User) (int, sessionTokensResponse) {
+		t.Helper()
+		provider.User = user
+		state, code := startOidc(t, ts, "mock")
+		return finishOidc(t, ts, "mock", state, code)
+	}
+
+	item, _ := createItem(app, t)
+
+	t.Run("links the user with the verified email", func(t *testing.T) {
+		status, tokens := signIn(oidctest.User{Subject: "1", Email: "test@mail.ru", EmailVerified: true})
+		if status != http.StatusCreated || tokens.Data.Attributes.Token == "" {
+			t.Fatalf("want %d status code with a token; got %d", http.StatusCreated, status)
+		}
+		if status := sendWithToken(t, ts, tokens.Data.Attributes.Token, "GET", "/api/v1/items", ""); status != http.StatusOK {
+			t.Errorf("want the token to work; got %d", status)
+		}
+		user, err := app.models.Identities.GetUser(context.Background(), "mock", "1")
+		if err != nil || user.ID != item.UserId {
+			t.Fatalf("want the identity linked to the user %d; got %v %v", item.UserId, user, err)
+		}
+	})
+
+	t.Run("finds the linked user after the email changed", func(t *testing.T) {
+		if status, _ := signIn(oidctest.User{Subject: "1", Email: "changed@mail.ru"}); status != http.StatusCreated {
+			t.Errorf("want %d status code; got %d", http.StatusCreated, status)
+		}
+	})
+
+	t.Run("refuses an unverified email", func(t *testing.T) {
+		if status, _ := signIn(oidctest.User{Subject: "2", Email: "test@mail.ru"}); status != http.StatusUnprocessableEntity {
+			t.Errorf("want %d status code; got %d", http.StatusUnprocessableEntity, status)
+		}
+	})
+
+	t.Run("registers a new user", func(t *testing.T) {
+		if status, _ := signIn(oidctest.User{Subject: "3", Email: "new@mail.ru", EmailVerified: true, Name: "New"}); status != http.StatusCreated {
+			t.Fatalf("want %d status code; got %d", http.StatusCreated, status)
+		}
+		user, err := app.models.Users.GetByEmail(context.Background(), "new@mail.ru")
+		if err != nil || user.Name != "New" || !user.IsActive {
+			t.Fatalf("want an active user; got %+v %v", user, err)
+		}
+		permissions, err := app.models.Permissions.GetAllForUser(context.Background(), user.ID)
+		if err != nil || !permissions.Include("items:write") {
+			t.Errorf("want the default permissions; got %v %v", permissions, err)
+		}
+	})
+
+	t.Run("registers again after the user is deleted", func(t *testing.T) {
+		var account = oidctest.User{Subject: "6", Email: "deleted@mail.ru", EmailVerified: true}
+		status, tokens := signIn(account)
+		if status != http.StatusCreated {
+			t.Fatalf("want %d status code; got %d", http.StatusCreated, status)
+		}
+		user, err := app.models.Identities.GetUser(context.Background(), "mock", "6")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var path = "/api/v1/users/" + strconv.Itoa(int(user.ID))
+		if status := sendWithToken(t, ts, tokens.Data.Attributes.Token, "DELETE", path, ""); status != http.StatusNoContent {
+			t.Fatalf("want %d status code; got %d", http.StatusNoContent, status)
+		}
+
+		if status, _ := signIn(account); status != http.StatusCreated {
+			t.Fatalf("want %d status code; got %d", http.StatusCreated, status)
+		}
+		registered, err := app.models.Identities.GetUser(context.Background(), "mock", "6")
+		if err != nil || registered.ID == user.ID {
+			t.Errorf("want the identity linked to a new user; got %v %v", registered, err)
+		}
+	})
+
+	t.Run("takes over an inactive account", func(t *testing.T) {
+		var user = &data.User{Name: "Squatter", Email: "inactive@mail.ru"}
+		err := user.Password.Set("password123")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = app.models.Users.Insert(context.Background(), user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		activation, err := app.models.Tokens.New(context.Background(), user.ID, time.Hour, data.ScopeActivation)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if status, _ := signIn(oidctest.User{Subject: "5", Email: "inactive@mail.ru", EmailVerified: true}); status != http.StatusCreated {
+			t.Fatalf("want %d status code; got %d", http.StatusCreated, status)
+		}
+		if status, _ := postTokens(t, ts, "authentication", `{"email": "inactive@mail.ru", "password": "password123"}`); status != http.StatusUnauthorized {
+			t.Errorf("want the password of the earlier registration to be replaced; got %d", status)
+		}
+		if _, err := app.models.Users.GetForToken(context.Background(), data.ScopeActivation, activation.Plaintext); !errors.Is(err, data.ErrRecordNotFound) {
+			t.Errorf("want the tokens of the earlier registration to be deleted; got %v", err)
+		}
+	})
+
+	t.Run("does not register when the registration is closed", func(t *testing.T) {
+		app.config.Registration = false
+		defer func() { app.config.Registration = true }()
+		if status, _ := signIn(oidctest.User{Subject: "4", Email: "closed@mail.ru", EmailVerified: true}); status != http.StatusUnprocessableEntity {
+			t.Errorf("want %d status code; got %d", http.StatusUnprocessableEntity, status)
+		}
+	})
+
+	t.Run("state and code work once", func(t *testing.T) {
+		provider.User = oidctest.User{Subject: "1"}
+		state, code := startOidc(t, ts, "mock")
+		if status, _ := finishOidc(t, ts, "mock", state, "wrong"); status != http.StatusUnauthorized {
+			t.Errorf("want a wrong code to be refused; got %d", status)
+		}
+		if status, _ := finishOidc(t, ts, "mock", state, code); status != http.StatusUnprocessableEntity {
+			t.Errorf("want the spent state to be refused; got %d", status)
+		}
+	})
+
+	t.Run("unknown provider", func(t *testing.T) {
+		if status, _ := finishOidc(t, ts, "other", "state", "code"); status != http.StatusUnprocessableEntity {
+			t.Errorf("want %d status code; got %d", http.StatusUnprocessableEntity, status)
+		}
+		if status := sendWithToken(t, ts, "", "POST", "/api/v1/oidc/other/authorization", ""); status != http.StatusNotFound {
+			t.Errorf("want %d status code; got %d", http.StatusNotFound, status)
+		}
+	})
+}
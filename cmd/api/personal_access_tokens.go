@@ -0,0 +1,125 @@
+package main
+
+import (
+	"easylist/internal/data"
+	"easylist/internal/validator"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const defaultPersonalAccessTokenTtl = 30 * 24 * time.Hour
+const maxPersonalAccessTokenTtl = 365 * 24 * time.Hour
+
+func (app *application) indexPersonalAccessTokensHandler(w http.ResponseWriter, r *http.Request) {
+	var userModel = app.contextGetUser(r)
+
+	accessTokens, err := app.models.PersonalAccessTokens.GetAll(r.Context(), userModel.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, accessTokens, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// createPersonalAccessTokenHandler creates a token for scripts and integrations, e.g.
+// {"data": {"type": "personal-access-tokens", "attributes": {"name": "Shopping bot", "permissions": ["items:read", "items:write"], "list_id": 7, "expiry": "2026-01-01T00:00:00Z"}}}.
+// The permissions must be granted to the user, the list restricts the token to the list and its items. The plaintext
+// of the token is only returned in this response.
+func (app *application) createPersonalAccessTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var input = Input[PersonalAccessTokenAttributes]{Data: InputAttributes[PersonalAccessTokenAttributes]{
+		Attributes: PersonalAccessTokenAttributes{},
+	}}
+	var err = readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, "createPersonalAccessTokenHandler", err)
+		return
+	}
+
+	var userModel = app.contextGetUser(r)
+	var attributes = input.Data.Attributes
+
+	permissions, err := app.models.Permissions.GetAllForUser(r.Context(), userModel.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var accessToken = &data.PersonalAccessToken{
+		UserId:      userModel.ID,
+		Name:        attributes.Name,
+		Permissions: attributes.Permissions,
+		Expiry:      time.Now().Add(defaultPersonalAccessTokenTtl),
+	}
+	if attributes.Expiry != nil {
+		accessToken.Expiry = *attributes.Expiry
+	}
+	if attributes.ListId != nil {
+		accessToken.ListId = *attributes.ListId
+	}
+
+	var v = validator.New()
+	v.Check(input.Data.Type == data.PersonalAccessTokensType, "data.type", "Wrong type provided, accepted type is personal-access-tokens")
+	v.Check(accessToken.Name != "", "name", "must be provided")
+	v.Check(len(accessToken.Name) <= 255, "name", "must not be more than 255 bytes long")
+	v.Check(len(accessToken.Permissions) > 0, "permissions", "must contain at least one permission")
+	v.Check(validator.Unique(accessToken.Permissions), "permissions", "must not contain duplicate values")
+	for _, code := range accessToken.Permissions {
+		v.Check(permissions.Include(code), "permissions", fmt.Sprintf("%q is not granted to the user", code))
+	}
+	v.Check(accessToken.Expiry.After(time.Now()), "expiry", "must be in the future")
+	v.Check(time.Until(accessToken.Expiry) <= maxPersonalAccessTokenTtl, "expiry", "must not be more than a year ahead")
+	if accessToken.ListId != 0 {
+		_, err = app.models.Lists.Get(r.Context(), accessToken.ListId, userModel.ID)
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("list_id", "must be one of the lists of the user")
+		case err != nil:
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.PersonalAccessTokens.Insert(r.Context(), accessToken)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, accessToken, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deletePersonalAccessTokenHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var userModel = app.contextGetUser(r)
+
+	err = app.models.PersonalAccessTokens.Delete(r.Context(), id, userModel.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
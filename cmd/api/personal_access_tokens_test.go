@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"easylist/internal/data"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type personalAccessTokenResponse struct {
+	Data struct {
+		Id         string `json:"id"`
+		Attributes struct {
+			Token       string   `json:"token"`
+			Name        string   `json:"name"`
+			Permissions []string `json:"permissions"`
+			ListId      int64    `json:"list_id"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+func createPersonalAccessToken(t *testing.T, ts *testServer, token string, attributes string) (int, personalAccessTokenResponse) {
+	var body = []byte(`{"data": {"type": "personal-access-tokens", "attributes": ` + attributes + `}}`)
+	req := generateRequestWithToken(ts.URL+"/api/v1/tokens/personal-access", token, "POST", bytes.NewBuffer(body))
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var check personalAccessTokenResponse
+	if resp.StatusCode == http.StatusCreated {
+		err = json.NewDecoder(resp.Body).Decode(&check)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp.StatusCode, check
+}
+
+func sendWithToken(t *testing.T, ts *testServer, token string, method string, path string, body string) int {
+	req := generateRequestWithToken(ts.URL+path, token, method, strings.NewReader(body))
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestPersonalAccessTokenScopes(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	item, token := createItem(app, t)
+	list, err := app.models.Lists.Get(context.Background(), item.ListId, item.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var other = data.List{FolderId: list.FolderId, UserId: item.UserId, Name: "Other"}
+	err = createTestList(app, &other)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status, created := createPersonalAccessToken(t, ts, token.Plaintext, `{"name": "Shopping bot", "permissions": ["items:read"], "list_id": `+strconv.FormatInt(item.ListId, 10)+`}`)
+	if status != http.StatusCreated {
+		t.Fatalf("want %d status code; got %d", http.StatusCreated, status)
+	}
+	var accessToken = created.Data.Attributes.Token
+	if !strings.HasPrefix(accessToken, data.PersonalAccessTokenPrefix) || created.Data.Attributes.ListId != item.ListId {
+		t.Fatalf("want a prefixed token restricted to the list; got %+v", created.Data.Attributes)
+	}
+
+	var itemPath = "/api/v1/items/" + strconv.FormatInt(item.ID, 10)
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		status int
+	}{
+		{name: "items of the list", method: "GET", path: "/api/v1/lists/" + strconv.FormatInt(item.ListId, 10) + "/items", status: http.StatusOK},
+		{name: "item of the list", method: "GET", path: itemPath, status: http.StatusOK},
+		{name: "permission out of the scopes", method: "DELETE", path: itemPath, status: http.StatusForbidden},
+		{name: "another list", method: "GET", path: "/api/v1/lists/" + strconv.FormatInt(other.ID, 10) + "/items", status: http.StatusForbidden},
+		{name: "all items", method: "GET", path: "/api/v1/items", status: http.StatusForbidden},
+		{name: "folders", method: "GET", path: "/api/v1/folders", status: http.StatusForbidden},
+		{name: "sessions", method: "GET", path: "/api/v1/sessions", status: http.StatusForbidden},
+		{name: "tokens", method: "GET", path: "/api/v1/tokens/personal-access", status: http.StatusForbidden},
+		{name: "account", method: "DELETE", path: "/api/v1/users/" + strconv.FormatInt(item.UserId, 10), status: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status := sendWithToken(t, ts, accessToken, tt.method, tt.path, ""); status != tt.status {
+				t.Errorf("want %d status code; got %d", tt.status, status)
+			}
+		})
+	}
+
+	accessTokens, err := app.models.PersonalAccessTokens.GetAll(context.Background(), item.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accessTokens) != 1 || accessTokens[0].LastUsedAt == nil || !accessTokens[0].Permissions.Include("items:read") {
+		t.Fatalf("want the used token with its permissions; got %+v", accessTokens)
+	}
+
+	if status := sendWithToken(t, ts, token.Plaintext, "DELETE", "/api/v1/tokens/personal-access/"+created.Data.Id, ""); status != http.StatusNoContent {
+		t.Errorf("want %d status code; got %d", http.StatusNoContent, status)
+	}
+	if status := sendWithToken(t, ts, accessToken, "GET", itemPath, ""); status != http.StatusUnauthorized {
+		t.Errorf("want the revoked token to be refused; got %d", status)
+	}
+}
+
+func TestPersonalAccessTokenWritesItemsOfItsList(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	item, token := createItem(app, t)
+	var other = data.List{UserId: item.UserId, Name: "Other"}
+	err := createTestList(app, &other)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status, created := createPersonalAccessToken(t, ts, token.Plaintext, `{"name": "Shopping bot", "permissions": ["items:read", "items:write"], "list_id": `+strconv.FormatInt(item.ListId, 10)+`}`)
+	if status != http.StatusCreated {
+		t.Fatalf("want %d status code; got %d", http.StatusCreated, status)
+	}
+	var accessToken = created.Data.Attributes.Token
+	var itemPath = "/api/v1/items/" + strconv.FormatInt(item.ID, 10)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{name: "create in the list", method: "POST", path: "/api/v1/items", body: `{"data": {"type": "items", "attributes": {"name": "Milk", "list_id": ` + strconv.FormatInt(item.ListId, 10) + `}}}`, status: http.StatusCreated},
+		{name: "create in another list", method: "POST", path: "/api/v1/items", body: `{"data": {"type": "items", "attributes": {"name": "Milk", "list_id": ` + strconv.FormatInt(other.ID, 10) + `}}}`, status: http.StatusForbidden},
+		{name: "move to another list", method: "PATCH", path: itemPath, body: `{"data": {"type": "items", "id": "` + strconv.FormatInt(item.ID, 10) + `", "attributes": {"list_id": ` + strconv.FormatInt(other.ID, 10) + `}}}`, status: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status := sendWithToken(t, ts, accessToken, tt.method, tt.path, tt.body); status != tt.status {
+				t.Errorf("want %d status code; got %d", tt.status, status)
+			}
+		})
+	}
+}
+
+func TestPersonalAccessTokenValidation(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	// the list belongs to another user
+	item, _ := createItem(app, t)
+	_, token, err := createTestUserWithToken(t, app, "other@mail.ru")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, attributes := range []string{
+		`{"name": "", "permissions": ["items:read"]}`,
+		`{"name": "Bot", "permissions": []}`,
+		`{"name": "Bot", "permissions": ["rates:write"]}`,
+		`{"name": "Bot", "permissions": ["items:read", "items:read"]}`,
+		`{"name": "Bot", "permissions": ["items:read"], "expiry": "2000-01-01T00:00:00Z"}`,
+		`{"name": "Bot", "permissions": ["items:read"], "expiry": "2999-01-01T00:00:00Z"}`,
+		`{"name": "Bot", "permissions": ["items:read"], "list_id": ` + strconv.FormatInt(item.ListId, 10) + `}`,
+	} {
+		if status, _ := createPersonalAccessToken(t, ts, token.Plaintext, attributes); status != http.StatusUnprocessableEntity {
+			t.Errorf("want %d status code for %s; got %d", http.StatusUnprocessableEntity, attributes, status)
+		}
+	}
+}
@@ -14,7 +14,7 @@ func (app *application) publicList(w http.ResponseWriter, r *http.Request) {
 	var params = httprouter.ParamsFromContext(r.Context())
 	var emailData EmailData
 	link := params.ByName("id")
-	listModel, err := app.models.Lists.GetPublic(link)
+	listModel, err := app.models.Lists.GetPublic(r.Context(), link)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -28,12 +28,14 @@ func (app *application) publicList(w http.ResponseWriter, r *http.Request) {
 	v := validator.New()
 	var input = app.NewItemInput(r, v)
 	input.Filters.Size = 100
-	items, _, err := app.models.Items.GetAll("", listModel.UserId, listModel.ID, false, input.Filters)
+	input.Filters.Group = data.GroupByCategory
+	items, _, err := app.models.Items.GetAll(r.Context(), listModel.UserId, listModel.ID, data.ItemFilters{}, input.Filters)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 	emailData.Items = items
+	emailData.Groups = data.GroupItems(items)
 	ts, err := template.ParseFiles("./ui/html/public.page.html")
 	if err != nil {
 		log.Println(err.Error())
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"easylist/internal/data"
+	"easylist/internal/events"
+	"easylist/internal/validator"
+	"encoding/json"
+	"errors"
+	"github.com/google/jsonapi"
+	"net/http"
+	"strconv"
+)
+
+const maxReorderRecords = 10000
+
+// reorderItemsHandler changes the order of the items of a list, see readReorder for the accepted input.
+func (app *application) reorderItemsHandler(w http.ResponseWriter, r *http.Request) {
+	listId, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var userModel = app.contextGetUser(r)
+
+	list, err := app.models.Lists.Get(r.Context(), listId, userModel.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if !list.CanEdit() {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	reorder, ok := app.readReorder(w, r, "reorderItemsHandler")
+	if !ok {
+		return
+	}
+
+	ranks, err := app.models.Items.Reorder(r.Context(), list.ID, reorder)
+	if err != nil {
+		app.reorderErrorResponse(w, r, err, "items of the list")
+		return
+	}
+	if len(ranks) > 0 {
+		app.publishListEvent(events.ItemsReordered, list.ID)
+	}
+	app.writeRanks(w, r, ItemType, ranks)
+}
+
+// reorderListsHandler changes the order of the lists of the user in a folder.
+func (app *application) reorderListsHandler(w http.ResponseWriter, r *http.Request) {
+	folderId, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var userModel = app.contextGetUser(r)
+
+	_, err = app.models.Folders.Get(r.Context(), folderId, userModel.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	reorder, ok := app.readReorder(w, r, "reorderListsHandler")
+	if !ok {
+		return
+	}
+
+	ranks, err := app.models.Lists.Reorder(r.Context(), folderId, userModel.ID, reorder)
+	if err != nil {
+		app.reorderErrorResponse(w, r, err, "lists of the folder")
+		return
+	}
+	app.writeRanks(w, r, ListType, ranks)
+}
+
+// reorderFoldersHandler changes the order of the folders of the user.
+func (app *application) reorderFoldersHandler(w http.ResponseWriter, r *http.Request) {
+	var userModel = app.contextGetUser(r)
+
+	reorder, ok := app.readReorder(w, r, "reorderFoldersHandler")
+	if !ok {
+		return
+	}
+
+	ranks, err := app.models.Folders.Reorder(r.Context(), userModel.ID, reorder)
+	if err != nil {
+		app.reorderErrorResponse(w, r, err, "folders")
+		return
+	}
+	app.writeRanks(w, r, data.FolderType, ranks)
+}
+
+// readReorder reads either the complete new order, {"order": [3, 1, 2]}, or a move of one record right before or
+// after another one, {"move": {"id": 3, "before": 1}}. It writes the error response when the input is invalid.
+func (app *application) readReorder(w http.ResponseWriter, r *http.Request, handlerName string) (data.Reorder, bool) {
+	var input ReorderInput
+	err := decodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, handlerName, err)
+		return data.Reorder{}, false
+	}
+
+	var v = validator.New()
+	v.Check((input.Order != nil) != (input.Move != nil), "order", "either order or move must be provided")
+	if input.Order != nil {
+		v.Check(len(input.Order) <= maxReorderRecords, "order", "must contain no more than 10000 ids")
+		var ids = make([]string, 0, len(input.Order))
+		for _, id := range input.Order {
+			ids = append(ids, strconv.FormatInt(id, 10))
+		}
+		v.Check(validator.Unique(ids), "order", "must not contain duplicate ids")
+	}
+	if input.Move != nil {
+		v.Check(input.Move.ID > 0, "move.id", "must be provided")
+		v.Check((input.Move.Before > 0) != (input.Move.After > 0), "move", "either before or after must be provided")
+		v.Check(input.Move.Before != input.Move.ID && input.Move.After != input.Move.ID, "move", "must not refer to the moved record")
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return data.Reorder{}, false
+	}
+
+	if input.Move != nil {
+		return data.Reorder{ID: input.Move.ID, Before: input.Move.Before, After: input.Move.After}, true
+	}
+	return data.Reorder{IDs: input.Order}, true
+}
+
+func (app *application) reorderErrorResponse(w http.ResponseWriter, r *http.Request, err error, records string) {
+	var v = validator.New()
+	switch {
+	case errors.Is(err, data.ErrInvalidOrder):
+		v.AddError("order", "must contain every one of the "+records+" exactly once")
+	case errors.Is(err, data.ErrRecordNotFound):
+		v.AddError("move", "must refer to the "+records)
+	default:
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	app.failedValidationResponse(w, r, v.Errors)
+}
+
+// writeRanks responds with the records whose order changed as resource identifiers, their new order and version are
+// in the meta, e.g. {"data": [{"type": "items", "id": "3", "meta": {"order": 1536, "version": 4}}]}.
+func (app *application) writeRanks(w http.ResponseWriter, r *http.Request, recordType string, ranks data.Ranks) {
+	var nodes = make([]*jsonapi.Node, 0, len(ranks))
+	for _, rank := range ranks {
+		var meta = jsonapi.Meta{"order": rank.Order, "version": rank.Version}
+		nodes = append(nodes, &jsonapi.Node{Type: recordType, ID: strconv.FormatInt(rank.ID, 10), Meta: &meta})
+	}
+
+	writeHeaders(w, http.StatusOK, nil)
+	err := json.NewEncoder(w).Encode(jsonapi.ManyPayload{Data: nodes})
+	if err != nil {
+		app.logError(r, err)
+	}
+}
@@ -0,0 +1,363 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"easylist/internal/data"
+	"easylist/internal/events"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+type rankResponse struct {
+	Data []struct {
+		Type string `json:"type"`
+		ID   string `json:"id"`
+		Meta struct {
+			Order   int32 `json:"order"`
+			Version int32 `json:"version"`
+		} `json:"meta"`
+	} `json:"data"`
+	Errors []struct {
+		Title string `json:"title"`
+	} `json:"errors"`
+}
+
+// createItems creates a list with count items and returns their ids in the order they were created.
+func createItems(app *application, t *testing.T, count int) (data.Item, []int64, *data.Token) {
+	item, token := createItem(app, t)
+	var ids = []int64{item.ID}
+	for len(ids) < count {
+		var next = data.Item{ListId: item.ListId, UserId: item.UserId, Name: "Item " + strconv.Itoa(len(ids))}
+		err := createTestItem(app, &next)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, next.ID)
+	}
+	return item, ids, token
+}
+
+// itemSequence returns the ids of the items sorted by their order.
+func itemSequence(app *application, t *testing.T, userId int64, ids []int64) []int64 {
+	var items = make([]*data.Item, 0, len(ids))
+	for _, id := range ids {
+		item, err := app.models.Items.Get(context.Background(), id, userId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		items = append(items, item)
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Order < items[j].Order
+	})
+	var sequence = make([]int64, 0, len(items))
+	for i, item := range items {
+		if i > 0 && items[i-1].Order == item.Order {
+			t.Errorf("want unique orders; items %d and %d share %d", items[i-1].ID, item.ID, item.Order)
+		}
+		sequence = append(sequence, item.ID)
+	}
+	return sequence
+}
+
+func postReorder(t *testing.T, ts *testServer, url string, token string, body string) (int, rankResponse) {
+	req := generateRequestWithToken(ts.URL+url, token, "POST", bytes.NewBufferString(body))
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var check rankResponse
+	err = json.NewDecoder(resp.Body).Decode(&check)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, check
+}
+
+func moveBody(id int64, position string, target int64) string {
+	return `{"move": {"id": ` + strconv.FormatInt(id, 10) + `, "` + position + `": ` + strconv.FormatInt(target, 10) + `}}`
+}
+
+func sameSequence(got []int64, want []int64) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestReorderItemsWithFullOrder(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	item, ids, token := createItems(app, t, 3)
+	var url = "/api/v1/lists/" + strconv.FormatInt(item.ListId, 10) + "/items/reorder"
+	var want = []int64{ids[2], ids[0], ids[1]}
+	received, unsubscribe := app.events.Subscribe(item.ListId)
+	defer unsubscribe()
+
+	status, check := postReorder(t, ts, url, token.Plaintext, `{"order": [`+strconv.FormatInt(want[0], 10)+`, `+strconv.FormatInt(want[1], 10)+`, `+strconv.FormatInt(want[2], 10)+`]}`)
+	if status != http.StatusOK {
+		t.Fatalf("want %d status code; got %d", http.StatusOK, status)
+	}
+	if len(check.Data) != 3 || check.Data[0].Type != ItemType || check.Data[0].Meta.Order != 1024 {
+		t.Errorf("want the three items with their new orders; got %+v", check.Data)
+	}
+	if got := itemSequence(app, t, item.UserId, ids); !sameSequence(got, want) {
+		t.Errorf("want items in order %v; got %v", want, got)
+	}
+	select {
+	case event := <-received:
+		if event.Name != events.ItemsReordered {
+			t.Errorf("want event %s, got %s", events.ItemsReordered, event.Name)
+		}
+	default:
+		t.Errorf("want %s event, got nothing", events.ItemsReordered)
+	}
+}
+
+func TestReorderItemsWithMoves(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	item, ids, token := createItems(app, t, 4)
+	var url = "/api/v1/lists/" + strconv.FormatInt(item.ListId, 10) + "/items/reorder"
+
+	// the items are created with the orders 1 to 4, so the first move renumbers the list and the next ones only
+	// change the moved item until a gap is used up
+	var want = append([]int64{}, ids...)
+	var moves = []struct {
+		from     int
+		position string
+		to       int
+	}{
+		{3, "before", 0}, {0, "after", 1}, {2, "before", 1}, {1, "after", 3}, {2, "before", 1}, {1, "after", 3},
+		{2, "before", 1}, {1, "after", 3}, {2, "before", 1}, {1, "after", 3}, {2, "before", 1}, {1, "after", 3},
+		{2, "before", 1}, {1, "after", 3}, {0, "before", 2}, {3, "after", 2},
+	}
+	for index, move := range moves {
+		var id, target = ids[move.from], ids[move.to]
+		status, check := postReorder(t, ts, url, token.Plaintext, moveBody(id, move.position, target))
+		if status != http.StatusOK {
+			t.Fatalf("move %d: want %d status code; got %d", index, http.StatusOK, status)
+		}
+		if index > 0 && len(check.Data) == len(ids) {
+			t.Logf("move %d renumbered the list", index)
+		}
+
+		want = moveInSequence(want, id, target, move.position == "after")
+		if got := itemSequence(app, t, item.UserId, ids); !sameSequence(got, want) {
+			t.Fatalf("move %d: want items in order %v; got %v", index, want, got)
+		}
+	}
+}
+
+func moveInSequence(sequence []int64, id int64, target int64, after bool) []int64 {
+	var result = make([]int64, 0, len(sequence))
+	for _, current := range sequence {
+		if current == id {
+			continue
+		}
+		if current == target && !after {
+			result = append(result, id)
+		}
+		result = append(result, current)
+		if current == target && after {
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+func TestReorderItemsValidation(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	item, ids, token := createItems(app, t, 2)
+	list, err := app.models.Lists.Get(context.Background(), item.ListId, item.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var otherList = data.List{FolderId: list.FolderId, UserId: item.UserId}
+	err = createTestList(app, &otherList)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var other = data.Item{ListId: otherList.ID, UserId: item.UserId}
+	err = createTestItem(app, &other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var url = "/api/v1/lists/" + strconv.FormatInt(item.ListId, 10) + "/items/reorder"
+	var first, second = strconv.FormatInt(ids[0], 10), strconv.FormatInt(ids[1], 10)
+
+	tests := []struct {
+		name  string
+		body  string
+		field string
+	}{
+		{name: "nothing", body: `{}`, field: "order"},
+		{name: "both", body: `{"order": [` + first + `, ` + second + `], "move": {"id": ` + first + `, "after": ` + second + `}}`, field: "order"},
+		{name: "duplicates", body: `{"order": [` + first + `, ` + first + `]}`, field: "order"},
+		{name: "incomplete", body: `{"order": [` + first + `]}`, field: "order"},
+		{name: "foreign", body: `{"order": [` + first + `, ` + strconv.FormatInt(other.ID, 10) + `]}`, field: "order"},
+		{name: "no target", body: `{"move": {"id": ` + first + `}}`, field: "move"},
+		{name: "itself", body: moveBody(ids[0], "before", ids[0]), field: "move"},
+		{name: "other list", body: moveBody(ids[0], "after", other.ID), field: "move"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, check := postReorder(t, ts, url, token.Plaintext, tt.body)
+			if status != http.StatusUnprocessableEntity {
+				t.Fatalf("want %d status code; got %d", http.StatusUnprocessableEntity, status)
+			}
+			if len(check.Errors) == 0 || check.Errors[0].Title != "Validation failed for field "+tt.field {
+				t.Errorf("want a validation error for %s; got %+v", tt.field, check.Errors)
+			}
+		})
+	}
+
+	if got := itemSequence(app, t, item.UserId, ids); !sameSequence(got, ids) {
+		t.Errorf("want the order unchanged; got %v", got)
+	}
+}
+
+func TestReorderListsAndFolders(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	user, token, err := createTestUserWithToken(t, app, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := createTestFolder(app, user.ID, "First", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := createTestFolder(app, user.ID, "Second", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status, check := postReorder(t, ts, "/api/v1/my/folders/reorder", token.Plaintext, moveBody(second.ID, "before", first.ID))
+	if status != http.StatusOK {
+		t.Fatalf("want %d status code; got %d", http.StatusOK, status)
+	}
+	if len(check.Data) == 0 || check.Data[0].Type != data.FolderType {
+		t.Errorf("want the moved folder in the response; got %+v", check.Data)
+	}
+	movedFirst, _ := app.models.Folders.Get(context.Background(), first.ID, user.ID)
+	movedSecond, _ := app.models.Folders.Get(context.Background(), second.ID, user.ID)
+	if movedSecond.Order >= movedFirst.Order {
+		t.Errorf("want the second folder before the first one; got orders %d and %d", movedSecond.Order, movedFirst.Order)
+	}
+
+	var lists []*data.List
+	for i := 0; i < 3; i++ {
+		var list = data.List{FolderId: first.ID, UserId: user.ID}
+		err = createTestList(app, &list)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lists = append(lists, &list)
+	}
+	// a list in another folder is not part of the order of the first folder
+	var elsewhere = data.List{FolderId: second.ID, UserId: user.ID}
+	err = createTestList(app, &elsewhere)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var url = "/api/v1/folders/" + strconv.FormatInt(first.ID, 10) + "/lists/reorder"
+	var order = `{"order": [` + strconv.FormatInt(lists[2].ID, 10) + `, ` + strconv.FormatInt(lists[1].ID, 10) + `, ` + strconv.FormatInt(lists[0].ID, 10) + `]}`
+	status, _ = postReorder(t, ts, url, token.Plaintext, order)
+	if status != http.StatusOK {
+		t.Fatalf("want %d status code; got %d", http.StatusOK, status)
+	}
+	var previous int32
+	for i := len(lists) - 1; i >= 0; i-- {
+		list, err := app.models.Lists.Get(context.Background(), lists[i].ID, user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if list.Order <= previous {
+			t.Errorf("want the lists in reverse order; list %d has order %d after %d", list.ID, list.Order, previous)
+		}
+		previous = list.Order
+	}
+	check2, _ := app.models.Lists.Get(context.Background(), elsewhere.ID, user.ID)
+	if check2.Order != elsewhere.Order {
+		t.Errorf("want the list of the other folder untouched; got order %d", check2.Order)
+	}
+
+	status, _ = postReorder(t, ts, "/api/v1/folders/999999/lists/reorder", token.Plaintext, order)
+	if status != http.StatusNotFound {
+		t.Errorf("want %d status code for an unknown folder; got %d", http.StatusNotFound, status)
+	}
+}
+
+func TestUpdateItemOrderShiftsOnlyItsList(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	item, ids, _ := createItems(app, t, 2)
+	list, err := app.models.Lists.Get(context.Background(), item.ListId, item.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var other = data.List{FolderId: list.FolderId, UserId: item.UserId}
+	err = createTestList(app, &other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var foreign = data.Item{ListId: other.ID, UserId: item.UserId}
+	err = createTestItem(app, &foreign)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	moved, err := app.models.Items.Get(context.Background(), ids[1], item.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var oldOrder = moved.Order
+	moved.Order = 1
+	err = app.models.Items.Update(context.Background(), moved, oldOrder, item.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check, err := app.models.Items.Get(context.Background(), foreign.ID, item.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if check.Order != foreign.Order {
+		t.Errorf("want the item of the other list to keep order %d; got %d", foreign.Order, check.Order)
+	}
+	if got := itemSequence(app, t, item.UserId, ids); !sameSequence(got, []int64{ids[1], ids[0]}) {
+		t.Errorf("want the moved item first; got %v", got)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"easylist/internal/data"
+	"easylist/internal/validator"
+	"encoding/json"
+	"github.com/google/jsonapi"
+	"net/http"
+	"time"
+)
+
+const defaultReportPeriod = 30 * 24 * time.Hour
+
+// maxReportPeriod keeps a single report from scanning the whole items table.
+const maxReportPeriod = 366 * 24 * time.Hour
+
+// showSpendingReportHandler sums the prices of the items marked done in the period, grouped by folder, list or month.
+func (app *application) showSpendingReportHandler(w http.ResponseWriter, r *http.Request) {
+	var v = validator.New()
+	var qs = r.URL.Query()
+
+	var today = time.Now().UTC().Truncate(24 * time.Hour)
+	var to = app.readDate(qs, "to", today, v)
+	var from = app.readDate(qs, "from", to.Add(-defaultReportPeriod), v)
+	var group = app.readString(qs, "group", data.SpendingGroupList)
+
+	v.Check(validator.In(group, data.SpendingGroupFolder, data.SpendingGroupList, data.SpendingGroupMonth), "group", "must be one of folder, list or month")
+	v.Check(!from.After(to), "from", "must not be after to")
+	v.Check(to.Sub(from) <= maxReportPeriod, "from", "the period must not be longer than a year")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	var userModel = app.contextGetUser(r)
+
+	spending, err := app.models.Reports.GetSpending(r.Context(), userModel.ID, from, to, group)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	res, err := jsonapi.Marshal(spending)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	var payload, ok = res.(*jsonapi.ManyPayload)
+	if !ok {
+		payload = &jsonapi.ManyPayload{Data: []*jsonapi.Node{}}
+	}
+	payload.Meta = &jsonapi.Meta{
+		"total":    spending.Total(),
+		"from":     from.Format(time.DateOnly),
+		"to":       to.Format(time.DateOnly),
+		"group":    group,
+		"currency": userModel.Currency,
+	}
+
+	writeHeaders(w, http.StatusOK, nil)
+	err = json.NewEncoder(w).Encode(payload)
+	if err != nil {
+		app.logError(r, err)
+	}
+}
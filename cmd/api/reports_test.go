@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"easylist/internal/data"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestListTotalsAndBudget(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	item, _ := createItem(app, t)
+
+	var second = data.Item{ListId: item.ListId, UserId: item.UserId, Price: 10, Quantity: 3}
+	err := createTestItem(app, &second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	item.IsDone = true
+	err = app.models.Items.Update(context.Background(), &item, item.Order, item.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := app.models.Lists.Get(context.Background(), item.ListId, item.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var budget int64 = 100
+	list.Budget = &budget
+	err = app.models.Lists.Update(context.Background(), list, list.Order)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list, err = app.models.Lists.Get(context.Background(), item.ListId, item.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.Total != 108 || list.DoneTotal != 78 || list.RemainingTotal != 30 {
+		t.Errorf("want totals 108, 78 and 30; got %v, %v and %v", list.Total, list.DoneTotal, list.RemainingTotal)
+	}
+	if !list.IsOverBudget {
+		t.Error("want the list to be over budget")
+	}
+}
+
+func TestSpendingReport(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	item, token := createItem(app, t)
+
+	var open = data.Item{ListId: item.ListId, UserId: item.UserId}
+	err := createTestItem(app, &open)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	item.IsDone = true
+	err = app.models.Items.Update(context.Background(), &item, item.Order, item.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := generateRequestWithToken(ts.URL+"/api/v1/reports/spending?group=list", token.Plaintext, "", nil)
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("want %d status code; got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	var check struct {
+		Data []struct {
+			Attributes struct {
+				Total      int64 `json:"total"`
+				ItemsCount int   `json:"items_count"`
+			} `json:"attributes"`
+		} `json:"data"`
+		Meta struct {
+			Total int64 `json:"total"`
+		} `json:"meta"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&check)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(check.Data) != 1 {
+		t.Fatalf("want 1 row for the only list, got %d", len(check.Data))
+	}
+	if check.Data[0].Attributes.Total != 78 || check.Data[0].Attributes.ItemsCount != 1 {
+		t.Errorf("want only the done item to be counted, got %+v", check.Data[0].Attributes)
+	}
+	if check.Meta.Total != 78 {
+		t.Errorf("want meta total 78, got %v", check.Meta.Total)
+	}
+}
+
+func TestSpendingReportRejectsUnknownGroup(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	_, token := createItem(app, t)
+
+	req := generateRequestWithToken(ts.URL+"/api/v1/reports/spending?group=year", token.Plaintext, "", nil)
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("want %d status code; got %d", http.StatusUnprocessableEntity, resp.StatusCode)
+	}
+}
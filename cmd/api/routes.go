@@ -17,7 +17,7 @@ func (app *application) routes() http.Handler {
 	router.HandlerFunc(http.MethodGet, "/public/:id", app.publicList)
 	router.HandlerFunc(http.MethodGet, "/reset-password", resetPasswordHandler)
 	router.ServeFiles("/static/*filepath", http.Dir("ui/static"))
-	router.ServeFiles("/storage/*filepath", http.Dir("storage"))
+	router.HandlerFunc(http.MethodGet, "/files/*key", app.signedFileHandler)
 
 	fileServer := http.FileServer(http.Dir("./"))
 
@@ -34,7 +34,10 @@ func (app *application) routes() http.Handler {
 	router.HandlerFunc(http.MethodPatch, "/api/v1/folders/:id", app.requirePermission("folders:write", app.updateFolderHandler))
 	router.HandlerFunc(http.MethodDelete, "/api/v1/folders/:id", app.requirePermission("folders:write", app.deleteFolderHandler))
 
+	router.HandlerFunc(http.MethodPost, "/api/v1/my/folders/reorder", app.requirePermission("folders:write", app.reorderFoldersHandler))
+
 	router.HandlerFunc(http.MethodGet, "/api/v1/folders/:id/lists", app.requirePermission("lists:read", app.indexListsHandler))
+	router.HandlerFunc(http.MethodPost, "/api/v1/folders/:id/lists/reorder", app.requirePermission("lists:write", app.reorderListsHandler))
 	router.HandlerFunc(http.MethodGet, "/api/v1/lists", app.requirePermission("lists:read", app.indexListsHandler))
 	router.HandlerFunc(http.MethodGet, "/api/v1/lists/:id", app.requirePermission("lists:read", app.showListByIdHandler))
 	router.HandlerFunc(http.MethodPost, "/api/v1/lists", app.requirePermission("lists:write", app.createListsHandler))
@@ -48,21 +51,79 @@ func (app *application) routes() http.Handler {
 	router.HandlerFunc(http.MethodGet, "/api/v1/items/:id", app.requirePermission("items:read", app.showItemByIdHandler))
 	router.HandlerFunc(http.MethodPost, "/api/v1/items", app.requirePermission("items:write", app.createItemsHandler))
 	router.HandlerFunc(http.MethodPatch, "/api/v1/items/:id", app.requirePermission("items:write", app.updateItemHandler))
+	router.HandlerFunc(http.MethodGet, "/api/v1/items/:id/file", app.requirePermission("items:read", app.showItemFileHandler))
+	router.HandlerFunc(http.MethodPut, "/api/v1/items/:id/file", app.requirePermission("items:write", app.uploadItemFileHandler))
+	router.HandlerFunc(http.MethodDelete, "/api/v1/items/:id/file", app.requirePermission("items:write", app.deleteItemFileHandler))
+	router.HandlerFunc(http.MethodGet, "/api/v1/items/:id/thumbnail", app.requirePermission("items:read", app.showItemThumbnailHandler))
+	router.HandlerFunc(http.MethodGet, "/api/v1/items/:id/file-url", app.requirePermission("items:read", app.showItemFileUrlHandler))
 	router.HandlerFunc(http.MethodDelete, "/api/v1/items/:id", app.requirePermission("items:write", app.deleteItemHandler))
 	router.HandlerFunc(http.MethodPatch, "/api/v1/lists/:id/items/undone", app.requirePermission("items:write", app.uncrossAllItems))
 	router.HandlerFunc(http.MethodDelete, "/api/v1/lists/:id/items", app.requirePermission("items:write", app.deleteAllItemsFromListHandler))
 	router.HandlerFunc(http.MethodDelete, "/api/v1/lists/:id/items/done", app.requirePermission("items:write", app.deleteDoneItemsFromListHandler))
+	router.HandlerFunc(http.MethodPost, "/api/v1/lists/:id/items/bulk", app.requirePermission("items:write", app.bulkItemsHandler))
+	router.HandlerFunc(http.MethodPost, "/api/v1/lists/:id/items/reorder", app.requirePermission("items:write", app.reorderItemsHandler))
+	router.HandlerFunc(http.MethodGet, "/api/v1/lists/:id/events", app.requirePermission("items:read", app.listEventsHandler))
 	router.HandlerFunc(http.MethodPost, "/api/v1/lists/:id/email", app.requirePermission("items:read", app.sendListByEmail))
 
+	router.HandlerFunc(http.MethodGet, "/api/v1/sync", app.requirePermission("items:read", app.showSyncChangesHandler))
+	router.HandlerFunc(http.MethodPost, "/api/v1/sync", app.requirePermission("items:write", app.applySyncOperationsHandler))
+
+	router.HandlerFunc(http.MethodGet, "/api/v1/templates", app.requirePermission("lists:read", app.indexTemplatesHandler))
+	router.HandlerFunc(http.MethodPost, "/api/v1/templates", app.requirePermission("lists:write", app.createTemplateHandler))
+	router.HandlerFunc(http.MethodGet, "/api/v1/templates/:id", app.requirePermission("lists:read", app.showTemplateHandler))
+	router.HandlerFunc(http.MethodPatch, "/api/v1/templates/:id", app.requirePermission("lists:write", app.updateTemplateHandler))
+	router.HandlerFunc(http.MethodDelete, "/api/v1/templates/:id", app.requirePermission("lists:write", app.deleteTemplateHandler))
+	router.HandlerFunc(http.MethodPost, "/api/v1/templates/:id/instantiate", app.requirePermission("lists:write", app.instantiateTemplateHandler))
+
+	router.HandlerFunc(http.MethodGet, "/api/v1/categories", app.requirePermission("items:read", app.indexCategoriesHandler))
+	router.HandlerFunc(http.MethodPost, "/api/v1/categories", app.requirePermission("items:write", app.createCategoryHandler))
+	router.HandlerFunc(http.MethodPost, "/api/v1/categories/reorder", app.requirePermission("items:write", app.reorderCategoriesHandler))
+	router.HandlerFunc(http.MethodGet, "/api/v1/categories/:id", app.requirePermission("items:read", app.showCategoryHandler))
+	router.HandlerFunc(http.MethodPatch, "/api/v1/categories/:id", app.requirePermission("items:write", app.updateCategoryHandler))
+	router.HandlerFunc(http.MethodDelete, "/api/v1/categories/:id", app.requirePermission("items:write", app.deleteCategoryHandler))
+
+	router.HandlerFunc(http.MethodGet, "/api/v1/suggestions", app.requirePermission("items:read", app.indexSuggestionsHandler))
+	router.HandlerFunc(http.MethodGet, "/api/v1/search", app.requirePermission("items:read", app.searchHandler))
+
+	router.HandlerFunc(http.MethodGet, "/api/v1/trash", app.requirePermission("items:read", app.showTrashHandler))
+	router.HandlerFunc(http.MethodPost, "/api/v1/trash/:type/:id/restore", app.requirePermission("items:write", app.restoreFromTrashHandler))
+
+	router.HandlerFunc(http.MethodGet, "/api/v1/reports/spending", app.requirePermission("items:read", app.showSpendingReportHandler))
+
+	router.HandlerFunc(http.MethodGet, "/api/v1/exchange-rates", app.requirePermission("items:read", app.indexExchangeRatesHandler))
+	router.HandlerFunc(http.MethodPut, "/api/v1/exchange-rates", app.requirePermission("rates:write", app.replaceExchangeRatesHandler))
+
+	router.HandlerFunc(http.MethodGet, "/api/v1/lists/:id/members", app.requirePermission("lists:read", app.indexMembersHandler))
+	router.HandlerFunc(http.MethodPost, "/api/v1/lists/:id/members", app.requirePermission("lists:write", app.inviteMemberHandler))
+	router.HandlerFunc(http.MethodPut, "/api/v1/members/accepted", app.requirePermission("lists:read", app.acceptInvitationHandler))
+	router.HandlerFunc(http.MethodPatch, "/api/v1/members/:id", app.requirePermission("lists:write", app.updateMemberHandler))
+	router.HandlerFunc(http.MethodDelete, "/api/v1/members/:id", app.requirePermission("lists:write", app.deleteMemberHandler))
+
 	router.HandlerFunc(http.MethodPost, "/api/v1/users", app.registerUserHandler)
-	router.HandlerFunc(http.MethodPatch, "/api/v1/users/:id", app.updateUserHandler)
+	router.HandlerFunc(http.MethodPatch, "/api/v1/users/:id", app.denyPersonalAccessTokens(app.updateUserHandler))
 	router.HandlerFunc(http.MethodGet, "/api/v1/my", app.showCurrentUserHandler)
 	router.HandlerFunc(http.MethodPut, "/api/v1/users/activated", app.activateUserHandler)
 	router.HandlerFunc(http.MethodPut, "/api/v1/users/password", app.resetUserPasswordHandler)
-	router.HandlerFunc(http.MethodDelete, "/api/v1/users/:id", app.deleteUserHandler)
+	router.HandlerFunc(http.MethodDelete, "/api/v1/users/:id", app.denyPersonalAccessTokens(app.deleteUserHandler))
 
 	router.HandlerFunc(http.MethodPost, "/api/v1/tokens/authentication", app.createAuthenticationTokenHandler)
+	router.HandlerFunc(http.MethodPost, "/api/v1/tokens/refresh", app.refreshAuthenticationTokenHandler)
+	router.HandlerFunc(http.MethodDelete, "/api/v1/tokens/authentication", app.requireAuthenticatedUser(app.denyPersonalAccessTokens(app.deleteAuthenticationTokenHandler)))
 	router.HandlerFunc(http.MethodPost, "/api/v1/tokens/password-reset", app.createPasswordResetTokenHandler)
+	router.HandlerFunc(http.MethodPost, "/api/v1/tokens/oidc", app.createOidcTokenHandler)
+	router.HandlerFunc(http.MethodPost, "/api/v1/oidc/:provider/authorization", app.createOidcAuthorizationHandler)
+
+	router.HandlerFunc(http.MethodGet, "/api/v1/tokens/personal-access", app.requireActivatedUser(app.denyPersonalAccessTokens(app.indexPersonalAccessTokensHandler)))
+	router.HandlerFunc(http.MethodPost, "/api/v1/tokens/personal-access", app.requireActivatedUser(app.denyPersonalAccessTokens(app.createPersonalAccessTokenHandler)))
+	router.HandlerFunc(http.MethodDelete, "/api/v1/tokens/personal-access/:id", app.requireActivatedUser(app.denyPersonalAccessTokens(app.deletePersonalAccessTokenHandler)))
+
+	router.HandlerFunc(http.MethodPost, "/api/v1/totp", app.requireActivatedUser(app.denyPersonalAccessTokens(app.createTwoFactorHandler)))
+	router.HandlerFunc(http.MethodPut, "/api/v1/totp/enabled", app.requireActivatedUser(app.denyPersonalAccessTokens(app.enableTwoFactorHandler)))
+	router.HandlerFunc(http.MethodDelete, "/api/v1/totp", app.requireActivatedUser(app.denyPersonalAccessTokens(app.deleteTwoFactorHandler)))
+
+	router.HandlerFunc(http.MethodGet, "/api/v1/sessions", app.requireAuthenticatedUser(app.denyPersonalAccessTokens(app.indexSessionsHandler)))
+	router.HandlerFunc(http.MethodDelete, "/api/v1/sessions", app.requireAuthenticatedUser(app.denyPersonalAccessTokens(app.deleteSessionsHandler)))
+	router.HandlerFunc(http.MethodDelete, "/api/v1/sessions/:id", app.requireAuthenticatedUser(app.denyPersonalAccessTokens(app.deleteSessionHandler)))
 
 	router.Handler(http.MethodGet, "/api/v1/debug/vars", expvar.Handler())
 
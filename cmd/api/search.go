@@ -0,0 +1,90 @@
+package main
+
+import (
+	"easylist/internal/data"
+	"easylist/internal/validator"
+	"encoding/json"
+	"github.com/google/jsonapi"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// searchHandler searches the folders, lists and items of the user at once, see data.ParseSearch for the syntax of q.
+// The response mixes the three types, the meta of every record has the snippet of its matching field, e.g.
+// {"type": "items", "id": "7", "attributes": {...}, "meta": {"highlight": {"field": "description", "snippet": "fresh <mark>milk</mark>"}}}.
+func (app *application) searchHandler(w http.ResponseWriter, r *http.Request) {
+	var v = validator.New()
+	var qs = r.URL.Query()
+
+	var q = app.readString(qs, "q", "")
+	var filters data.Filters
+	filters.Page = app.readInt(qs, jsonapi.QueryParamPageNumber, 1, v)
+	filters.Size = app.readInt(qs, jsonapi.QueryParamPageSize, 20, v)
+	filters.Sort = app.readString(qs, "sort", "relevance")
+	filters.SortSafelist = []string{"relevance", "name", "updated_at", "-name", "-updated_at"}
+
+	var search = data.ParseSearch(q)
+	v.Check(len(q) <= 255, "q", "must be no more than 255 characters")
+	v.Check(search.Required(), "q", "must contain a word to search for")
+	v.Check(len(search) <= 10, "q", "must contain no more than 10 terms")
+	if data.ValidateFilters(v, filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	var userModel = app.contextGetUser(r)
+
+	results, metadata, err := app.models.Search.Search(r.Context(), userModel.ID, search, filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var payload = &jsonapi.ManyPayload{Data: []*jsonapi.Node{}}
+	for _, result := range results {
+		if result.Record == nil {
+			continue
+		}
+		res, err := jsonapi.Marshal(result.Record)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		if onePayload, ok := res.(*jsonapi.OnePayload); ok {
+			onePayload.Data.Meta = &jsonapi.Meta{
+				"highlight": map[string]string{"field": result.Field, "snippet": result.Snippet},
+			}
+			payload.Data = append(payload.Data, onePayload.Data)
+		}
+	}
+	payload.Meta = &jsonapi.Meta{"total": metadata.TotalRecords}
+	payload.Links = app.searchLinks(q, filters, metadata)
+
+	writeHeaders(w, http.StatusOK, nil)
+	err = json.NewEncoder(w).Encode(payload)
+	if err != nil {
+		app.logError(r, err)
+	}
+}
+
+// searchLinks builds the pagination links of the search, they keep the search string and the sort.
+func (app *application) searchLinks(q string, filters data.Filters, metadata data.Metadata) *jsonapi.Links {
+	var link = func(page int) any {
+		if page == 0 {
+			return nil
+		}
+		var qs = url.Values{}
+		qs.Set("q", q)
+		qs.Set("sort", filters.Sort)
+		qs.Set(jsonapi.QueryParamPageNumber, strconv.Itoa(page))
+		qs.Set(jsonapi.QueryParamPageSize, strconv.Itoa(filters.Size))
+		return app.config.Domain + "/api/v1/search?" + qs.Encode()
+	}
+	return &jsonapi.Links{
+		jsonapi.KeyFirstPage:    link(metadata.FirstPage),
+		jsonapi.KeyPreviousPage: link(metadata.PrevPage),
+		jsonapi.KeyNextPage:     link(metadata.NextPage),
+		jsonapi.KeyLastPage:     link(metadata.LastPage),
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"easylist/internal/data"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type searchResponse struct {
+	Data []struct {
+		Type string `json:"type"`
+		Id   string `json:"id"`
+		Meta struct {
+			Highlight struct {
+				Field   string `json:"field"`
+				Snippet string `json:"snippet"`
+			} `json:"highlight"`
+		} `json:"meta"`
+	} `json:"data"`
+	Meta struct {
+		Total int `json:"total"`
+	} `json:"meta"`
+	Links map[string]*string `json:"links"`
+}
+
+func getSearch(t *testing.T, ts *testServer, token string, query string) (int, searchResponse) {
+	req := generateRequestWithToken(ts.URL+"/api/v1/search?"+query, token, "GET", nil)
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var check searchResponse
+	if resp.StatusCode == http.StatusOK {
+		err = json.NewDecoder(resp.Body).Decode(&check)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp.StatusCode, check
+}
+
+func TestSearchFoldersListsAndItems(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	user, token, err := createTestUserWithToken(t, app, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	folder, err := createTestFolder(app, user.ID, "Breakfast", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var list = data.List{FolderId: folder.ID, UserId: user.ID, Name: "Breakfast shopping"}
+	err = createTestList(app, &list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var milk = data.Item{ListId: list.ID, UserId: user.ID, Name: "Milk", Description: "For a cup of tea"}
+	var bread = data.Item{ListId: list.ID, UserId: user.ID, Name: "Bread", Description: "The one with a milk crust"}
+	var trashed = data.Item{ListId: list.ID, UserId: user.ID, Name: "Milk chocolate"}
+	for _, next := range []*data.Item{&milk, &bread, &trashed} {
+		err = createTestItem(app, next)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = app.models.Items.Delete(context.Background(), trashed.ID, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status, check := getSearch(t, ts, token.Plaintext, "q=milk")
+	if status != http.StatusOK {
+		t.Fatalf("want %d status code; got %d", http.StatusOK, status)
+	}
+	if check.Meta.Total != 2 || len(check.Data) != 2 {
+		t.Fatalf("want the two items which are not in the trash; got %+v", check)
+	}
+	if check.Data[0].Id != strconv.FormatInt(milk.ID, 10) || check.Data[0].Meta.Highlight.Snippet != "<mark>Milk</mark>" {
+		t.Errorf("want the item matching by name first; got %+v", check.Data[0])
+	}
+	if check.Data[1].Id != strconv.FormatInt(bread.ID, 10) || check.Data[1].Meta.Highlight.Field != data.SearchByDescription || check.Data[1].Meta.Highlight.Snippet != "The one with a <mark>milk</mark> crust" {
+		t.Errorf("want the item matching by description second; got %+v", check.Data[1])
+	}
+
+	status, check = getSearch(t, ts, token.Plaintext, "q="+url.QueryEscape("breakf*"))
+	if status != http.StatusOK || len(check.Data) != 2 {
+		t.Fatalf("want the folder and the list; got %d, %+v", status, check.Data)
+	}
+	var types = []string{check.Data[0].Type, check.Data[1].Type}
+	if !(types[0] == data.FolderType && types[1] == data.ListsType || types[0] == data.ListsType && types[1] == data.FolderType) {
+		t.Errorf("want a folder and a list; got %v", types)
+	}
+
+	// the short words MySQL leaves out of its index are found as well
+	status, check = getSearch(t, ts, token.Plaintext, "q="+url.QueryEscape(`"of tea" -bread`))
+	if status != http.StatusOK || len(check.Data) != 1 || check.Data[0].Id != strconv.FormatInt(milk.ID, 10) {
+		t.Errorf("want only the milk; got %d, %+v", status, check.Data)
+	}
+
+	status, check = getSearch(t, ts, token.Plaintext, "q=milk&page[size]=1")
+	if status != http.StatusOK || len(check.Data) != 1 || check.Meta.Total != 2 {
+		t.Fatalf("want one of two results; got %d, %+v", status, check)
+	}
+	if check.Links["next"] == nil || !strings.Contains(*check.Links["next"], "q=milk") || check.Links["prev"] != nil {
+		t.Errorf("want a link to the next page keeping the search; got %+v", check.Links)
+	}
+
+	_, otherToken, err := createTestUserWithToken(t, app, "other@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, check = getSearch(t, ts, otherToken.Plaintext, "q=milk")
+	if status != http.StatusOK || len(check.Data) != 0 {
+		t.Errorf("want nothing from the lists of another user; got %d, %+v", status, check.Data)
+	}
+}
+
+func TestSearchValidation(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	_, token, err := createTestUserWithToken(t, app, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, query := range []string{"q=", "q=-milk", "q=milk&sort=id", "q=milk&page[size]=0"} {
+		status, _ := getSearch(t, ts, token.Plaintext, query)
+		if status != http.StatusUnprocessableEntity {
+			t.Errorf("want %d status code for %s; got %d", http.StatusUnprocessableEntity, query, status)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -20,6 +21,28 @@ func (app *application) serve() error {
 		WriteTimeout: 30 * time.Second,
 	}
 
+	srv.RegisterOnShutdown(app.events.Close)
+
+	// The requests and the jobs share a context, it is cancelled when the shutdown takes too long,
+	// which cancels the queries still running.
+	baseCtx, cancelBase := context.WithCancel(context.Background())
+	defer cancelBase()
+	app.baseCtx = baseCtx
+	srv.BaseContext = func(net.Listener) context.Context {
+		return baseCtx
+	}
+
+	stopJobs := make(chan struct{})
+	app.wg.Add(2)
+	go func() {
+		defer app.wg.Done()
+		app.runTrashPurge(baseCtx, stopJobs)
+	}()
+	go func() {
+		defer app.wg.Done()
+		app.runTemplateScheduler(baseCtx, stopJobs)
+	}()
+
 	shutdownError := make(chan error)
 
 	go func() {
@@ -36,6 +59,7 @@ func (app *application) serve() error {
 
 		err := srv.Shutdown(ctx)
 		if err != nil {
+			cancelBase()
 			shutdownError <- err
 		}
 
@@ -43,6 +67,7 @@ func (app *application) serve() error {
 			"addr": srv.Addr,
 		})
 
+		close(stopJobs)
 		app.wg.Wait()
 		shutdownError <- nil
 	}()
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"easylist/internal/data"
+	"errors"
+	"net/http"
+)
+
+// indexSessionsHandler lists the devices the user is signed in on, the session of the request is marked as current.
+func (app *application) indexSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	var userModel = app.contextGetUser(r)
+
+	sessions, err := app.models.Sessions.GetAll(r.Context(), userModel.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if current := app.contextGetSession(r); current != nil {
+		for _, session := range sessions {
+			session.IsCurrent = session.ID == current.ID
+		}
+	}
+
+	err = app.writeJSON(w, http.StatusOK, sessions, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteSessionHandler signs one of the devices of the user out.
+func (app *application) deleteSessionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var userModel = app.contextGetUser(r)
+
+	err = app.models.Sessions.Delete(r.Context(), id, userModel.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deleteSessionsHandler signs out every device of the user except the one making the request.
+func (app *application) deleteSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	var userModel = app.contextGetUser(r)
+
+	var exceptId int64
+	if current := app.contextGetSession(r); current != nil {
+		exceptId = current.ID
+	}
+
+	err := app.models.Sessions.DeleteAllForUser(r.Context(), userModel.ID, exceptId)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+type sessionTokensResponse struct {
+	Data struct {
+		Attributes struct {
+			Token        string `json:"token"`
+			RefreshToken string `json:"refresh_token"`
+			SessionId    int64  `json:"session_id"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+type sessionsResponse struct {
+	Data []struct {
+		Id         string `json:"id"`
+		Attributes struct {
+			DeviceName string `json:"device_name"`
+			UserAgent  string `json:"user_agent"`
+			IsCurrent  bool   `json:"is_current"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+// postTokens sends the attributes to the token endpoint and returns the status code with the issued tokens.
+func postTokens(t *testing.T, ts *testServer, endpoint string, attributes string) (int, sessionTokensResponse) {
+	var body = []byte(`{"data": {"type": "tokens", "attributes": ` + attributes + `}}`)
+	req := generateRequestWithToken(ts.URL+"/api/v1/tokens/"+endpoint, "", "POST", bytes.NewBuffer(body))
+	req.Header.Set("User-Agent", "EasyList/1.0")
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var check sessionTokensResponse
+	if resp.StatusCode == http.StatusCreated {
+		err = json.NewDecoder(resp.Body).Decode(&check)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp.StatusCode, check
+}
+
+func signIn(t *testing.T, ts *testServer, deviceName string) sessionTokensResponse {
+	status, tokens := postTokens(t, ts, "authentication", fmt.Sprintf(`{"email": "test@mail.ru", "password": "password123", "device_name": %q}`, deviceName))
+	if status != http.StatusCreated {
+		t.Fatalf("want %d status code; got %d", http.StatusCreated, status)
+	}
+	return tokens
+}
+
+func getSessions(t *testing.T, ts *testServer, token string) (int, sessionsResponse) {
+	req := generateRequestWithToken(ts.URL+"/api/v1/sessions", token, "GET", nil)
+	req.Header.Set("User-Agent", "EasyList/1.1")
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var check sessionsResponse
+	if resp.StatusCode == http.StatusOK {
+		err = json.NewDecoder(resp.Body).Decode(&check)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp.StatusCode, check
+}
+
+func sendDelete(t *testing.T, ts *testServer, token string, path string) int {
+	req := generateRequestWithToken(ts.URL+path, token, "DELETE", nil)
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestSessionRefresh(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	_, _, err := createTestUserWithToken(t, app, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var phone = signIn(t, ts, "Phone")
+	if phone.Data.Attributes.Token == "" || phone.Data.Attributes.RefreshToken == "" {
+		t.Fatalf("want an authentication and a refresh token; got %+v", phone)
+	}
+
+	status, sessions := getSessions(t, ts, phone.Data.Attributes.Token)
+	if status != http.StatusOK || len(sessions.Data) != 1 {
+		t.Fatalf("want the session of the phone; got %d, %+v", status, sessions)
+	}
+	var session = sessions.Data[0].Attributes
+	if session.DeviceName != "Phone" || session.UserAgent != "EasyList/1.1" || !session.IsCurrent {
+		t.Errorf("want the device and the user agent of the last request of the current session; got %+v", session)
+	}
+
+	status, refreshed := postTokens(t, ts, "refresh", `{"refresh_token": "`+phone.Data.Attributes.RefreshToken+`"}`)
+	if status != http.StatusCreated || refreshed.Data.Attributes.SessionId != phone.Data.Attributes.SessionId || refreshed.Data.Attributes.RefreshToken == phone.Data.Attributes.RefreshToken {
+		t.Fatalf("want a new pair of tokens of the same session; got %d, %+v", status, refreshed)
+	}
+	if status, _ := getSessions(t, ts, refreshed.Data.Attributes.Token); status != http.StatusOK {
+		t.Errorf("want the new authentication token to work; got %d", status)
+	}
+
+	// the refresh token is used for the second time, the session is revoked
+	status, _ = postTokens(t, ts, "refresh", `{"refresh_token": "`+phone.Data.Attributes.RefreshToken+`"}`)
+	if status != http.StatusUnauthorized {
+		t.Errorf("want %d status code for a reused refresh token; got %d", http.StatusUnauthorized, status)
+	}
+	if status, _ := getSessions(t, ts, refreshed.Data.Attributes.Token); status != http.StatusUnauthorized {
+		t.Errorf("want the tokens of the revoked session to stop working; got %d", status)
+	}
+	status, _ = postTokens(t, ts, "refresh", `{"refresh_token": "`+refreshed.Data.Attributes.RefreshToken+`"}`)
+	if status != http.StatusUnauthorized {
+		t.Errorf("want %d status code for the refresh token of the revoked session; got %d", http.StatusUnauthorized, status)
+	}
+
+	status, _ = postTokens(t, ts, "refresh", `{"refresh_token": "short"}`)
+	if status != http.StatusUnprocessableEntity {
+		t.Errorf("want %d status code for a malformed refresh token; got %d", http.StatusUnprocessableEntity, status)
+	}
+}
+
+func TestSessionLogout(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	_, _, err := createTestUserWithToken(t, app, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherToken, err := createTestUserWithToken(t, app, "other@mail.ru")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var phone, laptop, tablet = signIn(t, ts, "Phone"), signIn(t, ts, "Laptop"), signIn(t, ts, "Tablet")
+
+	status, sessions := getSessions(t, ts, phone.Data.Attributes.Token)
+	if status != http.StatusOK || len(sessions.Data) != 3 {
+		t.Fatalf("want three sessions; got %d, %+v", status, sessions)
+	}
+
+	var tabletPath = "/api/v1/sessions/" + strconv.FormatInt(tablet.Data.Attributes.SessionId, 10)
+	if status := sendDelete(t, ts, otherToken.Plaintext, tabletPath); status != http.StatusNotFound {
+		t.Errorf("want %d status code for the session of another user; got %d", http.StatusNotFound, status)
+	}
+	if status := sendDelete(t, ts, phone.Data.Attributes.Token, tabletPath); status != http.StatusNoContent {
+		t.Errorf("want %d status code; got %d", http.StatusNoContent, status)
+	}
+	if status, _ := getSessions(t, ts, tablet.Data.Attributes.Token); status != http.StatusUnauthorized {
+		t.Errorf("want the tablet to be signed out; got %d", status)
+	}
+
+	if status := sendDelete(t, ts, phone.Data.Attributes.Token, "/api/v1/sessions"); status != http.StatusNoContent {
+		t.Errorf("want %d status code; got %d", http.StatusNoContent, status)
+	}
+	if status, _ := getSessions(t, ts, laptop.Data.Attributes.Token); status != http.StatusUnauthorized {
+		t.Errorf("want the laptop to be signed out; got %d", status)
+	}
+	status, sessions = getSessions(t, ts, phone.Data.Attributes.Token)
+	if status != http.StatusOK || len(sessions.Data) != 1 || !sessions.Data[0].Attributes.IsCurrent {
+		t.Fatalf("want only the current session to be left; got %d, %+v", status, sessions)
+	}
+
+	if status := sendDelete(t, ts, phone.Data.Attributes.Token, "/api/v1/tokens/authentication"); status != http.StatusNoContent {
+		t.Errorf("want %d status code; got %d", http.StatusNoContent, status)
+	}
+	if status, _ := getSessions(t, ts, phone.Data.Attributes.Token); status != http.StatusUnauthorized {
+		t.Errorf("want the phone to be signed out; got %d", status)
+	}
+	status, _ = postTokens(t, ts, "refresh", `{"refresh_token": "`+phone.Data.Attributes.RefreshToken+`"}`)
+	if status != http.StatusUnauthorized {
+		t.Errorf("want %d status code for the refresh token of a signed out session; got %d", http.StatusUnauthorized, status)
+	}
+
+	// the tokens issued before the sessions are signed out one by one
+	if status := sendDelete(t, ts, otherToken.Plaintext, "/api/v1/tokens/authentication"); status != http.StatusNoContent {
+		t.Errorf("want %d status code; got %d", http.StatusNoContent, status)
+	}
+	if status, _ := getSessions(t, ts, otherToken.Plaintext); status != http.StatusUnauthorized {
+		t.Errorf("want the token without a session to be revoked; got %d", status)
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"easylist/internal/validator"
+	"net/http"
+)
+
+const (
+	defaultSuggestions = 10
+	maxSuggestions     = 50
+)
+
+// indexSuggestionsHandler suggests the names the user bought before while the name of a new item is typed,
+// e.g. ?q=mil finds "Milk 2L" together with the quantity, price and category of the last purchase.
+func (app *application) indexSuggestionsHandler(w http.ResponseWriter, r *http.Request) {
+	var v = validator.New()
+	var qs = r.URL.Query()
+
+	var prefix = app.readString(qs, "q", "")
+	var limit = app.readInt(qs, "limit", defaultSuggestions, v)
+
+	v.Check(len(prefix) <= 190, "q", "must be no more than 190 characters")
+	v.Check(limit > 0 && limit <= maxSuggestions, "limit", "must be between 1 and 50")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	var userModel = app.contextGetUser(r)
+
+	suggestions, err := app.models.Suggestions.GetAll(r.Context(), userModel.ID, prefix, limit)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, suggestions, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
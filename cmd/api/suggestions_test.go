@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"easylist/internal/data"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+type suggestionsResponse struct {
+	Data []struct {
+		Id         string `json:"id"`
+		Attributes struct {
+			Name         string `json:"name"`
+			Quantity     int32  `json:"quantity"`
+			QuantityType string `json:"quantity_type"`
+			Price        int64  `json:"price"`
+			CategoryId   int64  `json:"category_id"`
+			TimesBought  int32  `json:"times_bought"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+func getSuggestions(t *testing.T, ts *testServer, token string, q string) (int, suggestionsResponse) {
+	req := generateRequestWithToken(ts.URL+"/api/v1/suggestions?q="+url.QueryEscape(q), token, "GET", nil)
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var check suggestionsResponse
+	if resp.StatusCode == http.StatusOK {
+		err = json.NewDecoder(resp.Body).Decode(&check)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp.StatusCode, check
+}
+
+// markDone marks the item done with a PATCH request like the app does.
+func markDone(t *testing.T, ts *testServer, token string, item *data.Item) {
+	var body = `{"data": {"type": "items", "id": "` + strconv.FormatInt(item.ID, 10) + `", "attributes": {"is_done": true}}}`
+	req := generateRequestWithToken(ts.URL+"/api/v1/items/"+strconv.FormatInt(item.ID, 10), token, "PATCH", bytes.NewBufferString(body))
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("want %d status code marking the item done; got %d", http.StatusOK, resp.StatusCode)
+	}
+}
+
+func TestSuggestionsFromPurchases(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	item, token := createItem(app, t)
+	dairy, err := createTestCategory(app, item.UserId, "Dairy")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var milk = data.Item{ListId: item.ListId, UserId: item.UserId, Name: "Milk 2L", Quantity: 1, QuantityType: "piece", Price: 129, CategoryId: dairy.ID}
+	var chocolate = data.Item{ListId: item.ListId, UserId: item.UserId, Name: "Milk chocolate"}
+	var oatMilk = data.Item{ListId: item.ListId, UserId: item.UserId, Name: "Oat milk"}
+	for _, next := range []*data.Item{&milk, &chocolate, &oatMilk} {
+		err = createTestItem(app, next)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	status, check := getSuggestions(t, ts, token.Plaintext, "mil")
+	if status != http.StatusOK || len(check.Data) != 0 {
+		t.Fatalf("want no suggestions before anything was bought; got %d, %+v", status, check.Data)
+	}
+
+	markDone(t, ts, token.Plaintext, &milk)
+	markDone(t, ts, token.Plaintext, &chocolate)
+	markDone(t, ts, token.Plaintext, &oatMilk)
+	// marking a done item done again is not another purchase
+	markDone(t, ts, token.Plaintext, &oatMilk)
+
+	// the chocolate is bought a second time from a new item
+	var again = data.Item{ListId: item.ListId, UserId: item.UserId, Name: "milk  chocolate", IsDone: true}
+	err = createTestItem(app, &again)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status, check = getSuggestions(t, ts, token.Plaintext, "MIL")
+	if status != http.StatusOK {
+		t.Fatalf("want %d status code; got %d", http.StatusOK, status)
+	}
+	if len(check.Data) != 3 {
+		t.Fatalf("want 3 suggestions; got %+v", check.Data)
+	}
+	var first, second, third = check.Data[0].Attributes, check.Data[1].Attributes, check.Data[2].Attributes
+	if first.Name != "milk  chocolate" || first.TimesBought != 2 {
+		t.Errorf("want the chocolate bought twice first; got %+v", first)
+	}
+	if second.Name != "Milk 2L" || second.Quantity != 1 || second.QuantityType != "piece" || second.Price != 129 || second.CategoryId != dairy.ID {
+		t.Errorf("want the milk with the details of the purchase second; got %+v", second)
+	}
+	if third.Name != "Oat milk" || third.TimesBought != 1 {
+		t.Errorf("want the oat milk matching by a later word last; got %+v", third)
+	}
+
+	status, check = getSuggestions(t, ts, token.Plaintext, "milk_")
+	if status != http.StatusOK || len(check.Data) != 0 {
+		t.Errorf("want the underscore to match literally; got %+v", check.Data)
+	}
+
+	_, otherToken, err := createTestUserWithToken(t, app, "other@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, check = getSuggestions(t, ts, otherToken.Plaintext, "mil")
+	if status != http.StatusOK || len(check.Data) != 0 {
+		t.Errorf("want no suggestions from the history of another user; got %+v", check.Data)
+	}
+}
+
+func TestSuggestionsValidation(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	_, token, err := createTestUserWithToken(t, app, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := generateRequestWithToken(ts.URL+"/api/v1/suggestions?q=milk&limit=500", token.Plaintext, "GET", nil)
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("want %d status code; got %d", http.StatusUnprocessableEntity, resp.StatusCode)
+	}
+
+	suggestions, err := app.models.Suggestions.GetAll(context.Background(), 0, "", 10)
+	if err != nil || len(suggestions) != 0 {
+		t.Errorf("want an empty history; got %v, %v", suggestions, err)
+	}
+}
@@ -0,0 +1,493 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"easylist/internal/data"
+	"easylist/internal/events"
+	"easylist/internal/validator"
+	"encoding/json"
+	"errors"
+	"github.com/google/jsonapi"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	SyncOpAdd    = "add"
+	SyncOpUpdate = "update"
+	SyncOpRemove = "remove"
+)
+
+const (
+	SyncStatusApplied   = "applied"
+	SyncStatusConflict  = "conflict"
+	SyncStatusNotFound  = "not_found"
+	SyncStatusForbidden = "forbidden"
+	SyncStatusInvalid   = "invalid"
+	SyncStatusFailed    = "failed"
+)
+
+const maxSyncOperations = 500
+
+// defaultSyncRetention is how long the tombstones of deleted records are kept for the sync.
+const defaultSyncRetention = 90 * 24 * time.Hour
+
+type SyncResult struct {
+	Index  int               `json:"index"`
+	Status string            `json:"status"`
+	Data   *jsonapi.Node     `json:"data,omitempty"`
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+func (app *application) showSyncChangesHandler(w http.ResponseWriter, r *http.Request) {
+	var qs = r.URL.Query()
+	var v = validator.New()
+
+	since, err := data.DecodeSyncCursor(app.readString(qs, "since", ""))
+	if err != nil {
+		v.AddError("since", "must be a cursor returned by the previous sync")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	// the deletions older than the retention are purged, such a client has to start from the beginning
+	if !since.IsZero() && since.Before(time.Now().Add(-app.syncRetention())) {
+		v.AddError("since", "has expired, sync again without it")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	// The cursor is taken before reading so changes made while the queries run are returned again next time instead of being lost.
+	var cursor = data.EncodeSyncCursor(time.Now())
+	var userModel = app.contextGetUser(r)
+
+	folders, err := app.models.Folders.GetUpdatedSince(r.Context(), userModel.ID, since)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	lists, err := app.models.Lists.GetUpdatedSince(r.Context(), userModel.ID, since)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	items, err := app.models.Items.GetUpdatedSince(r.Context(), userModel.ID, since)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	tombstones, err := app.models.Tombstones.GetAllSince(r.Context(), userModel.ID, since)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var payload = &jsonapi.ManyPayload{Data: []*jsonapi.Node{}}
+	for _, collection := range []any{folders, lists, items} {
+		res, err := jsonapi.Marshal(collection)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		if manyPayload, ok := res.(*jsonapi.ManyPayload); ok {
+			payload.Data = append(payload.Data, manyPayload.Data...)
+		}
+	}
+	payload.Meta = &jsonapi.Meta{
+		"cursor":  cursor,
+		"deleted": tombstones,
+	}
+	payload.Links = &jsonapi.Links{
+		"next": app.config.Domain + "/api/v1/sync?since=" + cursor,
+	}
+
+	writeHeaders(w, http.StatusOK, nil)
+	err = json.NewEncoder(w).Encode(payload)
+	if err != nil {
+		app.logError(r, err)
+	}
+}
+
+func (app *application) applySyncOperationsHandler(w http.ResponseWriter, r *http.Request) {
+	var input SyncInput
+	var err = decodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, "applySyncOperationsHandler", err)
+		return
+	}
+
+	var v = validator.New()
+	v.Check(len(input.Operations) > 0, "operations", "must be provided")
+	v.Check(len(input.Operations) <= maxSyncOperations, "operations", "must contain no more than 500 operations")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	var cursor = data.EncodeSyncCursor(time.Now())
+	var userModel = app.contextGetUser(r)
+
+	var results = make([]SyncResult, 0, len(input.Operations))
+	for index, operation := range input.Operations {
+		var result SyncResult
+		switch operation.Type {
+		case data.ItemsType:
+			result = app.applyItemOperation(r.Context(), operation, userModel)
+		case data.ListsType:
+			result = app.applyListOperation(r.Context(), operation, userModel)
+		case data.FolderType:
+			result = app.applyFolderOperation(r.Context(), operation, userModel)
+		default:
+			result = invalidSyncResult("type", "must be one of folders, lists or items")
+		}
+		result.Index = index
+		results = append(results, result)
+	}
+
+	writeHeaders(w, http.StatusOK, nil)
+	err = json.NewEncoder(w).Encode(map[string]any{
+		"results": results,
+		"meta": map[string]any{
+			"cursor": cursor,
+		},
+	})
+	if err != nil {
+		app.logError(r, err)
+	}
+}
+
+func (app *application) applyItemOperation(ctx context.Context, operation SyncOperation, user *data.User) SyncResult {
+	var attributes ItemAttributes
+	if result, ok := decodeSyncAttributes(operation, &attributes); !ok {
+		return result
+	}
+	var v = validator.New()
+
+	if operation.Op == SyncOpAdd {
+		var item = &data.Item{Version: 1, Order: 1, UserId: user.ID, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+		attributes.applyTo(item)
+		if attributes.ListId != nil {
+			item.ListId = *attributes.ListId
+		}
+		if result, ok := app.checkSyncListAccess(ctx, item.ListId, user, v); !ok {
+			return result
+		}
+		if data.ValidateItem(v, item); !v.Valid() {
+			return SyncResult{Status: SyncStatusInvalid, Errors: v.Errors}
+		}
+		if err := app.models.Items.Insert(ctx, item); err != nil {
+			return app.failedSyncResult(err)
+		}
+		app.publishItemEvent(events.ItemCreated, item)
+		return app.appliedSyncResult(item)
+	}
+
+	id, result, ok := readSyncOperationId(operation)
+	if !ok {
+		return result
+	}
+	item, err := app.models.Items.Get(ctx, id, user.ID)
+	if err != nil {
+		return app.missingSyncResult(err)
+	}
+	if operation.Version != item.Version {
+		return app.conflictSyncResult(item)
+	}
+	if result, ok := app.checkSyncListAccess(ctx, item.ListId, user, v); !ok {
+		return result
+	}
+
+	switch operation.Op {
+	case SyncOpUpdate:
+		var oldOrder = item.Order
+		var oldListId = item.ListId
+		attributes.applyTo(item)
+		if attributes.ListId != nil && *attributes.ListId != item.ListId {
+			if result, ok := app.checkSyncListAccess(ctx, *attributes.ListId, user, v); !ok {
+				return result
+			}
+			item.ListId = *attributes.ListId
+		}
+		if data.ValidateItem(v, item); !v.Valid() {
+			return SyncResult{Status: SyncStatusInvalid, Errors: v.Errors}
+		}
+		err = app.models.Items.Update(ctx, item, oldOrder, user.ID)
+		if err != nil {
+			if errors.Is(err, data.ErrEditConflict) {
+				return app.conflictSyncResult(item)
+			}
+			return app.failedSyncResult(err)
+		}
+		if oldListId != item.ListId {
+			app.publishItemDeletedEvent(&data.Item{ID: item.ID, ListId: oldListId})
+			app.publishItemEvent(events.ItemCreated, item)
+		} else {
+			app.publishItemEvent(events.ItemUpdated, item)
+		}
+		return app.appliedSyncResult(item)
+	case SyncOpRemove:
+		err = app.models.Items.Delete(ctx, item.ID, user.ID)
+		if err != nil {
+			return app.missingSyncResult(err)
+		}
+		app.publishItemDeletedEvent(item)
+		return SyncResult{Status: SyncStatusApplied}
+	}
+	return invalidSyncResult("op", "must be one of add, update or remove")
+}
+
+func (app *application) applyListOperation(ctx context.Context, operation SyncOperation, user *data.User) SyncResult {
+	var attributes ListAttributes
+	if result, ok := decodeSyncAttributes(operation, &attributes); !ok {
+		return result
+	}
+	var v = validator.New()
+
+	if operation.Op == SyncOpAdd {
+		var list = &data.List{FolderId: 1, UserId: user.ID, Order: 1, Version: 1, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+		attributes.applyTo(list)
+		if result, ok := app.checkSyncFolderAccess(ctx, list.FolderId, user, v); !ok {
+			return result
+		}
+		if data.ValidateList(v, list); !v.Valid() {
+			return SyncResult{Status: SyncStatusInvalid, Errors: v.Errors}
+		}
+		if err := app.models.Lists.Insert(ctx, list); err != nil {
+			return app.failedSyncResult(err)
+		}
+		list.Role = data.RoleOwner
+		return app.appliedSyncResult(list)
+	}
+
+	id, result, ok := readSyncOperationId(operation)
+	if !ok {
+		return result
+	}
+	list, err := app.models.Lists.Get(ctx, id, user.ID)
+	if err != nil {
+		return app.missingSyncResult(err)
+	}
+	if operation.Version != list.Version {
+		return app.conflictSyncResult(list)
+	}
+	if list.Role != data.RoleOwner {
+		return SyncResult{Status: SyncStatusForbidden}
+	}
+
+	switch operation.Op {
+	case SyncOpUpdate:
+		var oldOrder = list.Order
+		attributes.applyTo(list)
+		if result, ok := app.checkSyncFolderAccess(ctx, list.FolderId, user, v); !ok {
+			return result
+		}
+		if data.ValidateList(v, list); !v.Valid() {
+			return SyncResult{Status: SyncStatusInvalid, Errors: v.Errors}
+		}
+		err = app.models.Lists.Update(ctx, list, oldOrder)
+		if err != nil {
+			if errors.Is(err, data.ErrEditConflict) {
+				return app.conflictSyncResult(list)
+			}
+			return app.failedSyncResult(err)
+		}
+		return app.appliedSyncResult(list)
+	case SyncOpRemove:
+		err = app.models.Lists.Delete(ctx, list.ID, user.ID)
+		if err != nil {
+			return app.missingSyncResult(err)
+		}
+		return SyncResult{Status: SyncStatusApplied}
+	}
+	return invalidSyncResult("op", "must be one of add, update or remove")
+}
+
+func (app *application) applyFolderOperation(ctx context.Context, operation SyncOperation, user *data.User) SyncResult {
+	var attributes FolderAttributes
+	if result, ok := decodeSyncAttributes(operation, &attributes); !ok {
+		return result
+	}
+	var v = validator.New()
+
+	if operation.Op == SyncOpAdd {
+		var folder = &data.Folder{Version: 1, UserId: sql.NullInt64{Int64: user.ID, Valid: true}}
+		attributes.applyTo(folder)
+		if data.ValidateFolder(v, folder); !v.Valid() {
+			return SyncResult{Status: SyncStatusInvalid, Errors: v.Errors}
+		}
+		if err := app.models.Folders.Insert(ctx, folder); err != nil {
+			return app.failedSyncResult(err)
+		}
+		return app.appliedSyncResult(folder)
+	}
+
+	id, result, ok := readSyncOperationId(operation)
+	if !ok {
+		return result
+	}
+	folder, err := app.models.Folders.Get(ctx, id, user.ID)
+	if err != nil {
+		return app.missingSyncResult(err)
+	}
+	if operation.Version != folder.Version {
+		return app.conflictSyncResult(folder)
+	}
+	if !folder.UserId.Valid {
+		return SyncResult{Status: SyncStatusForbidden}
+	}
+
+	switch operation.Op {
+	case SyncOpUpdate:
+		var oldOrder = folder.Order
+		attributes.applyTo(folder)
+		v.Check(folder.Order > 0, "data.attributes.order", "order should be greater then zero")
+		if data.ValidateFolder(v, folder); !v.Valid() {
+			return SyncResult{Status: SyncStatusInvalid, Errors: v.Errors}
+		}
+		err = app.models.Folders.Update(ctx, folder, oldOrder)
+		if err != nil {
+			if errors.Is(err, data.ErrEditConflict) {
+				return app.conflictSyncResult(folder)
+			}
+			return app.failedSyncResult(err)
+		}
+		return app.appliedSyncResult(folder)
+	case SyncOpRemove:
+		err = app.models.Folders.Delete(ctx, folder.ID, user.ID)
+		if err != nil {
+			return app.missingSyncResult(err)
+		}
+		return SyncResult{Status: SyncStatusApplied}
+	}
+	return invalidSyncResult("op", "must be one of add, update or remove")
+}
+
+func (attributes ListAttributes) applyTo(list *data.List) {
+	if attributes.FolderId != nil {
+		list.FolderId = *attributes.FolderId
+	}
+	if attributes.Name != nil {
+		list.Name = *attributes.Name
+	}
+	if attributes.Icon != nil {
+		list.Icon = *attributes.Icon
+	}
+	if attributes.Order != nil {
+		list.Order = *attributes.Order
+	}
+	if attributes.Budget != nil {
+		list.Budget = attributes.Budget
+		if *attributes.Budget == 0 {
+			list.Budget = nil
+		}
+	}
+	if attributes.BudgetCurrency != nil {
+		list.BudgetCurrency = *attributes.BudgetCurrency
+	}
+}
+
+func (attributes FolderAttributes) applyTo(folder *data.Folder) {
+	if attributes.Name != nil {
+		folder.Name = *attributes.Name
+	}
+	if attributes.Icon != nil {
+		folder.Icon = *attributes.Icon
+	}
+	if attributes.Order != nil {
+		folder.Order = *attributes.Order
+	}
+}
+
+func (app *application) checkSyncListAccess(ctx context.Context, listId int64, user *data.User, v *validator.Validator) (SyncResult, bool) {
+	list, err := app.models.Lists.Get(ctx, listId, user.ID)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			v.AddError("data.attributes.list_id", "Can not find current list id")
+			return SyncResult{Status: SyncStatusInvalid, Errors: v.Errors}, false
+		}
+		return app.failedSyncResult(err), false
+	}
+	if !list.CanEdit() {
+		return SyncResult{Status: SyncStatusForbidden}, false
+	}
+	return SyncResult{}, true
+}
+
+func (app *application) checkSyncFolderAccess(ctx context.Context, folderId int64, user *data.User, v *validator.Validator) (SyncResult, bool) {
+	_, err := app.models.Folders.Get(ctx, folderId, user.ID)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			v.AddError("data.attributes.folder_id", "this folder does not exists")
+			return SyncResult{Status: SyncStatusInvalid, Errors: v.Errors}, false
+		}
+		return app.failedSyncResult(err), false
+	}
+	return SyncResult{}, true
+}
+
+func decodeSyncAttributes(operation SyncOperation, dst any) (SyncResult, bool) {
+	if len(operation.Attributes) == 0 {
+		return SyncResult{}, true
+	}
+	if err := json.Unmarshal(operation.Attributes, dst); err != nil {
+		return invalidSyncResult("attributes", err.Error()), false
+	}
+	return SyncResult{}, true
+}
+
+func readSyncOperationId(operation SyncOperation) (int64, SyncResult, bool) {
+	id, err := strconv.ParseInt(operation.Id, 10, 64)
+	if err != nil || id < 1 {
+		return 0, invalidSyncResult("id", "must be provided for update and remove operations"), false
+	}
+	if operation.Op != SyncOpUpdate && operation.Op != SyncOpRemove {
+		return 0, invalidSyncResult("op", "must be one of add, update or remove"), false
+	}
+	return id, SyncResult{}, true
+}
+
+func invalidSyncResult(field string, message string) SyncResult {
+	return SyncResult{Status: SyncStatusInvalid, Errors: map[string]string{field: message}}
+}
+
+func (app *application) appliedSyncResult(model any) SyncResult {
+	return SyncResult{Status: SyncStatusApplied, Data: app.syncNode(model)}
+}
+
+// conflictSyncResult returns the current server state of the record so the client can merge it.
+func (app *application) conflictSyncResult(model any) SyncResult {
+	return SyncResult{Status: SyncStatusConflict, Data: app.syncNode(model)}
+}
+
+func (app *application) missingSyncResult(err error) SyncResult {
+	if errors.Is(err, data.ErrRecordNotFound) {
+		return SyncResult{Status: SyncStatusNotFound}
+	}
+	return app.failedSyncResult(err)
+}
+
+func (app *application) failedSyncResult(err error) SyncResult {
+	app.logger.PrintError(err, nil)
+	return SyncResult{Status: SyncStatusFailed}
+}
+
+func (app *application) syncNode(model any) *jsonapi.Node {
+	res, err := jsonapi.Marshal(model)
+	if err != nil {
+		app.logger.PrintError(err, nil)
+		return nil
+	}
+	if onePayload, ok := res.(*jsonapi.OnePayload); ok {
+		return onePayload.Data
+	}
+	return nil
+}
+
+// syncRetention is how long the tombstones are kept, configured with sync.retention.
+func (app *application) syncRetention() time.Duration {
+	retention, err := time.ParseDuration(app.config.Sync.Retention)
+	if err != nil || retention <= 0 {
+		return defaultSyncRetention
+	}
+	return retention
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"easylist/internal/data"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type syncChangesResponse struct {
+	Data []struct {
+		Type string `json:"type"`
+		Id   string `json:"id"`
+	} `json:"data"`
+	Meta struct {
+		Cursor  string `json:"cursor"`
+		Deleted []struct {
+			Type string `json:"type"`
+			Id   string `json:"id"`
+		} `json:"deleted"`
+	} `json:"meta"`
+}
+
+type syncResultsResponse struct {
+	Results []SyncResult `json:"results"`
+}
+
+func TestSyncReturnsDeletedItemsSinceCursor(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	item, token := createItem(app, t)
+	var cursor = data.EncodeSyncCursor(time.Now().Add(-time.Minute))
+
+	err := app.models.Items.Delete(context.Background(), item.ID, item.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := generateRequestWithToken(ts.URL+"/api/v1/sync?since="+cursor, token.Plaintext, "", nil)
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("want %d status code; got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	var check syncChangesResponse
+	err = json.NewDecoder(resp.Body).Decode(&check)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if check.Meta.Cursor == "" {
+		t.Error("want cursor to be returned")
+	}
+	var found bool
+	for _, deleted := range check.Meta.Deleted {
+		if deleted.Type == data.ItemsType && deleted.Id == strconv.Itoa(int(item.ID)) {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("want item %d in deleted records, got %v", item.ID, check.Meta.Deleted)
+	}
+}
+
+func TestSyncRejectsInvalidCursor(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	_, token := createItem(app, t)
+
+	req := generateRequestWithToken(ts.URL+"/api/v1/sync?since=!!!", token.Plaintext, "", nil)
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("want %d status code; got %d", http.StatusUnprocessableEntity, resp.StatusCode)
+	}
+}
+
+func TestSyncRejectsExpiredCursor(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	app.config.Sync.Retention = "1h"
+	item, token := createItem(app, t)
+	err := app.models.Items.Delete(context.Background(), item.ID, item.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cursor = data.EncodeSyncCursor(time.Now().Add(-2 * time.Hour))
+	req := generateRequestWithToken(ts.URL+"/api/v1/sync?since="+cursor, token.Plaintext, "", nil)
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("want %d status code; got %d", http.StatusUnprocessableEntity, resp.StatusCode)
+	}
+
+	// the purge keeps the tombstones within the retention
+	err = app.purgeTrash(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tombstones, err := app.models.Tombstones.GetAllSince(context.Background(), item.UserId, time.Time{})
+	if err != nil || len(tombstones) != 1 {
+		t.Fatalf("want the recent tombstone to stay; got %v %v", tombstones, err)
+	}
+	purged, err := app.models.Tombstones.Purge(context.Background(), -time.Hour)
+	if err != nil || purged != 1 {
+		t.Errorf("want the old tombstone to be purged; got %d %v", purged, err)
+	}
+}
+
+func TestSyncOperationsReportVersionConflict(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	item, token := createItem(app, t)
+
+	var operations = []byte(`{
+	  "operations": [
+		{"op": "update", "type": "items", "id": "` + strconv.Itoa(int(item.ID)) + `", "version": 7, "attributes": {"name": "Stale"}},
+		{"op": "update", "type": "items", "id": "` + strconv.Itoa(int(item.ID)) + `", "version": 1, "attributes": {"name": "Fresh"}},
+		{"op": "add", "type": "items", "attributes": {"name": "Bread", "quantity": 1, "list_id": ` + strconv.Itoa(int(item.ListId)) + `}}
+	  ]
+	}`)
+	req := generateRequestWithToken(ts.URL+"/api/v1/sync", token.Plaintext, "POST", bytes.NewBuffer(operations))
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("want %d status code; got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	var check syncResultsResponse
+	err = json.NewDecoder(resp.Body).Decode(&check)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expected = []string{SyncStatusConflict, SyncStatusApplied, SyncStatusApplied}
+	if len(check.Results) != len(expected) {
+		t.Fatalf("want %d results, got %d", len(expected), len(check.Results))
+	}
+	for i, status := range expected {
+		if check.Results[i].Status != status {
+			t.Errorf("want result %d to be %s, got %s", i, status, check.Results[i].Status)
+		}
+	}
+}
+
+func TestSyncReturnsListOfRemovedMember(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	item, ownerToken := createItem(app, t)
+	removed, removedToken, err := createTestUserWithToken(t, app, "removed@mail.ru")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept, keptToken, err := createTestUserWithToken(t, app, "kept@mail.ru")
+	if err != nil {
+		t.Fatal(err)
+	}
+	member, err := createTestMember(app, item.ListId, removed.ID, data.RoleEditor, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = createTestMember(app, item.ListId, kept.ID, data.RoleEditor, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cursor = data.EncodeSyncCursor(time.Now().Add(-time.Minute))
+
+	if status := sendWithToken(t, ts, ownerToken.Plaintext, "DELETE", "/api/v1/members/"+strconv.Itoa(int(member.ID)), ""); status != http.StatusNoContent {
+		t.Fatalf("want %d status code; got %d", http.StatusNoContent, status)
+	}
+
+	var listDeleted = func(token string) bool {
+		t.Helper()
+		req := generateRequestWithToken(ts.URL+"/api/v1/sync?since="+cursor, token, "", nil)
+		resp, err := ts.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		var check syncChangesResponse
+		err = json.NewDecoder(resp.Body).Decode(&check)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, deleted := range check.Meta.Deleted {
+			if deleted.Type == data.ListsType && deleted.Id == strconv.Itoa(int(item.ListId)) {
+				return true
+			}
+		}
+		return false
+	}
+	if !listDeleted(removedToken.Plaintext) {
+		t.Errorf("want list %d in the deleted records of the removed member", item.ListId)
+	}
+	if listDeleted(keptToken.Plaintext) || listDeleted(ownerToken.Plaintext) {
+		t.Errorf("want list %d to stay with the owner and the other members", item.ListId)
+	}
+}
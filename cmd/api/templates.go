@@ -0,0 +1,382 @@
+package main
+
+import (
+	"context"
+	"easylist/internal/data"
+	"easylist/internal/validator"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const templateSchedulerInterval = time.Minute
+
+// maxTemplateItems is how many items of a list are copied into a template.
+const maxTemplateItems = 500
+
+// applyTo copies the attributes which were sent by the client, list_id is only used when the template is created.
+func (attributes TemplateAttributes) applyTo(template *data.Template) {
+	if attributes.FolderId != nil {
+		template.FolderId = *attributes.FolderId
+	}
+	if attributes.Name != nil {
+		template.Name = *attributes.Name
+	}
+	if attributes.Icon != nil {
+		template.Icon = *attributes.Icon
+	}
+	if attributes.Schedule != nil {
+		template.Schedule = *attributes.Schedule
+	}
+}
+
+func (app *application) indexTemplatesHandler(w http.ResponseWriter, r *http.Request) {
+	var userModel = app.contextGetUser(r)
+
+	templates, err := app.models.Templates.GetAll(r.Context(), userModel.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, templates, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	var input = Input[TemplateAttributes]{Data: InputAttributes[TemplateAttributes]{
+		Attributes: TemplateAttributes{},
+	}}
+	var err = readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, "createTemplateHandler", err)
+		return
+	}
+
+	var v = validator.New()
+	v.Check(input.Data.Type == data.TemplatesType, "data.type", "Wrong type provided, accepted type is templates")
+	v.Check(input.Data.Attributes.ListId != nil, "data.attributes.list_id", "must be provided")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	var userModel = app.contextGetUser(r)
+
+	list, err := app.models.Lists.Get(r.Context(), *input.Data.Attributes.ListId, userModel.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("data.attributes.list_id", "Can not find current list id")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var filters = data.Filters{Page: 1, Size: maxTemplateItems, Sort: "order", SortSafelist: []string{"order"}}
+	items, _, err := app.models.Items.GetAll(r.Context(), userModel.ID, list.ID, data.ItemFilters{}, filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var template = data.NewTemplateFromList(list, items)
+	template.UserId = userModel.ID
+	if list.Role != data.RoleOwner {
+		// the folder belongs to the owner of a shared list
+		template.FolderId = 1
+	}
+	input.Data.Attributes.applyTo(template)
+
+	if !app.validateTemplate(w, r, template, v) {
+		return
+	}
+	err = template.ScheduleNextRun(time.Now())
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.models.Templates.Insert(r.Context(), template)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var headers = make(http.Header)
+	headers.Set("Location", fmt.Sprintf("%s/api/v1/templates/%d", app.config.Domain, template.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, template, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	template, ok := app.readTemplate(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.writeJSON(w, http.StatusOK, template, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	template, ok := app.readTemplate(w, r)
+	if !ok {
+		return
+	}
+
+	var input = Input[TemplateAttributes]{Data: InputAttributes[TemplateAttributes]{
+		Attributes: TemplateAttributes{},
+	}}
+	var err = readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, "updateTemplateHandler", err)
+		return
+	}
+
+	var v = validator.New()
+	v.Check(input.Data.Type == data.TemplatesType, "data.type", "Wrong type provided, accepted type is templates")
+	v.Check(input.Data.Id == strconv.FormatInt(template.ID, 10), "data.id", "Passed json id does not match request id")
+
+	var oldSchedule = template.Schedule
+	input.Data.Attributes.applyTo(template)
+
+	if !app.validateTemplate(w, r, template, v) {
+		return
+	}
+	// the planned run is kept unless the schedule changes
+	if template.Schedule != oldSchedule {
+		err = template.ScheduleNextRun(time.Now())
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	err = app.models.Templates.Update(r.Context(), template)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r, "updateTemplateHandler")
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, template, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var userModel = app.contextGetUser(r)
+
+	err = app.models.Templates.Delete(r.Context(), id, userModel.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) instantiateTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	template, ok := app.readTemplate(w, r)
+	if !ok {
+		return
+	}
+
+	list, err := app.instantiateTemplate(r.Context(), template)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var headers = make(http.Header)
+	headers.Set("Location", fmt.Sprintf("%s/api/v1/lists/%d", app.config.Domain, list.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, list, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readTemplate loads the template from the id in the url, on failure the response is already written.
+func (app *application) readTemplate(w http.ResponseWriter, r *http.Request) (*data.Template, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	var userModel = app.contextGetUser(r)
+
+	template, err := app.models.Templates.Get(r.Context(), id, userModel.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+	return template, true
+}
+
+// validateTemplate checks the template and its folder, on failure the response is already written.
+func (app *application) validateTemplate(w http.ResponseWriter, r *http.Request, template *data.Template, v *validator.Validator) bool {
+	_, err := app.models.Folders.Get(r.Context(), template.FolderId, template.UserId)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("data.attributes.folder_id", "this folder does not exists")
+		default:
+			app.serverErrorResponse(w, r, err)
+			return false
+		}
+	}
+
+	if data.ValidateTemplate(v, template); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return false
+	}
+	return true
+}
+
+// instantiateTemplate creates a new list with the items of the template in one transaction, the list goes to the
+// default folder when the folder of the template does not exist anymore.
+func (app *application) instantiateTemplate(ctx context.Context, template *data.Template) (*data.List, error) {
+	var folderId = template.FolderId
+	_, err := app.models.Folders.Get(ctx, folderId, template.UserId)
+	if err != nil {
+		if !errors.Is(err, data.ErrRecordNotFound) {
+			return nil, err
+		}
+		folderId = 1
+	}
+
+	var list = &data.List{
+		UserId:    template.UserId,
+		FolderId:  folderId,
+		Name:      template.Name,
+		Icon:      template.Icon,
+		Version:   1,
+		Role:      data.RoleOwner,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	err = app.models.WithTx(ctx, func(tx data.Models) error {
+		err := tx.Lists.Insert(ctx, list)
+		if err != nil {
+			return err
+		}
+
+		for _, templateItem := range template.Items {
+			var item = &data.Item{
+				UserId:       template.UserId,
+				ListId:       list.ID,
+				Name:         templateItem.Name,
+				Description:  templateItem.Description,
+				Quantity:     templateItem.Quantity,
+				QuantityType: templateItem.QuantityType,
+				Price:        templateItem.Price,
+				Currency:     templateItem.Currency,
+				IsStarred:    templateItem.IsStarred,
+				Version:      1,
+				CreatedAt:    time.Now(),
+				UpdatedAt:    time.Now(),
+			}
+			err = tx.Items.Insert(ctx, item)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	list.ItemsCount = int32(len(template.Items))
+	return list, nil
+}
+
+// runScheduledTemplates creates the lists of the templates which are due. The next run is saved before the
+// list is created in the background, so a slow run is not picked up twice.
+func (app *application) runScheduledTemplates(ctx context.Context) error {
+	var now = time.Now()
+	templates, err := app.models.Templates.GetDue(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	for _, template := range templates {
+		err = template.ScheduleNextRun(now)
+		if err != nil {
+			// the schedule was valid when it was saved, stop it instead of failing every minute
+			app.logger.PrintError(err, map[string]string{"template": strconv.FormatInt(template.ID, 10)})
+			template.NextRunAt = nil
+		}
+		err = app.models.Templates.SetNextRun(ctx, template.ID, template.NextRunAt)
+		if err != nil {
+			return err
+		}
+
+		var template = template
+		app.background(func() {
+			list, err := app.instantiateTemplate(ctx, template)
+			if err != nil {
+				app.logger.PrintError(err, map[string]string{"template": strconv.FormatInt(template.ID, 10)})
+				return
+			}
+			app.logger.PrintInfo("list created from template", map[string]string{
+				"template": strconv.FormatInt(template.ID, 10),
+				"list":     strconv.FormatInt(list.ID, 10),
+			})
+		})
+	}
+	return nil
+}
+
+// runTemplateScheduler checks for due templates every templateSchedulerInterval until done is closed,
+// the queries are cancelled together with ctx.
+func (app *application) runTemplateScheduler(ctx context.Context, done <-chan struct{}) {
+	var ticker = time.NewTicker(templateSchedulerInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			err := app.runScheduledTemplates(ctx)
+			if err != nil {
+				app.logger.PrintError(err, nil)
+			}
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"easylist/internal/data"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type templateResponse struct {
+	Data struct {
+		Id         string `json:"id"`
+		Attributes struct {
+			Name      string     `json:"name"`
+			Schedule  string     `json:"schedule"`
+			NextRunAt *time.Time `json:"next_run_at"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+func createTemplateRequest(ts *testServer, token string, listId int64, schedule string) (*http.Response, error) {
+	var body = []byte(`{
+	  "data": {
+		"type": "templates",
+		"attributes": {
+		  "list_id": ` + strconv.Itoa(int(listId)) + `,
+		  "schedule": "` + schedule + `"
+		}
+	  }
+	}`)
+	req := generateRequestWithToken(ts.URL+"/api/v1/templates", token, "POST", bytes.NewBuffer(body))
+	return ts.Client().Do(req)
+}
+
+func TestCreateTemplateFromList(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	item, token := createItem(app, t)
+
+	resp, err := createTemplateRequest(ts, token.Plaintext, item.ListId, "weekly")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("want %d status code; got %d", http.StatusCreated, resp.StatusCode)
+	}
+
+	var check templateResponse
+	err = json.NewDecoder(resp.Body).Decode(&check)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if check.Data.Attributes.NextRunAt == nil || !check.Data.Attributes.NextRunAt.After(time.Now()) {
+		t.Errorf("want next run in the future for a weekly template, got %v", check.Data.Attributes.NextRunAt)
+	}
+
+	id, _ := strconv.ParseInt(check.Data.Id, 10, 64)
+	template, err := app.models.Templates.Get(context.Background(), id, item.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(template.Items) != 1 || template.Items[0].Name != item.Name {
+		t.Errorf("want the item of the list to be copied into the template, got %v", template.Items)
+	}
+}
+
+func TestCreateTemplateRejectsInvalidSchedule(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	item, token := createItem(app, t)
+
+	resp, err := createTemplateRequest(ts, token.Plaintext, item.ListId, "every now and then")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("want %d status code; got %d", http.StatusUnprocessableEntity, resp.StatusCode)
+	}
+}
+
+func TestInstantiateTemplate(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	item, token := createItem(app, t)
+
+	resp, err := createTemplateRequest(ts, token.Plaintext, item.ListId, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var created templateResponse
+	err = json.NewDecoder(resp.Body).Decode(&created)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := generateRequestWithToken(ts.URL+"/api/v1/templates/"+created.Data.Id+"/instantiate", token.Plaintext, "POST", nil)
+	resp, err = ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("want %d status code; got %d", http.StatusCreated, resp.StatusCode)
+	}
+
+	lists, _, err := app.models.Lists.GetAll(context.Background(), 0, item.UserId, data.ListFilters{}, data.Filters{Page: 1, Size: 10, Sort: "id", SortSafelist: []string{"id"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lists) != 2 {
+		t.Fatalf("want a second list to be created, got %d lists", len(lists))
+	}
+	if lists[1].ItemsCount != 1 {
+		t.Errorf("want the new list to have 1 item, got %d", lists[1].ItemsCount)
+	}
+}
+
+func TestScheduledTemplateCreatesList(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	item, _ := createItem(app, t)
+
+	list, err := app.models.Lists.Get(context.Background(), item.ListId, item.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var template = data.NewTemplateFromList(list, data.Items{&item})
+	template.Schedule = "daily"
+	var due = time.Now().Add(-time.Minute)
+	template.NextRunAt = &due
+	err = app.models.Templates.Insert(context.Background(), template)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = app.runScheduledTemplates(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.wg.Wait()
+
+	lists, _, err := app.models.Lists.GetAll(context.Background(), 0, item.UserId, data.ListFilters{}, data.Filters{Page: 1, Size: 10, Sort: "id", SortSafelist: []string{"id"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lists) != 2 {
+		t.Errorf("want the scheduler to create a list, got %d lists", len(lists))
+	}
+
+	template, err = app.models.Templates.Get(context.Background(), template.ID, item.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if template.NextRunAt == nil || !template.NextRunAt.After(time.Now()) {
+		t.Errorf("want the next run to move into the future, got %v", template.NextRunAt)
+	}
+}
@@ -3,12 +3,15 @@ package main
 import (
 	"database/sql"
 	"easylist/internal/data"
+	"easylist/internal/events"
 	"easylist/internal/jsonlog"
+	"easylist/internal/storage"
 	"github.com/google/jsonapi"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -17,7 +20,7 @@ type JsonapiErrors struct {
 }
 
 func newTestApplication(t *testing.T) *application {
-	return &application{
+	var app = &application{
 		config: config{
 			Port:         4000,
 			Env:          "development",
@@ -42,72 +45,53 @@ func newTestApplication(t *testing.T) *application {
 				TrustedOrigins []string `yaml:"trustedOrigins"`
 			}{},
 		},
-		logger: jsonlog.New(os.Stdout, jsonlog.LevelError),
+		logger:  jsonlog.New(os.Stdout, jsonlog.LevelError),
+		events:  events.NewHub(),
+		storage: storage.NewLocal(t.TempDir(), "http://127.0.0.1/files", []byte("secret")),
 	}
+	app.loginAttempts = newLoginAttempts(app.config, app.now)
+	return app
 }
 
 func newTestAppWithDb(t *testing.T) (*application, func()) {
 	var app = newTestApplication(t)
 	db, teardown := newTestDB(t)
-	app.models.Users = data.UserModel{DB: db}
-	app.models.Folders = data.FolderModel{DB: db}
-	app.models.Tokens = data.TokenModel{DB: db}
-	app.models.Permissions = data.PermissionModel{DB: db}
-	app.models.Lists = data.ListModel{DB: db}
-	app.models.Items = data.ItemModel{DB: db}
+	app.models = data.NewModels(db)
 	return app, teardown
 }
 
-func newTestDB(t *testing.T) (*sql.DB, func()) {
-	var dbCred = os.Getenv("EASYLIST_TEST_DB")
-	db, err := sql.Open("mysql", dbCred)
+// newTestDB connects to the database from EASYLIST_TEST_DB, EASYLIST_TEST_DB_DRIVER tells its driver and defaults to MySQL.
+// Without EASYLIST_TEST_DB the tests run against a new SQLite database in a temporary directory.
+// The schema is created with the embedded migrations and dropped again by the returned teardown function.
+func newTestDB(t *testing.T) (*data.DB, func()) {
+	var cfg = config{Db: &database{Dsn: os.Getenv("EASYLIST_TEST_DB")}}
+	dialect, err := data.ParseDialect(os.Getenv("EASYLIST_TEST_DB_DRIVER"))
 	if err != nil {
 		t.Fatal(err)
 	}
-
-	migrations := [...]string{
-		"../../migrations/000001_create_users_table.up.sql",
-		"../../migrations/000002_create_tokens_table.up.sql",
-		"../../migrations/000003_create_permissions_table.up.sql",
-		"../../migrations/000004_create_folders_table.up.sql",
-		"../../migrations/000005_create_lists_table.up.sql",
-		"../../migrations/000006_create_items_table.up.sql",
-		"../../migrations/000007_add_default_folder.up.sql",
-		"../../migrations/000008_add_fulltext_search_to_name_column_in_folders_table.up.sql",
-		"../../migrations/000009_add_fulltext_search_index_to_name_column_in_lists_table.up.sql",
-		"../../migrations/000010_add_fulltext_search_index_to_name_column_in_items_table.up.sql",
-		"../../migrations/000011_add_is_done_column_to_items_table.up.sql",
+	if cfg.Db.Dsn == "" {
+		dialect = data.SQLite
+		cfg.Db.Dsn = "file:" + filepath.Join(t.TempDir(), "easylist.db") + "?_foreign_keys=on&_busy_timeout=5000&_txlock=immediate"
 	}
-	for _, migration := range migrations {
-		script, err := os.ReadFile(migration)
-		if err != nil {
-			t.Fatal(err)
-		}
-		_, err = db.Exec(string(script))
+	sqlDb, err := sql.Open(dialect.DriverName(), cfg.Db.Dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrator, err := openMigrator(cfg, dialect)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = migrator.Up()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data.NewDB(sqlDb, dialect), func() {
+		_, err := migrator.Down(0)
 		if err != nil {
 			t.Fatal(err)
 		}
-	}
-	return db, func() {
-		migrations := [...]string{
-			"../../migrations/000001_create_users_table.down.sql",
-			"../../migrations/000002_create_tokens_table.down.sql",
-			"../../migrations/000003_create_permissions_table.down.sql",
-			"../../migrations/000004_create_folders_table.down.sql",
-			"../../migrations/000005_create_lists_table.down.sql",
-			"../../migrations/000006_create_items_table.down.sql",
-		}
-		for _, migration := range migrations {
-			script, err := os.ReadFile(migration)
-			if err != nil {
-				t.Fatal(err)
-			}
-			_, err = db.Exec(string(script))
-			if err != nil {
-				t.Fatal(err)
-			}
-		}
-		db.Close()
+		migrator.DB.Close()
+		sqlDb.Close()
 	}
 }
 
@@ -5,9 +5,31 @@ import (
 	"easylist/internal/validator"
 	"errors"
 	"net/http"
+	"strings"
 	"time"
 )
 
+const defaultAccessTokenTtl = 15 * time.Minute
+const defaultRefreshTokenTtl = 90 * 24 * time.Hour
+
+// accessTokenTtl is how long the authentication tokens of the sessions are valid, configured with auth.accessTtl.
+func (app *application) accessTokenTtl() time.Duration {
+	ttl, err := time.ParseDuration(app.config.Auth.AccessTtl)
+	if err != nil || ttl <= 0 {
+		return defaultAccessTokenTtl
+	}
+	return ttl
+}
+
+// refreshTokenTtl is how long a session can be refreshed without being used, configured with auth.refreshTtl.
+func (app *application) refreshTokenTtl() time.Duration {
+	ttl, err := time.ParseDuration(app.config.Auth.RefreshTtl)
+	if err != nil || ttl <= 0 {
+		return defaultRefreshTokenTtl
+	}
+	return ttl
+}
+
 /*func (app *application) createActivationTokenHandler(w http.ResponseWriter, r *http.Request) {
 	var input struct {
 		Email string `json:"email"`
@@ -75,6 +97,10 @@ import (
 	}
 }*/
 
+// createAuthenticationTokenHandler signs the user in on a new device, the response has a short-lived authentication
+// token and a refresh token which is exchanged for a new pair by refreshAuthenticationTokenHandler. When the user has
+// enabled the two-factor authentication the right password only gives a challenge token, which is sent back to the
+// same endpoint together with a code of the authenticator app or a recovery code to get the session.
 func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter, r *http.Request) {
 	var input = Input[TokensAttributes]{Data: InputAttributes[TokensAttributes]{
 		Type:       "tokens",
@@ -86,41 +112,195 @@ func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter,
 		app.badRequestResponse(w, r, "createAuthenticationTokenHandler", err)
 		return
 	}
+	var attributes = input.Data.Attributes
 	var v = validator.New()
 	v.Check(input.Data.Type == "tokens", "data.type", "Wrong type provided, accepted type is tokens")
-	data.ValidateEmail(v, input.Data.Attributes.Email)
-	data.ValidatePasswordPlaintext(v, input.Data.Attributes.Password)
+	if attributes.ChallengeToken != "" {
+		v.Check(len(attributes.ChallengeToken) == 26, "challenge_token", "must be 26 bytes long")
+		v.Check(attributes.Code != "", "code", "must be provided")
+	} else {
+		data.ValidateEmail(v, attributes.Email)
+		data.ValidatePasswordPlaintext(v, attributes.Password)
+	}
+	v.Check(len(attributes.DeviceName) <= 255, "device_name", "must not be more than 255 bytes long")
 
 	if !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
-	user, err := app.models.Users.GetByEmail(input.Data.Attributes.Email)
-	if err != nil {
-		switch {
-		case errors.Is(err, data.ErrRecordNotFound):
+	if app.throttleLogin(w, r, attributes.Email) {
+		return
+	}
+
+	var user *data.User
+	if attributes.ChallengeToken != "" {
+		user, err = app.models.Users.GetForToken(r.Context(), data.ScopeTwoFactor, attributes.ChallengeToken)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				app.failLogin(r, "", nil)
+				app.invalidCredentialsResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+		if app.throttleLogin(w, r, user.Email) {
+			return
+		}
+		ok, err := app.verifyTwoFactorCode(r, user.ID, attributes.Code)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		if !ok {
+			err = app.failTwoFactorCode(r, user)
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+				return
+			}
 			app.invalidCredentialsResponse(w, r)
-		default:
+			return
+		}
+		err = app.models.Tokens.DeleteAllForUser(r.Context(), data.ScopeTwoFactor, user.ID)
+		if err != nil {
 			app.serverErrorResponse(w, r, err)
+			return
+		}
+	} else {
+		user, err = app.models.Users.GetByEmail(r.Context(), attributes.Email)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				app.failLogin(r, attributes.Email, nil)
+				app.invalidCredentialsResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+		match := user.Password.Matches(attributes.Password)
+		if !match {
+			app.failLogin(r, attributes.Email, user)
+			app.invalidCredentialsResponse(w, r)
+			return
+		}
+		if app.challengeTwoFactor(w, r, user) {
+			return
 		}
-		return
 	}
-	match := user.Password.Matches(input.Data.Attributes.Password)
-	if !match {
-		app.invalidCredentialsResponse(w, r)
+
+	app.startSession(w, r, user, attributes.DeviceName)
+}
+
+// challengeTwoFactor answers with a challenge token when the user has enabled the two-factor authentication, it
+// reports whether the response has been written.
+func (app *application) challengeTwoFactor(w http.ResponseWriter, r *http.Request, user *data.User) bool {
+	twoFactor, err := app.models.TwoFactor.Get(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return true
+	}
+	if !twoFactor.Enabled {
+		return false
+	}
+	challenge, err := app.models.Tokens.New(r.Context(), user.ID, twoFactorChallengeTtl, data.ScopeTwoFactor)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return true
+	}
+	var response = &data.TwoFactorChallenge{
+		ID:                challenge.ID,
+		ChallengeToken:    challenge.Plaintext,
+		Expiry:            challenge.Expiry,
+		TwoFactorRequired: true,
+	}
+	err = app.writeJSON(w, http.StatusAccepted, response, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+	return true
+}
+
+// startSession signs the user in on the device and answers with the tokens of the new session. Only then the failed
+// attempts of the user are forgotten, the right password alone does not reset them while the code is guessed.
+func (app *application) startSession(w http.ResponseWriter, r *http.Request, user *data.User, deviceName string) {
+	var session = &data.Session{
+		UserId:     user.ID,
+		DeviceName: deviceName,
+		Ip:         clientIp(r),
+		UserAgent:  truncate(r.UserAgent(), 255),
+	}
+	tokens, err := app.models.Sessions.Start(r.Context(), session, app.accessTokenTtl(), app.refreshTokenTtl())
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
 		return
 	}
-	token, err := app.models.Tokens.New(user.ID, 24*time.Hour*90, data.ScopeAuthentication)
+	app.succeedLogin(user)
+	err = app.writeJSON(w, http.StatusCreated, tokens, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// refreshAuthenticationTokenHandler exchanges the refresh token for a new pair of tokens of the same session. A refresh
+// token can be exchanged once, presenting it again means it was stolen, so the whole session is revoked.
+func (app *application) refreshAuthenticationTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var input = Input[TokensAttributes]{Data: InputAttributes[TokensAttributes]{
+		Type:       "tokens",
+		Attributes: TokensAttributes{},
+	}}
+
+	var err = readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, "refreshAuthenticationTokenHandler", err)
+		return
+	}
+	var v = validator.New()
+	v.Check(input.Data.Type == "tokens", "data.type", "Wrong type provided, accepted type is tokens")
+	v.Check(input.Data.Attributes.RefreshToken != "", "refresh_token", "must be provided")
+	v.Check(len(input.Data.Attributes.RefreshToken) == 26, "refresh_token", "must be 26 bytes long")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
-	err = app.writeJSON(w, http.StatusCreated, token, nil)
+
+	tokens, err := app.models.Sessions.Refresh(r.Context(), input.Data.Attributes.RefreshToken, app.accessTokenTtl(), app.refreshTokenTtl())
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound), errors.Is(err, data.ErrRefreshTokenReused):
+			app.invalidAuthenticationTokenResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusCreated, tokens, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
 
+// deleteAuthenticationTokenHandler signs the current device out, all tokens of its session are revoked.
+func (app *application) deleteAuthenticationTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var userModel = app.contextGetUser(r)
+
+	var err error
+	if session := app.contextGetSession(r); session != nil {
+		err = app.models.Sessions.Delete(r.Context(), session.ID, userModel.ID)
+	} else {
+		var token = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		err = app.models.Tokens.DeleteForPlaintext(r.Context(), data.ScopeAuthentication, token, userModel.ID)
+	}
+	if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (app *application) createPasswordResetTokenHandler(w http.ResponseWriter, r *http.Request) {
 	var input = Input[TokensAttributes]{Data: InputAttributes[TokensAttributes]{
 		Type:       "tokens",
@@ -141,11 +321,16 @@ func (app *application) createPasswordResetTokenHandler(w http.ResponseWriter, r
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
+	if app.throttleLogin(w, r, input.Data.Attributes.Email) {
+		return
+	}
 
-	user, err := app.models.Users.GetByEmail(input.Data.Attributes.Email)
+	user, err := app.models.Users.GetByEmail(r.Context(), input.Data.Attributes.Email)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
+			// guessing the emails of the users counts against the ip address
+			app.failLogin(r, "", nil)
 			v.AddError("email", "no matching email address found")
 			app.failedValidationResponse(w, r, v.Errors)
 		default:
@@ -160,7 +345,7 @@ func (app *application) createPasswordResetTokenHandler(w http.ResponseWriter, r
 		return
 	}
 
-	token, err := app.models.Tokens.New(user.ID, 45*time.Minute, data.ScopePasswordReset)
+	token, err := app.models.Tokens.New(r.Context(), user.ID, 45*time.Minute, data.ScopePasswordReset)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
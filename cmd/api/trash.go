@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"easylist/internal/data"
+	"easylist/internal/events"
+	"encoding/json"
+	"errors"
+	"github.com/google/jsonapi"
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const defaultTrashRetention = 30 * 24 * time.Hour
+const trashPurgeInterval = time.Hour
+
+func (app *application) showTrashHandler(w http.ResponseWriter, r *http.Request) {
+	var userModel = app.contextGetUser(r)
+
+	folders, err := app.models.Folders.GetTrashed(r.Context(), userModel.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	lists, err := app.models.Lists.GetTrashed(r.Context(), userModel.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	items, err := app.models.Items.GetTrashed(r.Context(), userModel.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var payload = &jsonapi.ManyPayload{Data: []*jsonapi.Node{}}
+	for _, collection := range []any{folders, lists, items} {
+		res, err := jsonapi.Marshal(collection)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		if manyPayload, ok := res.(*jsonapi.ManyPayload); ok {
+			payload.Data = append(payload.Data, manyPayload.Data...)
+		}
+	}
+	payload.Meta = &jsonapi.Meta{
+		"total":     len(payload.Data),
+		"retention": app.trashRetention().String(),
+	}
+
+	writeHeaders(w, http.StatusOK, nil)
+	err = json.NewEncoder(w).Encode(payload)
+	if err != nil {
+		app.logError(r, err)
+	}
+}
+
+func (app *application) restoreFromTrashHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var userModel = app.contextGetUser(r)
+	var recordType = httprouter.ParamsFromContext(r.Context()).ByName("type")
+
+	var restored any
+	switch recordType {
+	case data.FolderType:
+		err = app.models.Folders.Restore(r.Context(), id, userModel.ID)
+		if err == nil {
+			restored, err = app.models.Folders.Get(r.Context(), id, userModel.ID)
+		}
+	case data.ListsType:
+		err = app.models.Lists.Restore(r.Context(), id, userModel.ID)
+		if err == nil {
+			restored, err = app.models.Lists.Get(r.Context(), id, userModel.ID)
+		}
+	case data.ItemsType:
+		var item *data.Item
+		err = app.models.Items.Restore(r.Context(), id, userModel.ID)
+		if err == nil {
+			item, err = app.models.Items.Get(r.Context(), id, userModel.ID)
+		}
+		if err == nil {
+			app.publishItemEvent(events.ItemCreated, item)
+			restored = item
+		}
+	default:
+		app.notFoundResponse(w, r)
+		return
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, restored, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// trashRetention is how long deleted records can be restored, configured with trash.retention.
+func (app *application) trashRetention() time.Duration {
+	retention, err := time.ParseDuration(app.config.Trash.Retention)
+	if err != nil || retention <= 0 {
+		return defaultTrashRetention
+	}
+	return retention
+}
+
+// purgeTrash removes the records which stayed in the trash longer than the retention period in one transaction.
+// Items go first, so the items of purged lists are removed together with their files, which are deleted from the
+// storage only once the transaction is committed. The tombstones older than the sync retention go as well.
+func (app *application) purgeTrash(ctx context.Context) error {
+	var retention = app.trashRetention()
+
+	var items, lists, folders, tombstones int64
+	var files []string
+	err := app.models.WithTx(ctx, func(tx data.Models) error {
+		var err error
+		items, files, err = tx.Items.Purge(ctx, retention)
+		if err != nil {
+			return err
+		}
+		lists, err = tx.Lists.Purge(ctx, retention)
+		if err != nil {
+			return err
+		}
+		folders, err = tx.Folders.Purge(ctx, retention)
+		if err != nil {
+			return err
+		}
+		tombstones, err = tx.Tombstones.Purge(ctx, app.syncRetention())
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		app.deleteFiles(ctx, itemFiles(file))
+	}
+
+	if items+lists+folders+tombstones > 0 {
+		app.logger.PrintInfo("trash purged", map[string]string{
+			"items":      strconv.FormatInt(items, 10),
+			"lists":      strconv.FormatInt(lists, 10),
+			"folders":    strconv.FormatInt(folders, 10),
+			"tombstones": strconv.FormatInt(tombstones, 10),
+		})
+	}
+	return nil
+}
+
+// runTrashPurge purges the trash every trashPurgeInterval until done is closed, the queries are cancelled together with ctx.
+func (app *application) runTrashPurge(ctx context.Context, done <-chan struct{}) {
+	var ticker = time.NewTicker(trashPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		err := app.purgeTrash(ctx)
+		if err != nil {
+			app.logger.PrintError(err, nil)
+		}
+
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"easylist/internal/data"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type trashResponse struct {
+	Data []struct {
+		Type string `json:"type"`
+		Id   string `json:"id"`
+	} `json:"data"`
+}
+
+func TestDeletedItemMovesToTrash(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	item, token := createItem(app, t)
+
+	err := app.models.Items.Delete(context.Background(), item.ID, item.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := generateRequestWithToken(ts.URL+"/api/v1/items/"+strconv.Itoa(int(item.ID)), token.Plaintext, "", nil)
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("want %d status code for a trashed item; got %d", http.StatusNotFound, resp.StatusCode)
+	}
+
+	req = generateRequestWithToken(ts.URL+"/api/v1/trash", token.Plaintext, "", nil)
+	resp, err = ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("want %d status code; got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	var check trashResponse
+	err = json.NewDecoder(resp.Body).Decode(&check)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(check.Data) != 1 || check.Data[0].Type != data.ItemsType || check.Data[0].Id != strconv.Itoa(int(item.ID)) {
+		t.Errorf("want item %d in the trash, got %v", item.ID, check.Data)
+	}
+}
+
+func TestRestoreItemFromTrash(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	item, token := createItem(app, t)
+
+	err := app.models.Items.Delete(context.Background(), item.ID, item.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := generateRequestWithToken(ts.URL+"/api/v1/trash/items/"+strconv.Itoa(int(item.ID))+"/restore", token.Plaintext, "POST", nil)
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("want %d status code; got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	restored, err := app.models.Items.Get(context.Background(), item.ID, item.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Version != item.Version+1 {
+		t.Errorf("want version %d after restore, got %d", item.Version+1, restored.Version)
+	}
+
+	resp, err = ts.Client().Do(generateRequestWithToken(ts.URL+"/api/v1/trash/items/"+strconv.Itoa(int(item.ID))+"/restore", token.Plaintext, "POST", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("want %d status code for an item which is not in the trash; got %d", http.StatusNotFound, resp.StatusCode)
+	}
+}
+
+func TestRestoreRejectsUnknownType(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	_, token := createItem(app, t)
+
+	req := generateRequestWithToken(ts.URL+"/api/v1/trash/users/1/restore", token.Plaintext, "POST", nil)
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("want %d status code; got %d", http.StatusNotFound, resp.StatusCode)
+	}
+}
+
+func TestDeletedFolderHidesItsLists(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	user, token, err := createTestUserWithToken(t, app, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	folder, err := createTestFolder(app, user.ID, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var list = data.List{UserId: user.ID, FolderId: folder.ID}
+	err = createTestList(app, &list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var item = data.Item{UserId: user.ID, ListId: list.ID, File: "items/1/photo.jpg"}
+	err = createTestItem(app, &item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var listPath = "/api/v1/lists/" + strconv.Itoa(int(list.ID))
+	var itemPath = "/api/v1/items/" + strconv.Itoa(int(item.ID))
+
+	err = app.models.Folders.Delete(context.Background(), folder.ID, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{listPath, itemPath} {
+		if status := sendWithToken(t, ts, token.Plaintext, "GET", path, ""); status != http.StatusNotFound {
+			t.Errorf("%s: want %d status code in a trashed folder; got %d", path, http.StatusNotFound, status)
+		}
+	}
+	tombstones, err := app.models.Tombstones.GetAllSince(context.Background(), user.ID, time.Time{})
+	if err != nil || len(tombstones) != 2 {
+		t.Errorf("want tombstones of the folder and its list; got %v %v", tombstones, err)
+	}
+
+	if status := sendWithToken(t, ts, token.Plaintext, "POST", "/api/v1/trash/folders/"+strconv.Itoa(int(folder.ID))+"/restore", ""); status != http.StatusOK {
+		t.Fatalf("want %d status code; got %d", http.StatusOK, status)
+	}
+	for _, path := range []string{listPath, itemPath} {
+		if status := sendWithToken(t, ts, token.Plaintext, "GET", path, ""); status != http.StatusOK {
+			t.Errorf("%s: want %d status code after the restore; got %d", path, http.StatusOK, status)
+		}
+	}
+
+	// the purge removes the contents of the folder together with the files
+	err = app.models.Folders.Delete(context.Background(), folder.ID, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	purged, files, err := app.models.Items.Purge(context.Background(), -time.Hour)
+	if err != nil || purged != 1 || len(files) != 1 || files[0] != item.File {
+		t.Fatalf("want the item and its file to be purged; got %d %v %v", purged, files, err)
+	}
+	purged, err = app.models.Lists.Purge(context.Background(), -time.Hour)
+	if err != nil || purged != 1 {
+		t.Errorf("want the list to be purged; got %d %v", purged, err)
+	}
+	purged, err = app.models.Folders.Purge(context.Background(), -time.Hour)
+	if err != nil || purged != 1 {
+		t.Errorf("want the folder to be purged; got %d %v", purged, err)
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"easylist/internal/data"
+	"easylist/internal/totp"
+	"easylist/internal/validator"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// twoFactorChallengeTtl is how long the user has to type the code after the right password.
+const twoFactorChallengeTtl = 5 * time.Minute
+
+const defaultTotpIssuer = "EasyList"
+
+// totpIssuer is the name of the account shown by the authenticator apps.
+func (app *application) totpIssuer() string {
+	if app.config.AppName == "" {
+		return defaultTotpIssuer
+	}
+	return app.config.AppName
+}
+
+// verifyTwoFactorCode checks a code of the authenticator app or a recovery code of the user with the two-factor
+// authentication enabled. An accepted code is spent, so it can not be used again.
+func (app *application) verifyTwoFactorCode(r *http.Request, userId int64, code string) (bool, error) {
+	twoFactor, err := app.models.TwoFactor.Get(r.Context(), userId)
+	if err != nil {
+		return false, err
+	}
+	if !twoFactor.Enabled {
+		return false, nil
+	}
+	code = strings.TrimSpace(code)
+	if len(code) == totp.Digits {
+		counter, ok := totp.Verify(twoFactor.Secret, code, app.now())
+		if !ok {
+			return false, nil
+		}
+		return app.models.TwoFactor.UseCounter(r.Context(), userId, counter)
+	}
+	return app.models.TwoFactor.UseRecoveryCode(r.Context(), userId, code)
+}
+
+func (app *application) readTwoFactorCode(w http.ResponseWriter, r *http.Request, source string) (string, bool) {
+	var input = Input[TwoFactorAttributes]{Data: InputAttributes[TwoFactorAttributes]{
+		Attributes: TwoFactorAttributes{},
+	}}
+	var err = readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, source, err)
+		return "", false
+	}
+	var v = validator.New()
+	v.Check(input.Data.Type == data.TwoFactorType, "data.type", "Wrong type provided, accepted type is totp")
+	v.Check(input.Data.Attributes.Code != "", "code", "must be provided")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return "", false
+	}
+	return input.Data.Attributes.Code, true
+}
+
+// createTwoFactorHandler starts the enrolment, the response has the new secret and its otpauth:// uri for the QR
+// code. The two-factor authentication stays disabled until a code of the secret is confirmed by enableTwoFactorHandler.
+func (app *application) createTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	var userModel = app.contextGetUser(r)
+
+	twoFactor, err := app.models.TwoFactor.Get(r.Context(), userModel.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if twoFactor.Enabled {
+		app.failedValidationResponse(w, r, map[string]string{"totp": "is already enabled"})
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.models.TwoFactor.SetSecret(r.Context(), userModel.ID, secret)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var enrolment = &data.TwoFactorEnrolment{
+		ID:     userModel.ID,
+		Secret: secret,
+		Uri:    totp.URI(app.totpIssuer(), userModel.Email, secret),
+	}
+	err = app.writeJSON(w, http.StatusCreated, enrolment, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// enableTwoFactorHandler confirms the enrolment with a code of the authenticator app, e.g.
+// {"data": {"type": "totp", "attributes": {"code": "123456"}}}. The response has the recovery codes, they are only
+// shown once.
+func (app *application) enableTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	code, ok := app.readTwoFactorCode(w, r, "enableTwoFactorHandler")
+	if !ok {
+		return
+	}
+	var userModel = app.contextGetUser(r)
+	if app.throttleLogin(w, r, userModel.Email) {
+		return
+	}
+
+	twoFactor, err := app.models.TwoFactor.Get(r.Context(), userModel.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	var v = validator.New()
+	v.Check(!twoFactor.Enabled, "totp", "is already enabled")
+	v.Check(twoFactor.Enabled || twoFactor.Secret != "", "totp", "must be enrolled first")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	counter, ok := totp.Verify(twoFactor.Secret, strings.TrimSpace(code), app.now())
+	if !ok {
+		app.failLogin(r, userModel.Email, userModel)
+		app.failedValidationResponse(w, r, map[string]string{"code": "is not valid"})
+		return
+	}
+
+	codes, err := data.GenerateRecoveryCodes()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.models.TwoFactor.Enable(r.Context(), userModel.ID, counter, codes)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r, "totp")
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, &data.RecoveryCodes{ID: userModel.ID, Codes: codes}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteTwoFactorHandler turns the two-factor authentication off, it needs a code of the authenticator app or a
+// recovery code, so a stolen session is not enough. Wrong codes count as failed sign ins of the user, so the codes
+// can not be guessed.
+func (app *application) deleteTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	code, ok := app.readTwoFactorCode(w, r, "deleteTwoFactorHandler")
+	if !ok {
+		return
+	}
+	var userModel = app.contextGetUser(r)
+	if app.throttleLogin(w, r, userModel.Email) {
+		return
+	}
+
+	ok, err := app.verifyTwoFactorCode(r, userModel.ID, code)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !ok {
+		app.failLogin(r, userModel.Email, userModel)
+		app.failedValidationResponse(w, r, map[string]string{"code": "is not valid"})
+		return
+	}
+	err = app.models.TwoFactor.Disable(r.Context(), userModel.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bytes"
+	"easylist/internal/totp"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type twoFactorResponse struct {
+	Data struct {
+		Attributes struct {
+			Secret         string   `json:"secret"`
+			Uri            string   `json:"uri"`
+			Codes          []string `json:"codes"`
+			ChallengeToken string   `json:"challenge_token"`
+			Token          string   `json:"token"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+// sendTwoFactor sends the attributes of the type to the path and returns the status code with the decoded response.
+func sendTwoFactor(t *testing.T, ts *testServer, token string, method string, path string, typeData string, attributes string) (int, twoFactorResponse) {
+	var body = []byte(`{"data": {"type": "` + typeData + `", "attributes": ` + attributes + `}}`)
+	req := generateRequestWithToken(ts.URL+path, token, method, bytes.NewBuffer(body))
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var check twoFactorResponse
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 && resp.StatusCode != http.StatusNoContent {
+		err = json.NewDecoder(resp.Body).Decode(&check)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp.StatusCode, check
+}
+
+func TestTwoFactorAuthentication(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	var now = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	app.clock = func() time.Time { return now }
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	_, token := createItem(app, t)
+	var password = `{"email": "test@mail.ru", "password": "password123"}`
+
+	status, enrolment := sendTwoFactor(t, ts, token.Plaintext, "POST", "/api/v1/totp", "totp", `{}`)
+	if status != http.StatusCreated || enrolment.Data.Attributes.Secret == "" || enrolment.Data.Attributes.Uri == "" {
+		t.Fatalf("want the secret and its uri; got %d %+v", status, enrolment.Data.Attributes)
+	}
+	var code = func() string {
+		t.Helper()
+		code, err := totp.Code(enrolment.Data.Attributes.Secret, totp.Counter(now))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return code
+	}
+	if status, _ := sendTwoFactor(t, ts, token.Plaintext, "PUT", "/api/v1/totp/enabled", "totp", `{"code": "000000"}`); status != http.StatusUnprocessableEntity {
+		t.Errorf("want a wrong code to be refused; got %d", status)
+	}
+	status, recovery := sendTwoFactor(t, ts, token.Plaintext, "PUT", "/api/v1/totp/enabled", "totp", `{"code": "`+code()+`"}`)
+	if status != http.StatusOK || len(recovery.Data.Attributes.Codes) != 10 {
+		t.Fatalf("want the recovery codes; got %d %+v", status, recovery.Data.Attributes)
+	}
+	var codes = recovery.Data.Attributes.Codes
+	if status, _ := sendTwoFactor(t, ts, token.Plaintext, "POST", "/api/v1/totp", "totp", `{}`); status != http.StatusUnprocessableEntity {
+		t.Errorf("want a second enrolment to be refused; got %d", status)
+	}
+
+	var challenge = func() string {
+		t.Helper()
+		status, response := sendTwoFactor(t, ts, "", "POST", "/api/v1/tokens/authentication", "tokens", password)
+		if status != http.StatusAccepted || response.Data.Attributes.ChallengeToken == "" || response.Data.Attributes.Token != "" {
+			t.Fatalf("want a challenge instead of the session; got %d %+v", status, response.Data.Attributes)
+		}
+		return response.Data.Attributes.ChallengeToken
+	}
+	var answer = func(challengeToken string, code string) int {
+		t.Helper()
+		status, response := sendTwoFactor(t, ts, "", "POST", "/api/v1/tokens/authentication", "tokens", `{"challenge_token": "`+challengeToken+`", "code": "`+code+`"}`)
+		if status == http.StatusCreated && response.Data.Attributes.Token == "" {
+			t.Fatalf("want the authentication token")
+		}
+		return status
+	}
+
+	var challengeToken = challenge()
+	if status := answer(challengeToken, code()); status != http.StatusUnauthorized {
+		t.Errorf("want the code of the enrolment not to be replayed; got %d", status)
+	}
+	now = now.Add(totp.Period)
+	var current = code()
+	if status := answer(challengeToken, current); status != http.StatusCreated {
+		t.Errorf("want %d status code; got %d", http.StatusCreated, status)
+	}
+	if status := answer(challengeToken, current); status != http.StatusUnauthorized {
+		t.Errorf("want the challenge token to be spent; got %d", status)
+	}
+	if status := answer(challenge(), current); status != http.StatusUnauthorized {
+		t.Errorf("want the code to be spent; got %d", status)
+	}
+
+	if status := answer(challenge(), codes[0]); status != http.StatusCreated {
+		t.Errorf("want the recovery code to be accepted; got %d", status)
+	}
+	if status := answer(challenge(), codes[0]); status != http.StatusUnauthorized {
+		t.Errorf("want the recovery code to be spent; got %d", status)
+	}
+
+	if status, _ := sendTwoFactor(t, ts, token.Plaintext, "DELETE", "/api/v1/totp", "totp", `{"code": "`+codes[0]+`"}`); status != http.StatusUnprocessableEntity {
+		t.Errorf("want a spent code not to disable the two-factor authentication; got %d", status)
+	}
+	if status, _ := sendTwoFactor(t, ts, token.Plaintext, "DELETE", "/api/v1/totp", "totp", `{"code": "`+codes[1]+`"}`); status != http.StatusNoContent {
+		t.Errorf("want %d status code; got %d", http.StatusNoContent, status)
+	}
+	if status, _ := postTokens(t, ts, "authentication", password); status != http.StatusCreated {
+		t.Errorf("want the password to be enough again; got %d", status)
+	}
+}
+
+func TestTwoFactorCodesAreThrottled(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	var now = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	app.clock = func() time.Time { return now }
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	_, token := createItem(app, t)
+	_, enrolment := sendTwoFactor(t, ts, token.Plaintext, "POST", "/api/v1/totp", "totp", `{}`)
+	code, err := totp.Code(enrolment.Data.Attributes.Secret, totp.Counter(now))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the wrong codes count as failed sign ins of the user, after the free attempts even the right code has to wait
+	for _, method := range []string{"PUT", "DELETE"} {
+		var path = "/api/v1/totp"
+		if method == "PUT" {
+			path += "/enabled"
+		}
+		for i := 1; i <= 4; i++ {
+			if status, _ := sendTwoFactor(t, ts, token.Plaintext, method, path, "totp", `{"code": "000000"}`); status != http.StatusUnprocessableEntity {
+				t.Fatalf("%s attempt %d: want %d status code; got %d", method, i, http.StatusUnprocessableEntity, status)
+			}
+		}
+		if status, _ := sendTwoFactor(t, ts, token.Plaintext, method, path, "totp", `{"code": "`+code+`"}`); status != http.StatusTooManyRequests {
+			t.Fatalf("%s: want %d status code; got %d", method, http.StatusTooManyRequests, status)
+		}
+		now = now.Add(time.Minute)
+		code, err = totp.Code(enrolment.Data.Attributes.Secret, totp.Counter(now))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var expected = http.StatusOK
+		if method == "DELETE" {
+			expected = http.StatusNoContent
+		}
+		if status, _ := sendTwoFactor(t, ts, token.Plaintext, method, path, "totp", `{"code": "`+code+`"}`); status != expected {
+			t.Fatalf("%s: want %d status code after the wait; got %d", method, expected, status)
+		}
+		// the next round starts without the failures
+		app.loginAttempts.byEmail.Succeed("test@mail.ru")
+	}
+}
+
+func TestTwoFactorChallengeGuessing(t *testing.T) {
+	app, teardown := newTestAppWithDb(t)
+	defer teardown()
+
+	var now = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	app.clock = func() time.Time { return now }
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	_, token := createItem(app, t)
+	_, enrolment := sendTwoFactor(t, ts, token.Plaintext, "POST", "/api/v1/totp", "totp", `{}`)
+	code, err := totp.Code(enrolment.Data.Attributes.Secret, totp.Counter(now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := sendTwoFactor(t, ts, token.Plaintext, "PUT", "/api/v1/totp/enabled", "totp", `{"code": "`+code+`"}`); status != http.StatusOK {
+		t.Fatalf("want %d status code; got %d", http.StatusOK, status)
+	}
+
+	var challenge = func() string {
+		t.Helper()
+		status, response := sendTwoFactor(t, ts, "", "POST", "/api/v1/tokens/authentication", "tokens", `{"email": "test@mail.ru", "password": "password123"}`)
+		if status != http.StatusAccepted {
+			t.Fatalf("want %d status code; got %d", http.StatusAccepted, status)
+		}
+		return response.Data.Attributes.ChallengeToken
+	}
+	var answer = func(challengeToken string, code string) int {
+		t.Helper()
+		status, _ := sendTwoFactor(t, ts, "", "POST", "/api/v1/tokens/authentication", "tokens", `{"challenge_token": "`+challengeToken+`", "code": "`+code+`"}`)
+		return status
+	}
+
+	// the back-off of the wrong codes is waited out, the challenge token is deleted after maxTwoFactorFailures of them
+	var challengeToken = challenge()
+	for i := 1; i <= maxTwoFactorFailures; i++ {
+		if status := answer(challengeToken, "000000"); status != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: want %d status code; got %d", i, http.StatusUnauthorized, status)
+		}
+		now = now.Add(time.Minute)
+	}
+	code, err = totp.Code(enrolment.Data.Attributes.Secret, totp.Counter(now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := answer(challengeToken, code); status != http.StatusUnauthorized {
+		t.Errorf("want the challenge token to be deleted; got %d", status)
+	}
+
+	// the right password does not forget the wrong codes, only the session does
+	now = now.Add(time.Minute)
+	challengeToken = challenge()
+	if status := answer(challengeToken, "000000"); status != http.StatusUnauthorized {
+		t.Fatalf("want %d status code; got %d", http.StatusUnauthorized, status)
+	}
+	if wait := app.loginAttempts.byEmail.Check("test@mail.ru"); wait == 0 {
+		t.Errorf("want the email to wait after the password")
+	}
+	now = now.Add(time.Minute)
+	code, err = totp.Code(enrolment.Data.Attributes.Secret, totp.Counter(now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := answer(challengeToken, code); status != http.StatusCreated {
+		t.Fatalf("want %d status code; got %d", http.StatusCreated, status)
+	}
+	if wait := app.loginAttempts.byEmail.Check("test@mail.ru"); wait != 0 {
+		t.Errorf("want the session to forget the failures; got %v", wait)
+	}
+}
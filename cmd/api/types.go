@@ -1,5 +1,10 @@
 package main
 
+import (
+	"encoding/json"
+	"time"
+)
+
 type InputAttributes[T ComplexInputModels] struct {
 	Id         string `json:"id,omitempty"`
 	Type       string `json:"type"`
@@ -10,26 +15,29 @@ type Input[T ComplexInputModels] struct {
 }
 
 type ComplexInputModels interface {
-	TokensAttributes | ItemAttributes | UserAttributes | ActivationAttributes | ResetPasswordAttributes
+	TokensAttributes | ItemAttributes | UserAttributes | ActivationAttributes | ResetPasswordAttributes | TemplateAttributes | CategoryAttributes | PersonalAccessTokenAttributes | TwoFactorAttributes
 }
 
 type ItemAttributes struct {
-	ListId       *int64   `json:"list_id"`
-	Name         *string  `json:"name"`
-	Description  *string  `json:"description"`
-	Quantity     *int32   `json:"quantity"`
-	QuantityType *string  `json:"quantity_type"`
-	Price        *float32 `json:"price"`
-	IsStarred    *bool    `json:"is_starred"`
-	IsDone       *bool    `json:"is_done"`
-	File         *string  `json:"file"`
-	Order        *int32   `json:"order"`
+	ListId       *int64  `json:"list_id"`
+	Name         *string `json:"name"`
+	Description  *string `json:"description"`
+	Quantity     *int32  `json:"quantity"`
+	QuantityType *string `json:"quantity_type"`
+	Price        *int64  `json:"price"`
+	Currency     *string `json:"currency"`
+	IsStarred    *bool   `json:"is_starred"`
+	IsDone       *bool   `json:"is_done"`
+	File         *string `json:"file"`
+	Order        *int32  `json:"order"`
+	CategoryId   *int64  `json:"category_id"`
 }
 
 type UserAttributes struct {
 	Name     string `json:"name"`
 	Email    string `json:"email"`
 	Password string `json:"Password"`
+	Currency string `json:"currency"`
 }
 
 type ActivationAttributes struct {
@@ -42,6 +50,97 @@ type ResetPasswordAttributes struct {
 }
 
 type TokensAttributes struct {
-	Email    string `json:"email"`
-	Password string `json:"Password"`
+	Email          string `json:"email"`
+	Password       string `json:"Password"`
+	DeviceName     string `json:"device_name"`
+	RefreshToken   string `json:"refresh_token"`
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+	Provider       string `json:"provider"`
+	State          string `json:"state"`
+}
+
+type TwoFactorAttributes struct {
+	Code string `json:"code"`
+}
+
+type PersonalAccessTokenAttributes struct {
+	Name        string     `json:"name"`
+	Permissions []string   `json:"permissions"`
+	ListId      *int64     `json:"list_id"`
+	Expiry      *time.Time `json:"expiry"`
+}
+
+type ListAttributes struct {
+	FolderId       *int64  `json:"folder_id"`
+	Name           *string `json:"name"`
+	Icon           *string `json:"icon"`
+	Order          *int32  `json:"order"`
+	Budget         *int64  `json:"budget"`
+	BudgetCurrency *string `json:"budget_currency"`
+}
+
+type FolderAttributes struct {
+	Name  *string `json:"name"`
+	Icon  *string `json:"icon"`
+	Order *int32  `json:"order"`
+}
+
+type TemplateAttributes struct {
+	ListId   *int64  `json:"list_id"`
+	FolderId *int64  `json:"folder_id"`
+	Name     *string `json:"name"`
+	Icon     *string `json:"icon"`
+	Schedule *string `json:"schedule"`
+}
+
+type CategoryAttributes struct {
+	Name *string `json:"name"`
+	Icon *string `json:"icon"`
+}
+
+type SyncInput struct {
+	Operations []SyncOperation `json:"operations"`
+}
+
+type SyncOperation struct {
+	Op         string          `json:"op"`
+	Type       string          `json:"type"`
+	Id         string          `json:"id,omitempty"`
+	Version    int32           `json:"version"`
+	Attributes json.RawMessage `json:"attributes"`
+}
+
+// BulkInput follows the JSON:API Atomic Operations extension, every operation changes a single item.
+type BulkInput struct {
+	Operations []BulkOperation `json:"atomic:operations"`
+}
+
+type BulkOperation struct {
+	Op   string         `json:"op"`
+	Ref  *BulkReference `json:"ref"`
+	Data *BulkData      `json:"data"`
+}
+
+type BulkReference struct {
+	Type string `json:"type"`
+	Id   string `json:"id"`
+}
+
+type BulkData struct {
+	Type       string         `json:"type"`
+	Id         string         `json:"id,omitempty"`
+	Attributes ItemAttributes `json:"attributes"`
+}
+
+// ReorderInput either holds the complete new order of the records as their ids or moves one record next to another.
+type ReorderInput struct {
+	Order []int64    `json:"order"`
+	Move  *MoveInput `json:"move"`
+}
+
+type MoveInput struct {
+	ID     int64 `json:"id"`
+	Before int64 `json:"before"`
+	After  int64 `json:"after"`
 }
@@ -11,6 +11,9 @@ import (
 
 const USERS_TYPE_NAME = "users"
 
+// defaultPermissions are granted to every new user.
+var defaultPermissions = []string{"folders:read", "folders:write", "lists:write", "lists:read", "items:read", "items:write"}
+
 func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Request) {
 	if !app.config.Registration {
 		app.methodNotAllowedResponse(w, r)
@@ -34,8 +37,12 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 		Name:      input.Data.Attributes.Name,
 		Email:     input.Data.Attributes.Email,
 		IsActive:  true,
+		Currency:  input.Data.Attributes.Currency,
 		Version:   1,
 	}
+	if user.Currency == "" {
+		user.Currency = app.defaultCurrency()
+	}
 	if app.config.Confirmation {
 		user.IsActive = false
 	}
@@ -54,7 +61,7 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	err = app.models.Users.Insert(user)
+	err = app.models.Users.Insert(r.Context(), user)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrDuplicateEmail):
@@ -65,14 +72,14 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 		}
 		return
 	}
-	err = app.models.Permissions.AddForUser(user.ID, "folders:read", "folders:write", "lists:write", "lists:read", "items:read", "items:write")
+	err = app.models.Permissions.AddForUser(r.Context(), user.ID, defaultPermissions...)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
 	if app.config.Confirmation {
-		token, err := app.models.Tokens.New(user.ID, 3*24*time.Hour, data.ScopeActivation)
+		token, err := app.models.Tokens.New(r.Context(), user.ID, 3*24*time.Hour, data.ScopeActivation)
 		if err != nil {
 			app.serverErrorResponse(w, r, err)
 			return
@@ -114,7 +121,7 @@ func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Reque
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
-	user, err := app.models.Users.GetForToken(data.ScopeActivation, input.Data.Attributes.Token)
+	user, err := app.models.Users.GetForToken(r.Context(), data.ScopeActivation, input.Data.Attributes.Token)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -126,7 +133,7 @@ func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 	user.IsActive = true
-	err = app.models.Users.Update(user)
+	err = app.models.Users.Update(r.Context(), user)
 
 	if err != nil {
 		switch {
@@ -138,7 +145,7 @@ func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	err = app.models.Tokens.DeleteAllForUser(data.ScopeActivation, user.ID)
+	err = app.models.Tokens.DeleteAllForUser(r.Context(), data.ScopeActivation, user.ID)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -187,6 +194,9 @@ func (app *application) updateUserHandler(w http.ResponseWriter, r *http.Request
 			return
 		}
 	}
+	if input.Data.Attributes.Currency != "" {
+		userModel.Currency = input.Data.Attributes.Currency
+	}
 
 	var v = validator.New()
 
@@ -196,7 +206,7 @@ func (app *application) updateUserHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	err = app.models.Users.Update(userModel)
+	err = app.models.Users.Update(r.Context(), userModel)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrEditConflict):
@@ -246,20 +256,53 @@ func (app *application) deleteUserHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	err = app.models.Items.DeleteByUser(id)
-	if err != nil {
-		app.serverErrorResponse(w, r, err)
-	}
-	err = app.models.Lists.DeleteByUser(id)
-	if err != nil {
-		app.serverErrorResponse(w, r, err)
-	}
-	err = app.models.Folders.DeleteByUser(id)
-	if err != nil {
-		app.serverErrorResponse(w, r, err)
-	}
-
-	err = app.models.Users.Delete(id)
+	err = app.models.WithTx(r.Context(), func(tx data.Models) error {
+		err := tx.Items.DeleteByUser(r.Context(), id)
+		if err != nil {
+			return err
+		}
+		err = tx.Members.DeleteByUser(r.Context(), id)
+		if err != nil {
+			return err
+		}
+		err = tx.Lists.DeleteByUser(r.Context(), id)
+		if err != nil {
+			return err
+		}
+		err = tx.Folders.DeleteByUser(r.Context(), id)
+		if err != nil {
+			return err
+		}
+		err = tx.Templates.DeleteByUser(r.Context(), id)
+		if err != nil {
+			return err
+		}
+		err = tx.Categories.DeleteByUser(r.Context(), id)
+		if err != nil {
+			return err
+		}
+		err = tx.Suggestions.DeleteByUser(r.Context(), id)
+		if err != nil {
+			return err
+		}
+		err = tx.Sessions.DeleteAllForUser(r.Context(), id, 0)
+		if err != nil {
+			return err
+		}
+		err = tx.Tokens.DeleteByUser(r.Context(), id)
+		if err != nil {
+			return err
+		}
+		err = tx.TwoFactor.Disable(r.Context(), id)
+		if err != nil {
+			return err
+		}
+		err = tx.Identities.DeleteByUser(r.Context(), id)
+		if err != nil {
+			return err
+		}
+		return tx.Users.Delete(r.Context(), id)
+	})
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -295,7 +338,7 @@ func (app *application) resetUserPasswordHandler(w http.ResponseWriter, r *http.
 		return
 	}
 
-	user, err := app.models.Users.GetForToken(data.ScopePasswordReset, input.Data.Attributes.Token)
+	user, err := app.models.Users.GetForToken(r.Context(), data.ScopePasswordReset, input.Data.Attributes.Token)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -313,7 +356,7 @@ func (app *application) resetUserPasswordHandler(w http.ResponseWriter, r *http.
 		return
 	}
 
-	err = app.models.Users.Update(user)
+	err = app.models.Users.Update(r.Context(), user)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrEditConflict):
@@ -324,7 +367,19 @@ func (app *application) resetUserPasswordHandler(w http.ResponseWriter, r *http.
 		return
 	}
 
-	err = app.models.Tokens.DeleteAllForUser(data.ScopePasswordReset, user.ID)
+	err = app.models.Tokens.DeleteAllForUser(r.Context(), data.ScopePasswordReset, user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// whoever knew the old password is signed out of every device
+	err = app.models.Sessions.DeleteAllForUser(r.Context(), user.ID, 0)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.models.Tokens.DeleteAllForUser(r.Context(), data.ScopeAuthentication, user.ID)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"easylist/internal/data"
 	"encoding/json"
 	"errors"
@@ -237,7 +238,7 @@ func TestUserUpdate(t *testing.T) {
 		t.Errorf("want Email to be %s, got %s", "emelyanov86@km.ru", check.Email)
 	}
 
-	newUser, err := app.models.Users.GetByEmail("emelyanov86@km.ru")
+	newUser, err := app.models.Users.GetByEmail(context.Background(), "emelyanov86@km.ru")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -321,13 +322,13 @@ func TestDeleteUserWithAllContent(t *testing.T) {
 	if resp.StatusCode != http.StatusNoContent {
 		t.Errorf("want %d status code; got %d", http.StatusNoContent, resp.StatusCode)
 	}
-	oldUser, _ := app.models.Users.GetByEmail(user.Email)
+	oldUser, _ := app.models.Users.GetByEmail(context.Background(), user.Email)
 
 	if oldUser != nil {
 		t.Errorf("Expected no user, got user with id %d", oldUser.ID)
 	}
 
-	oldFolder, err := app.models.Folders.Get(folder.ID, user.ID)
+	oldFolder, err := app.models.Folders.Get(context.Background(), folder.ID, user.ID)
 	if oldFolder != nil {
 		t.Errorf("Expected no folder, got folder with id %d", oldFolder.ID)
 	}
@@ -341,12 +342,13 @@ func TestUpdatePasswordWithTokenHandler(t *testing.T) {
 	defer teardown()
 
 	ts := newTestServer(t, app.routes())
-	user, _, err := createTestUserWithToken(t, app, "")
+	user, authToken, err := createTestUserWithToken(t, app, "")
 	if err != nil {
 		t.Fatal(err)
 	}
 	defer ts.Close()
-	token, err := app.models.Tokens.New(user.ID, 45*time.Minute, data.ScopePasswordReset)
+	var session = signIn(t, ts, "Phone")
+	token, err := app.models.Tokens.New(context.Background(), user.ID, 45*time.Minute, data.ScopePasswordReset)
 	if err != nil {
 		t.Fatal(err)
 		return
@@ -387,4 +389,10 @@ func TestUpdatePasswordWithTokenHandler(t *testing.T) {
 	if check.ID != user.ID {
 		t.Errorf("want ID to be %d, got %d", user.ID, check.ID)
 	}
+
+	for _, plaintext := range []string{authToken.Plaintext, session.Data.Attributes.Token} {
+		if status := sendWithToken(t, ts, plaintext, "GET", "/api/v1/items", ""); status != http.StatusUnauthorized {
+			t.Errorf("want the tokens issued before the reset to be revoked; got %d", status)
+		}
+	}
 }
@@ -14,7 +14,10 @@ require (
 	github.com/google/uuid v1.3.0
 	github.com/jameskeane/bcrypt v0.0.0-20120420032655-c3cd44c1e20f
 	github.com/liamylian/jsontime v1.0.1
+	github.com/lib/pq v1.10.9
+	github.com/mattn/go-sqlite3 v1.14.17
 	github.com/octoper/go-ray v0.1.5
+	golang.org/x/image v0.18.0
 	golang.org/x/time v0.3.0
 	gopkg.in/yaml.v3 v3.0.1
 )
@@ -0,0 +1,283 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"easylist/internal/validator"
+	"errors"
+	"fmt"
+	"github.com/google/jsonapi"
+	"strings"
+	"time"
+)
+
+const CategoriesType = "categories"
+
+// GroupByCategory is the value of the group query parameter which sorts the items of a list by the order of their
+// categories, items without a category come last.
+const GroupByCategory = "category"
+
+// Category is a user-defined group of items, e.g. an aisle of the store, the items of a list can be walked in the
+// order of the categories.
+type Category struct {
+	ID        int64     `jsonapi:"primary,categories"`
+	UserId    int64     `json:"-"`
+	Name      string    `jsonapi:"attr,name"`
+	Icon      string    `jsonapi:"attr,icon"`
+	Order     int32     `jsonapi:"attr,order"`
+	Version   int32     `json:"-"`
+	CreatedAt time.Time `jsonapi:"attr,created_at,iso8601"`
+	UpdatedAt time.Time `jsonapi:"attr,updated_at,iso8601"`
+}
+
+type Categories []*Category
+
+// ItemGroup is a run of items of the same category, Category is nil for the items without one.
+type ItemGroup struct {
+	Category *Category
+	Items    Items
+}
+
+type ItemGroups []ItemGroup
+
+type CategoryModel struct {
+	DB *DB
+}
+
+// Insert saves the category after the last category of the user.
+func (c CategoryModel) Insert(ctx context.Context, category *Category) error {
+	var query = "INSERT INTO categories (user_id, name, icon, version, `order`, created_at, updated_at) VALUES (?, ?, ?, 1, ?, NOW(), NOW())"
+
+	ctx, cancel := c.DB.withTimeout(ctx)
+	defer cancel()
+
+	return c.DB.WithTx(ctx, func(tx *DB) error {
+		var lastOrder int32
+		err := tx.QueryRowContext(ctx, "SELECT COALESCE(MAX(`order`), 0) FROM categories WHERE user_id = ?", category.UserId).Scan(&lastOrder)
+		if err != nil {
+			return err
+		}
+
+		id, err := insert(ctx, tx, query, category.UserId, category.Name, category.Icon, lastOrder+1)
+		if err != nil {
+			return err
+		}
+		category.ID = id
+		category.Order = lastOrder + 1
+		category.Version = 1
+		return nil
+	})
+}
+
+func (c CategoryModel) Get(ctx context.Context, id int64, userId int64) (*Category, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	var query = "SELECT id, user_id, name, icon, `order`, version, created_at, updated_at FROM categories WHERE id = ? AND user_id = ?"
+
+	var category Category
+
+	ctx, cancel := c.DB.withTimeout(ctx)
+	defer cancel()
+
+	var err = c.DB.QueryRowContext(ctx, query, id, userId).Scan(&category.ID, &category.UserId, &category.Name, &category.Icon, &category.Order, &category.Version, &category.CreatedAt, &category.UpdatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &category, nil
+}
+
+// GetAll returns the categories of the user in their order.
+func (c CategoryModel) GetAll(ctx context.Context, userId int64) (Categories, error) {
+	var query = "SELECT id, user_id, name, icon, `order`, version, created_at, updated_at FROM categories WHERE user_id = ? ORDER BY `order` ASC, id ASC"
+
+	ctx, cancel := c.DB.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := c.DB.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories = Categories{}
+	for rows.Next() {
+		var category Category
+		err = rows.Scan(&category.ID, &category.UserId, &category.Name, &category.Icon, &category.Order, &category.Version, &category.CreatedAt, &category.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, &category)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+// Update saves the name and icon of the category, the order is changed by Reorder.
+func (c CategoryModel) Update(ctx context.Context, category *Category) error {
+	var query = "UPDATE categories SET name = ?, icon = ?, version = version + 1, updated_at = NOW() WHERE id = ? AND user_id = ? AND version = ?"
+
+	ctx, cancel := c.DB.withTimeout(ctx)
+	defer cancel()
+
+	result, err := c.DB.ExecContext(ctx, query, category.Name, category.Icon, category.ID, category.UserId, category.Version)
+	if err != nil {
+		return err
+	}
+	err = expectOneRow(result, ErrEditConflict)
+	if err != nil {
+		return err
+	}
+	category.Version++
+	category.UpdatedAt = time.Now()
+	return nil
+}
+
+// Delete removes the category, its items stay in their lists without a category.
+func (c CategoryModel) Delete(ctx context.Context, id int64, userId int64) error {
+	if id < 1 || userId < 1 {
+		return ErrRecordNotFound
+	}
+
+	ctx, cancel := c.DB.withTimeout(ctx)
+	defer cancel()
+
+	return c.DB.WithTx(ctx, func(tx *DB) error {
+		result, err := tx.ExecContext(ctx, "DELETE FROM categories WHERE id = ? AND user_id = ?", id, userId)
+		if err != nil {
+			return err
+		}
+		err = expectOneRow(result, ErrRecordNotFound)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "UPDATE items SET category_id = NULL, version = version + 1, updated_at = NOW() WHERE category_id = ?", id)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "UPDATE item_history SET category_id = NULL WHERE category_id = ?", id)
+		return err
+	})
+}
+
+func (c CategoryModel) DeleteByUser(ctx context.Context, userId int64) error {
+	if userId < 1 {
+		return ErrRecordNotFound
+	}
+
+	ctx, cancel := c.DB.withTimeout(ctx)
+	defer cancel()
+
+	return c.DB.WithTx(ctx, func(tx *DB) error {
+		// items of shared lists may still use the categories of the user
+		_, err := tx.ExecContext(ctx, "UPDATE items SET category_id = NULL, version = version + 1, updated_at = NOW() WHERE category_id IN (SELECT categories.id FROM categories WHERE categories.user_id = ?)", userId)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "UPDATE item_history SET category_id = NULL WHERE category_id IN (SELECT categories.id FROM categories WHERE categories.user_id = ?)", userId)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "DELETE FROM categories WHERE user_id = ?", userId)
+		return err
+	})
+}
+
+// Reorder changes the order of the categories of the user.
+func (c CategoryModel) Reorder(ctx context.Context, userId int64, reorder Reorder) (Ranks, error) {
+	var scope = rankScope{
+		table:    "categories",
+		where:    "user_id = ?",
+		args:     []any{userId},
+		lock:     "SELECT id FROM users WHERE id = ?",
+		lockArgs: []any{userId},
+	}
+	return scope.reorder(ctx, c.DB, reorder)
+}
+
+// Suggest returns the category the user gave to the most recent item with the same name, zero when there is none.
+func (c CategoryModel) Suggest(ctx context.Context, userId int64, name string) (int64, error) {
+	var query = "SELECT items.category_id FROM items INNER JOIN categories ON categories.id = items.category_id WHERE items.user_id = ? AND categories.user_id = ? AND LOWER(items.name) = LOWER(?) ORDER BY items.updated_at DESC, items.id DESC LIMIT 1"
+
+	ctx, cancel := c.DB.withTimeout(ctx)
+	defer cancel()
+
+	var categoryId int64
+	err := c.DB.QueryRowContext(ctx, query, userId, userId, strings.TrimSpace(name)).Scan(&categoryId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return categoryId, nil
+}
+
+// GroupItems splits items which are sorted by category into runs of the same category.
+func GroupItems(items Items) ItemGroups {
+	var groups ItemGroups
+	for _, item := range items {
+		var last = len(groups) - 1
+		if last >= 0 && groups[last].Items[0].CategoryId == item.CategoryId {
+			groups[last].Items = append(groups[last].Items, item)
+			continue
+		}
+		groups = append(groups, ItemGroup{Category: item.Category, Items: Items{item}})
+	}
+	return groups
+}
+
+func ValidateCategory(v *validator.Validator, category *Category) {
+	v.Check(strings.TrimSpace(category.Name) != "", "data.attributes.name", "must be provided")
+	v.Check(len(category.Name) <= 190, "data.attributes.name", "must be no more than 190 characters")
+	v.Check(category.Icon == "" || strings.HasPrefix(category.Icon, "mdi-"), "data.attributes.icon", "icon must starts with mdi- prefix")
+}
+
+func (category Category) JSONAPILinks() *jsonapi.Links {
+	return &jsonapi.Links{
+		"self": fmt.Sprintf("%s/api/v1/categories/%d", DomainName, category.ID),
+	}
+}
+
+type MockCategoryModel struct {
+}
+
+func (m MockCategoryModel) Insert(ctx context.Context, category *Category) error {
+	return nil
+}
+
+func (m MockCategoryModel) Get(ctx context.Context, id int64, userId int64) (*Category, error) {
+	return nil, nil
+}
+
+func (m MockCategoryModel) GetAll(ctx context.Context, userId int64) (Categories, error) {
+	return Categories{}, nil
+}
+
+func (m MockCategoryModel) Update(ctx context.Context, category *Category) error {
+	return nil
+}
+
+func (m MockCategoryModel) Delete(ctx context.Context, id int64, userId int64) error {
+	return nil
+}
+
+func (m MockCategoryModel) DeleteByUser(ctx context.Context, userId int64) error {
+	return nil
+}
+
+func (m MockCategoryModel) Reorder(ctx context.Context, userId int64, reorder Reorder) (Ranks, error) {
+	return Ranks{}, nil
+}
+
+func (m MockCategoryModel) Suggest(ctx context.Context, userId int64, name string) (int64, error) {
+	return 0, nil
+}
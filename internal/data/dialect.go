@@ -0,0 +1,404 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+// Dialect is the SQL flavour of the database behind the models. The queries are written for MySQL,
+// DB rewrites them for the other databases and the dialect builds the parts which differ too much to be rewritten.
+type Dialect string
+
+const (
+	MySQL    Dialect = "mysql"
+	Postgres Dialect = "postgres"
+	SQLite   Dialect = "sqlite"
+)
+
+var ErrUnknownDialect = errors.New("unknown database driver")
+
+// ParseDialect turns the driver from the configuration into a dialect, an empty driver means MySQL.
+func ParseDialect(driver string) (Dialect, error) {
+	switch strings.ToLower(driver) {
+	case "", "mysql":
+		return MySQL, nil
+	case "postgres", "postgresql", "pgsql":
+		return Postgres, nil
+	case "sqlite", "sqlite3":
+		return SQLite, nil
+	}
+	return "", fmt.Errorf("%w: %s", ErrUnknownDialect, driver)
+}
+
+// DriverName is the name of the database/sql driver for the dialect.
+func (d Dialect) DriverName() string {
+	switch d {
+	case Postgres:
+		return "postgres"
+	case SQLite:
+		return "sqlite3"
+	}
+	return "mysql"
+}
+
+// DefaultQueryTimeout limits the queries of the models when DB.QueryTimeout is not set.
+const DefaultQueryTimeout = 3 * time.Second
+
+// DB is a connection pool which knows its dialect, it has the same query methods as *sql.DB.
+// The DB passed to the function of WithTx runs its queries in the transaction instead.
+type DB struct {
+	*sql.DB
+	Dialect      Dialect
+	QueryTimeout time.Duration
+	tx           *sql.Tx
+}
+
+func NewDB(db *sql.DB, dialect Dialect) *DB {
+	return &DB{DB: db, Dialect: dialect, QueryTimeout: DefaultQueryTimeout}
+}
+
+func (db *DB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	if db.tx != nil {
+		return db.tx.ExecContext(ctx, db.Dialect.rebind(query), db.Dialect.args(args)...)
+	}
+	return db.DB.ExecContext(ctx, db.Dialect.rebind(query), db.Dialect.args(args)...)
+}
+
+func (db *DB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	if db.tx != nil {
+		return db.tx.QueryContext(ctx, db.Dialect.rebind(query), db.Dialect.args(args)...)
+	}
+	return db.DB.QueryContext(ctx, db.Dialect.rebind(query), db.Dialect.args(args)...)
+}
+
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	if db.tx != nil {
+		return db.tx.QueryRowContext(ctx, db.Dialect.rebind(query), db.Dialect.args(args)...)
+	}
+	return db.DB.QueryRowContext(ctx, db.Dialect.rebind(query), db.Dialect.args(args)...)
+}
+
+func (db *DB) Exec(query string, args ...any) (sql.Result, error) {
+	return db.ExecContext(context.Background(), query, args...)
+}
+
+func (db *DB) Query(query string, args ...any) (*sql.Rows, error) {
+	return db.QueryContext(context.Background(), query, args...)
+}
+
+func (db *DB) QueryRow(query string, args ...any) *sql.Row {
+	return db.QueryRowContext(context.Background(), query, args...)
+}
+
+// WithTx runs fn in a transaction, which is committed when fn returns nil and rolled back otherwise.
+// Inside another transaction fn joins it, so methods using WithTx can be combined into a larger unit of work.
+func (db *DB) WithTx(ctx context.Context, fn func(tx *DB) error) error {
+	if db.tx != nil {
+		return fn(db)
+	}
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = fn(&DB{DB: db.DB, Dialect: db.Dialect, QueryTimeout: db.QueryTimeout, tx: tx})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// withTimeout limits the context of a query to the query timeout, the query is also cancelled together with the
+// context, e.g. when the client of the request goes away.
+func (db *DB) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	var timeout = db.QueryTimeout
+	if timeout <= 0 {
+		timeout = DefaultQueryTimeout
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// insert runs the INSERT statement and returns the id of the new row. PostgreSQL does not support LastInsertId,
+// so there the id is returned by the statement itself.
+func insert(ctx context.Context, db *DB, query string, args ...any) (int64, error) {
+	if db.Dialect == Postgres {
+		var id int64
+		err := db.QueryRowContext(ctx, query+" RETURNING id", args...).Scan(&id)
+		return id, err
+	}
+	result, err := db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// rebind rewrites a query written for MySQL: placeholders become $1, $2... for PostgreSQL, identifiers quoted with
+// backticks are quoted with double quotes and NOW() becomes the current time with milliseconds in SQLite.
+func (d Dialect) rebind(query string) string {
+	if d == MySQL || d == "" {
+		return query
+	}
+	var b strings.Builder
+	b.Grow(len(query) + 16)
+	var inString bool
+	var n int
+	for i := 0; i < len(query); i++ {
+		var c = query[i]
+		switch {
+		case c == '\'':
+			inString = !inString
+			b.WriteByte(c)
+		case inString:
+			b.WriteByte(c)
+		case c == '`':
+			b.WriteByte('"')
+		case c == '?' && d == Postgres:
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+		case d == SQLite && strings.HasPrefix(query[i:], "NOW()"):
+			b.WriteString("strftime('%Y-%m-%d %H:%M:%f', 'now')")
+			i += len("NOW()") - 1
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// args converts the query arguments for the driver. SQLite stores times as text, so they are kept in UTC
+// to be comparable with each other and with the times set by the database.
+func (d Dialect) args(args []any) []any {
+	if d != SQLite {
+		return args
+	}
+	var converted = make([]any, len(args))
+	for i, arg := range args {
+		switch t := arg.(type) {
+		case time.Time:
+			arg = t.UTC()
+		case *time.Time:
+			if t != nil {
+				arg = t.UTC()
+			}
+		}
+		converted[i] = arg
+	}
+	return converted
+}
+
+// fullText returns the condition searching the words of the search string in the column together with its arguments,
+// an empty search string matches everything. Like the natural language mode of MySQL, a row matches when it contains
+// any of the words. PostgreSQL uses its text search, SQLite looks for the words anywhere in the column.
+func (d Dialect) fullText(column string, search string) (string, []any) {
+	switch d {
+	case Postgres:
+		return "(to_tsvector('simple', " + column + ") @@ to_tsquery('simple', ?) OR ? = '')", []any{tsQuery(search), search}
+	case SQLite:
+		var words = searchWords(search)
+		if len(words) == 0 {
+			return "(? = '')", []any{search}
+		}
+		var conditions = make([]string, 0, len(words))
+		var args = make([]any, 0, len(words))
+		for _, word := range words {
+			conditions = append(conditions, column+" LIKE ?")
+			args = append(args, "%"+word+"%")
+		}
+		return "(" + strings.Join(conditions, " OR ") + ")", args
+	}
+	return "(MATCH(" + column + ") AGAINST(? IN NATURAL LANGUAGE MODE) OR ? = '')", []any{search, search}
+}
+
+// searchWords splits the search string into words, the punctuation is dropped.
+func searchWords(search string) []string {
+	return strings.FieldsFunc(search, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// tsQuery joins the words of the search string with the OR operator of to_tsquery.
+func tsQuery(search string) string {
+	return strings.Join(searchWords(search), " | ")
+}
+
+// mySQLStopwords are the default stopwords of InnoDB, like the words shorter than its minimal token size of three
+// characters they are not in the full-text index.
+var mySQLStopwords = map[string]bool{"a": true, "about": true, "an": true, "are": true, "as": true, "at": true, "be": true, "by": true, "com": true, "de": true, "en": true, "for": true, "from": true, "how": true, "i": true, "in": true, "is": true, "it": true, "la": true, "of": true, "on": true, "or": true, "that": true, "the": true, "this": true, "to": true, "was": true, "what": true, "when": true, "where": true, "who": true, "will": true, "with": true, "und": true, "www": true}
+
+// search returns the condition matching the terms of the query in any of the columns together with its arguments,
+// unlike fullText every term must match and the excluded ones must not. MySQL uses the boolean mode of its full-text
+// search, which needs a FULLTEXT index on exactly these columns, and falls back to LIKE for the words it does not
+// index. PostgreSQL uses its text search and SQLite looks for the terms at the beginning of a word with LIKE.
+func (d Dialect) search(columns []string, query SearchQuery) (string, []any) {
+	var conditions []string
+	var args []any
+	switch d {
+	case Postgres:
+		if len(query) > 0 {
+			var document = columns[0]
+			for _, column := range columns[1:] {
+				document += " || ' ' || COALESCE(" + column + ", '')"
+			}
+			conditions = append(conditions, "to_tsvector('simple', "+document+") @@ to_tsquery('simple', ?)")
+			args = append(args, query.tsQuery())
+		}
+	case SQLite:
+		for _, term := range query {
+			var condition, termArgs = d.likeTerm(columns, term)
+			conditions = append(conditions, condition)
+			args = append(args, termArgs...)
+		}
+	default:
+		var boolean []string
+		for _, term := range query {
+			if !term.Exclude && term.indexed() {
+				boolean = append(boolean, term.boolean())
+				continue
+			}
+			var condition, termArgs = d.likeTerm(columns, term)
+			conditions = append(conditions, condition)
+			args = append(args, termArgs...)
+		}
+		if len(boolean) > 0 {
+			conditions = append([]string{"MATCH(" + strings.Join(columns, ", ") + ") AGAINST(? IN BOOLEAN MODE)"}, conditions...)
+			args = append([]any{strings.Join(boolean, " ")}, args...)
+		}
+	}
+	if len(conditions) == 0 {
+		return "(1 = 1)", nil
+	}
+	return "(" + strings.Join(conditions, " AND ") + ")", args
+}
+
+// likeTerm matches the term at the beginning of a word of any of the columns.
+func (d Dialect) likeTerm(columns []string, term SearchTerm) (string, []any) {
+	var conditions = make([]string, 0, len(columns))
+	var args = make([]any, 0, len(columns))
+	for _, column := range columns {
+		conditions = append(conditions, d.concat("' '", "COALESCE("+column+", '')")+" LIKE ? ESCAPE '!'")
+		args = append(args, "% "+escapeLike(strings.Join(term.Words, " "))+"%")
+	}
+	var condition = "(" + strings.Join(conditions, " OR ") + ")"
+	if term.Exclude {
+		condition = "NOT " + condition
+	}
+	return condition, args
+}
+
+// concat joins the strings, in MySQL || is the logical OR.
+func (d Dialect) concat(values ...string) string {
+	if d == MySQL || d == "" {
+		return "CONCAT(" + strings.Join(values, ", ") + ")"
+	}
+	return strings.Join(values, " || ")
+}
+
+// jsonArray aggregates the JSON objects of the grouped rows into a JSON array returned as text.
+func (d Dialect) jsonArray(object string) string {
+	switch d {
+	case Postgres:
+		return "CAST(json_agg(" + object + ") AS TEXT)"
+	case SQLite:
+		return "json_group_array(" + object + ")"
+	}
+	return "CONCAT('[',GROUP_CONCAT(" + object + "),']')"
+}
+
+// jsonObject builds a JSON object from the pairs of keys and values, the keys must be quoted string literals.
+func (d Dialect) jsonObject(pairs ...string) string {
+	var fn = "JSON_OBJECT"
+	switch d {
+	case Postgres:
+		fn = "json_build_object"
+	case SQLite:
+		fn = "json_object"
+	}
+	return fn + "(" + strings.Join(pairs, ", ") + ")"
+}
+
+// jsonBool turns a boolean column into a JSON true or false.
+func (d Dialect) jsonBool(column string) string {
+	switch d {
+	case Postgres:
+		return column
+	case SQLite:
+		return "json(CASE WHEN " + column + " THEN 'true' ELSE 'false' END)"
+	}
+	return "CASE WHEN " + column + " THEN CAST(TRUE AS JSON) ELSE CAST(FALSE AS JSON) END"
+}
+
+// jsonTime formats a time column inside a JSON object the way MySQL does, so that it is parsed as sql_datetime.
+func (d Dialect) jsonTime(column string) string {
+	switch d {
+	case Postgres:
+		return "to_char(" + column + ", 'YYYY-MM-DD HH24:MI:SS')"
+	case SQLite:
+		return "strftime('%Y-%m-%d %H:%M:%S', " + column + ")"
+	}
+	return column
+}
+
+// yearMonth formats a time column as 2006-01.
+func (d Dialect) yearMonth(column string) string {
+	switch d {
+	case Postgres:
+		return "to_char(" + column + ", 'YYYY-MM')"
+	case SQLite:
+		return "strftime('%Y-%m', " + column + ")"
+	}
+	return "DATE_FORMAT(" + column + ", '%Y-%m')"
+}
+
+// forUpdate locks the selected rows until the end of the transaction. SQLite has no row locks, its transactions
+// take the write lock as soon as they begin instead.
+func (d Dialect) forUpdate() string {
+	if d == SQLite {
+		return ""
+	}
+	return " FOR UPDATE"
+}
+
+// upsert turns an INSERT into an update of the row which already has the same unique key. The columns take the values
+// of the inserted row, the assignments are added as they are and may refer to the old row by the name of the table.
+func (d Dialect) upsert(key []string, columns []string, assignments ...string) string {
+	var onDuplicate = d == MySQL || d == ""
+	var sets = make([]string, 0, len(columns)+len(assignments))
+	for _, column := range columns {
+		if onDuplicate {
+			sets = append(sets, "`"+column+"` = VALUES(`"+column+"`)")
+		} else {
+			sets = append(sets, "`"+column+"` = excluded.`"+column+"`")
+		}
+	}
+	sets = append(sets, assignments...)
+	if onDuplicate {
+		return " ON DUPLICATE KEY UPDATE " + strings.Join(sets, ", ")
+	}
+	return " ON CONFLICT (`" + strings.Join(key, "`, `") + "`) DO UPDATE SET " + strings.Join(sets, ", ")
+}
+
+// isDuplicate reports whether the error is a violation of a unique key whose name contains the key.
+func isDuplicate(err error, key string) bool {
+	var mySQLError *mysql.MySQLError
+	if errors.As(err, &mySQLError) {
+		return mySQLError.Number == 1062 && strings.Contains(mySQLError.Message, key)
+	}
+	var pqError *pq.Error
+	if errors.As(err, &pqError) {
+		return pqError.Code == "23505" && strings.Contains(pqError.Constraint, key)
+	}
+	// the sqlite3 driver needs cgo, so its error is recognized by the message
+	return err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed") && strings.Contains(err.Error(), key)
+}
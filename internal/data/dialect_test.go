@@ -0,0 +1,165 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestParseDialect(t *testing.T) {
+	tests := map[string]Dialect{"": MySQL, "mysql": MySQL, "postgres": Postgres, "PostgreSQL": Postgres, "sqlite3": SQLite}
+	for driver, want := range tests {
+		got, err := ParseDialect(driver)
+		if err != nil || got != want {
+			t.Errorf("ParseDialect(%q) = %v, %v; want %v", driver, got, err, want)
+		}
+	}
+	if _, err := ParseDialect("oracle"); !errors.Is(err, ErrUnknownDialect) {
+		t.Errorf("ParseDialect() error = %v; want %v", err, ErrUnknownDialect)
+	}
+}
+
+func TestRebind(t *testing.T) {
+	var query = "UPDATE items SET `order` = ?, name = '?', updated_at = NOW() WHERE id = ? AND version = ?"
+	tests := []struct {
+		dialect Dialect
+		want    string
+	}{
+		{dialect: MySQL, want: query},
+		{dialect: Postgres, want: `UPDATE items SET "order" = $1, name = '?', updated_at = NOW() WHERE id = $2 AND version = $3`},
+		{dialect: SQLite, want: `UPDATE items SET "order" = ?, name = '?', updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now') WHERE id = ? AND version = ?`},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.dialect), func(t *testing.T) {
+			if got := tt.dialect.rebind(query); got != tt.want {
+				t.Errorf("rebind() = %s; want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	tests := []struct {
+		dialect Dialect
+		want    string
+	}{
+		{dialect: MySQL, want: " ON DUPLICATE KEY UPDATE `name` = VALUES(`name`), hits = t.hits + 1"},
+		{dialect: Postgres, want: " ON CONFLICT (`user_id`, `key`) DO UPDATE SET `name` = excluded.`name`, hits = t.hits + 1"},
+		{dialect: SQLite, want: " ON CONFLICT (`user_id`, `key`) DO UPDATE SET `name` = excluded.`name`, hits = t.hits + 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.dialect), func(t *testing.T) {
+			if got := tt.dialect.upsert([]string{"user_id", "key"}, []string{"name"}, "hits = t.hits + 1"); got != tt.want {
+				t.Errorf("upsert() = %s; want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFullText(t *testing.T) {
+	tests := []struct {
+		name      string
+		dialect   Dialect
+		search    string
+		condition string
+		args      []any
+	}{
+		{name: "mysql", dialect: MySQL, search: "milk bread", condition: "(MATCH(items.name) AGAINST(? IN NATURAL LANGUAGE MODE) OR ? = '')", args: []any{"milk bread", "milk bread"}},
+		{name: "postgres", dialect: Postgres, search: "milk, bread!", condition: "(to_tsvector('simple', items.name) @@ to_tsquery('simple', ?) OR ? = '')", args: []any{"milk | bread", "milk, bread!"}},
+		{name: "sqlite", dialect: SQLite, search: "milk bread", condition: "(items.name LIKE ? OR items.name LIKE ?)", args: []any{"%milk%", "%bread%"}},
+		{name: "sqlite empty", dialect: SQLite, search: "", condition: "(? = '')", args: []any{""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			condition, args := tt.dialect.fullText("items.name", tt.search)
+			if condition != tt.condition {
+				t.Errorf("fullText() condition = %s; want %s", condition, tt.condition)
+			}
+			if !reflect.DeepEqual(args, tt.args) {
+				t.Errorf("fullText() args = %v; want %v", args, tt.args)
+			}
+		})
+	}
+}
+
+func TestWithTx(t *testing.T) {
+	sqlDb, err := sql.Open("sqlite3", "file:"+filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlDb.Close()
+	var db = NewDB(sqlDb, SQLite)
+	_, err = db.Exec("CREATE TABLE folders (id INTEGER PRIMARY KEY, name TEXT NOT NULL)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var count = func() int {
+		var n int
+		err := db.QueryRow("SELECT COUNT(*) FROM folders").Scan(&n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	var errFailed = errors.New("failed")
+	err = db.WithTx(context.Background(), func(tx *DB) error {
+		_, err := tx.Exec("INSERT INTO folders (name) VALUES (?)", "Groceries")
+		if err != nil {
+			return err
+		}
+		// a nested transaction joins the outer one, so its insert is rolled back as well
+		err = tx.WithTx(context.Background(), func(tx *DB) error {
+			_, err := insert(context.Background(), tx, "INSERT INTO folders (name) VALUES (?)", "Hardware")
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		return errFailed
+	})
+	if !errors.Is(err, errFailed) {
+		t.Fatalf("WithTx() error = %v; want %v", err, errFailed)
+	}
+	if n := count(); n != 0 {
+		t.Errorf("want the transaction rolled back; got %d folders", n)
+	}
+
+	err = db.WithTx(context.Background(), func(tx *DB) error {
+		_, err := tx.Exec("INSERT INTO folders (name) VALUES (?)", "Groceries")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := count(); n != 1 {
+		t.Errorf("want the transaction committed; got %d folders", n)
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	var before = time.Now()
+	ctx, cancel := (&DB{}).withTimeout(context.Background())
+	defer cancel()
+	deadline, ok := ctx.Deadline()
+	if !ok || deadline.Before(before.Add(DefaultQueryTimeout)) {
+		t.Errorf("want the default timeout without a query timeout; got %v", deadline)
+	}
+
+	parent, cancelParent := context.WithCancel(context.Background())
+	ctx, cancel = (&DB{QueryTimeout: time.Minute}).withTimeout(parent)
+	defer cancel()
+	cancelParent()
+	if !errors.Is(ctx.Err(), context.Canceled) {
+		t.Errorf("want the query context cancelled together with its parent; got %v", ctx.Err())
+	}
+}
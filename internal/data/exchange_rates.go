@@ -0,0 +1,122 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"easylist/internal/money"
+	"errors"
+	"sort"
+	"time"
+)
+
+// ExchangeRate is how many units of Currency are worth one unit of the base currency the rates were loaded with.
+type ExchangeRate struct {
+	Currency  string    `jsonapi:"primary,exchange-rates"`
+	Rate      float64   `jsonapi:"attr,rate"`
+	UpdatedAt time.Time `jsonapi:"attr,updated_at,iso8601"`
+}
+
+type ExchangeRates []*ExchangeRate
+
+type ExchangeRateModel struct {
+	DB *DB
+}
+
+// Rates turns the list into the lookup table used for conversions.
+func (rates ExchangeRates) Rates() money.Rates {
+	var result = make(money.Rates, len(rates))
+	for _, rate := range rates {
+		result[rate.Currency] = rate.Rate
+	}
+	return result
+}
+
+func (e ExchangeRateModel) GetAll(ctx context.Context) (ExchangeRates, error) {
+	var query = "SELECT currency, rate, updated_at FROM exchange_rates ORDER BY currency ASC"
+
+	ctx, cancel := e.DB.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := e.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rates = ExchangeRates{}
+	for rows.Next() {
+		var rate ExchangeRate
+		err = rows.Scan(&rate.Currency, &rate.Rate, &rate.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		rates = append(rates, &rate)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+// Replace swaps all stored rates for the new ones in a single transaction.
+func (e ExchangeRateModel) Replace(ctx context.Context, rates money.Rates) error {
+	ctx, cancel := e.DB.withTimeout(ctx)
+	defer cancel()
+
+	var currencies = make([]string, 0, len(rates))
+	for currency := range rates {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+
+	return e.DB.WithTx(ctx, func(tx *DB) error {
+		_, err := tx.ExecContext(ctx, "DELETE FROM exchange_rates")
+		if err != nil {
+			return err
+		}
+		for _, currency := range currencies {
+			_, err = tx.ExecContext(ctx, "INSERT INTO exchange_rates (currency, rate, updated_at) VALUES (?, ?, NOW())", currency, rates[currency])
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// loadRates reads the stored rates, it is used by the models which convert totals into the currency of a user.
+func loadRates(ctx context.Context, db *DB) (money.Rates, error) {
+	rates, err := ExchangeRateModel{DB: db}.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return rates.Rates(), nil
+}
+
+// userCurrency returns the currency the user wants to see totals in.
+func userCurrency(ctx context.Context, db *DB, userId int64) (string, error) {
+	var currency string
+
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	err := db.QueryRowContext(ctx, "SELECT currency FROM users WHERE id = ?", userId).Scan(&currency)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return money.DefaultCurrency, nil
+		}
+		return "", err
+	}
+	return currency, nil
+}
+
+type MockExchangeRateModel struct {
+}
+
+func (m MockExchangeRateModel) GetAll(ctx context.Context) (ExchangeRates, error) {
+	return ExchangeRates{}, nil
+}
+
+func (m MockExchangeRateModel) Replace(ctx context.Context, rates money.Rates) error {
+	return nil
+}
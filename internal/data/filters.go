@@ -1,17 +1,57 @@
 package data
 
 import (
+	"easylist/internal/money"
 	"easylist/internal/validator"
+	"encoding/base64"
+	"fmt"
 	"math"
+	"strconv"
 	"strings"
+	"time"
 )
 
+// Filters selects a page of a collection. By default the pages are numbered, when After or Before is set the page
+// starts after or ends before the row of the cursor instead and an empty After asks for the first page.
 type Filters struct {
 	Page         int
 	Size         int
 	Sort         string
 	SortSafelist []string
 	Includes     []string
+	Group        string
+	After        *string
+	Before       *string
+}
+
+// ItemFilters narrows down the items returned by GetAll, the zero value matches every item. The nil pointers and
+// zero times are not applied, the date ranges include both of their days.
+type ItemFilters struct {
+	Name          string
+	IsStarred     *bool
+	IsDone        *bool
+	HasFile       *bool
+	PriceMin      *int64
+	PriceMax      *int64
+	Currency      string
+	QuantityTypes []string
+	Created       DateRange
+	Updated       DateRange
+}
+
+// ListFilters narrows down the lists returned by GetAll in the same way as ItemFilters.
+type ListFilters struct {
+	Name      string
+	HasUndone *bool
+	IsPublic  *bool
+	Created   DateRange
+	Updated   DateRange
+}
+
+// DateRange is a range of days, From and To are the first and the last day, a zero day leaves that side open.
+type DateRange struct {
+	From time.Time
+	To   time.Time
 }
 
 type Metadata struct {
@@ -24,6 +64,10 @@ type Metadata struct {
 	PrevPage     int
 	ParentId     int64
 	ParentName   string
+	UsesCursor   bool
+	Sort         string
+	NextCursor   string
+	PrevCursor   string
 }
 
 func calculateMetadata(totalRecords, page, pageSize int, parent int64, parentName string) Metadata {
@@ -48,6 +92,115 @@ func calculateMetadata(totalRecords, page, pageSize int, parent int64, parentNam
 	}
 }
 
+// calculateCursorMetadata returns the cursors pointing after the last and before the first row of the page, an empty
+// cursor means there is no page in that direction.
+func calculateCursorMetadata(filters Filters, first, last int64, hasNext, hasPrev bool, parent int64, parentName string) Metadata {
+	var metadata = Metadata{
+		PageSize:   filters.Size,
+		ParentId:   parent,
+		ParentName: parentName,
+		UsesCursor: true,
+		Sort:       filters.Sort,
+	}
+	if hasNext {
+		metadata.NextCursor = EncodePageCursor(filters.Sort, last)
+	}
+	if hasPrev {
+		metadata.PrevCursor = EncodePageCursor(filters.Sort, first)
+	}
+	return metadata
+}
+
+// cursorPage drops the extra row which was read to know whether there is another page and puts the rows of a page
+// before the cursor back in order, they are read backwards from it.
+func cursorPage[T any](rows []T, filters Filters, id func(T) int64, parent int64, parentName string) ([]T, Metadata) {
+	var more = len(rows) > filters.Size
+	if more {
+		rows = rows[:filters.Size]
+	}
+	var backwards = filters.Before != nil
+	if backwards {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+	if len(rows) == 0 {
+		return rows, calculateCursorMetadata(filters, 0, 0, false, false, parent, parentName)
+	}
+	var hasNext, hasPrev = more, filters.After != nil && *filters.After != ""
+	if backwards {
+		hasNext, hasPrev = true, more
+	}
+	return rows, calculateCursorMetadata(filters, id(rows[0]), id(rows[len(rows)-1]), hasNext, hasPrev, parent, parentName)
+}
+
+// EncodePageCursor returns the opaque cursor of the row with the id in the collection sorted by sort.
+func EncodePageCursor(sort string, id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(sort + ":" + strconv.FormatInt(id, 10)))
+}
+
+// decodePageCursor returns the id of the row of the cursor, the cursor of a collection sorted differently is refused.
+func decodePageCursor(cursor string, sort string) (int64, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	cursorSort, rawId, found := strings.Cut(string(decoded), ":")
+	if !found || cursorSort != sort {
+		return 0, ErrInvalidCursor
+	}
+	id, err := strconv.ParseInt(rawId, 10, 64)
+	if err != nil || id < 1 {
+		return 0, ErrInvalidCursor
+	}
+	return id, nil
+}
+
+// UsesCursor reports whether the page is selected by a cursor instead of its number.
+func (f Filters) UsesCursor() bool {
+	return f.After != nil || f.Before != nil
+}
+
+// count is the column counting all rows for the numbered pages, it is left out for the cursors as it needs to read
+// the whole collection.
+func (f Filters) count() string {
+	if f.UsesCursor() {
+		return "0"
+	}
+	return "COUNT(*) OVER()"
+}
+
+// page returns the condition selecting the rows of the page of the table prefixed with AND, its arguments and the
+// order of the rows. The rows after the cursor continue in the sort order, ties are ordered by id. The rows before it
+// are read in the opposite order, cursorPage puts them back. The sort value of the cursor is read from its row, so a
+// cursor stays valid while its row is in the trash. The row is only read when it matches scope, a condition on
+// cursor_rows with scopeArgs, so a cursor made up from the row of another user selects nothing.
+func (f Filters) page(table string, scope string, scopeArgs ...any) (string, []any, string) {
+	if !f.UsesCursor() {
+		return "", nil, fmt.Sprintf("%s.`%s` %s, %s.`order` ASC", table, f.sortColumn(), f.sortDirection(), table)
+	}
+	var column, direction = f.sortColumn(), f.sortDirection()
+	var cursor = f.After
+	if f.Before != nil {
+		cursor = f.Before
+		direction = map[string]string{"ASC": "DESC", "DESC": "ASC"}[direction]
+	}
+	var order = fmt.Sprintf("%s.`%s` %s, %s.id %s", table, column, direction, table, direction)
+	id, err := decodePageCursor(*cursor, f.Sort)
+	if err != nil {
+		return "", nil, order
+	}
+	var comparison = ">"
+	if direction == "DESC" {
+		comparison = "<"
+	}
+	var value = fmt.Sprintf("(SELECT cursor_rows.`%s` FROM %s AS cursor_rows WHERE cursor_rows.id = ? AND %s)", column, table, scope)
+	var condition = fmt.Sprintf(" AND (%[1]s.`%[2]s` %[3]s %[4]s OR (%[1]s.`%[2]s` = %[4]s AND %[1]s.id %[3]s ?))", table, column, comparison, value)
+	var valueArgs = append([]any{id}, scopeArgs...)
+	var args = append(append(valueArgs, valueArgs...), id)
+	return condition, args, order
+}
+
 func (f Filters) sortColumn() string {
 	for _, safeValue := range f.SortSafelist {
 		if f.Sort == safeValue {
@@ -64,20 +217,136 @@ func (f Filters) sortDirection() string {
 	return "ASC"
 }
 
+// limit reads one more row with a cursor to know whether there is another page.
 func (f Filters) limit() int {
+	if f.UsesCursor() {
+		return f.Size + 1
+	}
 	return f.Size
 }
 
 func (f Filters) offset() int {
+	if f.UsesCursor() {
+		return 0
+	}
 	return (f.Page - 1) * f.Size
 }
 
+// where returns the conditions of the filters prefixed with AND together with their arguments.
+func (f ItemFilters) where(d Dialect) (string, []any) {
+	var search, args = d.fullText("items.name", f.Name)
+	var conditions = []string{search}
+	if f.IsStarred != nil {
+		conditions = append(conditions, "items.is_starred = ?")
+		args = append(args, *f.IsStarred)
+	}
+	if f.IsDone != nil {
+		conditions = append(conditions, "items.is_done = ?")
+		args = append(args, *f.IsDone)
+	}
+	if f.HasFile != nil {
+		if *f.HasFile {
+			conditions = append(conditions, "items.file <> ''")
+		} else {
+			conditions = append(conditions, "items.file = ''")
+		}
+	}
+	if f.PriceMin != nil {
+		conditions = append(conditions, "items.price_minor >= ?")
+		args = append(args, *f.PriceMin)
+	}
+	if f.PriceMax != nil {
+		conditions = append(conditions, "items.price_minor <= ?")
+		args = append(args, *f.PriceMax)
+	}
+	if f.Currency != "" {
+		conditions = append(conditions, "items.currency = ?")
+		args = append(args, f.Currency)
+	}
+	if len(f.QuantityTypes) > 0 {
+		var types = make([]any, 0, len(f.QuantityTypes))
+		for _, quantityType := range f.QuantityTypes {
+			types = append(types, quantityType)
+		}
+		conditions = append(conditions, "items.quantity_type IN ("+ConvertSliceToQuestionMarks(types)+")")
+		args = append(args, types...)
+	}
+	conditions, args = f.Created.where("items.created_at", conditions, args)
+	conditions, args = f.Updated.where("items.updated_at", conditions, args)
+	return " AND " + strings.Join(conditions, " AND "), args
+}
+
+// where returns the conditions of the filters prefixed with AND together with their arguments.
+func (f ListFilters) where(d Dialect) (string, []any) {
+	var search, args = d.fullText("lists.name", f.Name)
+	var conditions = []string{search}
+	if f.HasUndone != nil {
+		var exists = "EXISTS (SELECT 1 FROM items WHERE items.list_id = lists.id AND items.deleted_at IS NULL AND items.is_done = FALSE)"
+		if !*f.HasUndone {
+			exists = "NOT " + exists
+		}
+		conditions = append(conditions, exists)
+	}
+	if f.IsPublic != nil {
+		if *f.IsPublic {
+			conditions = append(conditions, "lists.link IS NOT NULL")
+		} else {
+			conditions = append(conditions, "lists.link IS NULL")
+		}
+	}
+	conditions, args = f.Created.where("lists.created_at", conditions, args)
+	conditions, args = f.Updated.where("lists.updated_at", conditions, args)
+	return " AND " + strings.Join(conditions, " AND "), args
+}
+
+// where adds the conditions of the range on the column, the last day counts until its end.
+func (r DateRange) where(column string, conditions []string, args []any) ([]string, []any) {
+	if !r.From.IsZero() {
+		conditions = append(conditions, column+" >= ?")
+		args = append(args, r.From)
+	}
+	if !r.To.IsZero() {
+		conditions = append(conditions, column+" < ?")
+		args = append(args, r.To.AddDate(0, 0, 1))
+	}
+	return conditions, args
+}
+
+func ValidateItemFilters(v *validator.Validator, f ItemFilters) {
+	v.Check(f.PriceMin == nil || *f.PriceMin >= 0, "filter[price_min]", "must be zero or greater")
+	v.Check(f.PriceMax == nil || *f.PriceMax >= 0, "filter[price_max]", "must be zero or greater")
+	v.Check(f.PriceMin == nil || f.PriceMax == nil || *f.PriceMin <= *f.PriceMax, "filter[price_max]", "must not be less than filter[price_min]")
+	v.Check(f.Currency == "" || money.ValidCurrency(f.Currency), "filter[currency]", "must be a three letter ISO 4217 currency code")
+	v.Check(len(f.QuantityTypes) <= 20, "filter[quantity_type]", "must contain no more than 20 values")
+	validateDateRange(v, f.Created, "created")
+	validateDateRange(v, f.Updated, "updated")
+}
+
+func ValidateListFilters(v *validator.Validator, f ListFilters) {
+	validateDateRange(v, f.Created, "created")
+	validateDateRange(v, f.Updated, "updated")
+}
+
+func validateDateRange(v *validator.Validator, r DateRange, name string) {
+	v.Check(r.From.IsZero() || r.To.IsZero() || !r.To.Before(r.From), "filter["+name+"_to]", "must not be before filter["+name+"_from]")
+}
+
 func ValidateFilters(v *validator.Validator, f Filters) {
 	v.Check(f.Page > 0, "page[number]", "must be greater than zero")
 	v.Check(f.Page <= 10_000_000, "page[number]", "must be maximum 10 mln")
 	v.Check(f.Size > 0, "page[size]", "must be greater than zero")
 	v.Check(f.Size <= 200, "page[size]", "must be a maximum 200")
 	v.Check(validator.In(f.Sort, f.SortSafelist...), "sort", "invalid sort value")
+	if f.UsesCursor() {
+		v.Check(f.After == nil || f.Before == nil, "page[before]", "can't be combined with page[after]")
+		v.Check(f.Page == 1, "page[number]", "can't be combined with a cursor")
+		for key, cursor := range map[string]*string{"page[after]": f.After, "page[before]": f.Before} {
+			if cursor != nil && (*cursor != "" || key == "page[before]") {
+				_, err := decodePageCursor(*cursor, f.Sort)
+				v.Check(err == nil, key, "must be a cursor of the same collection with the same sort")
+			}
+		}
+	}
 }
 
 func GetDefaultFilterInstance() Filters {
@@ -3,6 +3,7 @@ package data
 import (
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestFilters_SortColumn(t *testing.T) {
@@ -159,3 +160,104 @@ func TestCalculateMetadata(t *testing.T) {
 		}
 	}
 }
+
+func TestItemFilters_Where(t *testing.T) {
+	var yes, no = true, false
+	var price = int64(100)
+	var day = time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	var filters = ItemFilters{IsStarred: &no, HasFile: &yes, PriceMax: &price, QuantityTypes: []string{"l", "kg"}, Updated: DateRange{From: day, To: day}}
+
+	condition, args := filters.where(SQLite)
+	var want = " AND (? = '') AND items.is_starred = ? AND items.file <> '' AND items.price_minor <= ? AND items.quantity_type IN (?,?) AND items.updated_at >= ? AND items.updated_at < ?"
+	if condition != want {
+		t.Errorf("where() condition = %s; want %s", condition, want)
+	}
+	var wantArgs = []any{"", false, int64(100), "l", "kg", day, day.AddDate(0, 0, 1)}
+	if !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("where() args = %v; want %v", args, wantArgs)
+	}
+}
+
+func TestListFilters_Where(t *testing.T) {
+	var yes, no = true, false
+	condition, args := ListFilters{HasUndone: &no, IsPublic: &yes}.where(SQLite)
+	var want = " AND (? = '') AND NOT EXISTS (SELECT 1 FROM items WHERE items.list_id = lists.id AND items.deleted_at IS NULL AND items.is_done = FALSE) AND lists.link IS NOT NULL"
+	if condition != want || !reflect.DeepEqual(args, []any{""}) {
+		t.Errorf("where() = %s, %v; want %s, []", condition, args, want)
+	}
+}
+
+func TestPageCursor(t *testing.T) {
+	var cursor = EncodePageCursor("-name", 42)
+	id, err := decodePageCursor(cursor, "-name")
+	if err != nil || id != 42 {
+		t.Errorf("decodePageCursor() = %d, %v; want 42, nil", id, err)
+	}
+	for _, tt := range []struct{ cursor, sort string }{{cursor, "name"}, {"not a cursor", "-name"}, {EncodePageCursor("-name", 0), "-name"}} {
+		if _, err := decodePageCursor(tt.cursor, tt.sort); err != ErrInvalidCursor {
+			t.Errorf("decodePageCursor(%q, %q) error = %v; want %v", tt.cursor, tt.sort, err, ErrInvalidCursor)
+		}
+	}
+}
+
+func TestFilters_Page(t *testing.T) {
+	var cursor = EncodePageCursor("-name", 7)
+	var empty = ""
+	tests := []struct {
+		name      string
+		filters   Filters
+		condition string
+		args      []any
+		order     string
+	}{
+		{
+			name:    "page number",
+			filters: Filters{Sort: "-name", SortSafelist: []string{"-name"}},
+			order:   "items.`name` DESC, items.`order` ASC",
+		},
+		{
+			name:    "first page",
+			filters: Filters{Sort: "-name", SortSafelist: []string{"-name"}, After: &empty},
+			order:   "items.`name` DESC, items.id DESC",
+		},
+		{
+			name:      "after",
+			filters:   Filters{Sort: "-name", SortSafelist: []string{"-name"}, After: &cursor},
+			condition: " AND (items.`name` < (SELECT cursor_rows.`name` FROM items AS cursor_rows WHERE cursor_rows.id = ? AND cursor_rows.user_id = ?) OR (items.`name` = (SELECT cursor_rows.`name` FROM items AS cursor_rows WHERE cursor_rows.id = ? AND cursor_rows.user_id = ?) AND items.id < ?))",
+			args:      []any{int64(7), int64(3), int64(7), int64(3), int64(7)},
+			order:     "items.`name` DESC, items.id DESC",
+		},
+		{
+			name:      "before",
+			filters:   Filters{Sort: "-name", SortSafelist: []string{"-name"}, Before: &cursor},
+			condition: " AND (items.`name` > (SELECT cursor_rows.`name` FROM items AS cursor_rows WHERE cursor_rows.id = ? AND cursor_rows.user_id = ?) OR (items.`name` = (SELECT cursor_rows.`name` FROM items AS cursor_rows WHERE cursor_rows.id = ? AND cursor_rows.user_id = ?) AND items.id > ?))",
+			args:      []any{int64(7), int64(3), int64(7), int64(3), int64(7)},
+			order:     "items.`name` ASC, items.id ASC",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			condition, args, order := tt.filters.page("items", "cursor_rows.user_id = ?", int64(3))
+			if condition != tt.condition || !reflect.DeepEqual(args, tt.args) || order != tt.order {
+				t.Errorf("page() = %q, %v, %q; want %q, %v, %q", condition, args, order, tt.condition, tt.args, tt.order)
+			}
+		})
+	}
+}
+
+func TestCursorPage(t *testing.T) {
+	var id = func(row int64) int64 { return row }
+	var cursor = EncodePageCursor("id", 2)
+
+	rows, metadata := cursorPage([]int64{3, 4, 5}, Filters{Size: 2, Sort: "id", After: &cursor}, id, 0, "")
+	if !reflect.DeepEqual(rows, []int64{3, 4}) || metadata.NextCursor != EncodePageCursor("id", 4) || metadata.PrevCursor != EncodePageCursor("id", 3) {
+		t.Errorf("cursorPage() after = %v, %+v", rows, metadata)
+	}
+
+	// the rows before the cursor are read in the reverse order
+	rows, metadata = cursorPage([]int64{1}, Filters{Size: 2, Sort: "id", Before: &cursor}, id, 0, "")
+	if !reflect.DeepEqual(rows, []int64{1}) || metadata.NextCursor != EncodePageCursor("id", 1) || metadata.PrevCursor != "" {
+		t.Errorf("cursorPage() before = %v, %+v", rows, metadata)
+	}
+}
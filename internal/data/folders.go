@@ -22,21 +22,22 @@ type Folder struct {
 	UserId    sql.NullInt64 `json:"-"`
 	CreatedAt time.Time     `jsonapi:"attr,created_at,iso8601"`
 	UpdatedAt time.Time     `jsonapi:"attr,updated_at,iso8601"`
+	DeletedAt *time.Time    `jsonapi:"attr,deleted_at,iso8601,omitempty" json:"-"`
 	Lists     Lists         `jsonapi:"relation,lists,omitempty"`
 }
 
 type Folders []*Folder
 
 type FolderModel struct {
-	DB *sql.DB
+	DB *DB
 }
 
-func (f FolderModel) GetLastFolderOrderForUser(userId int64) (int, error) {
-	var query = "SELECT COALESCE(MAX(`order`),0) AS 'order' FROM folders WHERE folders.user_id = ?"
+func (f FolderModel) GetLastFolderOrderForUser(ctx context.Context, userId int64) (int, error) {
+	var query = "SELECT COALESCE(MAX(`order`),0) FROM folders WHERE folders.user_id = ?"
 
 	var order = 0
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := f.DB.withTimeout(ctx)
 	defer cancel()
 
 	err := f.DB.QueryRowContext(ctx, query, userId).Scan(
@@ -54,22 +55,18 @@ func (f FolderModel) GetLastFolderOrderForUser(userId int64) (int, error) {
 	return order + 1, nil
 }
 
-func (f FolderModel) Insert(folder *Folder) error {
+func (f FolderModel) Insert(ctx context.Context, folder *Folder) error {
 	var query = "INSERT INTO folders (user_id, name, icon, version, `order`, created_at, updated_at) VALUES (?, ?, ?, ?, ?, NOW(), NOW())"
 
-	lastOrder, err := f.GetLastFolderOrderForUser(folder.UserId.Int64)
+	lastOrder, err := f.GetLastFolderOrderForUser(ctx, folder.UserId.Int64)
 	if err != nil {
 		return err
 	}
 
 	var args = []any{folder.UserId, folder.Name, folder.Icon, folder.Version, lastOrder}
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := f.DB.withTimeout(ctx)
 	defer cancel()
-	result, err := f.DB.ExecContext(ctx, query, args...)
-	if err != nil {
-		return err
-	}
-	id, err := result.LastInsertId()
+	id, err := insert(ctx, f.DB, query, args...)
 	if err != nil {
 		return err
 	}
@@ -78,16 +75,16 @@ func (f FolderModel) Insert(folder *Folder) error {
 	return nil
 }
 
-func (f FolderModel) Get(id int64, userId int64) (*Folder, error) {
+func (f FolderModel) Get(ctx context.Context, id int64, userId int64) (*Folder, error) {
 	if id < 1 {
 		return nil, ErrRecordNotFound
 	}
 
-	var query = "SELECT id, user_id, name, icon, version, `order`, created_at, updated_at FROM folders WHERE id = ? AND (user_id = ? OR user_id IS NULL)"
+	var query = "SELECT id, user_id, name, icon, version, `order`, created_at, updated_at FROM folders WHERE id = ? AND (user_id = ? OR user_id IS NULL) AND deleted_at IS NULL"
 
 	var folder Folder
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := f.DB.withTimeout(ctx)
 	defer cancel()
 
 	var err = f.DB.QueryRowContext(ctx, query, id, userId).Scan(&folder.ID, &folder.UserId, &folder.Name, &folder.Icon, &folder.Version, &folder.Order, &folder.CreatedAt, &folder.UpdatedAt)
@@ -103,14 +100,14 @@ func (f FolderModel) Get(id int64, userId int64) (*Folder, error) {
 	return &folder, nil
 }
 
-func (f FolderModel) GetByIds(ids []any) (Folders, error) {
+func (f FolderModel) GetByIds(ctx context.Context, ids []any) (Folders, error) {
 	var folders Folders
 	if len(ids) < 1 {
 		return folders, nil
 	}
-	var query = "SELECT id, user_id, name, icon, version, `order`, created_at, updated_at FROM folders WHERE folders.id IN (" + ConvertSliceToQuestionMarks(ids) + ")"
+	var query = "SELECT id, user_id, name, icon, version, `order`, created_at, updated_at FROM folders WHERE folders.id IN (" + ConvertSliceToQuestionMarks(ids) + ") AND folders.deleted_at IS NULL"
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := f.DB.withTimeout(ctx)
 	defer cancel()
 
 	rows, err := f.DB.QueryContext(ctx, query, ids...)
@@ -138,12 +135,8 @@ func (f FolderModel) GetByIds(ids []any) (Folders, error) {
 	return folders, nil
 }
 
-func (f FolderModel) Update(folder *Folder, oldOrder int32) error {
-	var _, err = f.DB.Exec("START TRANSACTION")
-	if err != nil {
-		return err
-	}
-	var query = "UPDATE folders SET name = ?, icon = ?, `order` = ?, version = version + 1, updated_at = NOW() WHERE id = ? AND user_id = ? AND version = ?"
+func (f FolderModel) Update(ctx context.Context, folder *Folder, oldOrder int32) error {
+	var query = "UPDATE folders SET name = ?, icon = ?, `order` = ?, version = version + 1, updated_at = NOW() WHERE id = ? AND user_id = ? AND version = ? AND deleted_at IS NULL"
 	var args = []any{
 		folder.Name,
 		folder.Icon,
@@ -152,68 +145,73 @@ func (f FolderModel) Update(folder *Folder, oldOrder int32) error {
 		folder.UserId,
 		folder.Version,
 	}
-	folder.Version++
-	folder.UpdatedAt = time.Now()
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := f.DB.withTimeout(ctx)
 	defer cancel()
 
-	var _, err2 = f.DB.ExecContext(ctx, query, args...)
-	if err2 != nil {
-		f.DB.Exec("ROLLBACK")
-		switch {
-		case errors.Is(err2, sql.ErrNoRows):
-			return ErrEditConflict
-		default:
-			return err2
+	var err = f.DB.WithTx(ctx, func(tx *DB) error {
+		result, err := tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		// a stale version, a deleted folder or a folder of another user
+		err = expectOneRow(result, ErrEditConflict)
+		if err != nil {
+			return err
 		}
-	}
 
-	if oldOrder != folder.Order {
-		var query2 = "UPDATE folders SET `order` = folders.order+1 WHERE folders.order >= ? AND user_id = ? AND id != ?"
-		var _, err3 = f.DB.ExecContext(ctx, query2, folder.Order, folder.UserId, folder.ID)
-		if err3 != nil {
-			f.DB.Exec("ROLLBACK")
-			return err3
+		if oldOrder != folder.Order {
+			var query2 = "UPDATE folders SET `order` = folders.`order`+1, updated_at = NOW() WHERE folders.`order` >= ? AND user_id = ? AND deleted_at IS NULL AND id != ?"
+			_, err = tx.ExecContext(ctx, query2, folder.Order, folder.UserId, folder.ID)
+			if err != nil {
+				return err
+			}
 		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
-
-	_, err = f.DB.Exec("COMMIT")
-	return err
+	folder.Version++
+	folder.UpdatedAt = time.Now()
+	return nil
 }
 
-func (f FolderModel) Delete(id int64, userId int64) error {
+// Delete moves the folder to the trash, it is removed for good by Purge once the retention period is over. The lists
+// of the folder and their items are hidden together with it, the sync clients get tombstones for the lists.
+func (f FolderModel) Delete(ctx context.Context, id int64, userId int64) error {
 	if id < 1 || userId < 1 {
 		return ErrRecordNotFound
 	}
-	var query = "DELETE FROM folders WHERE id = ? AND user_id = ?"
+	var query = "UPDATE folders SET deleted_at = NOW() WHERE id = ? AND user_id = ? AND deleted_at IS NULL"
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := f.DB.withTimeout(ctx)
 	defer cancel()
 
-	result, err := f.DB.ExecContext(ctx, query, id, userId)
-	if err != nil {
-		return err
-	}
-
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
-
-	if rowsAffected == 0 {
-		return ErrRecordNotFound
-	}
-	return nil
+	return f.DB.WithTx(ctx, func(tx *DB) error {
+		result, err := tx.ExecContext(ctx, query, id, userId)
+		if err != nil {
+			return err
+		}
+		err = expectOneRow(result, ErrRecordNotFound)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "INSERT INTO tombstones (user_id, list_id, record_type, record_id, deleted_at) SELECT lists.user_id, lists.id, '"+ListsType+"', lists.id, NOW() FROM lists WHERE lists.folder_id = ? AND lists.deleted_at IS NULL", id)
+		if err != nil {
+			return err
+		}
+		return recordTombstone(ctx, tx, userId, 0, FolderType, id)
+	})
 }
 
-func (f FolderModel) DeleteByUser(userId int64) error {
+func (f FolderModel) DeleteByUser(ctx context.Context, userId int64) error {
 	if userId < 1 {
 		return ErrRecordNotFound
 	}
 	var query = "DELETE FROM folders WHERE user_id = ?"
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := f.DB.withTimeout(ctx)
 	defer cancel()
 
 	_, err := f.DB.ExecContext(ctx, query, userId)
@@ -224,22 +222,28 @@ func (f FolderModel) DeleteByUser(userId int64) error {
 	return nil
 }
 
-func (f FolderModel) GetAll(name string, userId int64, filters Filters) (Folders, Metadata, error) {
+func (f FolderModel) GetAll(ctx context.Context, name string, userId int64, filters Filters) (Folders, Metadata, error) {
 	var joinList string
 	var fieldsList string
 	var groupList string
 	if Contains(filters.Includes, "lists") {
-		joinList = "LEFT JOIN lists ON lists.folder_id = folders.id"
-		fieldsList = ", (SELECT CONCAT('[',GROUP_CONCAT(JSON_OBJECT('id', lists.id, 'user_id', lists.user_id, 'FolderId', lists.folder_id, 'name', lists.name, 'icon', lists.icon, 'version', lists.version, 'order', lists.order, 'link', lists.link, 'created_at', lists.created_at, 'updated_at', lists.updated_at)),']')) as parsed_lists"
+		joinList = "LEFT JOIN lists ON lists.folder_id = folders.id AND lists.deleted_at IS NULL"
+		var list = f.DB.Dialect.jsonObject("'id'", "lists.id", "'user_id'", "lists.user_id", "'FolderId'", "lists.folder_id", "'name'", "lists.name", "'icon'", "lists.icon", "'version'", "lists.version", "'order'", "lists.`order`", "'link'", "lists.link", "'created_at'", f.DB.Dialect.jsonTime("lists.created_at"), "'updated_at'", f.DB.Dialect.jsonTime("lists.updated_at"))
+		fieldsList = ", " + f.DB.Dialect.jsonArray(list) + " as parsed_lists"
 		groupList = "GROUP BY folders.id"
 	}
-	var query = fmt.Sprintf("SELECT COUNT(*) OVER(), folders.id, folders.user_id, folders.name, folders.icon, folders.version, folders.order, folders.created_at, folders.updated_at%s FROM folders %s WHERE (folders.user_id = ? OR folders.user_id IS NULL) AND (MATCH(folders.name) AGAINST(? IN NATURAL LANGUAGE MODE) OR ? = '') %s ORDER BY folders.%s %s, folders.order ASC LIMIT ? OFFSET ?", fieldsList, joinList, groupList, filters.sortColumn(), filters.sortDirection())
+	var search, searchArgs = f.DB.Dialect.fullText("folders.name", name)
+	var page, pageArgs, order = filters.page("folders", "(cursor_rows.user_id = ? OR cursor_rows.user_id IS NULL)", userId)
+	var query = fmt.Sprintf("SELECT %s, folders.id, folders.user_id, folders.name, folders.icon, folders.version, folders.`order`, folders.created_at, folders.updated_at%s FROM folders %s WHERE (folders.user_id = ? OR folders.user_id IS NULL) AND folders.deleted_at IS NULL AND %s%s %s ORDER BY %s LIMIT ? OFFSET ?", filters.count(), fieldsList, joinList, search, page, groupList, order)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := f.DB.withTimeout(ctx)
 	defer cancel()
 	var emptyMeta Metadata
 
-	rows, err := f.DB.QueryContext(ctx, query, userId, name, name, filters.limit(), filters.offset())
+	var args = append([]any{userId}, searchArgs...)
+	args = append(args, pageArgs...)
+	args = append(args, filters.limit(), filters.offset())
+	rows, err := f.DB.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, emptyMeta, err
 	}
@@ -314,11 +318,135 @@ func (f FolderModel) GetAll(name string, userId int64, filters Filters) (Folders
 		return nil, emptyMeta, err
 	}
 
+	if filters.UsesCursor() {
+		folders, metadata := cursorPage(folders, filters, func(folder *Folder) int64 { return folder.ID }, 0, "")
+		return folders, metadata, nil
+	}
 	var metadata = calculateMetadata(totalRecords, filters.Page, filters.Size, 0, "")
 
 	return folders, metadata, nil
 }
 
+func (f FolderModel) GetUpdatedSince(ctx context.Context, userId int64, since time.Time) (Folders, error) {
+	var query = "SELECT id, user_id, name, icon, version, `order`, created_at, updated_at FROM folders WHERE (user_id = ? OR user_id IS NULL) AND deleted_at IS NULL AND updated_at >= ? ORDER BY id ASC"
+
+	ctx, cancel := f.DB.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := f.DB.QueryContext(ctx, query, userId, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var folders = Folders{}
+	for rows.Next() {
+		var folder Folder
+		err = rows.Scan(&folder.ID, &folder.UserId, &folder.Name, &folder.Icon, &folder.Version, &folder.Order, &folder.CreatedAt, &folder.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		folders = append(folders, &folder)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return folders, nil
+}
+
+// GetTrashed returns the folders of the user that are in the trash, the most recently deleted first.
+func (f FolderModel) GetTrashed(ctx context.Context, userId int64) (Folders, error) {
+	var query = "SELECT id, user_id, name, icon, version, `order`, created_at, updated_at, deleted_at FROM folders WHERE user_id = ? AND deleted_at IS NOT NULL ORDER BY deleted_at DESC, id DESC"
+
+	ctx, cancel := f.DB.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := f.DB.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var folders = Folders{}
+	for rows.Next() {
+		var folder Folder
+		err = rows.Scan(&folder.ID, &folder.UserId, &folder.Name, &folder.Icon, &folder.Version, &folder.Order, &folder.CreatedAt, &folder.UpdatedAt, &folder.DeletedAt)
+		if err != nil {
+			return nil, err
+		}
+		folders = append(folders, &folder)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return folders, nil
+}
+
+// Restore takes the folder out of the trash together with its lists, the lists trashed on their own stay in the trash.
+func (f FolderModel) Restore(ctx context.Context, id int64, userId int64) error {
+	if id < 1 || userId < 1 {
+		return ErrRecordNotFound
+	}
+	var query = "UPDATE folders SET deleted_at = NULL, version = version + 1, updated_at = NOW() WHERE id = ? AND user_id = ? AND deleted_at IS NOT NULL"
+
+	ctx, cancel := f.DB.withTimeout(ctx)
+	defer cancel()
+
+	return f.DB.WithTx(ctx, func(tx *DB) error {
+		result, err := tx.ExecContext(ctx, query, id, userId)
+		if err != nil {
+			return err
+		}
+		err = expectOneRow(result, ErrRecordNotFound)
+		if err != nil {
+			return err
+		}
+
+		// Like ListModel.Restore, touching the lists and their items makes the next sync send them again.
+		var lists = "SELECT lists.id FROM lists WHERE lists.folder_id = ? AND lists.deleted_at IS NULL"
+		_, err = tx.ExecContext(ctx, "UPDATE items SET updated_at = NOW() WHERE deleted_at IS NULL AND list_id IN ("+lists+")", id)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "DELETE FROM tombstones WHERE record_type = '"+ListsType+"' AND record_id IN ("+lists+")", id)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "UPDATE lists SET updated_at = NOW() WHERE folder_id = ? AND deleted_at IS NULL", id)
+		if err != nil {
+			return err
+		}
+		return forgetTombstone(ctx, tx, FolderType, id)
+	})
+}
+
+// Purge removes the folders which stayed in the trash longer than the retention period. It has to run after
+// ListModel.Purge, which removes their lists.
+func (f FolderModel) Purge(ctx context.Context, retention time.Duration) (int64, error) {
+	var query = "DELETE FROM folders WHERE deleted_at < ?"
+
+	ctx, cancel := f.DB.withTimeout(ctx)
+	defer cancel()
+
+	result, err := f.DB.ExecContext(ctx, query, time.Now().Add(-retention))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// Reorder changes the order of the folders of the user, the default folder shared by all users is left out.
+func (f FolderModel) Reorder(ctx context.Context, userId int64, reorder Reorder) (Ranks, error) {
+	var scope = rankScope{
+		table:    "folders",
+		where:    "user_id = ? AND deleted_at IS NULL",
+		args:     []any{userId},
+		lock:     "SELECT id FROM users WHERE id = ?",
+		lockArgs: []any{userId},
+	}
+	return scope.reorder(ctx, f.DB, reorder)
+}
+
 func ValidateFolder(v *validator.Validator, folder *Folder) {
 	v.Check(folder.Name != "", "data.attributes.name", "must be provided")
 	v.Check(len(folder.Name) <= 190, "data.attributes.name", "must be no more than 190 characters")
@@ -355,26 +483,46 @@ func (folder Folder) JSONAPIRelationshipLinks(relation string) *jsonapi.Links {
 type MockFolderModel struct {
 }
 
-func (m MockFolderModel) Insert(folder *Folder) error {
+func (m MockFolderModel) Insert(ctx context.Context, folder *Folder) error {
 	return nil
 }
 
-func (m MockFolderModel) Get(id int64, userId int64) (*Folder, error) {
+func (m MockFolderModel) Get(ctx context.Context, id int64, userId int64) (*Folder, error) {
 	return nil, nil
 }
 
-func (m MockFolderModel) Update(folder *Folder, oldOrder int32) error {
+func (m MockFolderModel) Update(ctx context.Context, folder *Folder, oldOrder int32) error {
 	return nil
 }
 
-func (m MockFolderModel) Delete(id int64, userId int64) error {
+func (m MockFolderModel) Delete(ctx context.Context, id int64, userId int64) error {
 	return nil
 }
 
-func (m MockFolderModel) GetAll(name string, userId int64, filters Filters) (Folders, Metadata, error) {
+func (m MockFolderModel) GetAll(ctx context.Context, name string, userId int64, filters Filters) (Folders, Metadata, error) {
 	return nil, Metadata{}, nil
 }
 
-func (f MockFolderModel) DeleteByUser(userId int64) error {
+func (f MockFolderModel) DeleteByUser(ctx context.Context, userId int64) error {
 	return nil
 }
+
+func (f MockFolderModel) GetUpdatedSince(ctx context.Context, userId int64, since time.Time) (Folders, error) {
+	return Folders{}, nil
+}
+
+func (f MockFolderModel) GetTrashed(ctx context.Context, userId int64) (Folders, error) {
+	return Folders{}, nil
+}
+
+func (f MockFolderModel) Restore(ctx context.Context, id int64, userId int64) error {
+	return nil
+}
+
+func (f MockFolderModel) Purge(ctx context.Context, retention time.Duration) (int64, error) {
+	return 0, nil
+}
+
+func (f MockFolderModel) Reorder(ctx context.Context, userId int64, reorder Reorder) (Ranks, error) {
+	return Ranks{}, nil
+}
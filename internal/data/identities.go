@@ -0,0 +1,105 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var ErrDuplicateIdentity = errors.New("duplicate identity")
+
+// Identity links the account of an OpenID Connect provider, known by its subject, to the user.
+type Identity struct {
+	ID        int64
+	UserId    int64
+	Provider  string
+	Subject   string
+	Email     string
+	CreatedAt time.Time
+}
+
+type IdentityModel struct {
+	DB *DB
+}
+
+// GetUser returns the user the account of the provider is linked to.
+func (m IdentityModel) GetUser(ctx context.Context, provider string, subject string) (*User, error) {
+	var query = `
+        SELECT users.id, users.created_at, users.updated_at, users.name, users.email, users.password, users.is_active, users.currency, users.version
+        FROM users
+        INNER JOIN user_identities
+        ON users.id = user_identities.user_id
+        WHERE user_identities.provider = ?
+        AND user_identities.subject = ?`
+
+	ctx, cancel := m.DB.withTimeout(ctx)
+	defer cancel()
+
+	var user User
+	err := m.DB.QueryRowContext(ctx, query, provider, subject).Scan(
+		&user.ID,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+		&user.Name,
+		&user.Email,
+		&user.Password.hash,
+		&user.IsActive,
+		&user.Currency,
+		&user.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &user, nil
+}
+
+// Insert links the account of the provider to the user, an account is linked to one user only.
+func (m IdentityModel) Insert(ctx context.Context, identity *Identity) error {
+	var query = "INSERT INTO user_identities (user_id, provider, subject, email, created_at) VALUES (?, ?, ?, ?, NOW())"
+
+	ctx, cancel := m.DB.withTimeout(ctx)
+	defer cancel()
+
+	id, err := insert(ctx, m.DB, query, identity.UserId, identity.Provider, identity.Subject, identity.Email)
+	if err != nil {
+		if isDuplicate(err, "subject") {
+			return ErrDuplicateIdentity
+		}
+		return err
+	}
+	identity.ID = id
+	identity.CreatedAt = time.Now()
+	return nil
+}
+
+// DeleteByUser unlinks every account of the user, so the accounts can sign up again once the user is deleted.
+func (m IdentityModel) DeleteByUser(ctx context.Context, userId int64) error {
+	var query = "DELETE FROM user_identities WHERE user_id = ?"
+
+	ctx, cancel := m.DB.withTimeout(ctx)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, userId)
+	return err
+}
+
+type MockIdentityModel struct {
+}
+
+func (m MockIdentityModel) GetUser(ctx context.Context, provider string, subject string) (*User, error) {
+	return nil, ErrRecordNotFound
+}
+
+func (m MockIdentityModel) Insert(ctx context.Context, identity *Identity) error {
+	return nil
+}
+
+func (m MockIdentityModel) DeleteByUser(ctx context.Context, userId int64) error {
+	return nil
+}
@@ -3,101 +3,123 @@ package data
 import (
 	"context"
 	"database/sql"
+	"easylist/internal/money"
 	"easylist/internal/validator"
 	"errors"
 	"fmt"
 	"github.com/google/jsonapi"
-	"os"
 	"time"
 )
 
 type Item struct {
-	ID           int64     `jsonapi:"primary,items"`
-	UserId       int64     `json:"-"`
-	ListId       int64     `jsonapi:"attr,list_id"`
-	Name         string    `jsonapi:"attr,name"`
-	Description  string    `jsonapi:"attr,description"`
-	Quantity     int32     `jsonapi:"attr,quantity"`
-	QuantityType string    `jsonapi:"attr,quantity_type"`
-	Price        float32   `jsonapi:"attr,price"`
-	IsStarred    bool      `jsonapi:"attr,is_starred"`
-	IsDone       bool      `jsonapi:"attr,is_done"`
-	File         string    `jsonapi:"attr,file"`
-	Order        int32     `jsonapi:"attr,order"`
-	Version      int32     `json:"-"`
-	CreatedAt    time.Time `jsonapi:"attr,created_at,iso8601" json:"created_at" time_format:"sql_datetime"`
-	UpdatedAt    time.Time `jsonapi:"attr,updated_at,iso8601" json:"updated_at" time_format:"sql_datetime"`
-	List         *List     `jsonapi:"relation,list,omitempty"`
+	ID           int64      `jsonapi:"primary,items"`
+	UserId       int64      `json:"-"`
+	ListId       int64      `jsonapi:"attr,list_id"`
+	Name         string     `jsonapi:"attr,name"`
+	Description  string     `jsonapi:"attr,description"`
+	Quantity     int32      `jsonapi:"attr,quantity"`
+	QuantityType string     `jsonapi:"attr,quantity_type"`
+	Price        int64      `jsonapi:"attr,price"`
+	Currency     string     `jsonapi:"attr,currency"`
+	IsStarred    bool       `jsonapi:"attr,is_starred"`
+	IsDone       bool       `jsonapi:"attr,is_done"`
+	CategoryId   int64      `jsonapi:"attr,category_id"`
+	File         string     `json:"file"`
+	FileUrl      string     `jsonapi:"attr,file_url" json:"-"`
+	ThumbnailUrl string     `jsonapi:"attr,thumbnail_url" json:"-"`
+	Order        int32      `jsonapi:"attr,order"`
+	Version      int32      `json:"-"`
+	CreatedAt    time.Time  `jsonapi:"attr,created_at,iso8601" json:"created_at" time_format:"sql_datetime"`
+	UpdatedAt    time.Time  `jsonapi:"attr,updated_at,iso8601" json:"updated_at" time_format:"sql_datetime"`
+	DeletedAt    *time.Time `jsonapi:"attr,deleted_at,iso8601,omitempty" json:"-"`
+	List         *List      `jsonapi:"relation,list,omitempty"`
+	Category     *Category  `jsonapi:"relation,category,omitempty"`
 }
 
 const ItemsType = "items"
 
+// itemLineTotal is what the item of the current items row costs in minor units of its currency, an item without a
+// quantity is counted once.
+const itemLineTotal = "items.price_minor * CASE WHEN items.quantity > 1 THEN items.quantity ELSE 1 END"
+
 type Items []*Item
 
 type ItemModel struct {
-	DB *sql.DB
+	DB *DB
 }
 
-func (i ItemModel) GetLastItemOrderForUser(userId int64, listId int64) (int, error) {
-	var query = "SELECT COALESCE(MAX(`order`),0) AS 'order' FROM items WHERE items.user_id = ? AND items.list_id = ?"
+// itemInsert and itemUpdate are shared by Insert, Update and ApplyBulk, so an item is saved the same way one at a
+// time and in bulk. The values come from insertArgs and updateArgs, itemUpdate is completed with the access check.
+const itemInsert = "INSERT INTO items (user_id, list_id, name, description, quantity, quantity_type, price_minor, currency, is_starred, file, category_id, is_done, done_at, version, `order`, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, 0), ?, CASE WHEN ? THEN NOW() ELSE NULL END, 1, ?, NOW(), NOW())"
+const itemUpdate = "UPDATE items SET list_id = ?, name = ?, description = ?, quantity = ?, quantity_type = ?, price_minor = ?, currency = ?, is_starred = ?, file = ?, is_done = ?, done_at = CASE WHEN ? THEN COALESCE(done_at, NOW()) ELSE NULL END, category_id = NULLIF(?, 0), `order` = ?, version = version + 1, updated_at = NOW() WHERE id = ? AND version = ? AND deleted_at IS NULL"
 
-	var order = 0
+// itemLastOrder is the highest order in a list, new items go after it whoever of the members adds them.
+const itemLastOrder = "SELECT COALESCE(MAX(`order`),0) FROM items WHERE list_id = ? AND deleted_at IS NULL"
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
+func (item *Item) insertArgs(order int32) []any {
+	return []any{item.UserId, item.ListId, item.Name, item.Description, item.Quantity, item.QuantityType, item.Price, item.Currency, item.IsStarred, item.File, item.CategoryId, item.IsDone, item.IsDone, order}
+}
 
-	err := i.DB.QueryRowContext(ctx, query, userId, listId).Scan(
-		&order,
-	)
+func (item *Item) updateArgs() []any {
+	return []any{item.ListId, item.Name, item.Description, item.Quantity, item.QuantityType, item.Price, item.Currency, item.IsStarred, item.File, item.IsDone, item.IsDone, item.CategoryId, item.Order, item.ID, item.Version}
+}
 
-	if err != nil {
-		switch {
-		case errors.Is(err, sql.ErrNoRows):
-			return 1, nil
-		default:
-			return 1, err
-		}
+// isItemDone tells whether the stored item is done, an update which makes it done is a new purchase for the history.
+func isItemDone(ctx context.Context, db *DB, id int64) (bool, error) {
+	var done bool
+	err := db.QueryRowContext(ctx, "SELECT is_done FROM items WHERE id = ?", id).Scan(&done)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return false, err
 	}
-	return order + 1, nil
+	return done, nil
 }
 
-func (i ItemModel) Insert(item *Item) error {
-	var query = "INSERT INTO items (user_id, list_id, name, description, quantity, quantity_type, price, is_starred, file, version, `order`, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, 1, ?, NOW(), NOW())"
-
-	lastOrder, err := i.GetLastItemOrderForUser(item.UserId, item.ListId)
-	if err != nil {
-		return err
+func (i ItemModel) Insert(ctx context.Context, item *Item) error {
+	var err error
+	if item.Currency == "" {
+		item.Currency, err = userCurrency(ctx, i.DB, item.UserId)
+		if err != nil {
+			return err
+		}
 	}
 
-	var args = []any{item.UserId, item.ListId, item.Name, item.Description, item.Quantity, item.QuantityType, item.Price, item.IsStarred, item.File, lastOrder}
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := i.DB.withTimeout(ctx)
 	defer cancel()
-	result, err := i.DB.ExecContext(ctx, query, args...)
-	if err != nil {
-		return err
-	}
-	id, err := result.LastInsertId()
-	if err != nil {
-		return err
-	}
-	item.ID = id
-	item.Order = int32(lastOrder)
-	return nil
+
+	return i.DB.WithTx(ctx, func(tx *DB) error {
+		var lastOrder int32
+		err := tx.QueryRowContext(ctx, itemLastOrder, item.ListId).Scan(&lastOrder)
+		if err != nil {
+			return err
+		}
+		lastOrder++
+		id, err := insert(ctx, tx, itemInsert, item.insertArgs(lastOrder)...)
+		if err != nil {
+			return err
+		}
+		item.ID = id
+		item.Version = 1
+		item.Order = lastOrder
+		if item.IsDone {
+			return recordPurchase(ctx, tx, item.UserId, item)
+		}
+		return nil
+	})
 }
 
-func (i ItemModel) Get(id int64, userId int64) (*Item, error) {
+func (i ItemModel) Get(ctx context.Context, id int64, userId int64) (*Item, error) {
 	if id < 1 {
 		return nil, ErrRecordNotFound
 	}
 
-	var query = "SELECT id, user_id, list_id, name, description, quantity, quantity_type, price, is_starred, file, version, `order`, is_done, created_at, updated_at FROM items WHERE id = ? AND user_id = ?"
+	var query = "SELECT items.id, items.user_id, items.list_id, items.name, items.description, items.quantity, items.quantity_type, items.price_minor, items.currency, items.is_starred, items.file, items.version, items.`order`, items.is_done, COALESCE(items.category_id, 0), items.created_at, items.updated_at FROM items INNER JOIN lists ON lists.id = items.list_id WHERE items.id = ? AND items.deleted_at IS NULL AND " + listReadAccess
 
 	var item Item
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := i.DB.withTimeout(ctx)
 	defer cancel()
 
-	var err = i.DB.QueryRowContext(ctx, query, id, userId).Scan(&item.ID, &item.UserId, &item.ListId, &item.Name, &item.Description, &item.Quantity, &item.QuantityType, &item.Price, &item.IsStarred, &item.File, &item.Version, &item.Order, &item.IsDone, &item.CreatedAt, &item.UpdatedAt)
+	var err = i.DB.QueryRowContext(ctx, query, id, userId, userId).Scan(&item.ID, &item.UserId, &item.ListId, &item.Name, &item.Description, &item.Quantity, &item.QuantityType, &item.Price, &item.Currency, &item.IsStarred, &item.File, &item.Version, &item.Order, &item.IsDone, &item.CategoryId, &item.CreatedAt, &item.UpdatedAt)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -106,160 +128,169 @@ func (i ItemModel) Get(id int64, userId int64) (*Item, error) {
 			return nil, err
 		}
 	}
+	item.SetFileUrls()
 
 	return &item, nil
 }
 
-func (i ItemModel) Update(item *Item, oldOrder int32) error {
-	var _, err = i.DB.Exec("START TRANSACTION")
-	if err != nil {
-		return err
-	}
-	var query = "UPDATE items SET list_id = ?, name = ?, description = ?, quantity = ?, quantity_type = ?, price = ?, is_starred = ?, file = ?, is_done = ?, `order` = ?, version = version + 1, updated_at = NOW() WHERE id = ? AND user_id = ? AND version = ?"
-	var args = []any{
-		item.ListId,
-		item.Name,
-		item.Description,
-		item.Quantity,
-		item.QuantityType,
-		item.Price,
-		item.IsStarred,
-		item.File,
-		item.IsDone,
-		item.Order,
-		item.ID,
-		item.UserId,
-		item.Version,
-	}
-	item.Version++
-	item.UpdatedAt = time.Now()
+// Update saves the item on behalf of userId, who must own the item's list or be one of its editors.
+func (i ItemModel) Update(ctx context.Context, item *Item, oldOrder int32, userId int64) error {
+	var query = itemUpdate + " AND list_id IN (SELECT lists.id FROM lists WHERE " + listWriteAccess + ")"
+	var args = append(item.updateArgs(), userId, userId)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := i.DB.withTimeout(ctx)
 	defer cancel()
 
-	var _, err2 = i.DB.ExecContext(ctx, query, args...)
-	if err2 != nil {
-		i.DB.Exec("ROLLBACK")
-		switch {
-		case errors.Is(err2, sql.ErrNoRows):
-			return ErrEditConflict
-		default:
-			return err2
+	var err = i.DB.WithTx(ctx, func(tx *DB) error {
+		// only an item which becomes done is a new purchase for the history
+		var wasDone bool
+		if item.IsDone {
+			var err error
+			wasDone, err = isItemDone(ctx, tx, item.ID)
+			if err != nil {
+				return err
+			}
 		}
-	}
 
-	if oldOrder != item.Order {
-		var query2 = "UPDATE items SET `order` = items.order+1 WHERE items.order >= ? AND user_id = ? AND id != ?"
-		var _, err3 = i.DB.ExecContext(ctx, query2, item.Order, item.UserId, item.ID)
-		if err3 != nil {
-			i.DB.Exec("ROLLBACK")
-			return err3
+		result, err := tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
 		}
-	}
-
-	_, err = i.DB.Exec("COMMIT")
-	return err
-}
-
-func (i ItemModel) Delete(id int64, userId int64) error {
-	if id < 1 || userId < 1 {
-		return ErrRecordNotFound
-	}
-	item, err := i.Get(id, userId)
-	if err != nil {
-		switch {
-		case errors.Is(err, sql.ErrNoRows):
-			return ErrRecordNotFound
-		default:
+		// a stale version, a deleted item or a list the user may only read
+		err = expectOneRow(result, ErrEditConflict)
+		if err != nil {
 			return err
 		}
-	}
-	var query = "DELETE FROM items WHERE id = ? AND user_id = ?"
-
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
 
-	result, err := i.DB.ExecContext(ctx, query, id, userId)
-	if err != nil {
-		return err
-	}
+		if item.IsDone && !wasDone {
+			err = recordPurchase(ctx, tx, userId, item)
+			if err != nil {
+				return err
+			}
+		}
 
-	rowsAffected, err := result.RowsAffected()
+		if oldOrder != item.Order {
+			var query2 = "UPDATE items SET `order` = items.`order`+1, updated_at = NOW() WHERE items.`order` >= ? AND list_id = ? AND deleted_at IS NULL AND id != ?"
+			_, err = tx.ExecContext(ctx, query2, item.Order, item.ListId, item.ID)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 	if err != nil {
 		return err
 	}
+	item.Version++
+	item.UpdatedAt = time.Now()
+	return nil
+}
 
-	if rowsAffected == 0 {
+// Delete moves the item to the trash, the item and its file are removed for good by Purge.
+func (i ItemModel) Delete(ctx context.Context, id int64, userId int64) error {
+	if id < 1 || userId < 1 {
 		return ErrRecordNotFound
 	}
-	if len(item.File) > 0 {
-		e := os.Remove(item.File)
-		if e != nil {
-			return e
+	var query = "UPDATE items SET deleted_at = NOW() WHERE id = ? AND deleted_at IS NULL AND list_id IN (SELECT lists.id FROM lists WHERE " + listWriteAccess + ")"
+
+	ctx, cancel := i.DB.withTimeout(ctx)
+	defer cancel()
+
+	return i.DB.WithTx(ctx, func(tx *DB) error {
+		var err = recordItemTombstones(ctx, tx, "items.id = ? AND items.deleted_at IS NULL AND "+listWriteAccess, id, userId, userId)
+		if err != nil {
+			return err
 		}
-	}
-	return nil
+
+		result, err := tx.ExecContext(ctx, query, id, userId, userId)
+		if err != nil {
+			return err
+		}
+		return expectOneRow(result, ErrRecordNotFound)
+	})
 }
 
-func (i ItemModel) DeleteByUser(userId int64) error {
+// DeleteByUser deletes the items of the lists the user owns. The items the user added to lists shared by others
+// stay in those lists and are handed over to their owners.
+func (i ItemModel) DeleteByUser(ctx context.Context, userId int64) error {
 	if userId < 1 {
 		return ErrRecordNotFound
 	}
-	var query = "DELETE FROM items WHERE user_id = ?"
+	var query1 = "UPDATE items SET user_id = (SELECT lists.user_id FROM lists WHERE lists.id = items.list_id) WHERE user_id = ? AND list_id NOT IN (SELECT id FROM lists WHERE user_id = ?)"
+	var query2 = "DELETE FROM items WHERE list_id IN (SELECT id FROM lists WHERE user_id = ?)"
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := i.DB.withTimeout(ctx)
 	defer cancel()
 
-	_, err := i.DB.ExecContext(ctx, query, userId)
-	if err != nil {
+	return i.DB.WithTx(ctx, func(tx *DB) error {
+		_, err := tx.ExecContext(ctx, query1, userId, userId)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, query2, userId)
 		return err
-	}
-
-	return nil
+	})
 }
 
-func (i ItemModel) DeleteFromList(userId int64, listId int64, onlyDone bool) error {
+// DeleteFromList moves the items of the list to the trash, only the done ones when onlyDone is set. Like
+// MarkAllAsUndone it changes no rows for an empty list, the callers check that the user can edit the list.
+func (i ItemModel) DeleteFromList(ctx context.Context, userId int64, listId int64, onlyDone bool) error {
 	if userId < 1 || listId < 1 {
 		return ErrRecordNotFound
 	}
-	var query = "DELETE FROM items WHERE user_id = ? AND list_id = ?"
+	var query = "UPDATE items SET deleted_at = NOW() WHERE deleted_at IS NULL AND list_id IN (SELECT lists.id FROM lists WHERE lists.id = ? AND " + listWriteAccess + ")"
 	var args = []any{
-		userId, listId,
+		listId, userId, userId,
 	}
+	var tombstoneWhere = "items.list_id = ? AND " + listWriteAccess + " AND items.deleted_at IS NULL"
 	if onlyDone {
 		query += " AND is_done = ?"
+		tombstoneWhere += " AND items.is_done = ?"
 		args = append(args, onlyDone)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := i.DB.withTimeout(ctx)
 	defer cancel()
 
-	_, err := i.DB.ExecContext(ctx, query, args...)
-	if err != nil {
-		return err
-	}
+	return i.DB.WithTx(ctx, func(tx *DB) error {
+		var err = recordItemTombstones(ctx, tx, tombstoneWhere, args...)
+		if err != nil {
+			return err
+		}
 
-	return nil
+		_, err = tx.ExecContext(ctx, query, args...)
+		return err
+	})
 }
 
-func (i ItemModel) GetAll(name string, userId int64, listId int64, isStarred bool, filters Filters) (Items, Metadata, error) {
+// GetAll returns a page of the items the user can read which match the item filters, of the list listId or of all
+// lists when it is zero. Grouped by category the items are sorted by the order of their categories first and come
+// with their category.
+func (i ItemModel) GetAll(ctx context.Context, userId int64, listId int64, itemFilters ItemFilters, filters Filters) (Items, Metadata, error) {
 	var joinList string
 	var fieldsList string
-	var starredFilter = ""
-	if isStarred {
-		starredFilter = "AND items.is_starred = 1"
-	}
+	var groupOrder string
 	if Contains(filters.Includes, "list") {
 		joinList = "INNER JOIN lists ON items.list_id = lists.id"
-		fieldsList = ", lists.id, lists.folder_id, lists.user_id, lists.name, lists.icon, lists.version, lists.order, lists.link, lists.created_at, lists.updated_at"
+		fieldsList = ", lists.id, lists.folder_id, lists.user_id, lists.name, lists.icon, lists.version, lists.`order`, lists.link, lists.created_at, lists.updated_at"
+	}
+	if filters.Group == GroupByCategory {
+		joinList += " LEFT JOIN categories ON items.category_id = categories.id"
+		fieldsList += ", COALESCE(categories.name, ''), COALESCE(categories.icon, ''), COALESCE(categories.`order`, 0)"
+		groupOrder = "CASE WHEN categories.id IS NULL THEN 1 ELSE 0 END ASC, categories.`order` ASC, categories.id ASC, "
 	}
-	var query = fmt.Sprintf("SELECT COUNT(*) OVER(), items.id, items.user_id, items.list_id, items.name, items.description, items.quantity, items.quantity_type, items.price, items.is_starred, items.file, items.version, items.order, items.is_done, items.created_at, items.updated_at%s FROM items %s WHERE items.user_id = ? AND (items.list_id = ? OR ? = 0) %s AND (MATCH(items.name) AGAINST(? IN NATURAL LANGUAGE MODE) OR ? = '') ORDER BY items.%s %s, items.order ASC LIMIT ? OFFSET ?", fieldsList, joinList, starredFilter, filters.sortColumn(), filters.sortDirection())
+	var where, whereArgs = itemFilters.where(i.DB.Dialect)
+	var page, pageArgs, order = filters.page("items", "cursor_rows.list_id IN (SELECT lists.id FROM lists WHERE "+listMembership+")", userId, userId)
+	var query = fmt.Sprintf("SELECT %s, items.id, items.user_id, items.list_id, items.name, items.description, items.quantity, items.quantity_type, items.price_minor, items.currency, items.is_starred, items.file, items.version, items.`order`, items.is_done, COALESCE(items.category_id, 0), items.created_at, items.updated_at%s FROM items %s WHERE items.deleted_at IS NULL AND items.list_id IN (SELECT lists.id FROM lists WHERE %s) AND (items.list_id = ? OR ? = 0)%s%s ORDER BY %s%s LIMIT ? OFFSET ?", filters.count(), fieldsList, joinList, listReadAccess, where, page, groupOrder, order)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := i.DB.withTimeout(ctx)
 	defer cancel()
 	var emptyMeta Metadata
 
-	rows, err := i.DB.QueryContext(ctx, query, userId, listId, listId, name, name, filters.limit(), filters.offset())
+	var args = append([]any{userId, userId, listId, listId}, whereArgs...)
+	args = append(args, pageArgs...)
+	args = append(args, filters.limit(), filters.offset())
+	rows, err := i.DB.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, emptyMeta, err
 	}
@@ -270,15 +301,24 @@ func (i ItemModel) GetAll(name string, userId int64, listId int64, isStarred boo
 	for rows.Next() {
 		var list List
 		var item Item
+		var category Category
+		var dest = []any{&totalRecords, &item.ID, &item.UserId, &item.ListId, &item.Name, &item.Description, &item.Quantity, &item.QuantityType, &item.Price, &item.Currency, &item.IsStarred, &item.File, &item.Version, &item.Order, &item.IsDone, &item.CategoryId, &item.CreatedAt, &item.UpdatedAt}
 		if Contains(filters.Includes, "list") {
-			err = rows.Scan(&totalRecords, &item.ID, &item.UserId, &item.ListId, &item.Name, &item.Description, &item.Quantity, &item.QuantityType, &item.Price, &item.IsStarred, &item.File, &item.Version, &item.Order, &item.IsDone, &item.CreatedAt, &item.UpdatedAt, &list.ID, &list.UserId, &list.FolderId, &list.Name, &list.Icon, &list.Version, &list.Order, &list.Link, &list.CreatedAt, &list.UpdatedAt)
+			dest = append(dest, &list.ID, &list.UserId, &list.FolderId, &list.Name, &list.Icon, &list.Version, &list.Order, &list.Link, &list.CreatedAt, &list.UpdatedAt)
 			item.List = &list
-		} else {
-			err = rows.Scan(&totalRecords, &item.ID, &item.UserId, &item.ListId, &item.Name, &item.Description, &item.Quantity, &item.QuantityType, &item.Price, &item.IsStarred, &item.File, &item.Version, &item.Order, &item.IsDone, &item.CreatedAt, &item.UpdatedAt)
 		}
+		if filters.Group == GroupByCategory {
+			dest = append(dest, &category.Name, &category.Icon, &category.Order)
+		}
+		err = rows.Scan(dest...)
 		if err != nil {
 			return nil, emptyMeta, err
 		}
+		if item.CategoryId != 0 && filters.Group == GroupByCategory {
+			category.ID = item.CategoryId
+			item.Category = &category
+		}
+		item.SetFileUrls()
 
 		items = append(items, &item)
 	}
@@ -287,19 +327,54 @@ func (i ItemModel) GetAll(name string, userId int64, listId int64, isStarred boo
 		return nil, emptyMeta, err
 	}
 
+	if filters.UsesCursor() {
+		items, metadata := cursorPage(items, filters, func(item *Item) int64 { return item.ID }, listId, "lists")
+		return items, metadata, nil
+	}
 	var metadata = calculateMetadata(totalRecords, filters.Page, filters.Size, listId, "lists")
 
 	return items, metadata, nil
 }
 
-func (i ItemModel) MarkAllAsUndone(listId int64, userId int64) error {
-	var query = "UPDATE items SET is_done = false, version = version + 1, updated_at = NOW() WHERE list_id = ? AND user_id = ?"
+func (i ItemModel) GetUpdatedSince(ctx context.Context, userId int64, since time.Time) (Items, error) {
+	var query = "SELECT items.id, items.user_id, items.list_id, items.name, items.description, items.quantity, items.quantity_type, items.price_minor, items.currency, items.is_starred, items.file, items.version, items.`order`, items.is_done, COALESCE(items.category_id, 0), items.created_at, items.updated_at FROM items WHERE items.deleted_at IS NULL AND items.list_id IN (SELECT lists.id FROM lists WHERE " + listReadAccess + ") AND items.updated_at >= ? ORDER BY items.id ASC"
+
+	ctx, cancel := i.DB.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := i.DB.QueryContext(ctx, query, userId, userId, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items = Items{}
+	for rows.Next() {
+		var item Item
+		err = rows.Scan(&item.ID, &item.UserId, &item.ListId, &item.Name, &item.Description, &item.Quantity, &item.QuantityType, &item.Price, &item.Currency, &item.IsStarred, &item.File, &item.Version, &item.Order, &item.IsDone, &item.CategoryId, &item.CreatedAt, &item.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		item.SetFileUrls()
+		items = append(items, &item)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// MarkAllAsUndone marks every item of the list as not done. A list without items changes no rows, so the callers
+// check that the user can edit the list first.
+func (i ItemModel) MarkAllAsUndone(ctx context.Context, listId int64, userId int64) error {
+	var query = "UPDATE items SET is_done = false, done_at = NULL, version = version + 1, updated_at = NOW() WHERE deleted_at IS NULL AND list_id IN (SELECT lists.id FROM lists WHERE lists.id = ? AND " + listWriteAccess + ")"
 	var args = []any{
 		listId,
 		userId,
+		userId,
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := i.DB.withTimeout(ctx)
 	defer cancel()
 
 	var _, err2 = i.DB.ExecContext(ctx, query, args...)
@@ -314,6 +389,238 @@ func (i ItemModel) MarkAllAsUndone(listId int64, userId int64) error {
 	return nil
 }
 
+// GetTrashed returns the deleted items of the lists the user can edit, the most recently deleted first.
+// Items of a list which is itself in the trash come back together with the list.
+func (i ItemModel) GetTrashed(ctx context.Context, userId int64) (Items, error) {
+	var query = "SELECT items.id, items.user_id, items.list_id, items.name, items.description, items.quantity, items.quantity_type, items.price_minor, items.currency, items.is_starred, items.file, items.version, items.`order`, items.is_done, COALESCE(items.category_id, 0), items.created_at, items.updated_at, items.deleted_at FROM items WHERE items.deleted_at IS NOT NULL AND items.list_id IN (SELECT lists.id FROM lists WHERE " + listWriteAccess + ") ORDER BY items.deleted_at DESC, items.id DESC"
+
+	ctx, cancel := i.DB.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := i.DB.QueryContext(ctx, query, userId, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items = Items{}
+	for rows.Next() {
+		var item Item
+		err = rows.Scan(&item.ID, &item.UserId, &item.ListId, &item.Name, &item.Description, &item.Quantity, &item.QuantityType, &item.Price, &item.Currency, &item.IsStarred, &item.File, &item.Version, &item.Order, &item.IsDone, &item.CategoryId, &item.CreatedAt, &item.UpdatedAt, &item.DeletedAt)
+		if err != nil {
+			return nil, err
+		}
+		item.SetFileUrls()
+		items = append(items, &item)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// Restore takes the item out of the trash on behalf of userId, who must be able to edit the item's list.
+func (i ItemModel) Restore(ctx context.Context, id int64, userId int64) error {
+	if id < 1 || userId < 1 {
+		return ErrRecordNotFound
+	}
+	var query = "UPDATE items SET deleted_at = NULL, version = version + 1, updated_at = NOW() WHERE id = ? AND deleted_at IS NOT NULL AND list_id IN (SELECT lists.id FROM lists WHERE " + listWriteAccess + ")"
+
+	ctx, cancel := i.DB.withTimeout(ctx)
+	defer cancel()
+
+	return i.DB.WithTx(ctx, func(tx *DB) error {
+		result, err := tx.ExecContext(ctx, query, id, userId, userId)
+		if err != nil {
+			return err
+		}
+		err = expectOneRow(result, ErrRecordNotFound)
+		if err != nil {
+			return err
+		}
+		return forgetTombstone(ctx, tx, ItemsType, id)
+	})
+}
+
+// Purge removes the items which stayed in the trash longer than the retention period or belong to a list or a folder
+// that did, it returns the keys of their files to be removed from the storage. It has to run before ListModel.Purge.
+func (i ItemModel) Purge(ctx context.Context, retention time.Duration) (int64, []string, error) {
+	var where = "items.deleted_at < ? OR items.list_id IN (SELECT lists.id FROM lists WHERE lists.deleted_at < ? OR lists.folder_id IN (SELECT folders.id FROM folders WHERE folders.deleted_at < ?))"
+	var before = time.Now().Add(-retention)
+
+	ctx, cancel := i.DB.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := i.DB.QueryContext(ctx, "SELECT items.file FROM items WHERE items.file != '' AND ("+where+")", before, before, before)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer rows.Close()
+
+	var files []string
+	for rows.Next() {
+		var file string
+		err = rows.Scan(&file)
+		if err != nil {
+			return 0, nil, err
+		}
+		files = append(files, file)
+	}
+	if err = rows.Err(); err != nil {
+		return 0, nil, err
+	}
+
+	result, err := i.DB.ExecContext(ctx, "DELETE FROM items WHERE "+where, before, before, before)
+	if err != nil {
+		return 0, nil, err
+	}
+	purged, err := result.RowsAffected()
+	if err != nil {
+		return 0, nil, err
+	}
+	return purged, files, nil
+}
+
+// Reorder changes the order of the items of the list, the caller must check that the user can edit the list.
+func (i ItemModel) Reorder(ctx context.Context, listId int64, reorder Reorder) (Ranks, error) {
+	var scope = rankScope{
+		table:    "items",
+		where:    "list_id = ? AND deleted_at IS NULL",
+		args:     []any{listId},
+		lock:     "SELECT id FROM lists WHERE id = ?",
+		lockArgs: []any{listId},
+	}
+	return scope.reorder(ctx, i.DB, reorder)
+}
+
+// BulkItemOperation is a single change applied by ApplyBulk, Op is one of BulkOpAdd, BulkOpUpdate or BulkOpRemove.
+type BulkItemOperation struct {
+	Op   string
+	Item *Item
+}
+
+const (
+	BulkOpAdd    = "add"
+	BulkOpUpdate = "update"
+	BulkOpRemove = "remove"
+)
+
+// BulkOperationError tells which operation of ApplyBulk failed.
+type BulkOperationError struct {
+	Index int
+	Err   error
+}
+
+func (e *BulkOperationError) Error() string {
+	return fmt.Sprintf("operation %d: %s", e.Index, e.Err)
+}
+
+func (e *BulkOperationError) Unwrap() error {
+	return e.Err
+}
+
+// ApplyBulk applies the operations to the items of the list in a single transaction, either all of them are saved or none.
+// The operations must be validated by the caller, the items of update and remove operations must be loaded from the list.
+// Added items get the next orders of the list, updated items get their version bumped.
+func (i ItemModel) ApplyBulk(ctx context.Context, listId int64, userId int64, operations []BulkItemOperation) error {
+	currency, err := userCurrency(ctx, i.DB, userId)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := i.DB.withTimeout(ctx)
+	defer cancel()
+
+	return i.DB.WithTx(ctx, func(tx *DB) error {
+		var lastOrder int32
+		err = tx.QueryRowContext(ctx, itemLastOrder, listId).Scan(&lastOrder)
+		if err != nil {
+			return err
+		}
+
+		var updateQuery = itemUpdate + " AND list_id = ?"
+		var removeQuery = "UPDATE items SET deleted_at = NOW() WHERE id = ? AND list_id = ? AND deleted_at IS NULL"
+
+		for index, operation := range operations {
+			var item = operation.Item
+			var result sql.Result
+			switch operation.Op {
+			case BulkOpAdd:
+				lastOrder++
+				if item.Currency == "" {
+					item.Currency = currency
+				}
+				item.UserId = userId
+				item.ListId = listId
+				item.ID, err = insert(ctx, tx, itemInsert, item.insertArgs(lastOrder)...)
+				if err == nil {
+					item.Order = lastOrder
+					item.Version = 1
+					item.CreatedAt = time.Now()
+					item.UpdatedAt = time.Now()
+				}
+				if err == nil && item.IsDone {
+					err = recordPurchase(ctx, tx, userId, item)
+				}
+			case BulkOpUpdate:
+				var wasDone bool
+				if item.IsDone {
+					wasDone, err = isItemDone(ctx, tx, item.ID)
+				}
+				if err == nil {
+					result, err = tx.ExecContext(ctx, updateQuery, append(item.updateArgs(), listId)...)
+				}
+				if err == nil {
+					err = expectOneRow(result, ErrEditConflict)
+				}
+				if err == nil && item.IsDone && !wasDone {
+					err = recordPurchase(ctx, tx, userId, item)
+				}
+				if err == nil {
+					item.Version++
+					item.UpdatedAt = time.Now()
+				}
+			case BulkOpRemove:
+				err = recordItemTombstones(ctx, tx, "items.id = ? AND items.list_id = ? AND items.deleted_at IS NULL", item.ID, listId)
+				if err == nil {
+					result, err = tx.ExecContext(ctx, removeQuery, item.ID, listId)
+				}
+				if err == nil {
+					err = expectOneRow(result, ErrRecordNotFound)
+				}
+			default:
+				err = fmt.Errorf("unknown operation %q", operation.Op)
+			}
+			if err != nil {
+				return &BulkOperationError{Index: index, Err: err}
+			}
+		}
+
+		return nil
+	})
+}
+
+func expectOneRow(result sql.Result, notFound error) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return notFound
+	}
+	return nil
+}
+
+// SetFileUrls fills the urls of the item file and its thumbnail, they are served only to users who can read the item.
+func (item *Item) SetFileUrls() {
+	if item.File == "" {
+		item.FileUrl, item.ThumbnailUrl = "", ""
+		return
+	}
+	item.FileUrl = fmt.Sprintf("%s/api/v1/items/%d/file", DomainName, item.ID)
+	item.ThumbnailUrl = fmt.Sprintf("%s/api/v1/items/%d/thumbnail", DomainName, item.ID)
+}
+
 func (item Item) JSONAPILinks() *jsonapi.Links {
 	return &jsonapi.Links{
 		"self": fmt.Sprintf("%s/api/v1/items/%d", DomainName, item.ID),
@@ -342,43 +649,65 @@ func ValidateItem(v *validator.Validator, item *Item) {
 	v.Check(item.Order > 0, "data.attributes.order", "order should be greater then zero")
 	v.Check(item.Quantity >= 0, "data.attributes.quantity", "should be greater then zero")
 	v.Check(item.ListId > 0, "data.attributes.list_id", "should be greater then zero")
+	v.Check(item.Price >= 0, "data.attributes.price", "should not be negative")
+	v.Check(item.Currency == "" || money.ValidCurrency(item.Currency), "data.attributes.currency", "must be a three letter ISO 4217 currency code")
 }
 
 type MockItemModel struct {
 }
 
-func (i MockItemModel) GetLastItemOrderForUser(userId int64, listId int64) (int, error) {
-	return 1, nil
-}
-
-func (i MockItemModel) Insert(item *Item) error {
+func (i MockItemModel) Insert(ctx context.Context, item *Item) error {
 	return nil
 }
 
-func (i MockItemModel) Get(id int64, userId int64) (*Item, error) {
+func (i MockItemModel) Get(ctx context.Context, id int64, userId int64) (*Item, error) {
 	return nil, nil
 }
 
-func (i MockItemModel) Update(item *Item, oldOrder int32) error {
+func (i MockItemModel) Update(ctx context.Context, item *Item, oldOrder int32, userId int64) error {
 	return nil
 }
 
-func (i MockItemModel) Delete(id int64, userId int64) error {
+func (i MockItemModel) Delete(ctx context.Context, id int64, userId int64) error {
 	return nil
 }
 
-func (i MockItemModel) GetAll(name string, userId int64, listId int64, isStarred bool, filters Filters) (Items, Metadata, error) {
+func (i MockItemModel) GetAll(ctx context.Context, userId int64, listId int64, itemFilters ItemFilters, filters Filters) (Items, Metadata, error) {
 	return Items{}, Metadata{}, nil
 }
 
-func (i MockItemModel) DeleteByUser(userId int64) error {
+func (i MockItemModel) DeleteByUser(ctx context.Context, userId int64) error {
 	return nil
 }
 
-func (i MockItemModel) MarkAllAsUndone(listId int64, userId int64) error {
+func (i MockItemModel) MarkAllAsUndone(ctx context.Context, listId int64, userId int64) error {
 	return nil
 }
 
-func (i MockItemModel) DeleteFromList(userId int64, listId int64, onlyDone bool) error {
+func (i MockItemModel) DeleteFromList(ctx context.Context, userId int64, listId int64, onlyDone bool) error {
 	return nil
 }
+
+func (i MockItemModel) GetUpdatedSince(ctx context.Context, userId int64, since time.Time) (Items, error) {
+	return Items{}, nil
+}
+
+func (i MockItemModel) ApplyBulk(ctx context.Context, listId int64, userId int64, operations []BulkItemOperation) error {
+	return nil
+}
+
+func (i MockItemModel) GetTrashed(ctx context.Context, userId int64) (Items, error) {
+	return Items{}, nil
+}
+
+func (i MockItemModel) Restore(ctx context.Context, id int64, userId int64) error {
+	return nil
+}
+
+func (i MockItemModel) Purge(ctx context.Context, retention time.Duration) (int64, []string, error) {
+	return 0, nil, nil
+}
+
+func (i MockItemModel) Reorder(ctx context.Context, listId int64, reorder Reorder) (Ranks, error) {
+	return Ranks{}, nil
+}
@@ -3,6 +3,7 @@ package data
 import (
 	"context"
 	"database/sql"
+	"easylist/internal/money"
 	"easylist/internal/validator"
 	"errors"
 	"fmt"
@@ -11,35 +12,49 @@ import (
 	"time"
 )
 
+const ListsType = "lists"
+
 type List struct {
-	ID         int64     `jsonapi:"primary,lists"`
-	UserId     int64     `json:"-"`
-	FolderId   int64     `jsonapi:"attr,folder_id"`
-	Name       string    `jsonapi:"attr,name"`
-	Icon       string    `jsonapi:"attr,icon"`
-	Link       Link      `jsonapi:"attr,link"`
-	Order      int32     `jsonapi:"attr,order"`
-	Version    int32     `json:"-"`
-	ItemsCount int32     `jsonapi:"attr,items_count,omitempty"`
-	CreatedAt  time.Time `jsonapi:"attr,created_at,iso8601" json:"created_at" time_format:"sql_datetime"`
-	UpdatedAt  time.Time `jsonapi:"attr,updated_at,iso8601" json:"updated_at" time_format:"sql_datetime"`
-	IsPublic   int       `jsonapi:"attr,is_public,omitempty"`
-	Folder     *Folder   `jsonapi:"relation,folder,omitempty"`
-	Items      Items     `jsonapi:"relation,items,omitempty"`
+	ID             int64      `jsonapi:"primary,lists"`
+	UserId         int64      `json:"-"`
+	FolderId       int64      `jsonapi:"attr,folder_id"`
+	Name           string     `jsonapi:"attr,name"`
+	Icon           string     `jsonapi:"attr,icon"`
+	Link           Link       `jsonapi:"attr,link"`
+	Order          int32      `jsonapi:"attr,order"`
+	Version        int32      `json:"-"`
+	ItemsCount     int32      `jsonapi:"attr,items_count,omitempty"`
+	CreatedAt      time.Time  `jsonapi:"attr,created_at,iso8601" json:"created_at" time_format:"sql_datetime"`
+	UpdatedAt      time.Time  `jsonapi:"attr,updated_at,iso8601" json:"updated_at" time_format:"sql_datetime"`
+	DeletedAt      *time.Time `jsonapi:"attr,deleted_at,iso8601,omitempty" json:"-"`
+	Total          int64      `jsonapi:"attr,total"`
+	DoneTotal      int64      `jsonapi:"attr,done_total"`
+	RemainingTotal int64      `jsonapi:"attr,remaining_total"`
+	Currency       string     `jsonapi:"attr,currency"`
+	Budget         *int64     `jsonapi:"attr,budget,omitempty"`
+	BudgetCurrency string     `jsonapi:"attr,budget_currency,omitempty"`
+	IsOverBudget   bool       `jsonapi:"attr,is_over_budget"`
+	IsPublic       int        `jsonapi:"attr,is_public,omitempty"`
+	Role           string     `jsonapi:"attr,role,omitempty"`
+	Folder         *Folder    `jsonapi:"relation,folder,omitempty"`
+	Items          Items      `jsonapi:"relation,items,omitempty"`
 }
 
 type Lists []*List
 
+// listBudget selects the budget of the current lists row, the totals are added by loadTotals.
+const listBudget = "lists.budget_minor, lists.budget_currency"
+
 type ListModel struct {
-	DB *sql.DB
+	DB *DB
 }
 
-func (l *ListModel) GetLastListOrderForUser(userId int64) (int, error) {
-	var query = "SELECT COALESCE(MAX(`order`),0) AS 'order' FROM lists WHERE lists.user_id = ?"
+func (l *ListModel) GetLastListOrderForUser(ctx context.Context, userId int64) (int, error) {
+	var query = "SELECT COALESCE(MAX(`order`),0) FROM lists WHERE lists.user_id = ?"
 
 	var order = 0
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := l.DB.withTimeout(ctx)
 	defer cancel()
 
 	err := l.DB.QueryRowContext(ctx, query, userId).Scan(
@@ -57,10 +72,10 @@ func (l *ListModel) GetLastListOrderForUser(userId int64) (int, error) {
 	return order + 1, nil
 }
 
-func (l ListModel) Insert(list *List) error {
-	var query = "INSERT INTO lists (user_id, folder_id, name, icon, version, `order`, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, NOW(), NOW())"
+func (l ListModel) Insert(ctx context.Context, list *List) error {
+	var query = "INSERT INTO lists (user_id, folder_id, name, icon, budget_minor, budget_currency, version, `order`, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, NOW(), NOW())"
 
-	lastOrder, err := l.GetLastListOrderForUser(list.UserId)
+	lastOrder, err := l.GetLastListOrderForUser(ctx, list.UserId)
 	if err != nil {
 		return err
 	}
@@ -69,27 +84,28 @@ func (l ListModel) Insert(list *List) error {
 	if folderId == 0 {
 		folderId = 1
 	}
-
-	var args = []any{list.UserId, folderId, list.Name, list.Icon, list.Version, lastOrder}
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	result, err := l.DB.ExecContext(ctx, query, args...)
+	err = l.defaultBudgetCurrency(ctx, list)
 	if err != nil {
 		return err
 	}
 
-	id, err := result.LastInsertId()
+	var args = []any{list.UserId, folderId, list.Name, list.Icon, list.Budget, list.BudgetCurrency, list.Version, lastOrder}
+	ctx, cancel := l.DB.withTimeout(ctx)
+	defer cancel()
+
+	id, err := insert(ctx, l.DB, query, args...)
 	if err != nil {
 		return err
 	}
 	list.ID = id
 	list.Order = int32(lastOrder)
 
-	return nil
+	return l.loadTotals(ctx, Lists{list}, list.UserId)
 }
 
-func (l ListModel) GetAll(folderId int64, name string, userId int64, filters Filters) (Lists, Metadata, error) {
+// GetAll returns a page of the lists the user can read which match the list filters, of the folder folderId or of all
+// folders when it is zero.
+func (l ListModel) GetAll(ctx context.Context, folderId int64, userId int64, listFilters ListFilters, filters Filters) (Lists, Metadata, error) {
 	var joinFolder string
 	var fieldsFolder string
 	var groupItems string
@@ -97,26 +113,38 @@ func (l ListModel) GetAll(folderId int64, name string, userId int64, filters Fil
 	var fieldsItems string
 	if Contains(filters.Includes, "folder") {
 		joinFolder = "INNER JOIN folders ON lists.folder_id = folders.id"
-		fieldsFolder = ", folders.id, folders.user_id, folders.name, folders.icon, folders.version, folders.order, folders.created_at, folders.updated_at"
+		fieldsFolder = ", folders.id, folders.user_id, folders.name, folders.icon, folders.version, folders.`order`, folders.created_at, folders.updated_at"
 	}
 	if Contains(filters.Includes, "items") {
-		var q = "SET SESSION group_concat_max_len = 1000000"
-		_, err := l.DB.Exec(q)
-		if err != nil {
-			return nil, Metadata{}, err
+		if l.DB.Dialect == MySQL {
+			var q = "SET SESSION group_concat_max_len = 1000000"
+			_, err := l.DB.Exec(q)
+			if err != nil {
+				return nil, Metadata{}, err
+			}
 		}
-		joinItems = "LEFT JOIN items ON items.list_id = lists.id"
-		fieldsItems = ", (SELECT CONCAT('[',GROUP_CONCAT(JSON_OBJECT('id', items.id, 'user_id', items.user_id, 'ListId', items.list_id, 'name', items.name, 'description', items.description, 'quantity', items.quantity, 'QuantityType', items.quantity_type, 'price', items.price, 'IsStarred', if(items.is_starred = 1, cast(TRUE as json), cast(FALSE as json)), 'file', items.file, 'version', items.version, 'order', items.order, 'created_at', items.created_at, 'updated_at', items.updated_at)),']')) as parsed_items"
+		var d = l.DB.Dialect
+		var item = d.jsonObject("'id'", "items.id", "'user_id'", "items.user_id", "'ListId'", "items.list_id", "'name'", "items.name", "'description'", "items.description", "'quantity'", "items.quantity", "'QuantityType'", "items.quantity_type", "'price'", "items.price_minor", "'currency'", "items.currency", "'IsStarred'", d.jsonBool("items.is_starred"), "'file'", "items.file", "'version'", "items.version", "'order'", "items.`order`", "'created_at'", d.jsonTime("items.created_at"), "'updated_at'", d.jsonTime("items.updated_at"))
+		joinItems = "LEFT JOIN items ON items.list_id = lists.id AND items.deleted_at IS NULL"
+		fieldsItems = ", " + d.jsonArray(item) + " as parsed_items"
 		groupItems = "GROUP BY lists.id"
+		if joinFolder != "" {
+			groupItems += ", folders.id"
+		}
 	}
+	var where, whereArgs = listFilters.where(l.DB.Dialect)
+	var page, pageArgs, order = filters.page("lists", "cursor_rows.id IN (SELECT lists.id FROM lists WHERE "+listMembership+")", userId, userId)
 
-	var query = fmt.Sprintf("SELECT COUNT(*) OVER(), lists.id, lists.user_id, lists.folder_id, lists.name, lists.icon, lists.version, lists.order, lists.link, lists.created_at, lists.updated_at, (SELECT COUNT(*) FROM items WHERE lists.id = items.list_id) AS items_count%s%s FROM lists %s %s WHERE lists.user_id = ? AND (lists.folder_id = ? OR ? = 0) AND (MATCH(lists.name) AGAINST(? IN NATURAL LANGUAGE MODE) OR ? = '') %s ORDER BY lists.`%s` %s, lists.`order` ASC LIMIT ? OFFSET ?", fieldsFolder, fieldsItems, joinFolder, joinItems, groupItems, filters.sortColumn(), filters.sortDirection())
+	var query = fmt.Sprintf("SELECT %s, lists.id, lists.user_id, lists.folder_id, lists.name, lists.icon, lists.version, lists.`order`, lists.link, lists.created_at, lists.updated_at, (SELECT COUNT(*) FROM items WHERE lists.id = items.list_id AND items.deleted_at IS NULL) AS items_count, %s, %s%s%s FROM lists %s %s WHERE %s AND (lists.folder_id = ? OR ? = 0)%s%s %s ORDER BY %s LIMIT ? OFFSET ?", filters.count(), listBudget, listRole, fieldsFolder, fieldsItems, joinFolder, joinItems, listReadAccess, where, page, groupItems, order)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := l.DB.withTimeout(ctx)
 	defer cancel()
 	var emptyMeta Metadata
 
-	rows, err := l.DB.QueryContext(ctx, query, userId, folderId, folderId, name, name, filters.limit(), filters.offset())
+	var args = append([]any{userId, userId, userId, userId, folderId, folderId}, whereArgs...)
+	args = append(args, pageArgs...)
+	args = append(args, filters.limit(), filters.offset())
+	rows, err := l.DB.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, emptyMeta, err
 	}
@@ -131,10 +159,10 @@ func (l ListModel) GetAll(folderId int64, name string, userId int64, filters Fil
 		var items []Item
 		var parsedItems sql.NullString
 		if len(filters.Includes) == 0 {
-			err = rows.Scan(&totalRecords, &list.ID, &list.UserId, &list.FolderId, &list.Name, &list.Icon, &list.Version, &list.Order, &list.Link, &list.CreatedAt, &list.UpdatedAt, &list.ItemsCount)
+			err = rows.Scan(&totalRecords, &list.ID, &list.UserId, &list.FolderId, &list.Name, &list.Icon, &list.Version, &list.Order, &list.Link, &list.CreatedAt, &list.UpdatedAt, &list.ItemsCount, &list.Budget, &list.BudgetCurrency, &list.Role)
 		}
 		if Contains(filters.Includes, "folder") && Contains(filters.Includes, "items") {
-			err = rows.Scan(&totalRecords, &list.ID, &list.UserId, &list.FolderId, &list.Name, &list.Icon, &list.Version, &list.Order, &list.Link, &list.CreatedAt, &list.UpdatedAt, &list.ItemsCount, &folder.ID, &folder.UserId, &folder.Name, &folder.Icon, &folder.Version, &folder.Order, &folder.CreatedAt, &folder.UpdatedAt, &parsedItems)
+			err = rows.Scan(&totalRecords, &list.ID, &list.UserId, &list.FolderId, &list.Name, &list.Icon, &list.Version, &list.Order, &list.Link, &list.CreatedAt, &list.UpdatedAt, &list.ItemsCount, &list.Budget, &list.BudgetCurrency, &list.Role, &folder.ID, &folder.UserId, &folder.Name, &folder.Icon, &folder.Version, &folder.Order, &folder.CreatedAt, &folder.UpdatedAt, &parsedItems)
 
 			list.Folder = &folder
 			if err != nil {
@@ -152,11 +180,11 @@ func (l ListModel) GetAll(folderId int64, name string, userId int64, filters Fil
 			}
 		} else {
 			if Contains(filters.Includes, "folder") {
-				err = rows.Scan(&totalRecords, &list.ID, &list.UserId, &list.FolderId, &list.Name, &list.Icon, &list.Version, &list.Order, &list.Link, &list.CreatedAt, &list.UpdatedAt, &list.ItemsCount, &folder.ID, &folder.UserId, &folder.Name, &folder.Icon, &folder.Version, &folder.Order, &folder.CreatedAt, &folder.UpdatedAt)
+				err = rows.Scan(&totalRecords, &list.ID, &list.UserId, &list.FolderId, &list.Name, &list.Icon, &list.Version, &list.Order, &list.Link, &list.CreatedAt, &list.UpdatedAt, &list.ItemsCount, &list.Budget, &list.BudgetCurrency, &list.Role, &folder.ID, &folder.UserId, &folder.Name, &folder.Icon, &folder.Version, &folder.Order, &folder.CreatedAt, &folder.UpdatedAt)
 				list.Folder = &folder
 			}
 			if Contains(filters.Includes, "items") {
-				err = rows.Scan(&totalRecords, &list.ID, &list.UserId, &list.FolderId, &list.Name, &list.Icon, &list.Version, &list.Order, &list.Link, &list.CreatedAt, &list.UpdatedAt, &list.ItemsCount, &parsedItems)
+				err = rows.Scan(&totalRecords, &list.ID, &list.UserId, &list.FolderId, &list.Name, &list.Icon, &list.Version, &list.Order, &list.Link, &list.CreatedAt, &list.UpdatedAt, &list.ItemsCount, &list.Budget, &list.BudgetCurrency, &list.Role, &parsedItems)
 				if err != nil {
 					return nil, emptyMeta, err
 				}
@@ -183,7 +211,15 @@ func (l ListModel) GetAll(folderId int64, name string, userId int64, filters Fil
 	if err = rows.Err(); err != nil {
 		return nil, emptyMeta, err
 	}
+	err = l.loadTotals(ctx, lists, userId)
+	if err != nil {
+		return nil, emptyMeta, err
+	}
 
+	if filters.UsesCursor() {
+		lists, metadata := cursorPage(lists, filters, func(list *List) int64 { return list.ID }, folderId, "folders")
+		return lists, metadata, nil
+	}
 	var metadata = calculateMetadata(totalRecords, filters.Page, filters.Size, folderId, "folders")
 
 	return lists, metadata, nil
@@ -197,22 +233,25 @@ func parseItems(parsedItems sql.NullString) ([]Item, error) {
 			return tempItems, err
 		}
 	}
+	for i := range tempItems {
+		tempItems[i].SetFileUrls()
+	}
 	return tempItems, nil
 }
 
-func (l ListModel) Get(id int64, userId int64) (*List, error) {
+func (l ListModel) Get(ctx context.Context, id int64, userId int64) (*List, error) {
 	if id < 1 {
 		return nil, ErrRecordNotFound
 	}
 
-	var query = "SELECT id, user_id, folder_id, name, icon, version, `order`, link, created_at, updated_at, (SELECT COUNT(*) FROM items WHERE lists.id = items.list_id) AS items_count FROM lists WHERE id = ? AND user_id = ?"
+	var query = "SELECT lists.id, lists.user_id, lists.folder_id, lists.name, lists.icon, lists.version, lists.`order`, lists.link, lists.created_at, lists.updated_at, (SELECT COUNT(*) FROM items WHERE lists.id = items.list_id AND items.deleted_at IS NULL) AS items_count, " + listBudget + ", " + listRole + " FROM lists WHERE lists.id = ? AND " + listReadAccess
 
 	var list List
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := l.DB.withTimeout(ctx)
 	defer cancel()
 
-	var err = l.DB.QueryRowContext(ctx, query, id, userId).Scan(&list.ID, &list.UserId, &list.FolderId, &list.Name, &list.Icon, &list.Version, &list.Order, &list.Link, &list.CreatedAt, &list.UpdatedAt, &list.ItemsCount)
+	var err = l.DB.QueryRowContext(ctx, query, userId, userId, id, userId, userId).Scan(&list.ID, &list.UserId, &list.FolderId, &list.Name, &list.Icon, &list.Version, &list.Order, &list.Link, &list.CreatedAt, &list.UpdatedAt, &list.ItemsCount, &list.Budget, &list.BudgetCurrency, &list.Role)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -222,22 +261,26 @@ func (l ListModel) Get(id int64, userId int64) (*List, error) {
 		}
 	}
 
+	err = l.loadTotals(ctx, Lists{&list}, userId)
+	if err != nil {
+		return nil, err
+	}
 	return &list, nil
 }
 
-func (l ListModel) GetPublic(link string) (*List, error) {
+func (l ListModel) GetPublic(ctx context.Context, link string) (*List, error) {
 	if link == "" {
 		return nil, ErrRecordNotFound
 	}
 
-	var query = "SELECT id, user_id, folder_id, name, icon, version, `order`, link, created_at, updated_at FROM lists WHERE link = ?"
+	var query = "SELECT lists.id, lists.user_id, lists.folder_id, lists.name, lists.icon, lists.version, lists.`order`, lists.link, lists.created_at, lists.updated_at, " + listBudget + " FROM lists WHERE lists.link = ? AND " + listNotTrashed
 
 	var list List
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := l.DB.withTimeout(ctx)
 	defer cancel()
 
-	var err = l.DB.QueryRowContext(ctx, query, link).Scan(&list.ID, &list.UserId, &list.FolderId, &list.Name, &list.Icon, &list.Version, &list.Order, &list.Link, &list.CreatedAt, &list.UpdatedAt)
+	var err = l.DB.QueryRowContext(ctx, query, link).Scan(&list.ID, &list.UserId, &list.FolderId, &list.Name, &list.Icon, &list.Version, &list.Order, &list.Link, &list.CreatedAt, &list.UpdatedAt, &list.Budget, &list.BudgetCurrency)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -247,103 +290,323 @@ func (l ListModel) GetPublic(link string) (*List, error) {
 		}
 	}
 
+	// public lists are shown in the currency of their owner
+	err = l.loadTotals(ctx, Lists{&list}, list.UserId)
+	if err != nil {
+		return nil, err
+	}
 	return &list, nil
 }
 
-func (l ListModel) Update(list *List, oldOrder int32) error {
-	var _, err = l.DB.Exec("START TRANSACTION")
+func (l ListModel) Update(ctx context.Context, list *List, oldOrder int32) error {
+	var err = l.defaultBudgetCurrency(ctx, list)
 	if err != nil {
 		return err
 	}
-	var query = "UPDATE lists SET name = ?, icon = ?, folder_id = ?, link = ?, `order` = ?, version = version + 1, updated_at = NOW() WHERE id = ? AND user_id = ? AND version = ?"
+	var query = "UPDATE lists SET name = ?, icon = ?, folder_id = ?, link = ?, budget_minor = ?, budget_currency = ?, `order` = ?, version = version + 1, updated_at = NOW() WHERE id = ? AND user_id = ? AND version = ? AND deleted_at IS NULL"
 	var args = []any{
 		list.Name,
 		list.Icon,
 		list.FolderId,
 		list.Link,
+		list.Budget,
+		list.BudgetCurrency,
 		list.Order,
 		list.ID,
 		list.UserId,
 		list.Version,
 	}
+
+	ctx, cancel := l.DB.withTimeout(ctx)
+	defer cancel()
+
+	err = l.DB.WithTx(ctx, func(tx *DB) error {
+		result, err := tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		// a stale version, a deleted list or a list of another user
+		err = expectOneRow(result, ErrEditConflict)
+		if err != nil {
+			return err
+		}
+
+		if oldOrder != list.Order {
+			var query2 = "UPDATE lists SET `order` = lists.`order`+1, updated_at = NOW() WHERE lists.`order` >= ? AND folder_id = ? AND user_id = ? AND deleted_at IS NULL AND id != ?"
+			_, err = tx.ExecContext(ctx, query2, list.Order, list.FolderId, list.UserId, list.ID)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
 	list.Version++
 	list.UpdatedAt = time.Now()
+	return l.loadTotals(ctx, Lists{list}, list.UserId)
+}
+
+// Delete moves the list to the trash, it is removed for good by Purge once the retention period is over.
+func (l ListModel) Delete(ctx context.Context, id int64, userId int64) error {
+	if id < 1 || userId < 1 {
+		return ErrRecordNotFound
+	}
+	var query = "UPDATE lists SET deleted_at = NOW() WHERE id = ? AND user_id = ? AND deleted_at IS NULL"
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := l.DB.withTimeout(ctx)
 	defer cancel()
 
-	var _, err2 = l.DB.ExecContext(ctx, query, args...)
-	if err2 != nil {
-		l.DB.Exec("ROLLBACK")
-		switch {
-		case errors.Is(err2, sql.ErrNoRows):
-			return ErrEditConflict
-		default:
-			return err2
+	return l.DB.WithTx(ctx, func(tx *DB) error {
+		result, err := tx.ExecContext(ctx, query, id, userId)
+		if err != nil {
+			return err
+		}
+		err = expectOneRow(result, ErrRecordNotFound)
+		if err != nil {
+			return err
 		}
+		return recordTombstone(ctx, tx, userId, id, ListsType, id)
+	})
+}
+
+func (l ListModel) DeleteByUser(ctx context.Context, userId int64) error {
+	if userId < 1 {
+		return ErrRecordNotFound
+	}
+	var query = "DELETE FROM lists WHERE user_id = ?"
+
+	ctx, cancel := l.DB.withTimeout(ctx)
+	defer cancel()
+
+	_, err := l.DB.ExecContext(ctx, query, userId)
+	if err != nil {
+		return err
 	}
 
-	if oldOrder != list.Order {
-		var query2 = "UPDATE lists SET `order` = lists.order+1 WHERE lists.order >= ? AND user_id = ? AND id != ?"
-		var _, err3 = l.DB.ExecContext(ctx, query2, list.Order, list.UserId, list.ID)
-		if err3 != nil {
-			l.DB.Exec("ROLLBACK")
-			return err3
+	return nil
+}
+
+func (l ListModel) GetUpdatedSince(ctx context.Context, userId int64, since time.Time) (Lists, error) {
+	var query = "SELECT lists.id, lists.user_id, lists.folder_id, lists.name, lists.icon, lists.version, lists.`order`, lists.link, lists.created_at, lists.updated_at, " + listBudget + ", " + listRole + " FROM lists WHERE " + listReadAccess + " AND lists.updated_at >= ? ORDER BY lists.id ASC"
+
+	ctx, cancel := l.DB.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := l.DB.QueryContext(ctx, query, userId, userId, userId, userId, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lists = Lists{}
+	for rows.Next() {
+		var list List
+		err = rows.Scan(&list.ID, &list.UserId, &list.FolderId, &list.Name, &list.Icon, &list.Version, &list.Order, &list.Link, &list.CreatedAt, &list.UpdatedAt, &list.Budget, &list.BudgetCurrency, &list.Role)
+		if err != nil {
+			return nil, err
 		}
+		lists = append(lists, &list)
 	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	err = l.loadTotals(ctx, lists, userId)
+	if err != nil {
+		return nil, err
+	}
+	return lists, nil
+}
 
-	_, err = l.DB.Exec("COMMIT")
-	return err
+// GetTrashed returns the lists of the user that are in the trash, the most recently deleted first.
+func (l ListModel) GetTrashed(ctx context.Context, userId int64) (Lists, error) {
+	var query = "SELECT lists.id, lists.user_id, lists.folder_id, lists.name, lists.icon, lists.version, lists.`order`, lists.link, lists.created_at, lists.updated_at, lists.deleted_at FROM lists WHERE lists.user_id = ? AND lists.deleted_at IS NOT NULL ORDER BY lists.deleted_at DESC, lists.id DESC"
+
+	ctx, cancel := l.DB.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := l.DB.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lists = Lists{}
+	for rows.Next() {
+		var list List
+		err = rows.Scan(&list.ID, &list.UserId, &list.FolderId, &list.Name, &list.Icon, &list.Version, &list.Order, &list.Link, &list.CreatedAt, &list.UpdatedAt, &list.DeletedAt)
+		if err != nil {
+			return nil, err
+		}
+		list.Role = RoleOwner
+		lists = append(lists, &list)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return lists, nil
 }
 
-func (l ListModel) Delete(id int64, userId int64) error {
+// Restore takes the list out of the trash, only the owner of the list can restore it.
+func (l ListModel) Restore(ctx context.Context, id int64, userId int64) error {
 	if id < 1 || userId < 1 {
 		return ErrRecordNotFound
 	}
-	var query = "DELETE FROM lists WHERE id = ? AND user_id = ?"
+	var query = "UPDATE lists SET deleted_at = NULL, version = version + 1, updated_at = NOW() WHERE id = ? AND user_id = ? AND deleted_at IS NOT NULL"
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := l.DB.withTimeout(ctx)
 	defer cancel()
 
-	result, err := l.DB.ExecContext(ctx, query, id, userId)
+	return l.DB.WithTx(ctx, func(tx *DB) error {
+		result, err := tx.ExecContext(ctx, query, id, userId)
+		if err != nil {
+			return err
+		}
+		err = expectOneRow(result, ErrRecordNotFound)
+		if err != nil {
+			return err
+		}
+
+		// Sync clients dropped the items together with the list, touching them makes the next sync send them again.
+		_, err = tx.ExecContext(ctx, "UPDATE items SET updated_at = NOW() WHERE list_id = ? AND deleted_at IS NULL", id)
+		if err != nil {
+			return err
+		}
+		return forgetTombstone(ctx, tx, ListsType, id)
+	})
+}
+
+// Purge removes the lists which stayed in the trash longer than the retention period or belong to a folder that did.
+// It has to run after ItemModel.Purge and before FolderModel.Purge.
+func (l ListModel) Purge(ctx context.Context, retention time.Duration) (int64, error) {
+	var query = "DELETE FROM lists WHERE deleted_at < ? OR folder_id IN (SELECT folders.id FROM folders WHERE folders.deleted_at < ?)"
+	var before = time.Now().Add(-retention)
+
+	ctx, cancel := l.DB.withTimeout(ctx)
+	defer cancel()
+
+	result, err := l.DB.ExecContext(ctx, query, before, before)
 	if err != nil {
-		return err
+		return 0, err
 	}
+	return result.RowsAffected()
+}
 
-	rowsAffected, err := result.RowsAffected()
+// Reorder changes the order of the lists the user owns in the folder, lists shared with the user keep the order
+// given by their owner.
+func (l ListModel) Reorder(ctx context.Context, folderId int64, userId int64, reorder Reorder) (Ranks, error) {
+	var scope = rankScope{
+		table:    "lists",
+		where:    "folder_id = ? AND user_id = ? AND deleted_at IS NULL",
+		args:     []any{folderId, userId},
+		lock:     "SELECT id FROM users WHERE id = ?",
+		lockArgs: []any{userId},
+	}
+	return scope.reorder(ctx, l.DB, reorder)
+}
+
+// loadTotals sums the items of the lists per currency and converts the sums into the currency of the user, items in a
+// currency without an exchange rate are left out of the totals.
+func (l ListModel) loadTotals(ctx context.Context, lists Lists, userId int64) error {
+	if len(lists) == 0 {
+		return nil
+	}
+	currency, err := userCurrency(ctx, l.DB, userId)
 	if err != nil {
 		return err
 	}
-
-	if rowsAffected == 0 {
-		return ErrRecordNotFound
+	rates, err := loadRates(ctx, l.DB)
+	if err != nil {
+		return err
 	}
-	return nil
-}
 
-func (l ListModel) DeleteByUser(userId int64) error {
-	if userId < 1 {
-		return ErrRecordNotFound
+	var ids = make([]any, 0, len(lists))
+	var byId = make(map[int64]*List, len(lists))
+	for _, list := range lists {
+		ids = append(ids, list.ID)
+		byId[list.ID] = list
+		list.Currency = currency
+		list.Total = 0
+		list.DoneTotal = 0
 	}
-	var query = "DELETE FROM lists WHERE user_id = ?"
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	var query = "SELECT items.list_id, items.currency, COALESCE(SUM(" + itemLineTotal + "), 0), COALESCE(SUM(CASE WHEN items.is_done = TRUE THEN " + itemLineTotal + " ELSE 0 END), 0) FROM items WHERE items.deleted_at IS NULL AND items.list_id IN (" + ConvertSliceToQuestionMarks(ids) + ") GROUP BY items.list_id, items.currency"
+
+	ctx, cancel := l.DB.withTimeout(ctx)
 	defer cancel()
 
-	_, err := l.DB.ExecContext(ctx, query, userId)
+	rows, err := l.DB.QueryContext(ctx, query, ids...)
 	if err != nil {
 		return err
 	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var listId, total, doneTotal int64
+		var itemCurrency string
+		err = rows.Scan(&listId, &itemCurrency, &total, &doneTotal)
+		if err != nil {
+			return err
+		}
+		total, err = rates.Convert(total, itemCurrency, currency)
+		if errors.Is(err, money.ErrUnknownRate) {
+			continue
+		}
+		doneTotal, _ = rates.Convert(doneTotal, itemCurrency, currency)
+		byId[listId].Total += total
+		byId[listId].DoneTotal += doneTotal
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
 
+	for _, list := range lists {
+		list.RemainingTotal = list.Total - list.DoneTotal
+		list.IsOverBudget = false
+		if list.Budget != nil {
+			budget, err := rates.Convert(*list.Budget, list.BudgetCurrency, currency)
+			list.IsOverBudget = err == nil && list.Total > budget
+		}
+	}
 	return nil
 }
 
+// defaultBudgetCurrency sets the currency of a budget which was given without one to the currency of the owner.
+func (l ListModel) defaultBudgetCurrency(ctx context.Context, list *List) error {
+	if list.Budget == nil || list.BudgetCurrency != "" {
+		return nil
+	}
+	var err error
+	list.BudgetCurrency, err = userCurrency(ctx, l.DB, list.UserId)
+	return err
+}
+
+// FormatAmount writes an amount in the currency of the list totals, used by the html templates.
+func (list List) FormatAmount(amount int64) string {
+	return money.Format(amount, list.Currency)
+}
+
+// FormatBudget writes the budget in its own currency, used by the html templates.
+func (list List) FormatBudget() string {
+	if list.Budget == nil {
+		return ""
+	}
+	return money.Format(*list.Budget, list.BudgetCurrency)
+}
+
+func (list List) CanEdit() bool {
+	return list.Role == RoleOwner || list.Role == RoleEditor
+}
+
 func ValidateList(v *validator.Validator, list *List) {
 	v.Check(list.Name != "", "data.attributes.name", "must be provided")
 	v.Check(len(list.Name) <= 190, "data.attributes.name", "must be no more than 190 characters")
 	v.Check(list.Icon == "" || strings.HasPrefix(list.Icon, "mdi-"), "data.attributes.icon", "icon must starts with mdi- prefix")
 	v.Check(list.Order > 0, "data.attributes.order", "order should be greater then zero")
 	v.Check(list.FolderId > 0, "data.attributes.folder_id", "should be greater then zero")
+	v.Check(list.Budget == nil || *list.Budget >= 0, "data.attributes.budget", "should not be negative")
+	v.Check(list.BudgetCurrency == "" || money.ValidCurrency(list.BudgetCurrency), "data.attributes.budget_currency", "must be a three letter ISO 4217 currency code")
 }
 
 func (list List) JSONAPILinks() *jsonapi.Links {
@@ -379,30 +642,50 @@ func (list List) JSONAPIRelationshipLinks(relation string) *jsonapi.Links {
 type MockListModel struct {
 }
 
-func (m MockListModel) Insert(list *List) error {
+func (m MockListModel) Insert(ctx context.Context, list *List) error {
 	return nil
 }
 
-func (m MockListModel) Get(id int64, userId int64) (*List, error) {
+func (m MockListModel) Get(ctx context.Context, id int64, userId int64) (*List, error) {
 	return nil, nil
 }
 
-func (m MockListModel) Update(list *List, oldOrder int32) error {
+func (m MockListModel) Update(ctx context.Context, list *List, oldOrder int32) error {
 	return nil
 }
 
-func (m MockListModel) Delete(id int64, userId int64) error {
+func (m MockListModel) Delete(ctx context.Context, id int64, userId int64) error {
 	return nil
 }
 
-func (m MockListModel) GetAll(folderId int64, name string, userId int64, filters Filters) (Lists, Metadata, error) {
+func (m MockListModel) GetAll(ctx context.Context, folderId int64, userId int64, listFilters ListFilters, filters Filters) (Lists, Metadata, error) {
 	return nil, Metadata{}, nil
 }
 
-func (m MockListModel) DeleteByUser(userId int64) error {
+func (m MockListModel) DeleteByUser(ctx context.Context, userId int64) error {
 	return nil
 }
 
-func (m MockListModel) GetPublic(link string) (*List, error) {
+func (m MockListModel) GetPublic(ctx context.Context, link string) (*List, error) {
 	return nil, nil
 }
+
+func (m MockListModel) GetUpdatedSince(ctx context.Context, userId int64, since time.Time) (Lists, error) {
+	return Lists{}, nil
+}
+
+func (m MockListModel) GetTrashed(ctx context.Context, userId int64) (Lists, error) {
+	return Lists{}, nil
+}
+
+func (m MockListModel) Restore(ctx context.Context, id int64, userId int64) error {
+	return nil
+}
+
+func (m MockListModel) Purge(ctx context.Context, retention time.Duration) (int64, error) {
+	return 0, nil
+}
+
+func (m MockListModel) Reorder(ctx context.Context, folderId int64, userId int64, reorder Reorder) (Ranks, error) {
+	return Ranks{}, nil
+}
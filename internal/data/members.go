@@ -0,0 +1,273 @@
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"easylist/internal/validator"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/google/jsonapi"
+	"time"
+)
+
+const MembersType = "members"
+
+const (
+	RoleOwner  = "owner"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+var ErrDuplicateMember = errors.New("duplicate member")
+
+// listNotTrashed leaves out the lists in the trash and the lists of a folder in the trash.
+const listNotTrashed = "lists.deleted_at IS NULL AND NOT EXISTS (SELECT 1 FROM folders WHERE folders.id = lists.folder_id AND folders.deleted_at IS NOT NULL)"
+
+// listMembership matches the lists the user owns or was invited to, the trash included, expects the user id twice.
+const listMembership = "(lists.user_id = ? OR EXISTS (SELECT 1 FROM list_members WHERE list_members.list_id = lists.id AND list_members.user_id = ? AND list_members.is_accepted = TRUE))"
+
+// listReadAccess and listWriteAccess restrict a query to the lists the user owns or was invited to,
+// lists in the trash are left out. Both expect the user id to be passed twice.
+const listReadAccess = "(" + listNotTrashed + " AND " + listMembership + ")"
+const listWriteAccess = "(" + listNotTrashed + " AND (lists.user_id = ? OR EXISTS (SELECT 1 FROM list_members WHERE list_members.list_id = lists.id AND list_members.user_id = ? AND list_members.is_accepted = TRUE AND list_members.role = 'editor')))"
+
+// listRole selects the role of the user for the current lists row, expects the user id twice.
+const listRole = "CASE WHEN lists.user_id = ? THEN 'owner' ELSE COALESCE((SELECT list_members.role FROM list_members WHERE list_members.list_id = lists.id AND list_members.user_id = ? AND list_members.is_accepted = TRUE), '') END"
+
+type Member struct {
+	ID          int64     `jsonapi:"primary,members"`
+	ListId      int64     `jsonapi:"attr,list_id"`
+	UserId      int64     `jsonapi:"attr,user_id"`
+	Name        string    `jsonapi:"attr,name"`
+	Email       string    `jsonapi:"attr,email"`
+	Role        string    `jsonapi:"attr,role"`
+	IsAccepted  bool      `jsonapi:"attr,is_accepted"`
+	TokenId     int64     `json:"-"`
+	ListOwnerId int64     `json:"-"`
+	CreatedAt   time.Time `jsonapi:"attr,created_at,iso8601"`
+	UpdatedAt   time.Time `jsonapi:"attr,updated_at,iso8601"`
+}
+
+type Members []*Member
+
+type MemberModel struct {
+	DB *DB
+}
+
+func (m MemberModel) Insert(ctx context.Context, member *Member) error {
+	var query = `INSERT INTO list_members (list_id, user_id, role, is_accepted, token_id, created_at, updated_at) VALUES (?, ?, ?, ?, NULLIF(?, 0), NOW(), NOW())`
+	var args = []any{member.ListId, member.UserId, member.Role, member.IsAccepted, member.TokenId}
+
+	ctx, cancel := m.DB.withTimeout(ctx)
+	defer cancel()
+
+	id, err := insert(ctx, m.DB, query, args...)
+	if err != nil {
+		if isDuplicate(err, "list_id") {
+			return ErrDuplicateMember
+		}
+		return err
+	}
+	member.ID = id
+	member.CreatedAt = time.Now()
+	member.UpdatedAt = time.Now()
+	return nil
+}
+
+func (m MemberModel) Get(ctx context.Context, id int64, userId int64) (*Member, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+	var query = `
+	SELECT list_members.id, list_members.list_id, list_members.user_id, users.name, users.email, list_members.role,
+	       list_members.is_accepted, COALESCE(list_members.token_id, 0), lists.user_id, list_members.created_at, list_members.updated_at
+	FROM list_members
+	INNER JOIN users ON users.id = list_members.user_id
+	INNER JOIN lists ON lists.id = list_members.list_id
+	WHERE list_members.id = ? AND (lists.user_id = ? OR list_members.user_id = ?)`
+
+	ctx, cancel := m.DB.withTimeout(ctx)
+	defer cancel()
+
+	var member Member
+	err := m.DB.QueryRowContext(ctx, query, id, userId, userId).Scan(&member.ID, &member.ListId, &member.UserId, &member.Name, &member.Email, &member.Role, &member.IsAccepted, &member.TokenId, &member.ListOwnerId, &member.CreatedAt, &member.UpdatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &member, nil
+}
+
+func (m MemberModel) GetForToken(ctx context.Context, tokenPlaintext string) (*Member, error) {
+	var tokenHash = sha256.Sum256([]byte(tokenPlaintext))
+	var query = `
+	SELECT list_members.id, list_members.list_id, list_members.user_id, users.name, users.email, list_members.role,
+	       list_members.is_accepted, list_members.token_id, lists.user_id, list_members.created_at, list_members.updated_at
+	FROM list_members
+	INNER JOIN users ON users.id = list_members.user_id
+	INNER JOIN lists ON lists.id = list_members.list_id
+	INNER JOIN tokens ON tokens.id = list_members.token_id AND tokens.user_id = list_members.user_id
+	WHERE tokens.hash = ? AND tokens.scope = ? AND tokens.expired_at > ?`
+	var args = []any{hex.EncodeToString(tokenHash[:]), ScopeListInvitation, time.Now()}
+
+	ctx, cancel := m.DB.withTimeout(ctx)
+	defer cancel()
+
+	var member Member
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&member.ID, &member.ListId, &member.UserId, &member.Name, &member.Email, &member.Role, &member.IsAccepted, &member.TokenId, &member.ListOwnerId, &member.CreatedAt, &member.UpdatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &member, nil
+}
+
+func (m MemberModel) GetAllForList(ctx context.Context, listId int64) (Members, error) {
+	var query = `
+	SELECT list_members.id, list_members.list_id, list_members.user_id, users.name, users.email, list_members.role,
+	       list_members.is_accepted, COALESCE(list_members.token_id, 0), lists.user_id, list_members.created_at, list_members.updated_at
+	FROM list_members
+	INNER JOIN users ON users.id = list_members.user_id
+	INNER JOIN lists ON lists.id = list_members.list_id
+	WHERE list_members.list_id = ?
+	ORDER BY list_members.id ASC`
+
+	ctx, cancel := m.DB.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, listId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members Members
+	for rows.Next() {
+		var member Member
+		err = rows.Scan(&member.ID, &member.ListId, &member.UserId, &member.Name, &member.Email, &member.Role, &member.IsAccepted, &member.TokenId, &member.ListOwnerId, &member.CreatedAt, &member.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, &member)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// Update saves the role and the invitation state of the member. An accepted member gets the list again, so the
+// tombstone left by an earlier removal from the list is dropped.
+func (m MemberModel) Update(ctx context.Context, member *Member) error {
+	var query = `UPDATE list_members SET role = ?, is_accepted = ?, token_id = NULLIF(?, 0), updated_at = NOW() WHERE id = ?`
+	var args = []any{member.Role, member.IsAccepted, member.TokenId, member.ID}
+
+	ctx, cancel := m.DB.withTimeout(ctx)
+	defer cancel()
+
+	err := m.DB.WithTx(ctx, func(tx *DB) error {
+		result, err := tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		err = expectOneRow(result, ErrRecordNotFound)
+		if err != nil || !member.IsAccepted {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "DELETE FROM tombstones WHERE user_id = ? AND list_id IS NULL AND record_type = ? AND record_id = ?", member.UserId, ListsType, member.ListId)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	member.UpdatedAt = time.Now()
+	return nil
+}
+
+// Delete removes the member from the list. The tombstone of the list belongs to the member alone and not to the
+// list, so the clients of the member drop the list on the next sync while the other members keep it.
+func (m MemberModel) Delete(ctx context.Context, id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+	var tombstoneQuery = `INSERT INTO tombstones (user_id, list_id, record_type, record_id, deleted_at) SELECT user_id, NULL, '` + ListsType + `', list_id, NOW() FROM list_members WHERE id = ?`
+	var query = `DELETE FROM list_members WHERE id = ?`
+
+	ctx, cancel := m.DB.withTimeout(ctx)
+	defer cancel()
+
+	return m.DB.WithTx(ctx, func(tx *DB) error {
+		_, err := tx.ExecContext(ctx, tombstoneQuery, id)
+		if err != nil {
+			return err
+		}
+		result, err := tx.ExecContext(ctx, query, id)
+		if err != nil {
+			return err
+		}
+		return expectOneRow(result, ErrRecordNotFound)
+	})
+}
+
+// DeleteByUser removes the user from the lists shared with the user and every member from the lists the user owns.
+func (m MemberModel) DeleteByUser(ctx context.Context, userId int64) error {
+	var query = "DELETE FROM list_members WHERE user_id = ? OR list_id IN (SELECT id FROM lists WHERE user_id = ?)"
+
+	ctx, cancel := m.DB.withTimeout(ctx)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, userId, userId)
+	return err
+}
+
+func ValidateMemberRole(v *validator.Validator, role string) {
+	v.Check(role != "", "data.attributes.role", "must be provided")
+	v.Check(validator.In(role, RoleEditor, RoleViewer), "data.attributes.role", "must be editor or viewer")
+}
+
+func (member Member) JSONAPILinks() *jsonapi.Links {
+	return &jsonapi.Links{
+		"self": fmt.Sprintf("%s/api/v1/members/%d", DomainName, member.ID),
+	}
+}
+
+type MockMemberModel struct {
+}
+
+func (m MockMemberModel) Insert(ctx context.Context, member *Member) error {
+	return nil
+}
+
+func (m MockMemberModel) Get(ctx context.Context, id int64, userId int64) (*Member, error) {
+	return nil, nil
+}
+
+func (m MockMemberModel) GetForToken(ctx context.Context, tokenPlaintext string) (*Member, error) {
+	return nil, nil
+}
+
+func (m MockMemberModel) GetAllForList(ctx context.Context, listId int64) (Members, error) {
+	return nil, nil
+}
+
+func (m MockMemberModel) Update(ctx context.Context, member *Member) error {
+	return nil
+}
+
+func (m MockMemberModel) Delete(ctx context.Context, id int64) error {
+	return nil
+}
+
+func (m MockMemberModel) DeleteByUser(ctx context.Context, userId int64) error {
+	return nil
+}
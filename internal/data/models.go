@@ -1,7 +1,8 @@
 package data
 
 import (
-	"database/sql"
+	"context"
+	"easylist/internal/money"
 	"errors"
 	"github.com/liamylian/jsontime"
 	"strings"
@@ -21,7 +22,7 @@ type ComplexModels interface {
 }
 
 type ComplexModel interface {
-	*Folder | *Item | *List | *EmailInput
+	*Folder | *Item | *List | *EmailInput | *Member
 }
 
 type EmailInput struct {
@@ -31,69 +32,207 @@ type EmailInput struct {
 
 type Models struct {
 	Users interface {
-		Insert(user *User) error
-		GetByEmail(email string) (*User, error)
-		Update(user *User) error
-		GetForToken(tokenScope, tokenPlaintext string) (*User, error)
-		Delete(id int64) error
+		Insert(ctx context.Context, user *User) error
+		GetByEmail(ctx context.Context, email string) (*User, error)
+		Update(ctx context.Context, user *User) error
+		GetForToken(ctx context.Context, tokenScope, tokenPlaintext string) (*User, error)
+		Delete(ctx context.Context, id int64) error
 	}
 	Tokens interface {
-		New(userId int64, ttl time.Duration, scope string) (*Token, error)
-		Insert(token *Token) error
-		DeleteAllForUser(scope string, userId int64) error
+		New(ctx context.Context, userId int64, ttl time.Duration, scope string) (*Token, error)
+		Insert(ctx context.Context, token *Token) error
+		DeleteAllForUser(ctx context.Context, scope string, userId int64) error
+		DeleteByUser(ctx context.Context, userId int64) error
+		Delete(ctx context.Context, id int64, userId int64) error
+		DeleteForPlaintext(ctx context.Context, scope string, tokenPlaintext string, userId int64) error
+	}
+	Sessions interface {
+		Start(ctx context.Context, session *Session, accessTtl time.Duration, refreshTtl time.Duration) (*SessionTokens, error)
+		Refresh(ctx context.Context, refreshPlaintext string, accessTtl time.Duration, refreshTtl time.Duration) (*SessionTokens, error)
+		GetForToken(ctx context.Context, tokenPlaintext string) (*Session, error)
+		GetAll(ctx context.Context, userId int64) (Sessions, error)
+		Touch(ctx context.Context, session *Session) error
+		Delete(ctx context.Context, id int64, userId int64) error
+		DeleteAllForUser(ctx context.Context, userId int64, exceptId int64) error
+	}
+	PersonalAccessTokens interface {
+		Insert(ctx context.Context, accessToken *PersonalAccessToken) error
+		GetForToken(ctx context.Context, tokenPlaintext string) (*PersonalAccessToken, error)
+		GetAll(ctx context.Context, userId int64) (PersonalAccessTokens, error)
+		Touch(ctx context.Context, id int64) error
+		Delete(ctx context.Context, id int64, userId int64) error
+	}
+	TwoFactor interface {
+		Get(ctx context.Context, userId int64) (*TwoFactor, error)
+		SetSecret(ctx context.Context, userId int64, secret string) error
+		Enable(ctx context.Context, userId int64, counter int64, recoveryCodes []string) error
+		Disable(ctx context.Context, userId int64) error
+		UseCounter(ctx context.Context, userId int64, counter int64) (bool, error)
+		UseRecoveryCode(ctx context.Context, userId int64, code string) (bool, error)
+	}
+	Identities interface {
+		GetUser(ctx context.Context, provider string, subject string) (*User, error)
+		Insert(ctx context.Context, identity *Identity) error
+		DeleteByUser(ctx context.Context, userId int64) error
+	}
+	OidcAuthorizations interface {
+		Insert(ctx context.Context, authorization *OidcAuthorization) error
+		Consume(ctx context.Context, provider string, state string) (*OidcAuthorization, error)
 	}
 	Permissions interface {
-		GetAllForUser(userId int64) (Permissions, error)
-		AddForUser(userId int64, codes ...string) error
+		GetAllForUser(ctx context.Context, userId int64) (Permissions, error)
+		AddForUser(ctx context.Context, userId int64, codes ...string) error
 	}
 	Folders interface {
-		Insert(folder *Folder) error
-		Get(id int64, userId int64) (*Folder, error)
-		Update(folder *Folder, oldOrder int32) error
-		Delete(id int64, userId int64) error
-		GetAll(name string, userId int64, filters Filters) (Folders, Metadata, error)
-		DeleteByUser(userId int64) error
+		Insert(ctx context.Context, folder *Folder) error
+		Get(ctx context.Context, id int64, userId int64) (*Folder, error)
+		Update(ctx context.Context, folder *Folder, oldOrder int32) error
+		Delete(ctx context.Context, id int64, userId int64) error
+		GetAll(ctx context.Context, name string, userId int64, filters Filters) (Folders, Metadata, error)
+		DeleteByUser(ctx context.Context, userId int64) error
+		GetUpdatedSince(ctx context.Context, userId int64, since time.Time) (Folders, error)
+		GetTrashed(ctx context.Context, userId int64) (Folders, error)
+		Restore(ctx context.Context, id int64, userId int64) error
+		Purge(ctx context.Context, retention time.Duration) (int64, error)
+		Reorder(ctx context.Context, userId int64, reorder Reorder) (Ranks, error)
 	}
 	Lists interface {
-		Insert(list *List) error
-		Get(id int64, userId int64) (*List, error)
-		GetAll(folderId int64, name string, userId int64, filters Filters) (Lists, Metadata, error)
-		Update(list *List, oldOrder int32) error
-		Delete(id int64, userId int64) error
-		DeleteByUser(userId int64) error
-		GetPublic(link string) (*List, error)
+		Insert(ctx context.Context, list *List) error
+		Get(ctx context.Context, id int64, userId int64) (*List, error)
+		GetAll(ctx context.Context, folderId int64, userId int64, listFilters ListFilters, filters Filters) (Lists, Metadata, error)
+		Update(ctx context.Context, list *List, oldOrder int32) error
+		Delete(ctx context.Context, id int64, userId int64) error
+		DeleteByUser(ctx context.Context, userId int64) error
+		GetPublic(ctx context.Context, link string) (*List, error)
+		GetUpdatedSince(ctx context.Context, userId int64, since time.Time) (Lists, error)
+		GetTrashed(ctx context.Context, userId int64) (Lists, error)
+		Restore(ctx context.Context, id int64, userId int64) error
+		Purge(ctx context.Context, retention time.Duration) (int64, error)
+		Reorder(ctx context.Context, folderId int64, userId int64, reorder Reorder) (Ranks, error)
 	}
 	Items interface {
-		Insert(item *Item) error
-		Get(id int64, userId int64) (*Item, error)
-		Update(item *Item, oldOrder int32) error
-		Delete(id int64, userId int64) error
-		GetAll(name string, userId int64, listId int64, isStarred bool, filters Filters) (Items, Metadata, error)
-		DeleteByUser(userId int64) error
-		MarkAllAsUndone(listId int64, userId int64) error
-		DeleteFromList(userId int64, listId int64, onlyDone bool) error
+		Insert(ctx context.Context, item *Item) error
+		Get(ctx context.Context, id int64, userId int64) (*Item, error)
+		Update(ctx context.Context, item *Item, oldOrder int32, userId int64) error
+		Delete(ctx context.Context, id int64, userId int64) error
+		GetAll(ctx context.Context, userId int64, listId int64, itemFilters ItemFilters, filters Filters) (Items, Metadata, error)
+		DeleteByUser(ctx context.Context, userId int64) error
+		MarkAllAsUndone(ctx context.Context, listId int64, userId int64) error
+		DeleteFromList(ctx context.Context, userId int64, listId int64, onlyDone bool) error
+		GetUpdatedSince(ctx context.Context, userId int64, since time.Time) (Items, error)
+		ApplyBulk(ctx context.Context, listId int64, userId int64, operations []BulkItemOperation) error
+		GetTrashed(ctx context.Context, userId int64) (Items, error)
+		Restore(ctx context.Context, id int64, userId int64) error
+		Purge(ctx context.Context, retention time.Duration) (int64, []string, error)
+		Reorder(ctx context.Context, listId int64, reorder Reorder) (Ranks, error)
+	}
+	Members interface {
+		Insert(ctx context.Context, member *Member) error
+		Get(ctx context.Context, id int64, userId int64) (*Member, error)
+		GetForToken(ctx context.Context, tokenPlaintext string) (*Member, error)
+		GetAllForList(ctx context.Context, listId int64) (Members, error)
+		Update(ctx context.Context, member *Member) error
+		Delete(ctx context.Context, id int64) error
+		DeleteByUser(ctx context.Context, userId int64) error
+	}
+	Tombstones interface {
+		GetAllSince(ctx context.Context, userId int64, since time.Time) (Tombstones, error)
+		Purge(ctx context.Context, retention time.Duration) (int64, error)
+	}
+	Templates interface {
+		Insert(ctx context.Context, template *Template) error
+		Get(ctx context.Context, id int64, userId int64) (*Template, error)
+		GetAll(ctx context.Context, userId int64) (Templates, error)
+		GetDue(ctx context.Context, now time.Time) (Templates, error)
+		Update(ctx context.Context, template *Template) error
+		SetNextRun(ctx context.Context, id int64, nextRunAt *time.Time) error
+		Delete(ctx context.Context, id int64, userId int64) error
+		DeleteByUser(ctx context.Context, userId int64) error
 	}
+	Categories interface {
+		Insert(ctx context.Context, category *Category) error
+		Get(ctx context.Context, id int64, userId int64) (*Category, error)
+		GetAll(ctx context.Context, userId int64) (Categories, error)
+		Update(ctx context.Context, category *Category) error
+		Delete(ctx context.Context, id int64, userId int64) error
+		DeleteByUser(ctx context.Context, userId int64) error
+		Reorder(ctx context.Context, userId int64, reorder Reorder) (Ranks, error)
+		Suggest(ctx context.Context, userId int64, name string) (int64, error)
+	}
+	Suggestions interface {
+		GetAll(ctx context.Context, userId int64, prefix string, limit int) (Suggestions, error)
+		DeleteByUser(ctx context.Context, userId int64) error
+	}
+	Search interface {
+		Search(ctx context.Context, userId int64, search SearchQuery, filters Filters) (SearchResults, Metadata, error)
+	}
+	Reports interface {
+		GetSpending(ctx context.Context, userId int64, from time.Time, to time.Time, group string) (SpendingRows, error)
+	}
+	ExchangeRates interface {
+		GetAll(ctx context.Context) (ExchangeRates, error)
+		Replace(ctx context.Context, rates money.Rates) error
+	}
+	db *DB
 }
 
-func NewModels(db *sql.DB) Models {
+func NewModels(db *DB) Models {
 	return Models{
-		Users:       UserModel{DB: db},
-		Tokens:      TokenModel{DB: db},
-		Permissions: PermissionModel{DB: db},
-		Folders:     FolderModel{DB: db},
-		Lists:       ListModel{DB: db},
-		Items:       ItemModel{DB: db},
+		db:                   db,
+		Users:                UserModel{DB: db},
+		Tokens:               TokenModel{DB: db},
+		Sessions:             SessionModel{DB: db},
+		PersonalAccessTokens: PersonalAccessTokenModel{DB: db},
+		TwoFactor:            TwoFactorModel{DB: db},
+		Identities:           IdentityModel{DB: db},
+		OidcAuthorizations:   OidcAuthorizationModel{DB: db},
+		Permissions:          PermissionModel{DB: db},
+		Folders:              FolderModel{DB: db},
+		Lists:                ListModel{DB: db},
+		Items:                ItemModel{DB: db},
+		Members:              MemberModel{DB: db},
+		Tombstones:           TombstoneModel{DB: db},
+		Templates:            TemplateModel{DB: db},
+		Categories:           CategoryModel{DB: db},
+		Suggestions:          SuggestionModel{DB: db},
+		Search:               SearchModel{DB: db},
+		Reports:              ReportModel{DB: db},
+		ExchangeRates:        ExchangeRateModel{DB: db},
+	}
+}
+
+// WithTx runs fn with models whose queries all run in one transaction, it is committed when fn returns nil and rolled
+// back otherwise. The mock models have no database, fn just gets them as they are.
+func (m Models) WithTx(ctx context.Context, fn func(tx Models) error) error {
+	if m.db == nil {
+		return fn(m)
 	}
+	return m.db.WithTx(ctx, func(tx *DB) error {
+		return fn(NewModels(tx))
+	})
 }
 
 func NewMockModels() Models {
 	return Models{
-		Users:       MockUserModel{},
-		Tokens:      MockTokenModel{},
-		Permissions: MockPermissionModel{},
-		Folders:     MockFolderModel{},
-		Lists:       MockListModel{},
-		Items:       MockItemModel{},
+		Users:                MockUserModel{},
+		Tokens:               MockTokenModel{},
+		Sessions:             MockSessionModel{},
+		PersonalAccessTokens: MockPersonalAccessTokenModel{},
+		TwoFactor:            MockTwoFactorModel{},
+		Identities:           MockIdentityModel{},
+		OidcAuthorizations:   MockOidcAuthorizationModel{},
+		Permissions:          MockPermissionModel{},
+		Folders:              MockFolderModel{},
+		Lists:                MockListModel{},
+		Items:                MockItemModel{},
+		Members:              MockMemberModel{},
+		Tombstones:           MockTombstoneModel{},
+		Templates:            MockTemplateModel{},
+		Categories:           MockCategoryModel{},
+		Suggestions:          MockSuggestionModel{},
+		Search:               MockSearchModel{},
+		Reports:              MockReportModel{},
+		ExchangeRates:        MockExchangeRateModel{},
 	}
 }
 
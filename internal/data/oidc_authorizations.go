@@ -0,0 +1,96 @@
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+const OidcAuthorizationsType = "oidc-authorizations"
+
+// OidcAuthorization is a sign in with an OpenID Connect provider waiting for the user to come back with the code.
+// Only the hash of the state is stored, the PKCE verifier and the nonce are needed in plain to finish the sign in.
+type OidcAuthorization struct {
+	ID               int64     `jsonapi:"primary,oidc-authorizations"`
+	Provider         string    `jsonapi:"attr,provider"`
+	State            string    `jsonapi:"attr,state"`
+	AuthorizationUrl string    `jsonapi:"attr,authorization_url"`
+	CodeVerifier     string    `json:"-"`
+	Nonce            string    `json:"-"`
+	Expiry           time.Time `jsonapi:"attr,expiry,iso8601"`
+}
+
+func oidcStateHash(state string) string {
+	var hash = sha256.Sum256([]byte(state))
+	return hex.EncodeToString(hash[:])
+}
+
+type OidcAuthorizationModel struct {
+	DB *DB
+}
+
+// Insert stores the authorization and forgets the ones which have expired.
+func (m OidcAuthorizationModel) Insert(ctx context.Context, authorization *OidcAuthorization) error {
+	var query = "INSERT INTO oidc_authorizations (hash, provider, code_verifier, nonce, expired_at) VALUES (?, ?, ?, ?, ?)"
+
+	ctx, cancel := m.DB.withTimeout(ctx)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, "DELETE FROM oidc_authorizations WHERE expired_at < ?", time.Now())
+	if err != nil {
+		return err
+	}
+	id, err := insert(ctx, m.DB, query, oidcStateHash(authorization.State), authorization.Provider, authorization.CodeVerifier, authorization.Nonce, authorization.Expiry)
+	if err != nil {
+		return err
+	}
+	authorization.ID = id
+	return nil
+}
+
+// Consume returns the authorization of the state and deletes it, so the state can finish one sign in only.
+func (m OidcAuthorizationModel) Consume(ctx context.Context, provider string, state string) (*OidcAuthorization, error) {
+	var query = `
+        SELECT id, provider, code_verifier, nonce, expired_at FROM oidc_authorizations
+        WHERE hash = ? AND provider = ? AND expired_at > ?` + m.DB.Dialect.forUpdate()
+
+	ctx, cancel := m.DB.withTimeout(ctx)
+	defer cancel()
+
+	var authorization = OidcAuthorization{State: state}
+	err := m.DB.WithTx(ctx, func(tx *DB) error {
+		err := tx.QueryRowContext(ctx, query, oidcStateHash(state), provider, time.Now()).Scan(
+			&authorization.ID,
+			&authorization.Provider,
+			&authorization.CodeVerifier,
+			&authorization.Nonce,
+			&authorization.Expiry,
+		)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrRecordNotFound
+			}
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "DELETE FROM oidc_authorizations WHERE id = ?", authorization.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &authorization, nil
+}
+
+type MockOidcAuthorizationModel struct {
+}
+
+func (m MockOidcAuthorizationModel) Insert(ctx context.Context, authorization *OidcAuthorization) error {
+	return nil
+}
+
+func (m MockOidcAuthorizationModel) Consume(ctx context.Context, provider string, state string) (*OidcAuthorization, error) {
+	return nil, ErrRecordNotFound
+}
@@ -2,9 +2,7 @@ package data
 
 import (
 	"context"
-	"database/sql"
 	"strings"
-	"time"
 )
 
 type Permissions []string
@@ -18,11 +16,23 @@ func (p Permissions) Include(code string) bool {
 	return false
 }
 
+// Intersect returns the permissions which are included in both, it limits the permissions of the user to the scopes
+// of a personal access token.
+func (p Permissions) Intersect(other Permissions) Permissions {
+	var permissions = Permissions{}
+	for i := range p {
+		if other.Include(p[i]) {
+			permissions = append(permissions, p[i])
+		}
+	}
+	return permissions
+}
+
 type PermissionModel struct {
-	DB *sql.DB
+	DB *DB
 }
 
-func (p PermissionModel) GetAllForUser(userId int64) (Permissions, error) {
+func (p PermissionModel) GetAllForUser(ctx context.Context, userId int64) (Permissions, error) {
 	var query = `
 	SELECT permissions.code from permissions 
     INNER JOIN users_permissions up on permissions.id = up.permission_id
@@ -30,7 +40,7 @@ func (p PermissionModel) GetAllForUser(userId int64) (Permissions, error) {
 	WHERE u.id = ?
     `
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := p.DB.withTimeout(ctx)
 	defer cancel()
 
 	rows, err := p.DB.QueryContext(ctx, query, userId)
@@ -54,7 +64,7 @@ func (p PermissionModel) GetAllForUser(userId int64) (Permissions, error) {
 	return permissions, nil
 }
 
-func (p PermissionModel) AddForUser(userId int64, codes ...string) error {
+func (p PermissionModel) AddForUser(ctx context.Context, userId int64, codes ...string) error {
 	var permissionMarks []string
 	for range codes {
 		permissionMarks = append(permissionMarks, "?")
@@ -62,9 +72,9 @@ func (p PermissionModel) AddForUser(userId int64, codes ...string) error {
 
 	var query = `
 INSERT INTO users_permissions (user_id, permission_id)
-SELECT ?, permissions.id FROM permissions WHERE permissions.code IN (
+SELECT users.id, permissions.id FROM users, permissions WHERE users.id = ? AND permissions.code IN (
 ` + strings.Join(permissionMarks, ",") + ")"
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := p.DB.withTimeout(ctx)
 	defer cancel()
 	var args = []any{userId}
 	for _, mark := range codes {
@@ -78,10 +88,10 @@ SELECT ?, permissions.id FROM permissions WHERE permissions.code IN (
 type MockPermissionModel struct {
 }
 
-func (p MockPermissionModel) GetAllForUser(userId int64) (Permissions, error) {
+func (p MockPermissionModel) GetAllForUser(ctx context.Context, userId int64) (Permissions, error) {
 	return nil, nil
 }
 
-func (p MockPermissionModel) AddForUser(userId int64, codes ...string) error {
+func (p MockPermissionModel) AddForUser(ctx context.Context, userId int64, codes ...string) error {
 	return nil
 }
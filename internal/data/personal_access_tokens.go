@@ -0,0 +1,277 @@
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"easylist/internal/validator"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+)
+
+const PersonalAccessTokensType = "personal-access-tokens"
+
+// ScopePersonalAccess tokens are created by the user for scripts and integrations, they are limited to a subset of
+// the permissions of the user and optionally to a single list.
+const ScopePersonalAccess = "personal-access"
+
+// PersonalAccessTokenPrefix starts the plaintext of every personal access token, it tells them apart from the
+// authentication tokens and lets secret scanners recognize them.
+const PersonalAccessTokenPrefix = "elp_"
+
+// PersonalAccessToken is a named token of the user, the plaintext is only known right after it is created.
+type PersonalAccessToken struct {
+	ID          int64       `jsonapi:"primary,personal-access-tokens"`
+	TokenId     int64       `json:"-"`
+	UserId      int64       `json:"-"`
+	Plaintext   string      `jsonapi:"attr,token,omitempty"`
+	Name        string      `jsonapi:"attr,name"`
+	Permissions Permissions `jsonapi:"attr,permissions"`
+	ListId      int64       `jsonapi:"attr,list_id,omitempty"`
+	Expiry      time.Time   `jsonapi:"attr,expiry,iso8601"`
+	CreatedAt   time.Time   `jsonapi:"attr,created_at,iso8601"`
+	LastUsedAt  *time.Time  `jsonapi:"attr,last_used_at,iso8601,omitempty"`
+}
+
+type PersonalAccessTokens []*PersonalAccessToken
+
+func ValidatePersonalAccessTokenPlaintext(v *validator.Validator, tokenPlaintext string) {
+	v.Check(strings.HasPrefix(tokenPlaintext, PersonalAccessTokenPrefix), "token", "must start with "+PersonalAccessTokenPrefix)
+	v.Check(len(tokenPlaintext) == len(PersonalAccessTokenPrefix)+26, "token", "must be 30 bytes long")
+}
+
+type PersonalAccessTokenModel struct {
+	DB *DB
+}
+
+// Insert creates the token with its permissions and sets the plaintext of the token.
+func (p PersonalAccessTokenModel) Insert(ctx context.Context, accessToken *PersonalAccessToken) error {
+	token, err := generateToken(accessToken.UserId, time.Until(accessToken.Expiry), ScopePersonalAccess)
+	if err != nil {
+		return err
+	}
+	token.Plaintext = PersonalAccessTokenPrefix + token.Plaintext
+	var hash = sha256.Sum256([]byte(token.Plaintext))
+	token.Hash = hex.EncodeToString(hash[:])
+
+	var query = "INSERT INTO personal_access_tokens (token_id, user_id, name, list_id, created_at) VALUES (?, ?, ?, NULLIF(?, 0), NOW())"
+	var permissionsQuery = `
+INSERT INTO tokens_permissions (token_id, permission_id)
+SELECT ?, permissions.id FROM permissions WHERE permissions.code IN (` + strings.Repeat("?,", len(accessToken.Permissions)-1) + "?)"
+
+	ctx, cancel := p.DB.withTimeout(ctx)
+	defer cancel()
+
+	return p.DB.WithTx(ctx, func(tx *DB) error {
+		err := TokenModel{DB: tx}.Insert(ctx, token)
+		if err != nil {
+			return err
+		}
+		id, err := insert(ctx, tx, query, token.ID, accessToken.UserId, accessToken.Name, accessToken.ListId)
+		if err != nil {
+			return err
+		}
+		var args = []any{token.ID}
+		for _, code := range accessToken.Permissions {
+			args = append(args, code)
+		}
+		_, err = tx.ExecContext(ctx, permissionsQuery, args...)
+		if err != nil {
+			return err
+		}
+		accessToken.ID = id
+		accessToken.TokenId = token.ID
+		accessToken.Plaintext = token.Plaintext
+		accessToken.Expiry = token.Expiry
+		accessToken.CreatedAt = time.Now()
+		return nil
+	})
+}
+
+// GetForToken returns the personal access token of the plaintext unless it has expired.
+func (p PersonalAccessTokenModel) GetForToken(ctx context.Context, tokenPlaintext string) (*PersonalAccessToken, error) {
+	var hash = sha256.Sum256([]byte(tokenPlaintext))
+	var query = `
+        SELECT personal_access_tokens.id, personal_access_tokens.token_id, personal_access_tokens.user_id, personal_access_tokens.name,
+               COALESCE(personal_access_tokens.list_id, 0), tokens.expired_at, personal_access_tokens.created_at, personal_access_tokens.last_used_at
+        FROM personal_access_tokens
+        INNER JOIN tokens
+        ON tokens.id = personal_access_tokens.token_id
+        WHERE tokens.hash = ?
+        AND tokens.scope = ?
+        AND tokens.expired_at > ?`
+
+	ctx, cancel := p.DB.withTimeout(ctx)
+	defer cancel()
+
+	var accessToken PersonalAccessToken
+	err := p.DB.QueryRowContext(ctx, query, hex.EncodeToString(hash[:]), ScopePersonalAccess, time.Now()).Scan(
+		&accessToken.ID,
+		&accessToken.TokenId,
+		&accessToken.UserId,
+		&accessToken.Name,
+		&accessToken.ListId,
+		&accessToken.Expiry,
+		&accessToken.CreatedAt,
+		&accessToken.LastUsedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	err = p.loadPermissions(ctx, PersonalAccessTokens{&accessToken})
+	if err != nil {
+		return nil, err
+	}
+	return &accessToken, nil
+}
+
+// GetAll returns the personal access tokens of the user including the expired ones, the newest first.
+func (p PersonalAccessTokenModel) GetAll(ctx context.Context, userId int64) (PersonalAccessTokens, error) {
+	var query = `
+        SELECT personal_access_tokens.id, personal_access_tokens.token_id, personal_access_tokens.user_id, personal_access_tokens.name,
+               COALESCE(personal_access_tokens.list_id, 0), tokens.expired_at, personal_access_tokens.created_at, personal_access_tokens.last_used_at
+        FROM personal_access_tokens
+        INNER JOIN tokens
+        ON tokens.id = personal_access_tokens.token_id
+        WHERE personal_access_tokens.user_id = ?
+        ORDER BY personal_access_tokens.id DESC`
+
+	ctx, cancel := p.DB.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := p.DB.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var accessTokens = PersonalAccessTokens{}
+	for rows.Next() {
+		var accessToken PersonalAccessToken
+		err = rows.Scan(&accessToken.ID, &accessToken.TokenId, &accessToken.UserId, &accessToken.Name, &accessToken.ListId, &accessToken.Expiry, &accessToken.CreatedAt, &accessToken.LastUsedAt)
+		if err != nil {
+			return nil, err
+		}
+		accessTokens = append(accessTokens, &accessToken)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	err = p.loadPermissions(ctx, accessTokens)
+	if err != nil {
+		return nil, err
+	}
+	return accessTokens, nil
+}
+
+// loadPermissions fills the permissions of the tokens with one query.
+func (p PersonalAccessTokenModel) loadPermissions(ctx context.Context, accessTokens PersonalAccessTokens) error {
+	if len(accessTokens) == 0 {
+		return nil
+	}
+	var byToken = make(map[int64]*PersonalAccessToken, len(accessTokens))
+	var ids []any
+	for _, accessToken := range accessTokens {
+		accessToken.Permissions = Permissions{}
+		byToken[accessToken.TokenId] = accessToken
+		ids = append(ids, accessToken.TokenId)
+	}
+
+	var query = `
+	SELECT tokens_permissions.token_id, permissions.code FROM permissions
+	INNER JOIN tokens_permissions ON permissions.id = tokens_permissions.permission_id
+	WHERE tokens_permissions.token_id IN (` + ConvertSliceToQuestionMarks(ids) + `)
+	ORDER BY permissions.id`
+
+	rows, err := p.DB.QueryContext(ctx, query, ids...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tokenId int64
+		var code string
+		err = rows.Scan(&tokenId, &code)
+		if err != nil {
+			return err
+		}
+		byToken[tokenId].Permissions = append(byToken[tokenId].Permissions, code)
+	}
+	return rows.Err()
+}
+
+// Touch records the time of the last request made with the token.
+func (p PersonalAccessTokenModel) Touch(ctx context.Context, id int64) error {
+	var query = "UPDATE personal_access_tokens SET last_used_at = NOW() WHERE id = ?"
+
+	ctx, cancel := p.DB.withTimeout(ctx)
+	defer cancel()
+
+	_, err := p.DB.ExecContext(ctx, query, id)
+	return err
+}
+
+// Delete revokes the token of the user.
+func (p PersonalAccessTokenModel) Delete(ctx context.Context, id int64, userId int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	ctx, cancel := p.DB.withTimeout(ctx)
+	defer cancel()
+
+	return p.DB.WithTx(ctx, func(tx *DB) error {
+		var tokenId int64
+		err := tx.QueryRowContext(ctx, "SELECT token_id FROM personal_access_tokens WHERE id = ? AND user_id = ?", id, userId).Scan(&tokenId)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrRecordNotFound
+			}
+			return err
+		}
+		for _, query := range []string{
+			"DELETE FROM tokens_permissions WHERE token_id = ?",
+			"DELETE FROM personal_access_tokens WHERE token_id = ?",
+			"DELETE FROM tokens WHERE id = ?",
+		} {
+			_, err = tx.ExecContext(ctx, query, tokenId)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+type MockPersonalAccessTokenModel struct {
+}
+
+func (m MockPersonalAccessTokenModel) Insert(ctx context.Context, accessToken *PersonalAccessToken) error {
+	return nil
+}
+
+func (m MockPersonalAccessTokenModel) GetForToken(ctx context.Context, tokenPlaintext string) (*PersonalAccessToken, error) {
+	return nil, ErrRecordNotFound
+}
+
+func (m MockPersonalAccessTokenModel) GetAll(ctx context.Context, userId int64) (PersonalAccessTokens, error) {
+	return PersonalAccessTokens{}, nil
+}
+
+func (m MockPersonalAccessTokenModel) Touch(ctx context.Context, id int64) error {
+	return nil
+}
+
+func (m MockPersonalAccessTokenModel) Delete(ctx context.Context, id int64, userId int64) error {
+	return nil
+}
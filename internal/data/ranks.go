@@ -0,0 +1,228 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// rankGap is the distance between the orders of neighbours after a rebalance. A move takes the middle of the gap
+// between its new neighbours, so the records of a parent are only renumbered once a gap is used up.
+const rankGap = 1024
+
+var ErrInvalidOrder = errors.New("the order must contain every record exactly once")
+
+// Reorder changes the order of the records of one parent, e.g. the items of a list. Either IDs holds the complete
+// new order or ID is moved right before the record Before or right after the record After.
+type Reorder struct {
+	IDs    []int64
+	ID     int64
+	Before int64
+	After  int64
+}
+
+// Rank is the new order and version of a record changed by a Reorder.
+type Rank struct {
+	ID      int64
+	Order   int32
+	Version int32
+}
+
+type Ranks []Rank
+
+// rankScope selects the records ordered together and the parent row which is locked while they are reordered,
+// so concurrent reorders of the same parent run one after the other.
+type rankScope struct {
+	table    string
+	where    string
+	args     []any
+	lock     string
+	lockArgs []any
+}
+
+func (s rankScope) reorder(ctx context.Context, db *DB, reorder Reorder) (Ranks, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	var ranks Ranks
+	err := db.WithTx(ctx, func(tx *DB) error {
+		var locked int64
+		err := tx.QueryRowContext(ctx, s.lock+tx.Dialect.forUpdate(), s.lockArgs...).Scan(&locked)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrRecordNotFound
+			}
+			return err
+		}
+		if reorder.IDs != nil {
+			ranks, err = s.replace(ctx, tx, reorder.IDs)
+		} else {
+			ranks, err = s.move(ctx, tx, reorder)
+		}
+		return err
+	})
+	return ranks, err
+}
+
+// load returns the records of the scope in their current order.
+func (s rankScope) load(ctx context.Context, tx *DB) (Ranks, error) {
+	var query = fmt.Sprintf("SELECT id, `order`, version FROM %s WHERE %s ORDER BY `order` ASC, id ASC", s.table, s.where)
+	rows, err := tx.QueryContext(ctx, query, s.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ranks Ranks
+	for rows.Next() {
+		var rank Rank
+		err = rows.Scan(&rank.ID, &rank.Order, &rank.Version)
+		if err != nil {
+			return nil, err
+		}
+		ranks = append(ranks, rank)
+	}
+	return ranks, rows.Err()
+}
+
+// replace spreads the records over the orders in the order of ids, only the records whose order changes are saved.
+func (s rankScope) replace(ctx context.Context, tx *DB, ids []int64) (Ranks, error) {
+	current, err := s.load(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) != len(current) {
+		return nil, ErrInvalidOrder
+	}
+	var byId = make(map[int64]Rank, len(current))
+	for _, rank := range current {
+		byId[rank.ID] = rank
+	}
+
+	var changed Ranks
+	for index, id := range ids {
+		rank, found := byId[id]
+		if !found {
+			return nil, ErrInvalidOrder
+		}
+		delete(byId, id)
+
+		var order = int32(index+1) * rankGap
+		if rank.Order == order {
+			continue
+		}
+		rank.Order = order
+		err = s.save(ctx, tx, &rank)
+		if err != nil {
+			return nil, err
+		}
+		changed = append(changed, rank)
+	}
+	return changed, nil
+}
+
+// move puts the record into the gap next to the target, only when there is no gap left the whole scope is renumbered.
+func (s rankScope) move(ctx context.Context, tx *DB, reorder Reorder) (Ranks, error) {
+	var target, after = reorder.Before, false
+	if reorder.After > 0 {
+		target, after = reorder.After, true
+	}
+	if target == reorder.ID {
+		return nil, ErrInvalidOrder
+	}
+
+	var moved Rank
+	var targetOrder int32
+	var query = fmt.Sprintf("SELECT id, `order`, version FROM %s WHERE id = ? AND %s", s.table, s.where)
+	err := tx.QueryRowContext(ctx, query, append([]any{reorder.ID}, s.args...)...).Scan(&moved.ID, &moved.Order, &moved.Version)
+	if err == nil {
+		query = fmt.Sprintf("SELECT `order` FROM %s WHERE id = ? AND %s", s.table, s.where)
+		err = tx.QueryRowContext(ctx, query, append([]any{target}, s.args...)...).Scan(&targetOrder)
+	}
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	// the neighbour on the other side of the gap, records sharing the order of the target leave no gap at all
+	var neighbour sql.NullInt64
+	var ties int
+	var neighbourQuery = "SELECT MAX(`order`) FROM %s WHERE `order` < ? AND id != ? AND %s"
+	if after {
+		neighbourQuery = "SELECT MIN(`order`) FROM %s WHERE `order` > ? AND id != ? AND %s"
+	}
+	var args = append([]any{targetOrder, moved.ID}, s.args...)
+	err = tx.QueryRowContext(ctx, fmt.Sprintf(neighbourQuery, s.table, s.where), args...).Scan(&neighbour)
+	if err != nil {
+		return nil, err
+	}
+	query = fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE `order` = ? AND id NOT IN (?, ?) AND %s", s.table, s.where)
+	err = tx.QueryRowContext(ctx, query, append([]any{targetOrder, moved.ID, target}, s.args...)...).Scan(&ties)
+	if err != nil {
+		return nil, err
+	}
+
+	var order, ok = rankBetween(int64(targetOrder), neighbour, after)
+	if ok && ties == 0 {
+		if moved.Order == order {
+			return Ranks{}, nil
+		}
+		moved.Order = order
+		err = s.save(ctx, tx, &moved)
+		if err != nil {
+			return nil, err
+		}
+		return Ranks{moved}, nil
+	}
+
+	current, err := s.load(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	var ids = make([]int64, 0, len(current))
+	for _, rank := range current {
+		if rank.ID == moved.ID {
+			continue
+		}
+		if rank.ID == target && !after {
+			ids = append(ids, moved.ID)
+		}
+		ids = append(ids, rank.ID)
+		if rank.ID == target && after {
+			ids = append(ids, moved.ID)
+		}
+	}
+	return s.replace(ctx, tx, ids)
+}
+
+// rankBetween returns the order in the middle of the gap between the target and its neighbour, without a neighbour
+// the record goes rankGap after the last or halfway to zero before the first record.
+func rankBetween(target int64, neighbour sql.NullInt64, after bool) (int32, bool) {
+	var order int64
+	switch {
+	case neighbour.Valid:
+		order = (target + neighbour.Int64) / 2
+	case after:
+		order = target + rankGap
+	default:
+		order = target / 2
+	}
+	if order <= 0 || order == target || (neighbour.Valid && order == neighbour.Int64) || order > math.MaxInt32 {
+		return 0, false
+	}
+	return int32(order), true
+}
+
+func (s rankScope) save(ctx context.Context, tx *DB, rank *Rank) error {
+	var query = fmt.Sprintf("UPDATE %s SET `order` = ?, version = version + 1, updated_at = NOW() WHERE id = ?", s.table)
+	_, err := tx.ExecContext(ctx, query, rank.Order, rank.ID)
+	if err != nil {
+		return err
+	}
+	rank.Version++
+	return nil
+}
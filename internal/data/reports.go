@@ -0,0 +1,110 @@
+package data
+
+import (
+	"context"
+	"easylist/internal/money"
+	"errors"
+	"time"
+)
+
+const (
+	SpendingGroupFolder = "folder"
+	SpendingGroupList   = "list"
+	SpendingGroupMonth  = "month"
+)
+
+// SpendingRow is what was spent on the done items of one folder, list or month, in minor units of Currency.
+type SpendingRow struct {
+	ID         string `jsonapi:"primary,spending"`
+	Name       string `jsonapi:"attr,name"`
+	Total      int64  `jsonapi:"attr,total"`
+	Currency   string `jsonapi:"attr,currency"`
+	ItemsCount int32  `jsonapi:"attr,items_count"`
+}
+
+type SpendingRows []*SpendingRow
+
+type ReportModel struct {
+	DB *DB
+}
+
+// GetSpending sums the items marked done between from and to, both days included, grouped by folder, list or month.
+// The sums are converted into the currency of the user, items in a currency without an exchange rate are left out.
+func (r ReportModel) GetSpending(ctx context.Context, userId int64, from time.Time, to time.Time, group string) (SpendingRows, error) {
+	var key, name, join string
+	switch group {
+	case SpendingGroupFolder:
+		key, name = "folders.id", "folders.name"
+		join = "INNER JOIN folders ON folders.id = lists.folder_id"
+	case SpendingGroupMonth:
+		key = r.DB.Dialect.yearMonth("items.done_at")
+		name = key
+	default:
+		key, name = "lists.id", "lists.name"
+	}
+
+	currency, err := userCurrency(ctx, r.DB, userId)
+	if err != nil {
+		return nil, err
+	}
+	rates, err := loadRates(ctx, r.DB)
+	if err != nil {
+		return nil, err
+	}
+
+	var query = "SELECT " + key + " AS spending_key, " + name + " AS spending_name, items.currency, COALESCE(SUM(" + itemLineTotal + "), 0), COUNT(*) FROM items INNER JOIN lists ON lists.id = items.list_id " + join + " WHERE items.deleted_at IS NULL AND items.is_done = TRUE AND items.done_at >= ? AND items.done_at < ? AND " + listReadAccess + " GROUP BY " + key + ", " + name + ", items.currency ORDER BY " + key + " ASC"
+
+	ctx, cancel := r.DB.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.DB.QueryContext(ctx, query, from.Format(time.DateOnly), to.AddDate(0, 0, 1).Format(time.DateOnly), userId, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var spending = SpendingRows{}
+	var byKey = make(map[string]*SpendingRow)
+	for rows.Next() {
+		var key, name, itemCurrency string
+		var total int64
+		var count int32
+		err = rows.Scan(&key, &name, &itemCurrency, &total, &count)
+		if err != nil {
+			return nil, err
+		}
+		total, err = rates.Convert(total, itemCurrency, currency)
+		if errors.Is(err, money.ErrUnknownRate) {
+			continue
+		}
+
+		var row, ok = byKey[key]
+		if !ok {
+			row = &SpendingRow{ID: key, Name: name, Currency: currency}
+			byKey[key] = row
+			spending = append(spending, row)
+		}
+		row.Total += total
+		row.ItemsCount += count
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return spending, nil
+}
+
+// Total is the sum of all rows of the report.
+func (rows SpendingRows) Total() int64 {
+	var total int64
+	for _, row := range rows {
+		total += row.Total
+	}
+	return total
+}
+
+type MockReportModel struct {
+}
+
+func (m MockReportModel) GetSpending(ctx context.Context, userId int64, from time.Time, to time.Time, group string) (SpendingRows, error) {
+	return SpendingRows{}, nil
+}
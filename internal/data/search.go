@@ -0,0 +1,405 @@
+package data
+
+import (
+	"context"
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	SearchByName        = "name"
+	SearchByDescription = "description"
+)
+
+// snippetContext is how many bytes of the text are kept before the first match, snippetLength is the longest snippet.
+const (
+	snippetContext = 40
+	snippetLength  = 160
+)
+
+// SearchTerm is a word or a quoted phrase of the search string. A prefix term matches the words starting with its
+// last word, an excluded term removes the records containing it.
+type SearchTerm struct {
+	Words   []string
+	Prefix  bool
+	Exclude bool
+}
+
+// SearchQuery is the parsed search string, a record matches when it contains every term which is not excluded.
+type SearchQuery []SearchTerm
+
+// SearchResult is a folder, list or item matching the query. The snippet is the matching field cut around the first
+// match and escaped for HTML, the matching words are wrapped in <mark>.
+type SearchResult struct {
+	Type    string
+	ID      int64
+	Field   string
+	Snippet string
+	Record  any
+}
+
+type SearchResults []*SearchResult
+
+type SearchModel struct {
+	DB *DB
+}
+
+// ParseSearch reads the search string. The words are all required, a word ending with * matches the words starting
+// with it, a word starting with - excludes the records containing it and the words in quotes must follow each other.
+// A leading + is accepted as in MySQL and the punctuation inside a word splits it into a phrase, e.g. 2l-milk.
+func ParseSearch(search string) SearchQuery {
+	var query SearchQuery
+	for len(search) > 0 {
+		search = strings.TrimLeftFunc(search, unicode.IsSpace)
+		if search == "" {
+			break
+		}
+		var term SearchTerm
+		switch search[0] {
+		case '-':
+			term.Exclude = true
+			search = search[1:]
+		case '+':
+			search = search[1:]
+		}
+		var token string
+		if strings.HasPrefix(search, `"`) {
+			var end = strings.Index(search[1:], `"`)
+			if end < 0 {
+				token, search = search[1:], ""
+			} else {
+				token, search = search[1:end+1], search[end+2:]
+			}
+		} else {
+			var end = strings.IndexFunc(search, unicode.IsSpace)
+			if end < 0 {
+				end = len(search)
+			}
+			token, search = search[:end], search[end:]
+		}
+		term.Prefix = strings.HasSuffix(token, "*")
+		term.Words = searchWords(strings.ToLower(token))
+		if len(term.Words) > 0 {
+			query = append(query, term)
+		}
+	}
+	return query
+}
+
+// Required reports whether the query has a term a record must contain, a query of only excluded terms is refused.
+func (query SearchQuery) Required() bool {
+	for _, term := range query {
+		if !term.Exclude {
+			return true
+		}
+	}
+	return false
+}
+
+// tsQuery builds the query of to_tsquery, the words are only letters and digits so they need no quoting.
+func (query SearchQuery) tsQuery() string {
+	var terms = make([]string, 0, len(query))
+	for _, term := range query {
+		var phrase = strings.Join(term.Words, " <-> ")
+		if term.Prefix {
+			phrase += ":*"
+		}
+		if term.Exclude {
+			phrase = "!(" + phrase + ")"
+		}
+		terms = append(terms, "("+phrase+")")
+	}
+	return strings.Join(terms, " & ")
+}
+
+// indexed reports whether MySQL has the words of the term in its full-text index.
+func (term SearchTerm) indexed() bool {
+	for _, word := range term.Words {
+		if utf8.RuneCountInString(word) < 3 || mySQLStopwords[word] {
+			return false
+		}
+	}
+	return true
+}
+
+// boolean writes the term in the syntax of the boolean mode of MySQL.
+func (term SearchTerm) boolean() string {
+	if len(term.Words) > 1 {
+		return `+"` + strings.Join(term.Words, " ") + `"`
+	}
+	if term.Prefix {
+		return "+" + term.Words[0] + "*"
+	}
+	return "+" + term.Words[0]
+}
+
+// Search returns the folders, lists and items of the user matching the query, a page at a time. The folders and
+// lists are searched by name, the items by name and description. By relevance the records matching by name come
+// first, the most recently updated first.
+func (s SearchModel) Search(ctx context.Context, userId int64, search SearchQuery, filters Filters) (SearchResults, Metadata, error) {
+	var d = s.DB.Dialect
+	var folderMatch, folderArgs = d.search([]string{"folders.name"}, search)
+	var listMatch, listArgs = d.search([]string{"lists.name"}, search)
+	var itemNameMatch, itemNameArgs = d.search([]string{"items.name"}, search)
+	var itemMatch, itemArgs = d.search([]string{"items.name", "items.description"}, search)
+
+	var order string
+	switch filters.Sort {
+	case "name", "-name":
+		order = "LOWER(results.name) " + filters.sortDirection()
+	case "updated_at", "-updated_at":
+		order = "results.updated_at " + filters.sortDirection()
+	default:
+		order = "results.name_match DESC, results.updated_at DESC"
+	}
+
+	var query = "SELECT COUNT(*) OVER(), results.record_type, results.id, results.name, results.description FROM (" +
+		"SELECT 'folders' AS record_type, folders.id AS id, folders.name AS name, '' AS description, 1 AS name_match, folders.updated_at AS updated_at FROM folders WHERE (folders.user_id = ? OR folders.user_id IS NULL) AND folders.deleted_at IS NULL AND " + folderMatch +
+		" UNION ALL SELECT 'lists', lists.id, lists.name, '', 1, lists.updated_at FROM lists WHERE " + listReadAccess + " AND " + listMatch +
+		" UNION ALL SELECT 'items', items.id, items.name, COALESCE(items.description, ''), CASE WHEN " + itemNameMatch + " THEN 1 ELSE 0 END, items.updated_at FROM items INNER JOIN lists ON lists.id = items.list_id WHERE items.deleted_at IS NULL AND " + listReadAccess + " AND " + itemMatch +
+		") AS results ORDER BY " + order + ", results.record_type ASC, results.id DESC LIMIT ? OFFSET ?"
+
+	var args = append([]any{userId}, folderArgs...)
+	args = append(append(args, userId, userId), listArgs...)
+	args = append(args, itemNameArgs...)
+	args = append(append(args, userId, userId), itemArgs...)
+	args = append(args, filters.limit(), filters.offset())
+
+	ctx, cancel := s.DB.withTimeout(ctx)
+	defer cancel()
+	var emptyMeta Metadata
+
+	rows, err := s.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, emptyMeta, err
+	}
+	defer rows.Close()
+
+	var totalRecords = 0
+	var results = SearchResults{}
+	for rows.Next() {
+		var result SearchResult
+		var name, description string
+		err = rows.Scan(&totalRecords, &result.Type, &result.ID, &name, &description)
+		if err != nil {
+			return nil, emptyMeta, err
+		}
+		// the snippet comes from the name unless only the description matches
+		var snippet, ok = highlight(name, search)
+		result.Field, result.Snippet = SearchByName, snippet
+		if !ok {
+			if snippet, ok = highlight(description, search); ok {
+				result.Field, result.Snippet = SearchByDescription, snippet
+			}
+		}
+		results = append(results, &result)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, emptyMeta, err
+	}
+
+	err = s.loadRecords(ctx, userId, results)
+	if err != nil {
+		return nil, emptyMeta, err
+	}
+	return results, calculateMetadata(totalRecords, filters.Page, filters.Size, 0, ""), nil
+}
+
+// loadRecords sets the records of the results, a record deleted since the search is left out.
+func (s SearchModel) loadRecords(ctx context.Context, userId int64, results SearchResults) error {
+	var ids = map[string][]any{}
+	for _, result := range results {
+		ids[result.Type] = append(ids[result.Type], result.ID)
+	}
+	var records = map[string]map[int64]any{FolderType: {}, ListsType: {}, ItemsType: {}}
+
+	if len(ids[FolderType]) > 0 {
+		var query = "SELECT id, user_id, name, icon, version, `order`, created_at, updated_at FROM folders WHERE deleted_at IS NULL AND id IN (" + ConvertSliceToQuestionMarks(ids[FolderType]) + ")"
+		rows, err := s.DB.QueryContext(ctx, query, ids[FolderType]...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var folder Folder
+			err = rows.Scan(&folder.ID, &folder.UserId, &folder.Name, &folder.Icon, &folder.Version, &folder.Order, &folder.CreatedAt, &folder.UpdatedAt)
+			if err != nil {
+				return err
+			}
+			records[FolderType][folder.ID] = &folder
+		}
+		if err = rows.Err(); err != nil {
+			return err
+		}
+	}
+
+	if len(ids[ListsType]) > 0 {
+		var query = "SELECT lists.id, lists.user_id, lists.folder_id, lists.name, lists.icon, lists.version, lists.`order`, lists.link, lists.created_at, lists.updated_at, (SELECT COUNT(*) FROM items WHERE lists.id = items.list_id AND items.deleted_at IS NULL) AS items_count, " + listBudget + ", " + listRole + " FROM lists WHERE lists.id IN (" + ConvertSliceToQuestionMarks(ids[ListsType]) + ") AND " + listReadAccess
+		var args = append(append([]any{userId, userId}, ids[ListsType]...), userId, userId)
+		rows, err := s.DB.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		var lists = Lists{}
+		for rows.Next() {
+			var list List
+			err = rows.Scan(&list.ID, &list.UserId, &list.FolderId, &list.Name, &list.Icon, &list.Version, &list.Order, &list.Link, &list.CreatedAt, &list.UpdatedAt, &list.ItemsCount, &list.Budget, &list.BudgetCurrency, &list.Role)
+			if err != nil {
+				return err
+			}
+			lists = append(lists, &list)
+			records[ListsType][list.ID] = &list
+		}
+		if err = rows.Err(); err != nil {
+			return err
+		}
+		err = ListModel{DB: s.DB}.loadTotals(ctx, lists, userId)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(ids[ItemsType]) > 0 {
+		var query = "SELECT items.id, items.user_id, items.list_id, items.name, items.description, items.quantity, items.quantity_type, items.price_minor, items.currency, items.is_starred, items.file, items.version, items.`order`, items.is_done, COALESCE(items.category_id, 0), items.created_at, items.updated_at FROM items INNER JOIN lists ON lists.id = items.list_id WHERE items.id IN (" + ConvertSliceToQuestionMarks(ids[ItemsType]) + ") AND items.deleted_at IS NULL AND " + listReadAccess
+		var args = append(append([]any{}, ids[ItemsType]...), userId, userId)
+		rows, err := s.DB.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var item Item
+			err = rows.Scan(&item.ID, &item.UserId, &item.ListId, &item.Name, &item.Description, &item.Quantity, &item.QuantityType, &item.Price, &item.Currency, &item.IsStarred, &item.File, &item.Version, &item.Order, &item.IsDone, &item.CategoryId, &item.CreatedAt, &item.UpdatedAt)
+			if err != nil {
+				return err
+			}
+			item.SetFileUrls()
+			records[ItemsType][item.ID] = &item
+		}
+		if err = rows.Err(); err != nil {
+			return err
+		}
+	}
+
+	for _, result := range results {
+		result.Record = records[result.Type][result.ID]
+	}
+	return nil
+}
+
+// wordSpan is the position of a word of a text, from its first byte to the byte after it.
+type wordSpan struct {
+	start, end int
+}
+
+func wordSpans(text string) []wordSpan {
+	var spans []wordSpan
+	var start = -1
+	for i, r := range text {
+		var isWord = unicode.IsLetter(r) || unicode.IsDigit(r)
+		switch {
+		case isWord && start < 0:
+			start = i
+		case !isWord && start >= 0:
+			spans = append(spans, wordSpan{start: start, end: i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, wordSpan{start: start, end: len(text)})
+	}
+	return spans
+}
+
+// matchAt returns how many words of the text starting with the i-th one match a required term, zero when none does.
+func (query SearchQuery) matchAt(text string, spans []wordSpan, i int) int {
+	var longest = 0
+	for _, term := range query {
+		if term.Exclude || i+len(term.Words) > len(spans) || len(term.Words) <= longest {
+			continue
+		}
+		var matches = true
+		for j, word := range term.Words {
+			var candidate = strings.ToLower(text[spans[i+j].start:spans[i+j].end])
+			var last = j == len(term.Words)-1
+			if candidate != word && !(last && term.Prefix && strings.HasPrefix(candidate, word)) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			longest = len(term.Words)
+		}
+	}
+	return longest
+}
+
+// highlight escapes the text for HTML and wraps the words matching the query in <mark>, a long text is cut to the
+// part around the first match. It reports whether anything matched.
+func highlight(text string, query SearchQuery) (string, bool) {
+	var spans = wordSpans(text)
+	var marks []wordSpan
+	for i := 0; i < len(spans); {
+		var n = query.matchAt(text, spans, i)
+		if n == 0 {
+			i++
+			continue
+		}
+		marks = append(marks, wordSpan{start: spans[i].start, end: spans[i+n-1].end})
+		i += n
+	}
+
+	var from, to = 0, len(text)
+	if len(marks) > 0 && marks[0].start > snippetContext {
+		for _, span := range spans {
+			if span.start >= marks[0].start-snippetContext {
+				from = span.start
+				break
+			}
+		}
+	}
+	if to-from > snippetLength {
+		to = from + snippetLength
+		for i := len(spans) - 1; i >= 0; i-- {
+			if spans[i].end <= to {
+				to = spans[i].end
+				break
+			}
+		}
+		if len(marks) > 0 && to < marks[0].end {
+			to = marks[0].end
+		}
+		for to < len(text) && !utf8.RuneStart(text[to]) {
+			to--
+		}
+	}
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("…")
+	}
+	var position = from
+	for _, mark := range marks {
+		if mark.start < from || mark.end > to {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[position:mark.start]))
+		b.WriteString("<mark>" + html.EscapeString(text[mark.start:mark.end]) + "</mark>")
+		position = mark.end
+	}
+	b.WriteString(html.EscapeString(text[position:to]))
+	if to < len(text) {
+		b.WriteString("…")
+	}
+	return b.String(), len(marks) > 0
+}
+
+type MockSearchModel struct {
+}
+
+func (m MockSearchModel) Search(ctx context.Context, userId int64, search SearchQuery, filters Filters) (SearchResults, Metadata, error) {
+	return SearchResults{}, Metadata{}, nil
+}
@@ -0,0 +1,99 @@
+package data
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSearch(t *testing.T) {
+	tests := []struct {
+		search string
+		want   SearchQuery
+	}{
+		{search: "", want: nil},
+		{search: "Milk", want: SearchQuery{{Words: []string{"milk"}}}},
+		{search: "+mil* -bread", want: SearchQuery{{Words: []string{"mil"}, Prefix: true}, {Words: []string{"bread"}, Exclude: true}}},
+		{search: `"Fresh  milk" 2l-pack`, want: SearchQuery{{Words: []string{"fresh", "milk"}}, {Words: []string{"2l", "pack"}}}},
+		{search: `-"oat milk`, want: SearchQuery{{Words: []string{"oat", "milk"}, Exclude: true}}},
+		{search: "- * !!", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.search, func(t *testing.T) {
+			if got := ParseSearch(tt.search); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseSearch(%q) = %+v; want %+v", tt.search, got, tt.want)
+			}
+		})
+	}
+
+	if ParseSearch("-bread").Required() {
+		t.Errorf("want a query of only excluded terms not to be required")
+	}
+}
+
+func TestSearch(t *testing.T) {
+	var query = ParseSearch(`mil* "of tea" -bread`)
+	var columns = []string{"items.name", "items.description"}
+	tests := []struct {
+		dialect   Dialect
+		condition string
+		args      []any
+	}{
+		{
+			dialect:   MySQL,
+			condition: "(MATCH(items.name, items.description) AGAINST(? IN BOOLEAN MODE) AND (CONCAT(' ', COALESCE(items.name, '')) LIKE ? ESCAPE '!' OR CONCAT(' ', COALESCE(items.description, '')) LIKE ? ESCAPE '!') AND NOT (CONCAT(' ', COALESCE(items.name, '')) LIKE ? ESCAPE '!' OR CONCAT(' ', COALESCE(items.description, '')) LIKE ? ESCAPE '!'))",
+			args:      []any{"+mil*", "% of tea%", "% of tea%", "% bread%", "% bread%"},
+		},
+		{
+			dialect:   Postgres,
+			condition: "(to_tsvector('simple', items.name || ' ' || COALESCE(items.description, '')) @@ to_tsquery('simple', ?))",
+			args:      []any{"(mil:*) & (of <-> tea) & (!(bread))"},
+		},
+		{
+			dialect:   SQLite,
+			condition: "((' ' || COALESCE(items.name, '') LIKE ? ESCAPE '!' OR ' ' || COALESCE(items.description, '') LIKE ? ESCAPE '!') AND (' ' || COALESCE(items.name, '') LIKE ? ESCAPE '!' OR ' ' || COALESCE(items.description, '') LIKE ? ESCAPE '!') AND NOT (' ' || COALESCE(items.name, '') LIKE ? ESCAPE '!' OR ' ' || COALESCE(items.description, '') LIKE ? ESCAPE '!'))",
+			args:      []any{"% mil%", "% mil%", "% of tea%", "% of tea%", "% bread%", "% bread%"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.dialect), func(t *testing.T) {
+			condition, args := tt.dialect.search(columns, query)
+			if condition != tt.condition {
+				t.Errorf("search() condition = %s; want %s", condition, tt.condition)
+			}
+			if !reflect.DeepEqual(args, tt.args) {
+				t.Errorf("search() args = %v; want %v", args, tt.args)
+			}
+		})
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		text   string
+		search string
+		want   string
+		ok     bool
+	}{
+		{text: "Milk & cookies", search: "milk", want: "<mark>Milk</mark> &amp; cookies", ok: true},
+		{text: "Buttermilk, milky way", search: "milk*", want: "Buttermilk, <mark>milky</mark> way", ok: true},
+		{text: "A cup of tea", search: `"of tea" cup -milk`, want: "A <mark>cup</mark> <mark>of tea</mark>", ok: true},
+		{text: "Bread", search: "milk", want: "Bread", ok: false},
+		{
+			text:   "Take the long road past the bakery and the old church, then at the corner buy some fresh milk for breakfast and something sweet for the kids who always ask for more on the way back home across the river and over the old stone bridge",
+			search: "milk",
+			want:   "…then at the corner buy some fresh <mark>milk</mark> for breakfast and something sweet for the kids who always ask for more on the way back home across the river and over the…",
+			ok:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.search, func(t *testing.T) {
+			got, ok := highlight(tt.text, ParseSearch(tt.search))
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("highlight() = %q, %v; want %q, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
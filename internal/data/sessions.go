@@ -0,0 +1,309 @@
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+const SessionsType = "sessions"
+
+// ErrRefreshTokenReused is returned when a refresh token is exchanged for the second time. Only the client which
+// took the token out of the session could do that, so the whole session is revoked.
+var ErrRefreshTokenReused = errors.New("refresh token reused")
+
+// Session is a device signed in with the password, its authentication and refresh tokens are revoked together.
+// The ip and the user agent are the ones of the last request made with the session.
+type Session struct {
+	ID         int64     `jsonapi:"primary,sessions"`
+	UserId     int64     `json:"-"`
+	DeviceName string    `jsonapi:"attr,device_name"`
+	Ip         string    `jsonapi:"attr,ip"`
+	UserAgent  string    `jsonapi:"attr,user_agent"`
+	IsCurrent  bool      `jsonapi:"attr,is_current"`
+	CreatedAt  time.Time `jsonapi:"attr,created_at,iso8601"`
+	LastUsedAt time.Time `jsonapi:"attr,last_used_at,iso8601"`
+}
+
+type Sessions []*Session
+
+// SessionTokens is the pair of tokens handed out on sign in and on every refresh, the id is the one of the
+// authentication token.
+type SessionTokens struct {
+	ID            int64     `jsonapi:"primary,tokens"`
+	Token         string    `jsonapi:"attr,token"`
+	Expiry        time.Time `jsonapi:"attr,expiry"`
+	RefreshToken  string    `jsonapi:"attr,refresh_token"`
+	RefreshExpiry time.Time `jsonapi:"attr,refresh_expiry"`
+	SessionId     int64     `jsonapi:"attr,session_id"`
+}
+
+type SessionModel struct {
+	DB *DB
+}
+
+// Start saves the session and issues its first pair of tokens.
+func (s SessionModel) Start(ctx context.Context, session *Session, accessTtl time.Duration, refreshTtl time.Duration) (*SessionTokens, error) {
+	var query = "INSERT INTO sessions (user_id, device_name, ip, user_agent, created_at, last_used_at) VALUES (?, ?, ?, ?, NOW(), NOW())"
+
+	ctx, cancel := s.DB.withTimeout(ctx)
+	defer cancel()
+
+	var tokens *SessionTokens
+	err := s.DB.WithTx(ctx, func(tx *DB) error {
+		id, err := insert(ctx, tx, query, session.UserId, session.DeviceName, session.Ip, session.UserAgent)
+		if err != nil {
+			return err
+		}
+		session.ID = id
+		tokens, err = issueSessionTokens(ctx, tx, session.UserId, session.ID, accessTtl, refreshTtl)
+		return err
+	})
+	return tokens, err
+}
+
+// Refresh exchanges the refresh token for a new pair of tokens of the same session, the refresh token can be used
+// only once. Exchanging it again revokes the session and returns ErrRefreshTokenReused.
+func (s SessionModel) Refresh(ctx context.Context, refreshPlaintext string, accessTtl time.Duration, refreshTtl time.Duration) (*SessionTokens, error) {
+	var hash = sha256.Sum256([]byte(refreshPlaintext))
+
+	ctx, cancel := s.DB.withTimeout(ctx)
+	defer cancel()
+
+	var tokens *SessionTokens
+	var sessionId int64
+	err := s.DB.WithTx(ctx, func(tx *DB) error {
+		var id, userId int64
+		var usedAt sql.NullTime
+		var query = "SELECT id, user_id, session_id, used_at FROM tokens WHERE hash = ? AND scope = ? AND expired_at > ?" + tx.Dialect.forUpdate()
+		err := tx.QueryRowContext(ctx, query, hex.EncodeToString(hash[:]), ScopeRefresh, time.Now()).Scan(&id, &userId, &sessionId, &usedAt)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrRecordNotFound
+			}
+			return err
+		}
+		if usedAt.Valid {
+			return ErrRefreshTokenReused
+		}
+
+		result, err := tx.ExecContext(ctx, "UPDATE tokens SET used_at = NOW() WHERE id = ? AND used_at IS NULL", id)
+		if err != nil {
+			return err
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return ErrRefreshTokenReused
+		}
+
+		_, err = tx.ExecContext(ctx, "UPDATE sessions SET last_used_at = NOW() WHERE id = ?", sessionId)
+		if err != nil {
+			return err
+		}
+		tokens, err = issueSessionTokens(ctx, tx, userId, sessionId, accessTtl, refreshTtl)
+		return err
+	})
+	if errors.Is(err, ErrRefreshTokenReused) {
+		if revokeErr := revokeSessions(ctx, s.DB, "id = ?", sessionId); revokeErr != nil {
+			return nil, revokeErr
+		}
+	}
+	return tokens, err
+}
+
+// GetForToken returns the session of the authentication token, the expiry of the token is checked by
+// UserModel.GetForToken. The tokens created before the sessions have none.
+func (s SessionModel) GetForToken(ctx context.Context, tokenPlaintext string) (*Session, error) {
+	var hash = sha256.Sum256([]byte(tokenPlaintext))
+	var query = `
+        SELECT sessions.id, sessions.user_id, sessions.device_name, sessions.ip, sessions.user_agent, sessions.created_at, sessions.last_used_at
+        FROM sessions
+        INNER JOIN tokens
+        ON sessions.id = tokens.session_id
+        WHERE tokens.hash = ?
+        AND tokens.scope = ?`
+
+	ctx, cancel := s.DB.withTimeout(ctx)
+	defer cancel()
+
+	var session Session
+	err := s.DB.QueryRowContext(ctx, query, hex.EncodeToString(hash[:]), ScopeAuthentication).Scan(
+		&session.ID,
+		&session.UserId,
+		&session.DeviceName,
+		&session.Ip,
+		&session.UserAgent,
+		&session.CreatedAt,
+		&session.LastUsedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &session, nil
+}
+
+// GetAll returns the sessions of the user which can still be refreshed, the recently used first.
+func (s SessionModel) GetAll(ctx context.Context, userId int64) (Sessions, error) {
+	var query = `
+        SELECT id, user_id, device_name, ip, user_agent, created_at, last_used_at
+        FROM sessions
+        WHERE user_id = ?
+        AND EXISTS (SELECT 1 FROM tokens WHERE tokens.session_id = sessions.id AND tokens.scope = ? AND tokens.used_at IS NULL AND tokens.expired_at > ?)
+        ORDER BY last_used_at DESC, id DESC`
+
+	ctx, cancel := s.DB.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.DB.QueryContext(ctx, query, userId, ScopeRefresh, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions = Sessions{}
+	for rows.Next() {
+		var session Session
+		err = rows.Scan(&session.ID, &session.UserId, &session.DeviceName, &session.Ip, &session.UserAgent, &session.CreatedAt, &session.LastUsedAt)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, &session)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// Touch records the ip, the user agent and the time of the last request made with the session.
+func (s SessionModel) Touch(ctx context.Context, session *Session) error {
+	var query = "UPDATE sessions SET ip = ?, user_agent = ?, last_used_at = NOW() WHERE id = ?"
+
+	ctx, cancel := s.DB.withTimeout(ctx)
+	defer cancel()
+
+	_, err := s.DB.ExecContext(ctx, query, session.Ip, session.UserAgent, session.ID)
+	return err
+}
+
+// Delete signs the device out, all tokens of the session are revoked.
+func (s SessionModel) Delete(ctx context.Context, id int64, userId int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	ctx, cancel := s.DB.withTimeout(ctx)
+	defer cancel()
+
+	return s.DB.WithTx(ctx, func(tx *DB) error {
+		var sessionId int64
+		err := tx.QueryRowContext(ctx, "SELECT id FROM sessions WHERE id = ? AND user_id = ?", id, userId).Scan(&sessionId)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrRecordNotFound
+			}
+			return err
+		}
+		return revokeSessions(ctx, tx, "id = ?", sessionId)
+	})
+}
+
+// DeleteAllForUser signs out every device of the user except the session with exceptId, zero signs out all of them.
+func (s SessionModel) DeleteAllForUser(ctx context.Context, userId int64, exceptId int64) error {
+	ctx, cancel := s.DB.withTimeout(ctx)
+	defer cancel()
+
+	return s.DB.WithTx(ctx, func(tx *DB) error {
+		return revokeSessions(ctx, tx, "user_id = ? AND id <> ?", userId, exceptId)
+	})
+}
+
+// revokeSessions deletes the sessions matching the condition together with their tokens. The tokens are deleted
+// first as MySQL and SQLite do not cascade the deletion of the sessions to them.
+func revokeSessions(ctx context.Context, db *DB, condition string, args ...any) error {
+	_, err := db.ExecContext(ctx, "DELETE FROM tokens WHERE session_id IN (SELECT id FROM sessions WHERE "+condition+")", args...)
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx, "DELETE FROM sessions WHERE "+condition, args...)
+	return err
+}
+
+// issueSessionTokens inserts a new pair of an authentication and a refresh token of the session.
+func issueSessionTokens(ctx context.Context, tx *DB, userId int64, sessionId int64, accessTtl time.Duration, refreshTtl time.Duration) (*SessionTokens, error) {
+	var tokens = TokenModel{DB: tx}
+	access, err := generateToken(userId, accessTtl, ScopeAuthentication)
+	if err != nil {
+		return nil, err
+	}
+	access.SessionId = sessionId
+	if err = tokens.Insert(ctx, access); err != nil {
+		return nil, err
+	}
+	refresh, err := generateToken(userId, refreshTtl, ScopeRefresh)
+	if err != nil {
+		return nil, err
+	}
+	refresh.SessionId = sessionId
+	if err = tokens.Insert(ctx, refresh); err != nil {
+		return nil, err
+	}
+	return &SessionTokens{
+		ID:            access.ID,
+		Token:         access.Plaintext,
+		Expiry:        access.Expiry,
+		RefreshToken:  refresh.Plaintext,
+		RefreshExpiry: refresh.Expiry,
+		SessionId:     sessionId,
+	}, nil
+}
+
+type MockSessionModel struct {
+}
+
+func (m MockSessionModel) Start(ctx context.Context, session *Session, accessTtl time.Duration, refreshTtl time.Duration) (*SessionTokens, error) {
+	access, err := generateToken(session.UserId, accessTtl, ScopeAuthentication)
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := generateToken(session.UserId, refreshTtl, ScopeRefresh)
+	if err != nil {
+		return nil, err
+	}
+	return &SessionTokens{Token: access.Plaintext, Expiry: access.Expiry, RefreshToken: refresh.Plaintext, RefreshExpiry: refresh.Expiry}, nil
+}
+
+func (m MockSessionModel) Refresh(ctx context.Context, refreshPlaintext string, accessTtl time.Duration, refreshTtl time.Duration) (*SessionTokens, error) {
+	return nil, ErrRecordNotFound
+}
+
+func (m MockSessionModel) GetForToken(ctx context.Context, tokenPlaintext string) (*Session, error) {
+	return nil, ErrRecordNotFound
+}
+
+func (m MockSessionModel) GetAll(ctx context.Context, userId int64) (Sessions, error) {
+	return Sessions{}, nil
+}
+
+func (m MockSessionModel) Touch(ctx context.Context, session *Session) error {
+	return nil
+}
+
+func (m MockSessionModel) Delete(ctx context.Context, id int64, userId int64) error {
+	return nil
+}
+
+func (m MockSessionModel) DeleteAllForUser(ctx context.Context, userId int64, exceptId int64) error {
+	return nil
+}
@@ -0,0 +1,141 @@
+package data
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+const SuggestionsType = "suggestions"
+
+// suggestionCandidates is how many matching names are ranked for a single request.
+const suggestionCandidates = 500
+
+// suggestionHalfLife is how long it takes for a purchase to count half as much in the ranking.
+const suggestionHalfLife = 30 * 24 * time.Hour
+
+// Suggestion is a name the user has bought before with the details of the last purchase, a new item can be filled
+// from it while its name is typed.
+type Suggestion struct {
+	ID           int64     `jsonapi:"primary,suggestions"`
+	UserId       int64     `json:"-"`
+	Name         string    `jsonapi:"attr,name"`
+	Quantity     int32     `jsonapi:"attr,quantity"`
+	QuantityType string    `jsonapi:"attr,quantity_type"`
+	Price        int64     `jsonapi:"attr,price"`
+	Currency     string    `jsonapi:"attr,currency"`
+	CategoryId   int64     `jsonapi:"attr,category_id"`
+	TimesBought  int32     `jsonapi:"attr,times_bought"`
+	LastBoughtAt time.Time `jsonapi:"attr,last_bought_at,iso8601"`
+}
+
+type Suggestions []*Suggestion
+
+type SuggestionModel struct {
+	DB *DB
+}
+
+// GetAll returns up to limit names of the history of the user starting with the prefix, or having a word starting
+// with it, the names bought often and recently first. An empty prefix returns the favourites of the user.
+func (s SuggestionModel) GetAll(ctx context.Context, userId int64, prefix string, limit int) (Suggestions, error) {
+	var key = historyKey(prefix)
+	var query = "SELECT id, user_id, name, quantity, quantity_type, price_minor, currency, COALESCE(category_id, 0), times_bought, last_bought_at FROM item_history WHERE user_id = ? AND (name_key LIKE ? ESCAPE '!' OR name_key LIKE ? ESCAPE '!') ORDER BY times_bought DESC, last_bought_at DESC LIMIT ?"
+	var pattern = escapeLike(key) + "%"
+
+	ctx, cancel := s.DB.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.DB.QueryContext(ctx, query, userId, pattern, "% "+pattern, suggestionCandidates)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var suggestions = Suggestions{}
+	for rows.Next() {
+		var suggestion Suggestion
+		err = rows.Scan(&suggestion.ID, &suggestion.UserId, &suggestion.Name, &suggestion.Quantity, &suggestion.QuantityType, &suggestion.Price, &suggestion.Currency, &suggestion.CategoryId, &suggestion.TimesBought, &suggestion.LastBoughtAt)
+		if err != nil {
+			return nil, err
+		}
+		suggestions = append(suggestions, &suggestion)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	suggestions.rank(key, time.Now())
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions, nil
+}
+
+func (s SuggestionModel) DeleteByUser(ctx context.Context, userId int64) error {
+	if userId < 1 {
+		return ErrRecordNotFound
+	}
+
+	ctx, cancel := s.DB.withTimeout(ctx)
+	defer cancel()
+
+	_, err := s.DB.ExecContext(ctx, "DELETE FROM item_history WHERE user_id = ?", userId)
+	return err
+}
+
+// rank sorts the suggestions by how often they were bought, every purchase counts less the longer ago the last one
+// was. Names starting with the prefix come before the names with only a later word starting with it.
+func (suggestions Suggestions) rank(prefix string, now time.Time) {
+	var score = func(suggestion *Suggestion) float64 {
+		var age = now.Sub(suggestion.LastBoughtAt)
+		if age < 0 {
+			age = 0
+		}
+		return float64(suggestion.TimesBought) * math.Pow(0.5, float64(age)/float64(suggestionHalfLife))
+	}
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		var iPrefix = strings.HasPrefix(historyKey(suggestions[i].Name), prefix)
+		var jPrefix = strings.HasPrefix(historyKey(suggestions[j].Name), prefix)
+		if iPrefix != jPrefix {
+			return iPrefix
+		}
+		return score(suggestions[i]) > score(suggestions[j])
+	})
+}
+
+// recordPurchase adds the item which was marked done to the history of the user who marked it. The details of the
+// last purchase replace the earlier ones, the name is counted once more.
+func recordPurchase(ctx context.Context, db *DB, userId int64, item *Item) error {
+	var name = strings.TrimSpace(item.Name)
+	if name == "" {
+		return nil
+	}
+	var query = "INSERT INTO item_history (user_id, name, name_key, quantity, quantity_type, price_minor, currency, category_id, times_bought, last_bought_at) VALUES (?, ?, ?, ?, ?, ?, ?, NULLIF(?, 0), 1, NOW())" +
+		db.Dialect.upsert([]string{"user_id", "name_key"}, []string{"name", "quantity", "quantity_type", "price_minor", "currency", "category_id", "last_bought_at"}, "times_bought = item_history.times_bought + 1")
+
+	_, err := db.ExecContext(ctx, query, userId, name, historyKey(name), item.Quantity, item.QuantityType, item.Price, item.Currency, item.CategoryId)
+	return err
+}
+
+// historyKey is the form of the name the history is searched by.
+func historyKey(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// escapeLike escapes the wildcards of LIKE with the ! escape character.
+func escapeLike(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
+}
+
+type MockSuggestionModel struct {
+}
+
+func (m MockSuggestionModel) GetAll(ctx context.Context, userId int64, prefix string, limit int) (Suggestions, error) {
+	return Suggestions{}, nil
+}
+
+func (m MockSuggestionModel) DeleteByUser(ctx context.Context, userId int64) error {
+	return nil
+}
@@ -0,0 +1,35 @@
+package data
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSuggestionsRank(t *testing.T) {
+	var now = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	var suggestions = Suggestions{
+		{ID: 1, Name: "Milk 2L", TimesBought: 10, LastBoughtAt: now.AddDate(0, -6, 0)},
+		{ID: 2, Name: "Milk chocolate", TimesBought: 3, LastBoughtAt: now.AddDate(0, 0, -2)},
+		{ID: 3, Name: "Oat milk", TimesBought: 20, LastBoughtAt: now},
+		{ID: 4, Name: "Millet", TimesBought: 1, LastBoughtAt: now.AddDate(0, 0, -1)},
+	}
+
+	suggestions.rank("mil", now)
+
+	// the milk bought often half a year ago counts less than the recent purchases, the oat milk only matches a word
+	var want = []int64{2, 4, 1, 3}
+	for i, id := range want {
+		if suggestions[i].ID != id {
+			t.Fatalf("rank() put %d at position %d; want %d", suggestions[i].ID, i, id)
+		}
+	}
+}
+
+func TestHistoryKeyAndEscapeLike(t *testing.T) {
+	if got := historyKey("  Milk   2L "); got != "milk 2l" {
+		t.Errorf("historyKey() = %q; want %q", got, "milk 2l")
+	}
+	if got := escapeLike("50%_off!"); got != "50!%!_off!!" {
+		t.Errorf("escapeLike() = %q; want %q", got, "50!%!_off!!")
+	}
+}
@@ -0,0 +1,373 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"easylist/internal/schedule"
+	"easylist/internal/validator"
+	"errors"
+	"fmt"
+	"github.com/google/jsonapi"
+	"strings"
+	"time"
+)
+
+const TemplatesType = "templates"
+
+// Template is a saved copy of a list with its items, new lists can be created from it by hand or on a schedule.
+type Template struct {
+	ID        int64         `jsonapi:"primary,templates"`
+	UserId    int64         `json:"-"`
+	FolderId  int64         `jsonapi:"attr,folder_id"`
+	Name      string        `jsonapi:"attr,name"`
+	Icon      string        `jsonapi:"attr,icon"`
+	Schedule  string        `jsonapi:"attr,schedule"`
+	NextRunAt *time.Time    `jsonapi:"attr,next_run_at,iso8601,omitempty"`
+	Version   int32         `json:"-"`
+	CreatedAt time.Time     `jsonapi:"attr,created_at,iso8601"`
+	UpdatedAt time.Time     `jsonapi:"attr,updated_at,iso8601"`
+	Items     TemplateItems `jsonapi:"relation,items,omitempty"`
+}
+
+type Templates []*Template
+
+type TemplateItem struct {
+	ID           int64  `jsonapi:"primary,template-items"`
+	TemplateId   int64  `jsonapi:"attr,template_id"`
+	Name         string `jsonapi:"attr,name"`
+	Description  string `jsonapi:"attr,description"`
+	Quantity     int32  `jsonapi:"attr,quantity"`
+	QuantityType string `jsonapi:"attr,quantity_type"`
+	Price        int64  `jsonapi:"attr,price"`
+	Currency     string `jsonapi:"attr,currency"`
+	IsStarred    bool   `jsonapi:"attr,is_starred"`
+	Order        int32  `jsonapi:"attr,order"`
+}
+
+type TemplateItems []*TemplateItem
+
+type TemplateModel struct {
+	DB *DB
+}
+
+// NewTemplateFromList copies the list and its items into a template which is not saved yet.
+func NewTemplateFromList(list *List, items Items) *Template {
+	var template = &Template{
+		UserId:   list.UserId,
+		FolderId: list.FolderId,
+		Name:     list.Name,
+		Icon:     list.Icon,
+		Version:  1,
+		Items:    TemplateItems{},
+	}
+	for _, item := range items {
+		template.Items = append(template.Items, &TemplateItem{
+			Name:         item.Name,
+			Description:  item.Description,
+			Quantity:     item.Quantity,
+			QuantityType: item.QuantityType,
+			Price:        item.Price,
+			Currency:     item.Currency,
+			IsStarred:    item.IsStarred,
+			Order:        item.Order,
+		})
+	}
+	return template
+}
+
+// Insert saves the template together with its items.
+func (t TemplateModel) Insert(ctx context.Context, template *Template) error {
+	var query = "INSERT INTO templates (user_id, folder_id, name, icon, schedule, next_run_at, version, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, 1, NOW(), NOW())"
+
+	ctx, cancel := t.DB.withTimeout(ctx)
+	defer cancel()
+
+	var id int64
+	err := t.DB.WithTx(ctx, func(tx *DB) error {
+		var err error
+		id, err = insert(ctx, tx, query, template.UserId, template.FolderId, template.Name, template.Icon, template.Schedule, template.NextRunAt)
+		if err != nil {
+			return err
+		}
+
+		var itemQuery = "INSERT INTO template_items (template_id, name, description, quantity, quantity_type, price_minor, currency, is_starred, `order`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)"
+		for _, item := range template.Items {
+			item.ID, err = insert(ctx, tx, itemQuery, id, item.Name, item.Description, item.Quantity, item.QuantityType, item.Price, item.Currency, item.IsStarred, item.Order)
+			if err != nil {
+				return err
+			}
+			item.TemplateId = id
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	template.ID = id
+	template.Version = 1
+	return nil
+}
+
+func (t TemplateModel) Get(ctx context.Context, id int64, userId int64) (*Template, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	var query = "SELECT id, user_id, folder_id, name, icon, schedule, next_run_at, version, created_at, updated_at FROM templates WHERE id = ? AND user_id = ?"
+
+	var template Template
+
+	ctx, cancel := t.DB.withTimeout(ctx)
+	defer cancel()
+
+	var err = t.DB.QueryRowContext(ctx, query, id, userId).Scan(&template.ID, &template.UserId, &template.FolderId, &template.Name, &template.Icon, &template.Schedule, &template.NextRunAt, &template.Version, &template.CreatedAt, &template.UpdatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	err = t.loadItems(ctx, Templates{&template})
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// GetAll returns the templates of the user sorted by name, the items are not loaded.
+func (t TemplateModel) GetAll(ctx context.Context, userId int64) (Templates, error) {
+	var query = "SELECT id, user_id, folder_id, name, icon, schedule, next_run_at, version, created_at, updated_at FROM templates WHERE user_id = ? ORDER BY name ASC, id ASC"
+
+	return t.query(ctx, query, userId)
+}
+
+// GetDue returns the scheduled templates whose next run is not after now, with their items.
+func (t TemplateModel) GetDue(ctx context.Context, now time.Time) (Templates, error) {
+	var query = "SELECT id, user_id, folder_id, name, icon, schedule, next_run_at, version, created_at, updated_at FROM templates WHERE schedule != '' AND next_run_at <= ? ORDER BY next_run_at ASC LIMIT 100"
+
+	templates, err := t.query(ctx, query, now)
+	if err != nil {
+		return nil, err
+	}
+	err = t.loadItems(ctx, templates)
+	if err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+func (t TemplateModel) query(ctx context.Context, query string, args ...any) (Templates, error) {
+	ctx, cancel := t.DB.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := t.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var templates = Templates{}
+	for rows.Next() {
+		var template Template
+		err = rows.Scan(&template.ID, &template.UserId, &template.FolderId, &template.Name, &template.Icon, &template.Schedule, &template.NextRunAt, &template.Version, &template.CreatedAt, &template.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, &template)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+func (t TemplateModel) loadItems(ctx context.Context, templates Templates) error {
+	if len(templates) == 0 {
+		return nil
+	}
+	var ids = make([]any, 0, len(templates))
+	var byId = make(map[int64]*Template, len(templates))
+	for _, template := range templates {
+		template.Items = TemplateItems{}
+		ids = append(ids, template.ID)
+		byId[template.ID] = template
+	}
+
+	var query = "SELECT id, template_id, name, COALESCE(description, ''), quantity, quantity_type, price_minor, currency, is_starred, `order` FROM template_items WHERE template_id IN (" + ConvertSliceToQuestionMarks(ids) + ") ORDER BY `order` ASC, id ASC"
+
+	ctx, cancel := t.DB.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := t.DB.QueryContext(ctx, query, ids...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item TemplateItem
+		err = rows.Scan(&item.ID, &item.TemplateId, &item.Name, &item.Description, &item.Quantity, &item.QuantityType, &item.Price, &item.Currency, &item.IsStarred, &item.Order)
+		if err != nil {
+			return err
+		}
+		byId[item.TemplateId].Items = append(byId[item.TemplateId].Items, &item)
+	}
+	return rows.Err()
+}
+
+// Update saves the attributes of the template, the items are not touched.
+func (t TemplateModel) Update(ctx context.Context, template *Template) error {
+	var query = "UPDATE templates SET folder_id = ?, name = ?, icon = ?, schedule = ?, next_run_at = ?, version = version + 1, updated_at = NOW() WHERE id = ? AND user_id = ? AND version = ?"
+	var args = []any{
+		template.FolderId,
+		template.Name,
+		template.Icon,
+		template.Schedule,
+		template.NextRunAt,
+		template.ID,
+		template.UserId,
+		template.Version,
+	}
+
+	ctx, cancel := t.DB.withTimeout(ctx)
+	defer cancel()
+
+	result, err := t.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrEditConflict
+	}
+	template.Version++
+	template.UpdatedAt = time.Now()
+	return nil
+}
+
+// SetNextRun moves the scheduled run of the template, a nil time stops the schedule.
+func (t TemplateModel) SetNextRun(ctx context.Context, id int64, nextRunAt *time.Time) error {
+	var query = "UPDATE templates SET next_run_at = ? WHERE id = ?"
+
+	ctx, cancel := t.DB.withTimeout(ctx)
+	defer cancel()
+
+	_, err := t.DB.ExecContext(ctx, query, nextRunAt, id)
+	return err
+}
+
+func (t TemplateModel) Delete(ctx context.Context, id int64, userId int64) error {
+	if id < 1 || userId < 1 {
+		return ErrRecordNotFound
+	}
+
+	ctx, cancel := t.DB.withTimeout(ctx)
+	defer cancel()
+
+	return t.DB.WithTx(ctx, func(tx *DB) error {
+		_, err := tx.ExecContext(ctx, "DELETE FROM template_items WHERE template_id IN (SELECT templates.id FROM templates WHERE templates.id = ? AND templates.user_id = ?)", id, userId)
+		if err != nil {
+			return err
+		}
+		result, err := tx.ExecContext(ctx, "DELETE FROM templates WHERE id = ? AND user_id = ?", id, userId)
+		if err != nil {
+			return err
+		}
+		return expectOneRow(result, ErrRecordNotFound)
+	})
+}
+
+func (t TemplateModel) DeleteByUser(ctx context.Context, userId int64) error {
+	if userId < 1 {
+		return ErrRecordNotFound
+	}
+
+	ctx, cancel := t.DB.withTimeout(ctx)
+	defer cancel()
+
+	_, err := t.DB.ExecContext(ctx, "DELETE FROM template_items WHERE template_id IN (SELECT templates.id FROM templates WHERE templates.user_id = ?)", userId)
+	if err != nil {
+		return err
+	}
+
+	_, err = t.DB.ExecContext(ctx, "DELETE FROM templates WHERE user_id = ?", userId)
+	return err
+}
+
+// ScheduleNextRun parses the schedule of the template and sets the next run after the given time.
+// Templates without a schedule are never run automatically.
+func (template *Template) ScheduleNextRun(after time.Time) error {
+	if template.Schedule == "" {
+		template.NextRunAt = nil
+		return nil
+	}
+	spec, err := schedule.Parse(template.Schedule)
+	if err != nil {
+		return err
+	}
+	var next = spec.Next(after)
+	if next.IsZero() {
+		template.NextRunAt = nil
+		return nil
+	}
+	template.NextRunAt = &next
+	return nil
+}
+
+func ValidateTemplate(v *validator.Validator, template *Template) {
+	v.Check(template.Name != "", "data.attributes.name", "must be provided")
+	v.Check(len(template.Name) <= 190, "data.attributes.name", "must be no more than 190 characters")
+	v.Check(template.Icon == "" || strings.HasPrefix(template.Icon, "mdi-"), "data.attributes.icon", "icon must starts with mdi- prefix")
+	v.Check(template.FolderId > 0, "data.attributes.folder_id", "should be greater then zero")
+	if template.Schedule != "" {
+		_, err := schedule.Parse(template.Schedule)
+		v.Check(err == nil, "data.attributes.schedule", "must be hourly, daily, weekly, monthly or a cron expression")
+	}
+}
+
+func (template Template) JSONAPILinks() *jsonapi.Links {
+	return &jsonapi.Links{
+		"self": fmt.Sprintf("%s/api/v1/templates/%d", DomainName, template.ID),
+	}
+}
+
+type MockTemplateModel struct {
+}
+
+func (m MockTemplateModel) Insert(ctx context.Context, template *Template) error {
+	return nil
+}
+
+func (m MockTemplateModel) Get(ctx context.Context, id int64, userId int64) (*Template, error) {
+	return nil, nil
+}
+
+func (m MockTemplateModel) GetAll(ctx context.Context, userId int64) (Templates, error) {
+	return Templates{}, nil
+}
+
+func (m MockTemplateModel) GetDue(ctx context.Context, now time.Time) (Templates, error) {
+	return Templates{}, nil
+}
+
+func (m MockTemplateModel) Update(ctx context.Context, template *Template) error {
+	return nil
+}
+
+func (m MockTemplateModel) SetNextRun(ctx context.Context, id int64, nextRunAt *time.Time) error {
+	return nil
+}
+
+func (m MockTemplateModel) Delete(ctx context.Context, id int64, userId int64) error {
+	return nil
+}
+
+func (m MockTemplateModel) DeleteByUser(ctx context.Context, userId int64) error {
+	return nil
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"crypto/rand"
 	"crypto/sha256"
-	"database/sql"
 	"easylist/internal/validator"
 	"encoding/base32"
 	"encoding/hex"
@@ -15,9 +14,13 @@ import (
 const ScopeActivation = "activation"
 const ScopeAuthentication = "authentication"
 const ScopePasswordReset = "password-reset"
+const ScopeListInvitation = "list-invitation"
+
+// ScopeRefresh tokens are exchanged for a new pair of an authentication and a refresh token of the same session.
+const ScopeRefresh = "refresh"
 
 type TokenModel struct {
-	DB *sql.DB
+	DB *DB
 }
 
 type Token struct {
@@ -27,6 +30,7 @@ type Token struct {
 	UserId    int64     `json:"-"`
 	Expiry    time.Time `jsonapi:"attr,expiry"`
 	Scope     string    `json:"-"`
+	SessionId int64     `json:"-"`
 }
 
 func generateToken(userId int64, ttl time.Duration, scope string) (*Token, error) {
@@ -51,49 +55,96 @@ func ValidateTokenPlaintext(v *validator.Validator, tokenPlaintext string) {
 	v.Check(len(tokenPlaintext) == 26, "token", "must be 26 bytes long")
 }
 
-func (t TokenModel) New(userId int64, ttl time.Duration, scope string) (*Token, error) {
+func (t TokenModel) New(ctx context.Context, userId int64, ttl time.Duration, scope string) (*Token, error) {
 	token, err := generateToken(userId, ttl, scope)
 	if err != nil {
 		return nil, err
 	}
-	err = t.Insert(token)
+	err = t.Insert(ctx, token)
 	return token, err
 }
 
-func (t TokenModel) Insert(token *Token) error {
-	var query = `INSERT INTO tokens (hash, user_id, expired_at, scope) VALUES (?,?,?,?)`
-	var args = []any{token.Hash, token.UserId, token.Expiry, token.Scope}
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+func (t TokenModel) Insert(ctx context.Context, token *Token) error {
+	var query = `INSERT INTO tokens (hash, user_id, expired_at, scope, session_id) VALUES (?,?,?,?,NULLIF(?, 0))`
+	var args = []any{token.Hash, token.UserId, token.Expiry, token.Scope, token.SessionId}
+	ctx, cancel := t.DB.withTimeout(ctx)
 	defer cancel()
-	result, err := t.DB.ExecContext(ctx, query, args...)
-	if err != nil {
-		return err
-	}
-	id, err := result.LastInsertId()
+	id, err := insert(ctx, t.DB, query, args...)
 	token.ID = id
 	return err
 }
 
-func (t TokenModel) DeleteAllForUser(scope string, userId int64) error {
+func (t TokenModel) DeleteAllForUser(ctx context.Context, scope string, userId int64) error {
 	var query = `DELETE FROM tokens WHERE scope = ? AND user_id = ?`
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := t.DB.withTimeout(ctx)
 	defer cancel()
 	_, err := t.DB.ExecContext(ctx, query, scope, userId)
 	return err
 }
 
+// DeleteByUser deletes every token of the user in all scopes, the personal access tokens included. The rows pointing
+// to the tokens go first as MySQL does not cascade the deletion.
+func (t TokenModel) DeleteByUser(ctx context.Context, userId int64) error {
+	ctx, cancel := t.DB.withTimeout(ctx)
+	defer cancel()
+
+	return t.DB.WithTx(ctx, func(tx *DB) error {
+		for _, query := range []string{
+			"DELETE FROM tokens_permissions WHERE token_id IN (SELECT id FROM tokens WHERE user_id = ?)",
+			"DELETE FROM personal_access_tokens WHERE user_id = ?",
+			"DELETE FROM tokens WHERE user_id = ?",
+		} {
+			_, err := tx.ExecContext(ctx, query, userId)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (t TokenModel) Delete(ctx context.Context, id int64, userId int64) error {
+	var query = `DELETE FROM tokens WHERE id = ? AND user_id = ?`
+	ctx, cancel := t.DB.withTimeout(ctx)
+	defer cancel()
+	_, err := t.DB.ExecContext(ctx, query, id, userId)
+	return err
+}
+
+// DeleteForPlaintext revokes the token of the user, it is used to sign out with a token which has no session.
+func (t TokenModel) DeleteForPlaintext(ctx context.Context, scope string, tokenPlaintext string, userId int64) error {
+	var hash = sha256.Sum256([]byte(tokenPlaintext))
+	var query = `DELETE FROM tokens WHERE hash = ? AND scope = ? AND user_id = ?`
+	ctx, cancel := t.DB.withTimeout(ctx)
+	defer cancel()
+	_, err := t.DB.ExecContext(ctx, query, hex.EncodeToString(hash[:]), scope, userId)
+	return err
+}
+
 type MockTokenModel struct {
 }
 
-func (t MockTokenModel) New(userId int64, ttl time.Duration, scope string) (*Token, error) {
+func (t MockTokenModel) New(ctx context.Context, userId int64, ttl time.Duration, scope string) (*Token, error) {
 	token, err := generateToken(userId, ttl, scope)
 	return token, err
 }
 
-func (t MockTokenModel) Insert(token *Token) error {
+func (t MockTokenModel) Insert(ctx context.Context, token *Token) error {
+	return nil
+}
+
+func (t MockTokenModel) DeleteAllForUser(ctx context.Context, scope string, userId int64) error {
+	return nil
+}
+
+func (t MockTokenModel) DeleteByUser(ctx context.Context, userId int64) error {
+	return nil
+}
+
+func (t MockTokenModel) Delete(ctx context.Context, id int64, userId int64) error {
 	return nil
 }
 
-func (t MockTokenModel) DeleteAllForUser(scope string, userId int64) error {
+func (t MockTokenModel) DeleteForPlaintext(ctx context.Context, scope string, tokenPlaintext string, userId int64) error {
 	return nil
 }
@@ -0,0 +1,136 @@
+package data
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Tombstone remembers a deleted record so offline clients can drop it during the next sync.
+type Tombstone struct {
+	ID         int64     `json:"-"`
+	UserId     int64     `json:"-"`
+	ListId     int64     `json:"-"`
+	RecordType string    `json:"type"`
+	RecordId   int64     `json:"id,string"`
+	DeletedAt  time.Time `json:"deleted_at"`
+}
+
+type Tombstones []*Tombstone
+
+type TombstoneModel struct {
+	DB *DB
+}
+
+// EncodeSyncCursor turns a point in time into the opaque cursor returned to the sync clients.
+func EncodeSyncCursor(t time.Time) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(t.Unix(), 10)))
+}
+
+// DecodeSyncCursor is the reverse of EncodeSyncCursor, an empty cursor means a full sync from the beginning.
+func DecodeSyncCursor(cursor string) (time.Time, error) {
+	if cursor == "" {
+		return time.Time{}, nil
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, ErrInvalidCursor
+	}
+	seconds, err := strconv.ParseInt(string(decoded), 10, 64)
+	if err != nil || seconds < 0 {
+		return time.Time{}, ErrInvalidCursor
+	}
+	return time.Unix(seconds, 0), nil
+}
+
+func recordTombstone(ctx context.Context, db *DB, userId int64, listId int64, recordType string, recordId int64) error {
+	var query = `INSERT INTO tombstones (user_id, list_id, record_type, record_id, deleted_at) VALUES (?, NULLIF(?, 0), ?, ?, NOW())`
+
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx, query, userId, listId, recordType, recordId)
+	return err
+}
+
+// recordItemTombstones stores tombstones for the items matched by the where clause, it must run before the items are deleted.
+func recordItemTombstones(ctx context.Context, db *DB, where string, args ...any) error {
+	var query = `INSERT INTO tombstones (user_id, list_id, record_type, record_id, deleted_at) SELECT lists.user_id, items.list_id, '` + ItemsType + `', items.id, NOW() FROM items INNER JOIN lists ON lists.id = items.list_id WHERE ` + where
+
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx, query, args...)
+	return err
+}
+
+// forgetTombstone drops the tombstones of a restored record, so the clients don't delete it again on the next sync.
+func forgetTombstone(ctx context.Context, db *DB, recordType string, recordId int64) error {
+	var query = `DELETE FROM tombstones WHERE record_type = ? AND record_id = ?`
+
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx, query, recordType, recordId)
+	return err
+}
+
+func (t TombstoneModel) GetAllSince(ctx context.Context, userId int64, since time.Time) (Tombstones, error) {
+	var query = `
+	SELECT id, user_id, COALESCE(list_id, 0), record_type, record_id, deleted_at
+	FROM tombstones
+	WHERE deleted_at >= ? AND (user_id = ? OR list_id IN (SELECT list_members.list_id FROM list_members WHERE list_members.user_id = ? AND list_members.is_accepted = TRUE))
+	ORDER BY id ASC`
+
+	ctx, cancel := t.DB.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := t.DB.QueryContext(ctx, query, since, userId, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tombstones = Tombstones{}
+	for rows.Next() {
+		var tombstone Tombstone
+		err = rows.Scan(&tombstone.ID, &tombstone.UserId, &tombstone.ListId, &tombstone.RecordType, &tombstone.RecordId, &tombstone.DeletedAt)
+		if err != nil {
+			return nil, err
+		}
+		tombstones = append(tombstones, &tombstone)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return tombstones, nil
+}
+
+// Purge removes the tombstones older than the retention period, the clients with an older cursor sync from the beginning.
+func (t TombstoneModel) Purge(ctx context.Context, retention time.Duration) (int64, error) {
+	var query = "DELETE FROM tombstones WHERE deleted_at < ?"
+
+	ctx, cancel := t.DB.withTimeout(ctx)
+	defer cancel()
+
+	result, err := t.DB.ExecContext(ctx, query, time.Now().Add(-retention))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+type MockTombstoneModel struct {
+}
+
+func (t MockTombstoneModel) GetAllSince(ctx context.Context, userId int64, since time.Time) (Tombstones, error) {
+	return Tombstones{}, nil
+}
+
+func (t MockTombstoneModel) Purge(ctx context.Context, retention time.Duration) (int64, error) {
+	return 0, nil
+}
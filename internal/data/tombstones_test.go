@@ -0,0 +1,50 @@
+package data
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSyncCursorRoundTrip(t *testing.T) {
+	var now = time.Unix(1700000000, 0)
+
+	decoded, err := DecodeSyncCursor(EncodeSyncCursor(now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Equal(now) {
+		t.Errorf("DecodeSyncCursor() = %v; want %v", decoded, now)
+	}
+}
+
+func TestDecodeSyncCursor(t *testing.T) {
+	tests := []struct {
+		name      string
+		cursor    string
+		expectErr bool
+	}{
+		{name: "empty cursor", cursor: ""},
+		{name: "not base64", cursor: "!!!", expectErr: true},
+		{name: "not a number", cursor: "YWJj", expectErr: true},
+		{name: "negative", cursor: "LTE", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoded, err := DecodeSyncCursor(tt.cursor)
+			if tt.expectErr {
+				if !errors.Is(err, ErrInvalidCursor) {
+					t.Errorf("DecodeSyncCursor() error = %v; want %v", err, ErrInvalidCursor)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !decoded.IsZero() {
+				t.Errorf("DecodeSyncCursor() = %v; want zero time", decoded)
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+)
+
+const TwoFactorType = "totp"
+const RecoveryCodesType = "recovery-codes"
+
+// ScopeTwoFactor tokens are handed out instead of a session when the password of a user with two-factor
+// authentication is right, they are exchanged for the session together with a code of the authenticator app.
+const ScopeTwoFactor = "two-factor"
+
+// RecoveryCodesCount is the number of recovery codes generated when the two-factor authentication is enabled.
+const RecoveryCodesCount = 10
+
+// TwoFactor is the TOTP state of the user. The secret is set when the user starts the enrolment and the two-factor
+// authentication is enabled once a code of the secret has been confirmed.
+type TwoFactor struct {
+	UserId  int64
+	Secret  string
+	Enabled bool
+	Counter int64
+}
+
+// TwoFactorEnrolment is the secret shown to the user once, uri is the otpauth:// uri for the QR code.
+type TwoFactorEnrolment struct {
+	ID     int64  `jsonapi:"primary,totp"`
+	Secret string `jsonapi:"attr,secret"`
+	Uri    string `jsonapi:"attr,uri"`
+}
+
+// TwoFactorChallenge answers the right password of a user with two-factor authentication, the challenge token is
+// sent back together with a code to get the session.
+type TwoFactorChallenge struct {
+	ID                int64     `jsonapi:"primary,tokens"`
+	ChallengeToken    string    `jsonapi:"attr,challenge_token"`
+	Expiry            time.Time `jsonapi:"attr,expiry"`
+	TwoFactorRequired bool      `jsonapi:"attr,two_factor_required"`
+}
+
+// RecoveryCodes are the one-time codes which replace the authenticator app when it is lost, they are shown once.
+type RecoveryCodes struct {
+	ID    int64    `jsonapi:"primary,recovery-codes"`
+	Codes []string `jsonapi:"attr,codes"`
+}
+
+// GenerateRecoveryCodes returns new random codes like "k3h7q-2mx9a".
+func GenerateRecoveryCodes() ([]string, error) {
+	var codes = make([]string, RecoveryCodesCount)
+	var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+	for i := range codes {
+		var randomBytes = make([]byte, 7)
+		_, err := rand.Read(randomBytes)
+		if err != nil {
+			return nil, err
+		}
+		var code = strings.ToLower(encoding.EncodeToString(randomBytes))[:10]
+		codes[i] = code[:5] + "-" + code[5:]
+	}
+	return codes, nil
+}
+
+// recoveryCodeHash hashes the code ignoring the case, the dashes and the spaces the user may type.
+func recoveryCodeHash(code string) string {
+	var normalized = strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(code))
+	var hash = sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(hash[:])
+}
+
+type TwoFactorModel struct {
+	DB *DB
+}
+
+func (m TwoFactorModel) Get(ctx context.Context, userId int64) (*TwoFactor, error) {
+	var query = "SELECT id, COALESCE(totp_secret, ''), totp_enabled, totp_counter FROM users WHERE id = ?"
+
+	ctx, cancel := m.DB.withTimeout(ctx)
+	defer cancel()
+
+	var twoFactor TwoFactor
+	err := m.DB.QueryRowContext(ctx, query, userId).Scan(&twoFactor.UserId, &twoFactor.Secret, &twoFactor.Enabled, &twoFactor.Counter)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &twoFactor, nil
+}
+
+// SetSecret starts the enrolment with a new secret, a previous enrolment which was not confirmed is replaced.
+func (m TwoFactorModel) SetSecret(ctx context.Context, userId int64, secret string) error {
+	var query = "UPDATE users SET totp_secret = ?, totp_enabled = false, totp_counter = 0 WHERE id = ? AND totp_enabled = false"
+
+	ctx, cancel := m.DB.withTimeout(ctx)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, secret, userId)
+	return err
+}
+
+// Enable turns the two-factor authentication on after the code of the counter was confirmed and replaces the
+// recovery codes of the user.
+func (m TwoFactorModel) Enable(ctx context.Context, userId int64, counter int64, recoveryCodes []string) error {
+	ctx, cancel := m.DB.withTimeout(ctx)
+	defer cancel()
+
+	return m.DB.WithTx(ctx, func(tx *DB) error {
+		result, err := tx.ExecContext(ctx, "UPDATE users SET totp_enabled = true, totp_counter = ? WHERE id = ? AND totp_secret IS NOT NULL AND totp_enabled = false", counter, userId)
+		if err != nil {
+			return err
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return ErrEditConflict
+		}
+		_, err = tx.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = ?", userId)
+		if err != nil {
+			return err
+		}
+		for _, code := range recoveryCodes {
+			_, err = tx.ExecContext(ctx, "INSERT INTO recovery_codes (user_id, hash, created_at) VALUES (?, ?, NOW())", userId, recoveryCodeHash(code))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Disable turns the two-factor authentication off and forgets the secret and the recovery codes.
+func (m TwoFactorModel) Disable(ctx context.Context, userId int64) error {
+	ctx, cancel := m.DB.withTimeout(ctx)
+	defer cancel()
+
+	return m.DB.WithTx(ctx, func(tx *DB) error {
+		_, err := tx.ExecContext(ctx, "UPDATE users SET totp_secret = NULL, totp_enabled = false, totp_counter = 0 WHERE id = ?", userId)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = ?", userId)
+		return err
+	})
+}
+
+// UseCounter records the period of an accepted code, it reports false when a code of the same or a later period
+// was already accepted, so a code can not be replayed.
+func (m TwoFactorModel) UseCounter(ctx context.Context, userId int64, counter int64) (bool, error) {
+	var query = "UPDATE users SET totp_counter = ? WHERE id = ? AND totp_counter < ?"
+
+	ctx, cancel := m.DB.withTimeout(ctx)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, counter, userId, counter)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	return rowsAffected == 1, err
+}
+
+// UseRecoveryCode spends the recovery code, it reports false when the code is unknown or was already used.
+func (m TwoFactorModel) UseRecoveryCode(ctx context.Context, userId int64, code string) (bool, error) {
+	var query = "UPDATE recovery_codes SET used_at = NOW() WHERE user_id = ? AND hash = ? AND used_at IS NULL"
+
+	ctx, cancel := m.DB.withTimeout(ctx)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, userId, recoveryCodeHash(code))
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	return rowsAffected > 0, err
+}
+
+type MockTwoFactorModel struct {
+}
+
+func (m MockTwoFactorModel) Get(ctx context.Context, userId int64) (*TwoFactor, error) {
+	return &TwoFactor{UserId: userId}, nil
+}
+
+func (m MockTwoFactorModel) SetSecret(ctx context.Context, userId int64, secret string) error {
+	return nil
+}
+
+func (m MockTwoFactorModel) Enable(ctx context.Context, userId int64, counter int64, recoveryCodes []string) error {
+	return nil
+}
+
+func (m MockTwoFactorModel) Disable(ctx context.Context, userId int64) error {
+	return nil
+}
+
+func (m MockTwoFactorModel) UseCounter(ctx context.Context, userId int64, counter int64) (bool, error) {
+	return false, nil
+}
+
+func (m MockTwoFactorModel) UseRecoveryCode(ctx context.Context, userId int64, code string) (bool, error) {
+	return false, nil
+}
@@ -4,12 +4,11 @@ import (
 	"context"
 	"crypto/sha256"
 	"database/sql"
+	"easylist/internal/money"
 	"easylist/internal/validator"
 	"encoding/hex"
 	"errors"
-	"github.com/go-sql-driver/mysql"
 	"github.com/jameskeane/bcrypt"
-	"strings"
 	"time"
 )
 
@@ -21,6 +20,7 @@ type User struct {
 	Email     string    `jsonapi:"attr,email"`
 	Password  password  `json:"-"`
 	IsActive  bool      `jsonapi:"attr,is_active"`
+	Currency  string    `jsonapi:"attr,currency"`
 	Version   int       `json:"-"`
 }
 
@@ -34,7 +34,7 @@ var AnonymousUser = &User{}
 var ErrDuplicateEmail = errors.New("duplicate email")
 
 type UserModel struct {
-	DB *sql.DB
+	DB *DB
 }
 
 func (p *password) Set(plaintext string) error {
@@ -67,6 +67,7 @@ func ValidatePasswordPlaintext(v *validator.Validator, password string) {
 func ValidateUser(v *validator.Validator, user *User) {
 	v.Check(user.Name != "", "data.attributes.name", "must be provided")
 	v.Check(len(user.Name) < 190, "data.attributes.name", "must not be more then 190 bytes")
+	v.Check(money.ValidCurrency(user.Currency), "data.attributes.currency", "must be a three letter ISO 4217 currency code")
 	ValidateEmail(v, user.Email)
 	if user.Password.plaintext != nil {
 		ValidatePasswordPlaintext(v, *user.Password.plaintext)
@@ -76,40 +77,36 @@ func ValidateUser(v *validator.Validator, user *User) {
 	}
 }
 
-func (u UserModel) Insert(user *User) error {
+func (u UserModel) Insert(ctx context.Context, user *User) error {
 	user.CreatedAt = time.Now()
 	user.UpdatedAt = time.Now()
 	user.Version = 1
+	if user.Currency == "" {
+		user.Currency = money.DefaultCurrency
+	}
 
 	var query = `
-				INSERT INTO users (name, email, password, is_active, created_at, updated_at, version) 
-				VALUES (?, ?, ?, ?, ?, ?, ?)`
-	var args = []any{user.Name, user.Email, user.Password.hash, user.IsActive, user.CreatedAt, user.UpdatedAt, user.Version}
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+				INSERT INTO users (name, email, password, is_active, currency, created_at, updated_at, version) 
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	var args = []any{user.Name, user.Email, user.Password.hash, user.IsActive, user.Currency, user.CreatedAt, user.UpdatedAt, user.Version}
+	ctx, cancel := u.DB.withTimeout(ctx)
 	defer cancel()
-	result, err := u.DB.ExecContext(ctx, query, args...)
+	id, err := insert(ctx, u.DB, query, args...)
 	if err != nil {
-		var mySQLError *mysql.MySQLError
-		if errors.As(err, &mySQLError) {
-			if mySQLError.Number == 1062 && strings.Contains(mySQLError.Message, "users.email") {
-				return ErrDuplicateEmail
-			}
+		if isDuplicate(err, "email") {
+			return ErrDuplicateEmail
 		}
 		return err
 	}
-	id, err := result.LastInsertId()
-	if err != nil {
-		return err
-	}
 	user.ID = id
 
 	return nil
 }
 
-func (u UserModel) GetByEmail(email string) (*User, error) {
-	var query = `SELECT id, name, email, password, created_at, updated_at, is_active, version FROM users WHERE email = ?`
+func (u UserModel) GetByEmail(ctx context.Context, email string) (*User, error) {
+	var query = `SELECT id, name, email, password, created_at, updated_at, is_active, currency, version FROM users WHERE email = ?`
 	var user User
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := u.DB.withTimeout(ctx)
 	defer cancel()
 	err := u.DB.QueryRowContext(ctx, query, email).Scan(
 		&user.ID,
@@ -119,6 +116,7 @@ func (u UserModel) GetByEmail(email string) (*User, error) {
 		&user.CreatedAt,
 		&user.UpdatedAt,
 		&user.IsActive,
+		&user.Currency,
 		&user.Version,
 	)
 	if err != nil {
@@ -132,18 +130,15 @@ func (u UserModel) GetByEmail(email string) (*User, error) {
 	return &user, nil
 }
 
-func (u UserModel) Update(user *User) error {
-	var query = `UPDATE users SET name = ?, email = ?, password = ?, is_active = ?, version = version + 1, updated_at = NOW() WHERE id = ? AND version = ?`
-	var args = []any{user.Name, user.Email, user.Password.hash, user.IsActive, user.ID, user.Version}
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+func (u UserModel) Update(ctx context.Context, user *User) error {
+	var query = `UPDATE users SET name = ?, email = ?, password = ?, is_active = ?, currency = ?, version = version + 1, updated_at = NOW() WHERE id = ? AND version = ?`
+	var args = []any{user.Name, user.Email, user.Password.hash, user.IsActive, user.Currency, user.ID, user.Version}
+	ctx, cancel := u.DB.withTimeout(ctx)
 	defer cancel()
 	_, err := u.DB.ExecContext(ctx, query, args...)
 	if err != nil {
-		var mySQLError *mysql.MySQLError
-		if errors.As(err, &mySQLError) {
-			if mySQLError.Number == 1062 && strings.Contains(mySQLError.Message, "users.email") {
-				return ErrDuplicateEmail
-			}
+		if isDuplicate(err, "email") {
+			return ErrDuplicateEmail
 		}
 		if errors.Is(err, sql.ErrNoRows) {
 			return ErrEditConflict
@@ -155,14 +150,14 @@ func (u UserModel) Update(user *User) error {
 	return nil
 }
 
-func (u UserModel) Delete(id int64) error {
+func (u UserModel) Delete(ctx context.Context, id int64) error {
 	if id < 1 {
 		return ErrRecordNotFound
 	}
 
 	var query = "DELETE FROM users WHERE id = ?"
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := u.DB.withTimeout(ctx)
 	defer cancel()
 
 	result, err := u.DB.ExecContext(ctx, query, id)
@@ -181,11 +176,11 @@ func (u UserModel) Delete(id int64) error {
 	return nil
 }
 
-func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error) {
+func (m UserModel) GetForToken(ctx context.Context, tokenScope, tokenPlaintext string) (*User, error) {
 	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
 
 	query := `
-        SELECT users.id, users.created_at, users.name, users.email, users.password, users.is_active, users.version
+        SELECT users.id, users.created_at, users.name, users.email, users.password, users.is_active, users.currency, users.version
         FROM users
         INNER JOIN tokens
         ON users.id = tokens.user_id
@@ -197,7 +192,7 @@ func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error)
 
 	var user User
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := m.DB.withTimeout(ctx)
 	defer cancel()
 
 	err := m.DB.QueryRowContext(ctx, query, args...).Scan(
@@ -207,6 +202,7 @@ func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error)
 		&user.Email,
 		&user.Password.hash,
 		&user.IsActive,
+		&user.Currency,
 		&user.Version,
 	)
 	if err != nil {
@@ -233,27 +229,28 @@ var MockUser = &User{
 	Email:     "emelyanov86@km.ru",
 	Password:  password{},
 	IsActive:  true,
+	Currency:  "USD",
 	Version:   1,
 }
 
 type MockUserModel struct{}
 
-func (u MockUserModel) Insert(user *User) error {
+func (u MockUserModel) Insert(ctx context.Context, user *User) error {
 	return nil
 }
 
-func (u MockUserModel) GetByEmail(email string) (*User, error) {
+func (u MockUserModel) GetByEmail(ctx context.Context, email string) (*User, error) {
 	return nil, nil
 }
 
-func (u MockUserModel) Update(user *User) error {
+func (u MockUserModel) Update(ctx context.Context, user *User) error {
 	return nil
 }
 
-func (m MockUserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error) {
+func (m MockUserModel) GetForToken(ctx context.Context, tokenScope, tokenPlaintext string) (*User, error) {
 	return nil, nil
 }
 
-func (m MockUserModel) Delete(id int64) error {
+func (m MockUserModel) Delete(ctx context.Context, id int64) error {
 	return nil
 }
@@ -0,0 +1,102 @@
+package events
+
+import (
+	"sync"
+)
+
+const (
+	ItemCreated    = "item.created"
+	ItemUpdated    = "item.updated"
+	ItemDeleted    = "item.deleted"
+	ItemsReordered = "items.reordered"
+	ItemsUpdated   = "items.updated"
+	ItemsDeleted   = "items.deleted"
+)
+
+// subscriberBuffer is the number of events a slow subscriber may lag behind before new events are dropped for it.
+const subscriberBuffer = 16
+
+type Event struct {
+	Name string
+	Data []byte
+}
+
+// Hub is an in-process publish/subscribe broker which fans out list events to every open stream of that list.
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[int64]map[chan Event]struct{}
+	closed      bool
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		subscribers: make(map[int64]map[chan Event]struct{}),
+	}
+}
+
+// Subscribe registers a new subscriber for the list. The returned channel is closed by unsubscribe or when the hub shuts down.
+func (h *Hub) Subscribe(listId int64) (<-chan Event, func()) {
+	var ch = make(chan Event, subscriberBuffer)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	if _, found := h.subscribers[listId]; !found {
+		h.subscribers[listId] = make(map[chan Event]struct{})
+	}
+	h.subscribers[listId][ch] = struct{}{}
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			if _, found := h.subscribers[listId][ch]; !found {
+				return
+			}
+			delete(h.subscribers[listId], ch)
+			if len(h.subscribers[listId]) == 0 {
+				delete(h.subscribers, listId)
+			}
+			close(ch)
+		})
+	}
+}
+
+// Publish sends the event to all subscribers of the list without blocking.
+func (h *Hub) Publish(listId int64, event Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.subscribers[listId] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// Close disconnects all subscribers, it is used during graceful shutdown so streams do not hold the server open.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for listId, subscribers := range h.subscribers {
+		for ch := range subscribers {
+			close(ch)
+		}
+		delete(h.subscribers, listId)
+	}
+}
+
+func (h *Hub) SubscribersCount(listId int64) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return len(h.subscribers[listId])
+}
@@ -0,0 +1,83 @@
+package events
+
+import (
+	"testing"
+)
+
+func TestPublishReachesOnlyListSubscribers(t *testing.T) {
+	t.Parallel()
+
+	var hub = NewHub()
+	first, unsubscribeFirst := hub.Subscribe(1)
+	defer unsubscribeFirst()
+	other, unsubscribeOther := hub.Subscribe(2)
+	defer unsubscribeOther()
+
+	hub.Publish(1, Event{Name: ItemCreated, Data: []byte("{}")})
+
+	select {
+	case event := <-first:
+		if event.Name != ItemCreated {
+			t.Errorf("want event %s, got %s", ItemCreated, event.Name)
+		}
+	default:
+		t.Fatal("want event for list 1 subscriber, got nothing")
+	}
+
+	select {
+	case event := <-other:
+		t.Errorf("want no event for list 2 subscriber, got %s", event.Name)
+	default:
+	}
+}
+
+func TestUnsubscribeClosesChannel(t *testing.T) {
+	t.Parallel()
+
+	var hub = NewHub()
+	ch, unsubscribe := hub.Subscribe(5)
+	if hub.SubscribersCount(5) != 1 {
+		t.Fatalf("want 1 subscriber, got %d", hub.SubscribersCount(5))
+	}
+
+	unsubscribe()
+	unsubscribe()
+
+	if _, ok := <-ch; ok {
+		t.Error("want channel to be closed")
+	}
+	if hub.SubscribersCount(5) != 0 {
+		t.Errorf("want 0 subscribers, got %d", hub.SubscribersCount(5))
+	}
+}
+
+func TestPublishDoesNotBlockOnSlowSubscriber(t *testing.T) {
+	t.Parallel()
+
+	var hub = NewHub()
+	_, unsubscribe := hub.Subscribe(3)
+	defer unsubscribe()
+
+	for i := 0; i < subscriberBuffer*2; i++ {
+		hub.Publish(3, Event{Name: ItemUpdated})
+	}
+}
+
+func TestCloseDisconnectsSubscribers(t *testing.T) {
+	t.Parallel()
+
+	var hub = NewHub()
+	ch, unsubscribe := hub.Subscribe(7)
+
+	hub.Close()
+	unsubscribe()
+
+	if _, ok := <-ch; ok {
+		t.Error("want channel to be closed")
+	}
+
+	late, _ := hub.Subscribe(7)
+	if _, ok := <-late; ok {
+		t.Error("want subscription after close to be closed")
+	}
+}
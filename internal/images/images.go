@@ -0,0 +1,218 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+
+	xdraw "golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// MaxPixels protects the server from images which are small files but huge bitmaps.
+const MaxPixels = 50_000_000
+
+// DefaultThumbnailSize is the longest side of thumbnails when the size is not configured.
+const DefaultThumbnailSize = 320
+
+const jpegQuality = 85
+
+var (
+	ErrInvalidImage  = errors.New("images: invalid or unsupported image")
+	ErrTooManyPixels = errors.New("images: image dimensions are too large")
+)
+
+// Image is an encoded image ready to be stored.
+type Image struct {
+	Content     []byte
+	ContentType string
+}
+
+// Process decodes a JPEG, PNG or WebP image, turns it according to its EXIF orientation and encodes it again, which drops
+// EXIF and any other metadata. It returns the cleaned image and a thumbnail which fits into a size by size square.
+// Photos are stored as JPEG, images with transparency as PNG.
+func Process(content []byte, size int) (Image, Image, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		return Image{}, Image{}, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	if config.Width*config.Height > MaxPixels {
+		return Image{}, Image{}, ErrTooManyPixels
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return Image{}, Image{}, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	if format == "jpeg" {
+		img = orient(img, jpegOrientation(content))
+	}
+
+	var asPNG = format == "png" || (format != "jpeg" && !opaque(img))
+	original, err := encode(img, asPNG)
+	if err != nil {
+		return Image{}, Image{}, err
+	}
+	thumbnail, err := encode(Thumbnail(img, size), asPNG)
+	if err != nil {
+		return Image{}, Image{}, err
+	}
+	return original, thumbnail, nil
+}
+
+// Thumbnail scales the image down to fit into a size by size square, smaller images are returned as they are.
+func Thumbnail(img image.Image, size int) image.Image {
+	if size <= 0 {
+		size = DefaultThumbnailSize
+	}
+	var bounds = img.Bounds()
+	var width, height = bounds.Dx(), bounds.Dy()
+	if width <= size && height <= size {
+		return img
+	}
+	if width >= height {
+		height = atLeastOne(height * size / width)
+		width = size
+	} else {
+		width = atLeastOne(width * size / height)
+		height = size
+	}
+	var dst = image.NewNRGBA(image.Rect(0, 0, width, height))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Src, nil)
+	return dst
+}
+
+func atLeastOne(n int) int {
+	if n < 1 {
+		return 1
+	}
+	return n
+}
+
+func encode(img image.Image, asPNG bool) (Image, error) {
+	var buf bytes.Buffer
+	if asPNG {
+		if err := png.Encode(&buf, img); err != nil {
+			return Image{}, err
+		}
+		return Image{Content: buf.Bytes(), ContentType: "image/png"}, nil
+	}
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+		return Image{}, err
+	}
+	return Image{Content: buf.Bytes(), ContentType: "image/jpeg"}, nil
+}
+
+func opaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	return false
+}
+
+// jpegOrientation reads the orientation tag of the EXIF block, 1 means the image is stored upright.
+func jpegOrientation(content []byte) int {
+	if len(content) < 4 || content[0] != 0xFF || content[1] != 0xD8 {
+		return 1
+	}
+	var pos = 2
+	for pos+4 <= len(content) {
+		if content[pos] != 0xFF {
+			return 1
+		}
+		var marker = content[pos+1]
+		// start of scan, the metadata segments are over
+		if marker == 0xDA || marker == 0xD9 {
+			return 1
+		}
+		var length = int(binary.BigEndian.Uint16(content[pos+2 : pos+4]))
+		if length < 2 || pos+2+length > len(content) {
+			return 1
+		}
+		var segment = content[pos+4 : pos+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return exifOrientation(segment[6:])
+		}
+		pos += 2 + length
+	}
+	return 1
+}
+
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	var offset = int(order.Uint32(tiff[4:8]))
+	if offset < 8 || offset+2 > len(tiff) {
+		return 1
+	}
+	var entries = int(order.Uint16(tiff[offset : offset+2]))
+	for i := 0; i < entries; i++ {
+		var entry = offset + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:entry+2]) == 0x0112 {
+			var orientation = int(order.Uint16(tiff[entry+8 : entry+10]))
+			if orientation < 1 || orientation > 8 {
+				return 1
+			}
+			return orientation
+		}
+	}
+	return 1
+}
+
+// orient turns and flips the image so that an image with the given EXIF orientation is displayed upright.
+func orient(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+	var bounds = img.Bounds()
+	var src = image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+
+	var w, h = src.Rect.Dx(), src.Rect.Dy()
+	var dw, dh = w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	var dst = image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2:
+				sx, sy = w-1-x, y
+			case 3:
+				sx, sy = w-1-x, h-1-y
+			case 4:
+				sx, sy = x, h-1-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, h-1-x
+			case 7:
+				sx, sy = w-1-y, h-1-x
+			case 8:
+				sx, sy = w-1-y, x
+			}
+			copy(dst.Pix[dst.PixOffset(x, y):dst.PixOffset(x, y)+4], src.Pix[src.PixOffset(sx, sy):src.PixOffset(sx, sy)+4])
+		}
+	}
+	return dst
+}
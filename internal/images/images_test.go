@@ -0,0 +1,100 @@
+package images
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func testImage(width, height int) *image.NRGBA {
+	var img = image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	return img
+}
+
+func decodeSize(t *testing.T, content []byte) (int, int) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return config.Width, config.Height
+}
+
+func TestProcessPNG(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, testImage(400, 200)); err != nil {
+		t.Fatal(err)
+	}
+
+	original, thumbnail, err := Process(buf.Bytes(), 320)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if original.ContentType != "image/png" || thumbnail.ContentType != "image/png" {
+		t.Errorf("unexpected content types: %s, %s", original.ContentType, thumbnail.ContentType)
+	}
+	if w, h := decodeSize(t, original.Content); w != 400 || h != 200 {
+		t.Errorf("unexpected original size: %dx%d", w, h)
+	}
+	if w, h := decodeSize(t, thumbnail.Content); w != 320 || h != 160 {
+		t.Errorf("unexpected thumbnail size: %dx%d, want 320x160", w, h)
+	}
+}
+
+func TestProcessJPEGWithOrientation(t *testing.T) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, testImage(40, 20), nil); err != nil {
+		t.Fatal(err)
+	}
+	// big endian EXIF block with a single orientation entry, 6 means the camera was turned clockwise
+	var exif = []byte("Exif\x00\x00MM\x00\x2a\x00\x00\x00\x08\x00\x01\x01\x12\x00\x03\x00\x00\x00\x01\x00\x06\x00\x00\x00\x00\x00\x00")
+	var segment = append([]byte{0xFF, 0xE1, 0, byte(len(exif) + 2)}, exif...)
+	var content = append([]byte{0xFF, 0xD8}, append(segment, buf.Bytes()[2:]...)...)
+
+	if orientation := jpegOrientation(content); orientation != 6 {
+		t.Fatalf("unexpected orientation: %d", orientation)
+	}
+
+	original, _, err := Process(content, 320)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if original.ContentType != "image/jpeg" {
+		t.Errorf("unexpected content type: %s", original.ContentType)
+	}
+	if w, h := decodeSize(t, original.Content); w != 20 || h != 40 {
+		t.Errorf("unexpected size of the turned image: %dx%d, want 20x40", w, h)
+	}
+	if bytes.Contains(original.Content, []byte("Exif")) {
+		t.Error("EXIF block was not stripped")
+	}
+}
+
+func TestProcessWebP(t *testing.T) {
+	// 1x1 lossless webp
+	content, _ := base64.StdEncoding.DecodeString("UklGRhoAAABXRUJQVlA4TA0AAAAvAAAAEAcQERGIiP4HAA==")
+
+	original, thumbnail, err := Process(content, 320)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(original.Content) == 0 || len(thumbnail.Content) == 0 {
+		t.Error("expected encoded images")
+	}
+}
+
+func TestProcessInvalid(t *testing.T) {
+	_, _, err := Process([]byte("This is a test test file"), 320)
+	if !errors.Is(err, ErrInvalidImage) {
+		t.Errorf("expected ErrInvalidImage, got %v", err)
+	}
+}
@@ -0,0 +1,135 @@
+// Package lockout slows down guessing of passwords. Every failed attempt of a key, e.g. an email or an ip address,
+// makes the key wait twice as long before the next attempt, and too many failures lock the key for a while. The
+// state is kept in memory like the one of the rate limiter.
+package lockout
+
+import (
+	"sync"
+	"time"
+)
+
+// Policy tells how the failures of a key are punished.
+type Policy struct {
+	// FreeAttempts is the number of failures allowed without waiting
+	FreeAttempts int
+	// BaseDelay is the wait after the first failure beyond the free attempts, it doubles with every further failure
+	BaseDelay time.Duration
+	// MaxDelay caps the wait of the back-off
+	MaxDelay time.Duration
+	// LockoutAttempts is the number of failures which lock the key
+	LockoutAttempts int
+	// LockoutDuration is how long a locked key has to wait
+	LockoutDuration time.Duration
+	// Window is how long the failures are remembered after the last one
+	Window time.Duration
+}
+
+// Tracker counts the failed attempts of the keys.
+type Tracker struct {
+	Policy Policy
+	// Now is the clock of the tracker, time.Now when nil
+	Now func() time.Time
+
+	mu          sync.Mutex
+	entries     map[string]*entry
+	lastCleanup time.Time
+}
+
+type entry struct {
+	failures     int
+	lastFailure  time.Time
+	blockedUntil time.Time
+}
+
+func New(policy Policy) *Tracker {
+	return &Tracker{Policy: policy, entries: make(map[string]*entry)}
+}
+
+func (t *Tracker) now() time.Time {
+	if t.Now == nil {
+		return time.Now()
+	}
+	return t.Now()
+}
+
+// Check returns how long the key has to wait before the next attempt, zero when it may try now.
+func (t *Tracker) Check(key string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var e, ok = t.entries[key]
+	if !ok {
+		return 0
+	}
+	var wait = e.blockedUntil.Sub(t.now())
+	if wait < 0 {
+		return 0
+	}
+	return wait
+}
+
+// Fail records a failed attempt of the key. It returns how long the key has to wait now and whether this failure
+// has locked the key. A lockout starts the count of the failures anew, so the key is not locked again by every
+// failure after it.
+func (t *Tracker) Fail(key string) (time.Duration, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var now = t.now()
+	t.cleanup(now)
+
+	var e, ok = t.entries[key]
+	if !ok || now.Sub(e.lastFailure) > t.Policy.Window {
+		e = &entry{}
+		t.entries[key] = e
+	}
+	e.failures++
+	e.lastFailure = now
+
+	if t.Policy.LockoutAttempts > 0 && e.failures >= t.Policy.LockoutAttempts {
+		e.failures = 0
+		e.blockedUntil = now.Add(t.Policy.LockoutDuration)
+		return t.Policy.LockoutDuration, true
+	}
+	if e.failures <= t.Policy.FreeAttempts {
+		return 0, false
+	}
+	var delay = t.Policy.BaseDelay
+	for i := t.Policy.FreeAttempts + 1; i < e.failures && delay < t.Policy.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > t.Policy.MaxDelay {
+		delay = t.Policy.MaxDelay
+	}
+	e.blockedUntil = now.Add(delay)
+	return delay, false
+}
+
+// Succeed forgets the failures of the key.
+func (t *Tracker) Succeed(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.entries, key)
+}
+
+// Len returns the number of keys with failures.
+func (t *Tracker) Len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return len(t.entries)
+}
+
+// cleanup forgets the keys whose failures are out of the window and which are not blocked, at most once a minute.
+func (t *Tracker) cleanup(now time.Time) {
+	if now.Sub(t.lastCleanup) < time.Minute {
+		return
+	}
+	t.lastCleanup = now
+	for key, e := range t.entries {
+		if now.Sub(e.lastFailure) > t.Policy.Window && now.After(e.blockedUntil) {
+			delete(t.entries, key)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package lockout
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTracker(t *testing.T) {
+	t.Parallel()
+
+	var now = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	var tracker = New(Policy{
+		FreeAttempts:    2,
+		BaseDelay:       time.Second,
+		MaxDelay:        4 * time.Second,
+		LockoutAttempts: 7,
+		LockoutDuration: time.Hour,
+		Window:          time.Hour,
+	})
+	tracker.Now = func() time.Time { return now }
+
+	// the free attempts, then the back-off doubles up to the maximum, then the lockout
+	for i, expected := range []time.Duration{0, 0, time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second} {
+		wait, locked := tracker.Fail("key")
+		if wait != expected || locked {
+			t.Fatalf("failure %d: Fail() = %v, %v; want %v, false", i+1, wait, locked, expected)
+		}
+		if check := tracker.Check("key"); check != expected {
+			t.Fatalf("failure %d: Check() = %v; want %v", i+1, check, expected)
+		}
+	}
+	if wait, locked := tracker.Fail("key"); wait != time.Hour || !locked {
+		t.Fatalf("Fail() = %v, %v; want the lockout", wait, locked)
+	}
+	if check := tracker.Check("other"); check != 0 {
+		t.Errorf("want the other keys not to wait; got %v", check)
+	}
+
+	now = now.Add(30 * time.Minute)
+	if check := tracker.Check("key"); check != 30*time.Minute {
+		t.Errorf("Check() = %v; want the rest of the lockout", check)
+	}
+
+	// after the lockout the failures are counted from the start, they don't lock the key again right away
+	now = now.Add(30 * time.Minute)
+	if wait, locked := tracker.Fail("key"); wait != 0 || locked {
+		t.Errorf("Fail() = %v, %v; want a free attempt after the lockout", wait, locked)
+	}
+
+	tracker.Succeed("key")
+	if check := tracker.Check("key"); check != 0 || tracker.Len() != 0 {
+		t.Errorf("want a success to forget the failures; got %v", check)
+	}
+
+	tracker.Fail("key")
+	tracker.Fail("key")
+	now = now.Add(2 * time.Hour)
+	if wait, _ := tracker.Fail("key"); wait != 0 {
+		t.Errorf("want the failures out of the window to be forgotten; got %v", wait)
+	}
+	now = now.Add(2 * time.Hour)
+	tracker.Fail("other")
+	if tracker.Len() != 1 {
+		t.Errorf("want the old keys to be cleaned up; got %d", tracker.Len())
+	}
+}
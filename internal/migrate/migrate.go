@@ -0,0 +1,252 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"easylist/internal/data"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrDirty          = errors.New("migrate: the last migration failed, fix the database and the schema_migrations table by hand")
+	ErrNoMigrations   = errors.New("migrate: no migrations found")
+	ErrUnknownVersion = errors.New("migrate: the database version is not one of the migrations")
+)
+
+// Migration is a pair of up and down scripts, the files are named like 000001_create_users_table.up.sql.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status tells whether a migration was applied to the database.
+type Status struct {
+	Migration
+	Applied bool
+}
+
+// Load reads the migrations of the directory sorted by version.
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	var byVersion = make(map[int64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+		var file = strings.TrimSuffix(entry.Name(), ".sql")
+		var direction = path.Ext(file)
+		if direction != ".up" && direction != ".down" {
+			continue
+		}
+		file = strings.TrimSuffix(file, direction)
+		number, name, ok := strings.Cut(file, "_")
+		if !ok {
+			continue
+		}
+		version, err := strconv.ParseInt(number, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migrate: invalid migration file %s", entry.Name())
+		}
+		script, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var migration, found = byVersion[version]
+		if !found {
+			migration = &Migration{Version: version, Name: name}
+			byVersion[version] = migration
+		}
+		if direction == ".up" {
+			migration.Up = string(script)
+		} else {
+			migration.Down = string(script)
+		}
+	}
+	if len(byVersion) == 0 {
+		return nil, ErrNoMigrations
+	}
+
+	var migrations = make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Migrator applies the migrations and keeps the current version in the schema_migrations table, the table has the
+// same layout as the one of the migrate CLI, so databases migrated with the CLI can be taken over.
+type Migrator struct {
+	DB         *data.DB
+	Migrations []Migration
+}
+
+func New(db *data.DB, migrations []Migration) *Migrator {
+	return &Migrator{DB: db, Migrations: migrations}
+}
+
+// Version returns the version of the last applied migration, zero when nothing was applied yet.
+func (m *Migrator) Version() (int64, bool, error) {
+	err := m.createTable()
+	if err != nil {
+		return 0, false, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var version int64
+	var dirty bool
+	err = m.DB.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	return version, dirty, err
+}
+
+// Latest is the version of the newest migration.
+func (m *Migrator) Latest() int64 {
+	if len(m.Migrations) == 0 {
+		return 0
+	}
+	return m.Migrations[len(m.Migrations)-1].Version
+}
+
+// Status lists all migrations and whether they were applied.
+func (m *Migrator) Status() ([]Status, error) {
+	version, _, err := m.Version()
+	if err != nil {
+		return nil, err
+	}
+	var statuses = make([]Status, 0, len(m.Migrations))
+	for _, migration := range m.Migrations {
+		statuses = append(statuses, Status{Migration: migration, Applied: migration.Version <= version})
+	}
+	return statuses, nil
+}
+
+// Pending returns the migrations which are not applied yet.
+func (m *Migrator) Pending() ([]Migration, error) {
+	version, dirty, err := m.Version()
+	if err != nil {
+		return nil, err
+	}
+	if dirty {
+		return nil, ErrDirty
+	}
+	var pending []Migration
+	for _, migration := range m.Migrations {
+		if migration.Version > version {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// Up applies all pending migrations and returns them.
+func (m *Migrator) Up() ([]Migration, error) {
+	pending, err := m.Pending()
+	if err != nil {
+		return nil, err
+	}
+	for i, migration := range pending {
+		err = m.run(migration.Up, migration.Version, migration.Version)
+		if err != nil {
+			return pending[:i], fmt.Errorf("migrate: %d_%s: %w", migration.Version, migration.Name, err)
+		}
+	}
+	return pending, nil
+}
+
+// Down rolls back the given number of the last applied migrations and returns them, the newest first.
+// A number below one rolls back all of them.
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	version, dirty, err := m.Version()
+	if err != nil {
+		return nil, err
+	}
+	if dirty {
+		return nil, ErrDirty
+	}
+	var index = m.index(version)
+	if version > 0 && index < 0 {
+		return nil, ErrUnknownVersion
+	}
+
+	var rolledBack []Migration
+	for ; index >= 0 && (steps < 1 || len(rolledBack) < steps); index-- {
+		var migration = m.Migrations[index]
+		var previous int64
+		if index > 0 {
+			previous = m.Migrations[index-1].Version
+		}
+		err = m.run(migration.Down, migration.Version, previous)
+		if err != nil {
+			return rolledBack, fmt.Errorf("migrate: %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		rolledBack = append(rolledBack, migration)
+	}
+	return rolledBack, nil
+}
+
+func (m *Migrator) index(version int64) int {
+	for i, migration := range m.Migrations {
+		if migration.Version == version {
+			return i
+		}
+	}
+	return -1
+}
+
+// run marks the database dirty with the version of the migration, runs the script and records the new version.
+// Schema changes of MySQL can't be rolled back, so a failed migration leaves the dirty flag set.
+func (m *Migrator) run(script string, version int64, next int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	err := m.setVersion(ctx, version, true)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(script) != "" {
+		// the scripts are run as they are, without rewriting them for the dialect
+		_, err = m.DB.DB.ExecContext(ctx, script)
+		if err != nil {
+			return err
+		}
+	}
+	return m.setVersion(ctx, next, false)
+}
+
+func (m *Migrator) setVersion(ctx context.Context, version int64, dirty bool) error {
+	return m.DB.WithTx(ctx, func(tx *data.DB) error {
+		_, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations")
+		if err != nil || version == 0 {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, dirty) VALUES (?, ?)", version, dirty)
+		return err
+	})
+}
+
+func (m *Migrator) createTable() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS schema_migrations (version BIGINT NOT NULL PRIMARY KEY, dirty BOOLEAN NOT NULL)")
+	return err
+}
@@ -0,0 +1,124 @@
+package migrate
+
+import (
+	"database/sql"
+	"easylist/internal/data"
+	"errors"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+var testMigrations = fstest.MapFS{
+	"000001_create_users_table.up.sql":     {Data: []byte("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL)")},
+	"000001_create_users_table.down.sql":   {Data: []byte("DROP TABLE users")},
+	"000002_add_email_to_users.up.sql":     {Data: []byte("ALTER TABLE users ADD COLUMN email TEXT")},
+	"000002_add_email_to_users.down.sql":   {Data: []byte("ALTER TABLE users DROP COLUMN email")},
+	"000003_create_lists_table.up.sql":     {Data: []byte("CREATE TABLE lists (id INTEGER PRIMARY KEY); CREATE INDEX lists_id_index ON lists (id)")},
+	"000003_create_lists_table.down.sql":   {Data: []byte("DROP TABLE lists")},
+	"postgres/000001_create_schema.up.sql": {Data: []byte("CREATE TABLE users (id BIGSERIAL PRIMARY KEY)")},
+	"README.md":                            {Data: []byte("not a migration")},
+}
+
+func newTestMigrator(t *testing.T, fsys fstest.MapFS) *Migrator {
+	migrations, err := Load(fsys, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite3", "file:"+filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return New(data.NewDB(db, data.SQLite), migrations)
+}
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load(testMigrations, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 3 {
+		t.Fatalf("want 3 migrations; got %d", len(migrations))
+	}
+	if migrations[0].Version != 1 || migrations[0].Name != "create_users_table" || migrations[0].Down != "DROP TABLE users" {
+		t.Errorf("unexpected first migration: %+v", migrations[0])
+	}
+	if migrations[2].Version != 3 {
+		t.Errorf("want migrations sorted by version; got %d last", migrations[2].Version)
+	}
+
+	_, err = Load(fstest.MapFS{"README.md": {Data: []byte("")}}, ".")
+	if !errors.Is(err, ErrNoMigrations) {
+		t.Errorf("want ErrNoMigrations; got %v", err)
+	}
+}
+
+func TestUpAndDown(t *testing.T) {
+	var migrator = newTestMigrator(t, testMigrations)
+
+	applied, err := migrator.Up()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 3 {
+		t.Errorf("want 3 applied migrations; got %d", len(applied))
+	}
+	version, dirty, err := migrator.Version()
+	if err != nil || version != 3 || dirty {
+		t.Errorf("want clean version 3; got %d, %v, %v", version, dirty, err)
+	}
+	applied, err = migrator.Up()
+	if err != nil || len(applied) != 0 {
+		t.Errorf("want nothing to apply the second time; got %d, %v", len(applied), err)
+	}
+
+	rolledBack, err := migrator.Down(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rolledBack) != 2 || rolledBack[0].Version != 3 || rolledBack[1].Version != 2 {
+		t.Errorf("want migrations 3 and 2 rolled back; got %+v", rolledBack)
+	}
+	statuses, err := migrator.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !statuses[0].Applied || statuses[1].Applied || statuses[2].Applied {
+		t.Errorf("want only the first migration applied; got %+v", statuses)
+	}
+
+	_, err = migrator.Down(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	version, _, err = migrator.Version()
+	if err != nil || version != 0 {
+		t.Errorf("want version 0 after rolling back everything; got %d, %v", version, err)
+	}
+}
+
+func TestFailedMigrationLeavesDirtyVersion(t *testing.T) {
+	var migrator = newTestMigrator(t, fstest.MapFS{
+		"000001_create_users_table.up.sql": {Data: []byte("CREATE TABLE users (id INTEGER PRIMARY KEY)")},
+		"000002_broken.up.sql":             {Data: []byte("CREATE TABLE")},
+	})
+
+	applied, err := migrator.Up()
+	if err == nil {
+		t.Fatal("want the broken migration to fail")
+	}
+	if len(applied) != 1 {
+		t.Errorf("want the first migration applied; got %d", len(applied))
+	}
+	version, dirty, err := migrator.Version()
+	if err != nil || version != 2 || !dirty {
+		t.Errorf("want dirty version 2; got %d, %v, %v", version, dirty, err)
+	}
+	_, err = migrator.Up()
+	if !errors.Is(err, ErrDirty) {
+		t.Errorf("want ErrDirty; got %v", err)
+	}
+}
@@ -0,0 +1,105 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency is used for users and items created before currencies were introduced.
+const DefaultCurrency = "USD"
+
+var (
+	ErrUnknownRate  = errors.New("unknown exchange rate")
+	ErrInvalidRates = errors.New("invalid exchange rates")
+)
+
+var CurrencyRX = regexp.MustCompile("^[A-Z]{3}$")
+
+// exponents lists the ISO 4217 currencies whose minor unit is not a hundredth of the major unit.
+var exponents = map[string]int{
+	"BHD": 3, "BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "IQD": 3, "ISK": 0, "JOD": 3, "JPY": 0, "KMF": 0,
+	"KRW": 0, "KWD": 3, "LYD": 3, "OMR": 3, "PYG": 0, "RWF": 0, "TND": 3, "UGX": 0, "UYI": 0, "VND": 0,
+	"VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+}
+
+// ValidCurrency reports whether code looks like an ISO 4217 currency code.
+func ValidCurrency(code string) bool {
+	return CurrencyRX.MatchString(code)
+}
+
+// Exponent is the number of decimal places of the currency, amounts are stored as integers of 10^-Exponent.
+func Exponent(currency string) int {
+	if exponent, ok := exponents[currency]; ok {
+		return exponent
+	}
+	return 2
+}
+
+// Format writes an amount of minor units as a decimal number followed by the currency, e.g. 1250 USD is "12.50 USD".
+func Format(amount int64, currency string) string {
+	var exponent = Exponent(currency)
+	var sign = ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	var digits = strconv.FormatInt(amount, 10)
+	if exponent > 0 {
+		if len(digits) <= exponent {
+			digits = strings.Repeat("0", exponent-len(digits)+1) + digits
+		}
+		digits = digits[:len(digits)-exponent] + "." + digits[len(digits)-exponent:]
+	}
+	return sign + digits + " " + currency
+}
+
+// Rates holds how many units of each currency are worth one unit of a common base currency.
+type Rates map[string]float64
+
+// Convert changes an amount of minor units of one currency into minor units of another, rounding half away from zero.
+func (r Rates) Convert(amount int64, from string, to string) (int64, error) {
+	if from == to {
+		return amount, nil
+	}
+	fromRate, ok := r[from]
+	if !ok || fromRate <= 0 {
+		return 0, ErrUnknownRate
+	}
+	toRate, ok := r[to]
+	if !ok || toRate <= 0 {
+		return 0, ErrUnknownRate
+	}
+	var converted = float64(amount) / fromRate * toRate * math.Pow10(Exponent(to)-Exponent(from))
+	return int64(math.Round(converted)), nil
+}
+
+// ParseRates reads rates in the common {"base": "EUR", "rates": {"USD": 1.08}} format, the base currency gets the rate 1.
+func ParseRates(r io.Reader) (Rates, error) {
+	var input struct {
+		Base  string             `json:"base"`
+		Rates map[string]float64 `json:"rates"`
+	}
+	var err = json.NewDecoder(r).Decode(&input)
+	if err != nil {
+		return nil, err
+	}
+	if !ValidCurrency(input.Base) || len(input.Rates) == 0 {
+		return nil, ErrInvalidRates
+	}
+
+	var rates = Rates{input.Base: 1}
+	for currency, rate := range input.Rates {
+		if !ValidCurrency(currency) || rate <= 0 || math.IsInf(rate, 0) {
+			return nil, ErrInvalidRates
+		}
+		if currency != input.Base {
+			rates[currency] = rate
+		}
+	}
+	return rates, nil
+}
@@ -0,0 +1,80 @@
+package money
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	var tests = []struct {
+		amount   int64
+		currency string
+		want     string
+	}{
+		{1250, "USD", "12.50 USD"},
+		{5, "EUR", "0.05 EUR"},
+		{-199, "EUR", "-1.99 EUR"},
+		{1500, "JPY", "1500 JPY"},
+		{1234, "KWD", "1.234 KWD"},
+		{0, "USD", "0.00 USD"},
+	}
+	for _, tt := range tests {
+		if got := Format(tt.amount, tt.currency); got != tt.want {
+			t.Errorf("Format(%d, %s) = %q; want %q", tt.amount, tt.currency, got, tt.want)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	var rates = Rates{"USD": 1, "EUR": 0.5, "JPY": 150}
+
+	var tests = []struct {
+		amount   int64
+		from, to string
+		want     int64
+	}{
+		{1000, "USD", "USD", 1000},
+		{1000, "USD", "EUR", 500},
+		{1000, "EUR", "USD", 2000},
+		{1000, "USD", "JPY", 1500},
+		{1500, "JPY", "USD", 1000},
+		{1, "USD", "EUR", 1},
+	}
+	for _, tt := range tests {
+		got, err := rates.Convert(tt.amount, tt.from, tt.to)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("Convert(%d, %s, %s) = %d; want %d", tt.amount, tt.from, tt.to, got, tt.want)
+		}
+	}
+
+	_, err := rates.Convert(100, "USD", "GBP")
+	if !errors.Is(err, ErrUnknownRate) {
+		t.Errorf("want ErrUnknownRate for a currency without rate, got %v", err)
+	}
+}
+
+func TestParseRates(t *testing.T) {
+	rates, err := ParseRates(strings.NewReader(`{"base": "EUR", "rates": {"USD": 1.08, "JPY": 160}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rates["EUR"] != 1 || rates["USD"] != 1.08 || rates["JPY"] != 160 {
+		t.Errorf("unexpected rates %v", rates)
+	}
+
+	for _, input := range []string{
+		`{"base": "euro", "rates": {"USD": 1.08}}`,
+		`{"base": "EUR", "rates": {}}`,
+		`{"base": "EUR", "rates": {"USD": -1}}`,
+		`{"base": "EUR", "rates": {"usd": 1.08}}`,
+	} {
+		_, err = ParseRates(strings.NewReader(input))
+		if !errors.Is(err, ErrInvalidRates) {
+			t.Errorf("want ErrInvalidRates for %s, got %v", input, err)
+		}
+	}
+}
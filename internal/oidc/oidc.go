@@ -0,0 +1,330 @@
+// Package oidc signs users in with an OpenID Connect provider using the authorization code flow with PKCE. The
+// endpoints of the provider are read from its discovery document and the id tokens are checked against its keys, so
+// only the issuer and the credentials of the client have to be configured.
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Leeway is the difference between the clocks of the provider and the server allowed when the expiry of an id
+// token is checked.
+const Leeway = time.Minute
+
+var ErrInvalidToken = errors.New("invalid id token")
+
+var DefaultScopes = []string{"openid", "email", "profile"}
+
+// Provider is an OpenID Connect provider the application is registered with as a client.
+type Provider struct {
+	Name         string
+	Issuer       string
+	ClientId     string
+	ClientSecret string
+	RedirectUrl  string
+	Scopes       []string
+	Client       *http.Client
+
+	mu       sync.Mutex
+	metadata *metadata
+	keys     map[string]*rsa.PublicKey
+}
+
+// metadata is the part of the discovery document the authorization code flow needs.
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksUri               string `json:"jwks_uri"`
+}
+
+// Claims are the claims of a verified id token.
+type Claims struct {
+	Issuer        string   `json:"iss"`
+	Subject       string   `json:"sub"`
+	Audience      audience `json:"aud"`
+	Expiry        int64    `json:"exp"`
+	Nonce         string   `json:"nonce"`
+	Email         string   `json:"email"`
+	EmailVerified boolean  `json:"email_verified"`
+	Name          string   `json:"name"`
+}
+
+// audience is a single client id or a list of them.
+type audience []string
+
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var single string
+	if json.Unmarshal(b, &single) == nil {
+		*a = audience{single}
+		return nil
+	}
+	var list []string
+	err := json.Unmarshal(b, &list)
+	*a = list
+	return err
+}
+
+// boolean is a boolean some providers send as the string "true".
+type boolean bool
+
+func (v *boolean) UnmarshalJSON(b []byte) error {
+	var s = strings.Trim(string(b), `"`)
+	*v = boolean(s == "true")
+	return nil
+}
+
+// RandomString returns a random url-safe string of 43 characters, used for the state, the nonce and the PKCE
+// verifier.
+func RandomString() (string, error) {
+	var randomBytes = make([]byte, 32)
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(randomBytes), nil
+}
+
+// Challenge returns the S256 PKCE challenge of the verifier.
+func Challenge(verifier string) string {
+	var hash = sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
+// AuthorizationUrl returns the url of the provider the user is sent to for signing in.
+func (p *Provider) AuthorizationUrl(ctx context.Context, state string, nonce string, verifier string) (string, error) {
+	m, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	var scopes = p.Scopes
+	if len(scopes) == 0 {
+		scopes = DefaultScopes
+	}
+	var qs = url.Values{}
+	qs.Set("response_type", "code")
+	qs.Set("client_id", p.ClientId)
+	qs.Set("redirect_uri", p.RedirectUrl)
+	qs.Set("scope", strings.Join(scopes, " "))
+	qs.Set("state", state)
+	qs.Set("nonce", nonce)
+	qs.Set("code_challenge", Challenge(verifier))
+	qs.Set("code_challenge_method", "S256")
+
+	var separator = "?"
+	if strings.Contains(m.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return m.AuthorizationEndpoint + separator + qs.Encode(), nil
+}
+
+// Exchange trades the authorization code for the id token of the user and returns its verified claims.
+func (p *Provider) Exchange(ctx context.Context, code string, verifier string, nonce string, now time.Time) (*Claims, error) {
+	m, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var form = url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.RedirectUrl)
+	form.Set("client_id", p.ClientId)
+	form.Set("code_verifier", verifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.ClientId), url.QueryEscape(p.ClientSecret))
+	}
+
+	var response struct {
+		IdToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	status, err := p.do(req, &response)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("oidc: token endpoint of %s answered %d: %s %s", p.Name, status, response.Error, response.ErrorDescription)
+	}
+	if response.IdToken == "" {
+		return nil, fmt.Errorf("oidc: token endpoint of %s returned no id token", p.Name)
+	}
+	return p.Verify(ctx, response.IdToken, nonce, now)
+}
+
+// Verify checks the signature and the claims of the id token. Only RS256, the algorithm every provider supports, is
+// accepted.
+func (p *Provider) Verify(ctx context.Context, idToken string, nonce string, now time.Time) (*Claims, error) {
+	m, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var parts = strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+	var header struct {
+		Algorithm string `json:"alg"`
+		KeyId     string `json:"kid"`
+	}
+	if decodeSegment(parts[0], &header) != nil || header.Algorithm != "RS256" {
+		return nil, ErrInvalidToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	key, err := p.key(ctx, m, header.KeyId)
+	if err != nil {
+		return nil, err
+	}
+	var hash = sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if rsa.VerifyPKCS1v15(key, crypto.SHA256, hash[:], signature) != nil {
+		return nil, ErrInvalidToken
+	}
+
+	var claims Claims
+	if decodeSegment(parts[1], &claims) != nil {
+		return nil, ErrInvalidToken
+	}
+	var audienceMatches = false
+	for _, clientId := range claims.Audience {
+		audienceMatches = audienceMatches || clientId == p.ClientId
+	}
+	switch {
+	case claims.Issuer != m.Issuer, !audienceMatches, claims.Subject == "":
+		return nil, ErrInvalidToken
+	case now.After(time.Unix(claims.Expiry, 0).Add(Leeway)):
+		return nil, ErrInvalidToken
+	case nonce != "" && claims.Nonce != nonce:
+		return nil, ErrInvalidToken
+	}
+	return &claims, nil
+}
+
+func decodeSegment(segment string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// discover reads the discovery document of the issuer once.
+func (p *Provider) discover(ctx context.Context) (*metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(p.Issuer, "/")+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+	var m metadata
+	status, err := p.do(req, &m)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("oidc: discovery document of %s answered %d", p.Name, status)
+	}
+	if strings.TrimSuffix(m.Issuer, "/") != strings.TrimSuffix(p.Issuer, "/") {
+		return nil, fmt.Errorf("oidc: discovery document of %s is for the issuer %q", p.Name, m.Issuer)
+	}
+	if m.AuthorizationEndpoint == "" || m.TokenEndpoint == "" || m.JwksUri == "" {
+		return nil, fmt.Errorf("oidc: discovery document of %s misses an endpoint", p.Name)
+	}
+	p.metadata = &m
+	return p.metadata, nil
+}
+
+// key returns the signing key of the provider, the keys are fetched again when the provider has rotated them.
+func (p *Provider) key(ctx context.Context, m *metadata, keyId string) (*rsa.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if key, ok := p.keys[keyId]; ok {
+		return key, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, m.JwksUri, nil)
+	if err != nil {
+		return nil, err
+	}
+	var jwks struct {
+		Keys []struct {
+			KeyType string `json:"kty"`
+			KeyId   string `json:"kid"`
+			N       string `json:"n"`
+			E       string `json:"e"`
+		} `json:"keys"`
+	}
+	status, err := p.do(req, &jwks)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("oidc: keys of %s answered %d", p.Name, status)
+	}
+
+	p.keys = make(map[string]*rsa.PublicKey, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		if jwk.KeyType != "RSA" {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(jwk.N)
+		e, errE := base64.RawURLEncoding.DecodeString(jwk.E)
+		if errN != nil || errE != nil {
+			continue
+		}
+		p.keys[jwk.KeyId] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	if key, ok := p.keys[keyId]; ok {
+		return key, nil
+	}
+	return nil, ErrInvalidToken
+}
+
+func (p *Provider) do(req *http.Request, v any) (int, error) {
+	var client = p.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return 0, err
+	}
+	if len(body) > 0 && json.Unmarshal(body, v) != nil && resp.StatusCode == http.StatusOK {
+		return 0, fmt.Errorf("oidc: %s answered with malformed json", req.URL.Host)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,104 @@
+package oidc
+
+import (
+	"context"
+	"easylist/internal/oidc/oidctest"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newProvider(t *testing.T) (*oidctest.Server, *Provider) {
+	t.Helper()
+	server := oidctest.NewServer("easylist", "secret")
+	t.Cleanup(server.Close)
+	server.User = oidctest.User{Subject: "42", Email: "me@example.com", EmailVerified: true, Name: "Me"}
+	return server, &Provider{Name: "test", Issuer: server.URL, ClientId: "easylist", ClientSecret: "secret", RedirectUrl: "http://127.0.0.1/callback"}
+}
+
+// authorize follows the authorization url to the redirect and returns its query.
+func authorize(t *testing.T, authorizationUrl string) url.Values {
+	t.Helper()
+	client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authorizationUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil || resp.StatusCode != http.StatusFound {
+		t.Fatalf("want a redirect; got %d %v", resp.StatusCode, err)
+	}
+	return location.Query()
+}
+
+func TestProvider_Exchange(t *testing.T) {
+	_, provider := newProvider(t)
+	var ctx = context.Background()
+
+	authorizationUrl, err := provider.AuthorizationUrl(ctx, "state", "nonce", "verifier-of-the-test-which-is-long-enough")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(authorizationUrl, "code_challenge="+Challenge("verifier-of-the-test-which-is-long-enough")) {
+		t.Fatalf("want the PKCE challenge in %s", authorizationUrl)
+	}
+	var qs = authorize(t, authorizationUrl)
+	if qs.Get("state") != "state" {
+		t.Fatalf("want the state back; got %q", qs.Get("state"))
+	}
+
+	if _, err := provider.Exchange(ctx, qs.Get("code"), "another-verifier", "nonce", time.Now()); err == nil {
+		t.Errorf("want a wrong verifier to be refused")
+	}
+
+	qs = authorize(t, authorizationUrl)
+	claims, err := provider.Exchange(ctx, qs.Get("code"), "verifier-of-the-test-which-is-long-enough", "nonce", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "42" || claims.Email != "me@example.com" || !bool(claims.EmailVerified) {
+		t.Errorf("want the claims of the user; got %+v", claims)
+	}
+}
+
+func TestProvider_Verify(t *testing.T) {
+	server, provider := newProvider(t)
+	var now = time.Unix(1700000000, 0)
+	var claims = func(overrides map[string]any) map[string]any {
+		var c = map[string]any{"iss": server.URL, "sub": "42", "aud": "easylist", "exp": now.Add(time.Hour).Unix(), "nonce": "nonce"}
+		for k, v := range overrides {
+			c[k] = v
+		}
+		return c
+	}
+	var valid = server.Sign(claims(nil))
+
+	tests := []struct {
+		name  string
+		token string
+		ok    bool
+	}{
+		{name: "valid", token: valid, ok: true},
+		{name: "audience list", token: server.Sign(claims(map[string]any{"aud": []string{"other", "easylist"}})), ok: true},
+		{name: "expiry within the leeway", token: server.Sign(claims(map[string]any{"exp": now.Add(-30 * time.Second).Unix()})), ok: true},
+		{name: "expired", token: server.Sign(claims(map[string]any{"exp": now.Add(-time.Hour).Unix()})), ok: false},
+		{name: "other audience", token: server.Sign(claims(map[string]any{"aud": "other"})), ok: false},
+		{name: "other issuer", token: server.Sign(claims(map[string]any{"iss": "https://example.com"})), ok: false},
+		{name: "other nonce", token: server.Sign(claims(map[string]any{"nonce": "other"})), ok: false},
+		{name: "no subject", token: server.Sign(claims(map[string]any{"sub": ""})), ok: false},
+		{name: "tampered", token: valid[:strings.LastIndex(valid, ".")] + ".c2lnbmF0dXJl", ok: false},
+		{name: "unsigned", token: "eyJhbGciOiJub25lIn0." + strings.Split(valid, ".")[1] + ".", ok: false},
+		{name: "malformed", token: "token", ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := provider.Verify(context.Background(), tt.token, "nonce", now)
+			if (err == nil) != tt.ok {
+				t.Errorf("Verify() error = %v; want ok %v", err, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+// Package oidctest provides a local OpenID Connect provider for the tests. It implements the discovery document,
+// the keys, an authorization endpoint which signs the configured user in without asking and a token endpoint which
+// checks the PKCE verifier.
+package oidctest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const KeyId = "test-key"
+
+// User is the account the provider signs in.
+type User struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// Server is the provider, its URL is the issuer.
+type Server struct {
+	*httptest.Server
+	ClientId     string
+	ClientSecret string
+	User         User
+
+	key   *rsa.PrivateKey
+	mu    sync.Mutex
+	codes map[string]authorization
+}
+
+type authorization struct {
+	user        User
+	redirectUri string
+	challenge   string
+	nonce       string
+}
+
+// NewServer starts the provider for the client.
+func NewServer(clientId string, clientSecret string) *Server {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	var s = &Server{ClientId: clientId, ClientSecret: clientSecret, key: key, codes: map[string]authorization{}}
+
+	var mux = http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", s.discovery)
+	mux.HandleFunc("/authorize", s.authorize)
+	mux.HandleFunc("/token", s.token)
+	mux.HandleFunc("/jwks", s.jwks)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+func (s *Server) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                           s.URL,
+		"authorization_endpoint":           s.URL + "/authorize",
+		"token_endpoint":                   s.URL + "/token",
+		"jwks_uri":                         s.URL + "/jwks",
+		"code_challenge_methods_supported": []string{"S256"},
+	})
+}
+
+// authorize signs the user in right away and redirects back to the client with the code.
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) {
+	var qs = r.URL.Query()
+	if qs.Get("client_id") != s.ClientId || qs.Get("response_type") != "code" || qs.Get("code_challenge_method") != "S256" {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	var code = randomString()
+	s.mu.Lock()
+	s.codes[code] = authorization{user: s.User, redirectUri: qs.Get("redirect_uri"), challenge: qs.Get("code_challenge"), nonce: qs.Get("nonce")}
+	s.mu.Unlock()
+
+	redirect, err := url.Parse(qs.Get("redirect_uri"))
+	if err != nil {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	var redirectQs = redirect.Query()
+	redirectQs.Set("code", code)
+	redirectQs.Set("state", qs.Get("state"))
+	redirect.RawQuery = redirectQs.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	var clientId, clientSecret, ok = r.BasicAuth()
+	if !ok {
+		clientId, clientSecret = r.PostFormValue("client_id"), r.PostFormValue("client_secret")
+	}
+	if clientId != s.ClientId || clientSecret != s.ClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	s.mu.Lock()
+	var code = r.PostFormValue("code")
+	a, found := s.codes[code]
+	delete(s.codes, code)
+	s.mu.Unlock()
+
+	var hash = sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+	if !found || r.PostFormValue("grant_type") != "authorization_code" || r.PostFormValue("redirect_uri") != a.redirectUri ||
+		base64.RawURLEncoding.EncodeToString(hash[:]) != a.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token": s.Sign(map[string]any{
+			"iss":            s.URL,
+			"sub":            a.user.Subject,
+			"aud":            s.ClientId,
+			"iat":            time.Now().Unix(),
+			"exp":            time.Now().Add(time.Hour).Unix(),
+			"nonce":          a.nonce,
+			"email":          a.user.Email,
+			"email_verified": a.user.EmailVerified,
+			"name":           a.user.Name,
+		}),
+	})
+}
+
+func (s *Server) jwks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": KeyId,
+			"n":   base64.RawURLEncoding.EncodeToString(s.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(s.key.E)).Bytes()),
+		}},
+	})
+}
+
+// Sign returns an id token with the claims signed by the key of the provider.
+func (s *Server) Sign(claims map[string]any) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": KeyId})
+	payload, _ := json.Marshal(claims)
+	var signingInput = base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	var hash = sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, hash[:])
+	if err != nil {
+		panic(err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func randomString() string {
+	var randomBytes = make([]byte, 16)
+	_, _ = rand.Read(randomBytes)
+	return base64.RawURLEncoding.EncodeToString(randomBytes)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,160 @@
+package schedule
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidSchedule = errors.New("invalid schedule")
+
+// aliases are the human friendly schedules accepted next to the five field cron expressions.
+var aliases = map[string]string{
+	"hourly":   "0 * * * *",
+	"daily":    "0 0 * * *",
+	"weekly":   "0 0 * * 1",
+	"monthly":  "0 0 1 * *",
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 1",
+	"@monthly": "0 0 1 * *",
+}
+
+type field struct {
+	min, max int
+}
+
+var fields = [...]field{
+	{0, 59}, // minute
+	{0, 23}, // hour
+	{1, 31}, // day of month
+	{1, 12}, // month
+	{0, 6},  // day of week, sunday is 0
+}
+
+// Schedule is a parsed cron expression with the fields minute, hour, day of month, month and day of week.
+type Schedule struct {
+	minutes  uint64
+	hours    uint64
+	days     uint64
+	months   uint64
+	weekdays uint64
+	// anyDay and anyWeekday remember a "*", cron matches either of the day fields when both are restricted.
+	anyDay     bool
+	anyWeekday bool
+}
+
+// Parse accepts hourly, daily, weekly, monthly or a cron expression like "30 8 * * 1-5".
+// Every field supports "*", numbers, ranges, lists and steps such as "*/15" or "1-10/2".
+func Parse(spec string) (*Schedule, error) {
+	spec = strings.ToLower(strings.TrimSpace(spec))
+	if alias, ok := aliases[spec]; ok {
+		spec = alias
+	}
+
+	var parts = strings.Fields(spec)
+	if len(parts) != len(fields) {
+		return nil, ErrInvalidSchedule
+	}
+
+	var bits [len(fields)]uint64
+	for i, part := range parts {
+		var err error
+		bits[i], err = parseField(part, fields[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &Schedule{
+		minutes:    bits[0],
+		hours:      bits[1],
+		days:       bits[2],
+		months:     bits[3],
+		weekdays:   bits[4],
+		anyDay:     parts[2] == "*",
+		anyWeekday: parts[4] == "*",
+	}, nil
+}
+
+func parseField(part string, f field) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(part, ",") {
+		var rangePart = item
+		var step = 1
+		if before, after, found := strings.Cut(item, "/"); found {
+			var err error
+			step, err = strconv.Atoi(after)
+			if err != nil || step < 1 {
+				return 0, ErrInvalidSchedule
+			}
+			rangePart = before
+		}
+
+		var start, end = f.min, f.max
+		if rangePart != "*" {
+			var err error
+			from, to, isRange := strings.Cut(rangePart, "-")
+			start, err = strconv.Atoi(from)
+			if err != nil {
+				return 0, ErrInvalidSchedule
+			}
+			end = start
+			if isRange {
+				end, err = strconv.Atoi(to)
+				if err != nil {
+					return 0, ErrInvalidSchedule
+				}
+			} else if step > 1 {
+				// "5/15" means every 15 starting at 5
+				end = f.max
+			}
+		}
+		if start < f.min || end > f.max || start > end {
+			return 0, ErrInvalidSchedule
+		}
+
+		for value := start; value <= end; value += step {
+			bits |= 1 << uint(value)
+		}
+	}
+	return bits, nil
+}
+
+// Next returns the first moment matching the schedule strictly after the given time, in the location of that time.
+// The zero time is returned when nothing matches during the next five years, e.g. for "0 0 31 2 *".
+func (s *Schedule) Next(after time.Time) time.Time {
+	var t = after.Truncate(time.Minute).Add(time.Minute)
+	var limit = t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if s.months&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hours&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.minutes&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (s *Schedule) matchesDay(t time.Time) bool {
+	var day = s.days&(1<<uint(t.Day())) != 0
+	var weekday = s.weekdays&(1<<uint(t.Weekday())) != 0
+	if s.anyDay || s.anyWeekday {
+		return day && weekday
+	}
+	return day || weekday
+}
@@ -0,0 +1,57 @@
+package schedule
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseRejectsInvalidSpec(t *testing.T) {
+	t.Parallel()
+
+	testCases := []string{"", "sometimes", "* * * *", "60 * * * *", "0 24 * * *", "0 0 0 * *", "0 0 * 13 *", "0 0 * * 7", "*/0 * * * *", "5-1 * * * *", "a * * * *"}
+
+	for _, spec := range testCases {
+		t.Run(spec, func(t *testing.T) {
+			_, err := Parse(spec)
+			if !errors.Is(err, ErrInvalidSchedule) {
+				t.Errorf("Parse(%q) error = %v; want %v", spec, err, ErrInvalidSchedule)
+			}
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	t.Parallel()
+
+	// 2024-01-10 is a wednesday
+	var from = time.Date(2024, time.January, 10, 10, 30, 15, 0, time.UTC)
+
+	testCases := []struct {
+		spec     string
+		expected time.Time
+	}{
+		{spec: "daily", expected: time.Date(2024, time.January, 11, 0, 0, 0, 0, time.UTC)},
+		{spec: "weekly", expected: time.Date(2024, time.January, 15, 0, 0, 0, 0, time.UTC)},
+		{spec: "@monthly", expected: time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{spec: "hourly", expected: time.Date(2024, time.January, 10, 11, 0, 0, 0, time.UTC)},
+		{spec: "*/15 * * * *", expected: time.Date(2024, time.January, 10, 10, 45, 0, 0, time.UTC)},
+		{spec: "30 8 * * 1-5", expected: time.Date(2024, time.January, 11, 8, 30, 0, 0, time.UTC)},
+		{spec: "0 9 1,15 * *", expected: time.Date(2024, time.January, 15, 9, 0, 0, 0, time.UTC)},
+		{spec: "0 0 29 2 *", expected: time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 0 13 * 5", expected: time.Date(2024, time.January, 12, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 0 31 2 *", expected: time.Time{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.spec, func(t *testing.T) {
+			s, err := Parse(tc.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if next := s.Next(from); !next.Equal(tc.expected) {
+				t.Errorf("Next() = %v; want %v", next, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Local keeps files in a directory on the local disk. The files are not served publicly,
+// URL signs links to BaseURL which are checked by Verify before the file is sent.
+type Local struct {
+	Root    string
+	BaseURL string
+	Secret  []byte
+}
+
+func NewLocal(root string, baseURL string, secret []byte) *Local {
+	return &Local{Root: root, BaseURL: strings.TrimSuffix(baseURL, "/"), Secret: secret}
+}
+
+func (l *Local) path(key string) (string, error) {
+	if !ValidKey(key) {
+		return "", ErrInvalidKey
+	}
+	return filepath.Join(l.Root, filepath.FromSlash(key)), nil
+}
+
+// Put writes the file to a temporary file first, so readers never see a partially written file.
+func (l *Local) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	name, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(name), 0755)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+func (l *Local) Get(ctx context.Context, key string) (io.ReadCloser, *Object, error) {
+	name, err := l.path(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	file, err := os.Open(name)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil, ErrNotFound
+		}
+		return nil, nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	if info.IsDir() {
+		file.Close()
+		return nil, nil, ErrNotFound
+	}
+	return file, &Object{Key: key, Size: info.Size(), ContentType: TypeByKey(key)}, nil
+}
+
+// Delete removes the file, a missing file is not an error.
+func (l *Local) Delete(ctx context.Context, key string) error {
+	name, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(name)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (l *Local) URL(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	if !ValidKey(key) {
+		return "", ErrInvalidKey
+	}
+	var expires = strconv.FormatInt(time.Now().Add(ttl).Unix(), 10)
+
+	var segments = strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	var query = url.Values{}
+	query.Set("expires", expires)
+	query.Set("signature", l.sign(key, expires))
+	return l.BaseURL + "/" + strings.Join(segments, "/") + "?" + query.Encode(), nil
+}
+
+// Verify checks the expires and signature parameters of a link made by URL.
+func (l *Local) Verify(key string, expires string, signature string) error {
+	if !ValidKey(key) {
+		return ErrInvalidKey
+	}
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > unix {
+		return ErrInvalidURL
+	}
+	if !hmac.Equal([]byte(l.sign(key, expires)), []byte(signature)) {
+		return ErrInvalidURL
+	}
+	return nil
+}
+
+func (l *Local) sign(key string, expires string) string {
+	var mac = hmac.New(sha256.New, l.Secret)
+	mac.Write([]byte(key + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
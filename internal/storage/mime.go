@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"net/http"
+	"path"
+	"strings"
+)
+
+// DefaultMaxSize is used when the size limit is not configured.
+const DefaultMaxSize = 5 << 20
+
+// DefaultAllowedTypes are the images accepted when the allowed types are not configured.
+var DefaultAllowedTypes = []string{"image/jpeg", "image/png", "image/webp"}
+
+var extensions = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"image/bmp":       ".bmp",
+	"application/pdf": ".pdf",
+	"text/plain":      ".txt",
+}
+
+// Limits restricts the size and the detected type of uploaded files.
+type Limits struct {
+	MaxSize      int64
+	AllowedTypes []string
+}
+
+// MaxBytes is the largest accepted file size.
+func (l Limits) MaxBytes() int64 {
+	if l.MaxSize <= 0 {
+		return DefaultMaxSize
+	}
+	return l.MaxSize
+}
+
+// Types are the accepted media types.
+func (l Limits) Types() []string {
+	if len(l.AllowedTypes) == 0 {
+		return DefaultAllowedTypes
+	}
+	return l.AllowedTypes
+}
+
+// Check detects the type of the file by its content and returns it if the file fits the limits.
+func (l Limits) Check(content []byte) (string, error) {
+	if int64(len(content)) > l.MaxBytes() {
+		return "", ErrTooLarge
+	}
+
+	var contentType = DetectType(content)
+	for _, t := range l.Types() {
+		if t == contentType {
+			return contentType, nil
+		}
+	}
+	return "", ErrUnsupportedType
+}
+
+// DetectType sniffs the media type of the content, parameters like charset are dropped.
+func DetectType(content []byte) string {
+	var contentType = http.DetectContentType(content)
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	return strings.TrimSpace(contentType)
+}
+
+// Extension returns the file extension for the media type, with the leading dot.
+func Extension(contentType string) string {
+	if ext, ok := extensions[contentType]; ok {
+		return ext
+	}
+	return ".bin"
+}
+
+// TypeByKey guesses the media type from the extension of the key, it is used by stores which do not keep it.
+func TypeByKey(key string) string {
+	var ext = strings.ToLower(path.Ext(key))
+	if ext == ".jpeg" {
+		return "image/jpeg"
+	}
+	for contentType, e := range extensions {
+		if e == ext {
+			return contentType
+		}
+	}
+	return "application/octet-stream"
+}
@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound        = errors.New("storage: object not found")
+	ErrInvalidKey      = errors.New("storage: invalid key")
+	ErrInvalidURL      = errors.New("storage: invalid or expired signed url")
+	ErrTooLarge        = errors.New("storage: file is too large")
+	ErrUnsupportedType = errors.New("storage: unsupported file type")
+)
+
+// Object describes a stored file.
+type Object struct {
+	Key         string
+	Size        int64
+	ContentType string
+}
+
+// Store keeps uploaded files, keys are slash separated relative paths like "covers/1/photo.jpg".
+type Store interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, *Object, error)
+	Delete(ctx context.Context, key string) error
+	// URL returns a link which allows to download the file without authentication until ttl passes.
+	URL(ctx context.Context, key string, ttl time.Duration) (string, error)
+}
+
+// ValidKey reports whether the key is a relative path which stays inside the store.
+func ValidKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+	return true
+}
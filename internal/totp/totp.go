@@ -0,0 +1,98 @@
+// Package totp implements the time-based one-time passwords of RFC 6238 the way the authenticator apps use them:
+// HMAC-SHA1, six digits and a period of 30 seconds. The time is always passed in, so the codes can be checked
+// against any clock.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const Digits = 6
+const Period = 30 * time.Second
+
+// Skew is the number of periods before and after the current one whose codes are accepted as well, it allows for
+// the clock of the phone to drift and for the time the user needs to type the code.
+const Skew = 1
+
+var ErrInvalidSecret = errors.New("invalid totp secret")
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret returns a new random secret of 160 bits encoded in base32, the size RFC 4226 recommends.
+func GenerateSecret() (string, error) {
+	var secret = make([]byte, 20)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return "", err
+	}
+	return encoding.EncodeToString(secret), nil
+}
+
+// URI returns the otpauth:// uri of the secret which the authenticator apps read from a QR code, e.g.
+// otpauth://totp/EasyList:me@example.com?secret=JBSWY3DPEHPK3PXP&issuer=EasyList&algorithm=SHA1&digits=6&period=30.
+func URI(issuer string, account string, secret string) string {
+	var qs = url.Values{}
+	qs.Set("secret", secret)
+	qs.Set("issuer", issuer)
+	qs.Set("algorithm", "SHA1")
+	qs.Set("digits", fmt.Sprint(Digits))
+	qs.Set("period", fmt.Sprint(int(Period.Seconds())))
+	var label = url.PathEscape(issuer) + ":" + url.PathEscape(account)
+	return "otpauth://totp/" + label + "?" + qs.Encode()
+}
+
+// Counter returns the number of the period the time falls in.
+func Counter(t time.Time) int64 {
+	return t.Unix() / int64(Period.Seconds())
+}
+
+// Code returns the code of the secret for the period with the counter.
+func Code(secret string, counter int64) (string, error) {
+	key, err := encoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil || len(key) == 0 {
+		return "", ErrInvalidSecret
+	}
+	var message = make([]byte, 8)
+	binary.BigEndian.PutUint64(message, uint64(counter))
+	var mac = hmac.New(sha1.New, key)
+	mac.Write(message)
+	var sum = mac.Sum(nil)
+
+	// the dynamic truncation of RFC 4226
+	var offset = sum[len(sum)-1] & 0x0f
+	var value = binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	var modulo uint32 = 1
+	for i := 0; i < Digits; i++ {
+		modulo *= 10
+	}
+	return fmt.Sprintf("%0*d", Digits, value%modulo), nil
+}
+
+// Verify checks the code against the periods around the time and returns the counter of the period it belongs to.
+// The caller keeps the counter to refuse the same code when it is entered again.
+func Verify(secret string, code string, t time.Time) (int64, bool) {
+	if len(code) != Digits {
+		return 0, false
+	}
+	var current = Counter(t)
+	for counter := current - Skew; counter <= current+Skew; counter++ {
+		expected, err := Code(secret, counter)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return counter, true
+		}
+	}
+	return 0, false
+}
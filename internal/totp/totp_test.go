@@ -0,0 +1,96 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA1 key of the test vectors in the appendix B of RFC 6238.
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestCode(t *testing.T) {
+	t.Parallel()
+
+	// the six last digits of the eight digit codes of RFC 6238
+	testCases := []struct {
+		unix     int64
+		expected string
+	}{
+		{unix: 59, expected: "287082"},
+		{unix: 1111111109, expected: "081804"},
+		{unix: 1111111111, expected: "050471"},
+		{unix: 1234567890, expected: "005924"},
+		{unix: 2000000000, expected: "279037"},
+		{unix: 20000000000, expected: "353130"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expected, func(t *testing.T) {
+			code, err := Code(rfcSecret, Counter(time.Unix(tc.unix, 0)))
+			if err != nil || code != tc.expected {
+				t.Errorf("Code() = %q, %v; want %q", code, err, tc.expected)
+			}
+		})
+	}
+
+	if _, err := Code("not base32!", 1); err != ErrInvalidSecret {
+		t.Errorf("Code() error = %v; want %v", err, ErrInvalidSecret)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	t.Parallel()
+
+	var now = time.Unix(1111111111, 0)
+	var current = Counter(now)
+
+	testCases := []struct {
+		name    string
+		counter int64
+		ok      bool
+	}{
+		{name: "current period", counter: current, ok: true},
+		{name: "previous period", counter: current - 1, ok: true},
+		{name: "next period", counter: current + 1, ok: true},
+		{name: "too old", counter: current - 2, ok: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			code, err := Code(rfcSecret, tc.counter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			counter, ok := Verify(rfcSecret, code, now)
+			if ok != tc.ok || ok && counter != tc.counter {
+				t.Errorf("Verify() = %d, %v; want %d, %v", counter, ok, tc.counter, tc.ok)
+			}
+		})
+	}
+
+	if _, ok := Verify(rfcSecret, "12345", now); ok {
+		t.Errorf("want a code of the wrong length to be refused")
+	}
+}
+
+func TestGenerateSecretAndURI(t *testing.T) {
+	t.Parallel()
+
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(secret) != 32 {
+		t.Errorf("want 32 base32 characters; got %q", secret)
+	}
+	if _, err := Code(secret, 1); err != nil {
+		t.Errorf("want the generated secret to be usable; got %v", err)
+	}
+
+	var uri = URI("Easy List", "me@example.com", secret)
+	if !strings.HasPrefix(uri, "otpauth://totp/Easy%20List:me@example.com?") || !strings.Contains(uri, "secret="+secret) || !strings.Contains(uri, "issuer=Easy+List") {
+		t.Errorf("URI() = %s", uri)
+	}
+}
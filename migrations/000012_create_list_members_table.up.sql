@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS `list_members`
+(
+    `id`          BIGINT UNSIGNED PRIMARY KEY NOT NULL AUTO_INCREMENT,
+    `list_id`     BIGINT          NOT NULL REFERENCES lists ON DELETE CASCADE,
+    `user_id`     BIGINT          NOT NULL REFERENCES users ON DELETE CASCADE,
+    `role`        VARCHAR(20)     NOT NULL DEFAULT 'viewer' COMMENT 'Роль участника: viewer или editor',
+    `is_accepted` BOOL            NOT NULL DEFAULT false COMMENT 'Принято ли приглашение',
+    `token_id`    BIGINT          NULL COMMENT 'Токен приглашения, пока оно не принято',
+    `created_at`  DATETIME        NOT NULL DEFAULT NOW(),
+    `updated_at`  DATETIME        NOT NULL DEFAULT NOW(),
+    UNIQUE KEY `list_members_list_id_user_id_unique` (`list_id`, `user_id`)
+);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS tombstones;
\ No newline at end of file
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS `tombstones`
+(
+    `id`          BIGINT UNSIGNED PRIMARY KEY NOT NULL AUTO_INCREMENT,
+    `user_id`     BIGINT          NOT NULL COMMENT 'Владелец удалённой записи',
+    `list_id`     BIGINT          NULL COMMENT 'Список, к которому относилась запись',
+    `record_type` VARCHAR(20)     NOT NULL COMMENT 'folders, lists или items',
+    `record_id`   BIGINT          NOT NULL,
+    `deleted_at`  DATETIME        NOT NULL DEFAULT NOW(),
+    INDEX `tombstones_user_id_deleted_at_index` (`user_id`, `deleted_at`),
+    INDEX `tombstones_list_id_deleted_at_index` (`list_id`, `deleted_at`)
+);
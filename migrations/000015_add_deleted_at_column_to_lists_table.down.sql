@@ -0,0 +1,2 @@
+ALTER TABLE `lists`
+    DROP COLUMN `deleted_at`;
\ No newline at end of file
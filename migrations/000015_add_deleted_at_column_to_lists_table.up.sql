@@ -0,0 +1 @@
+ALTER TABLE `lists` ADD COLUMN `deleted_at` DATETIME NULL DEFAULT NULL COMMENT 'Время перемещения в корзину';
\ No newline at end of file
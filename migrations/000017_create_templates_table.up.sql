@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS `templates`
+(
+    `id`          BIGINT UNSIGNED PRIMARY KEY NOT NULL AUTO_INCREMENT,
+    `user_id`     BIGINT          NOT NULL REFERENCES users ON DELETE CASCADE,
+    `folder_id`   BIGINT          NOT NULL DEFAULT 1 COMMENT 'Папка для создаваемых списков',
+    `name`        VARCHAR(255)    NOT NULL,
+    `icon`        VARCHAR(255)    NOT NULL DEFAULT 'mdi-view-list',
+    `schedule`    VARCHAR(100)    NOT NULL DEFAULT '' COMMENT 'Расписание в формате cron, пустое - без расписания',
+    `next_run_at` DATETIME        NULL COMMENT 'Когда будет создан следующий список',
+    `version`     INT             NOT NULL DEFAULT 1,
+    `created_at`  DATETIME        NOT NULL DEFAULT NOW(),
+    `updated_at`  DATETIME        NOT NULL DEFAULT NOW(),
+    INDEX `templates_next_run_at_index` (`next_run_at`)
+);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS `template_items`
+(
+    `id`            BIGINT UNSIGNED PRIMARY KEY NOT NULL AUTO_INCREMENT,
+    `template_id`   BIGINT          NOT NULL REFERENCES templates ON DELETE CASCADE,
+    `name`          VARCHAR(255)    NOT NULL,
+    `description`   TEXT,
+    `quantity`      INT             NOT NULL DEFAULT 0,
+    `quantity_type` VARCHAR(255)    NOT NULL DEFAULT 'piece',
+    `price`         DECIMAL(8, 2)   NOT NULL DEFAULT 0,
+    `is_starred`    BOOL            NOT NULL DEFAULT false,
+    `order`         INT             NOT NULL DEFAULT 1,
+    INDEX `template_items_template_id_index` (`template_id`)
+);
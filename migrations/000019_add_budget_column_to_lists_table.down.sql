@@ -0,0 +1,2 @@
+ALTER TABLE `lists`
+    DROP COLUMN `budget`;
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE `items`
+    DROP COLUMN `done_at`;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE `items` ADD COLUMN `done_at` DATETIME NULL DEFAULT NULL COMMENT 'Когда товар был отмечен купленным';
\ No newline at end of file
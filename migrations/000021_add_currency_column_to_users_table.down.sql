@@ -0,0 +1,2 @@
+ALTER TABLE users
+    DROP COLUMN currency;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE `users` ADD COLUMN `currency` CHAR(3) NOT NULL DEFAULT 'USD' COMMENT 'Валюта, в которой считаются итоги списков';
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE items
+    DROP COLUMN price_minor,
+    DROP COLUMN currency;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE `items`
+    ADD COLUMN `price_minor` BIGINT  NOT NULL DEFAULT 0 COMMENT 'Цена в минимальных единицах валюты (центах)',
+    ADD COLUMN `currency`    CHAR(3) NOT NULL DEFAULT 'USD' COMMENT 'Валюта цены, ISO 4217';
\ No newline at end of file
@@ -0,0 +1 @@
+UPDATE items SET price = price_minor / POW(10, IF(currency IN ('JPY', 'KRW', 'VND', 'ISK', 'CLP'), 0, IF(currency IN ('BHD', 'KWD', 'OMR', 'JOD', 'TND', 'IQD', 'LYD'), 3, 2)));
\ No newline at end of file
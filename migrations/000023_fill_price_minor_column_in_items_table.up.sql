@@ -0,0 +1 @@
+UPDATE `items` SET `price_minor` = ROUND(`price` * 100);
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE items
+    ADD COLUMN price DECIMAL(8, 2) NOT NULL DEFAULT 0 COMMENT 'Цена объекта DECIMAL(10,2)';
\ No newline at end of file
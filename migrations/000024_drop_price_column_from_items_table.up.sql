@@ -0,0 +1 @@
+ALTER TABLE `items` DROP COLUMN `price`;
\ No newline at end of file
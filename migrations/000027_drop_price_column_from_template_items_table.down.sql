@@ -0,0 +1,2 @@
+ALTER TABLE template_items
+    ADD COLUMN price DECIMAL(8, 2) NOT NULL DEFAULT 0;
\ No newline at end of file
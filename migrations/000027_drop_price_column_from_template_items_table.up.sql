@@ -0,0 +1 @@
+ALTER TABLE `template_items` DROP COLUMN `price`;
\ No newline at end of file
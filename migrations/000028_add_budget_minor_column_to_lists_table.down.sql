@@ -0,0 +1,3 @@
+ALTER TABLE lists
+    DROP COLUMN budget_minor,
+    DROP COLUMN budget_currency;
\ No newline at end of file
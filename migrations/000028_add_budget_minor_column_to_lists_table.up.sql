@@ -0,0 +1,3 @@
+ALTER TABLE `lists`
+    ADD COLUMN `budget_minor`    BIGINT  NULL DEFAULT NULL COMMENT 'Бюджет в минимальных единицах валюты, NULL - без бюджета',
+    ADD COLUMN `budget_currency` CHAR(3) NOT NULL DEFAULT 'USD' COMMENT 'Валюта бюджета, ISO 4217';
\ No newline at end of file
@@ -0,0 +1 @@
+UPDATE lists SET budget = budget_minor / POW(10, IF(budget_currency IN ('JPY', 'KRW', 'VND', 'ISK', 'CLP'), 0, IF(budget_currency IN ('BHD', 'KWD', 'OMR', 'JOD', 'TND', 'IQD', 'LYD'), 3, 2))) WHERE budget_minor IS NOT NULL;
\ No newline at end of file
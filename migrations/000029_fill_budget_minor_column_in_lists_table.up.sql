@@ -0,0 +1 @@
+UPDATE `lists` SET `budget_minor` = ROUND(`budget` * 100) WHERE `budget` IS NOT NULL;
\ No newline at end of file
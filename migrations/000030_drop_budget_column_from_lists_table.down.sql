@@ -0,0 +1,2 @@
+ALTER TABLE lists
+    ADD COLUMN budget DECIMAL(10, 2) NULL DEFAULT NULL COMMENT 'Бюджет списка, NULL - без бюджета';
\ No newline at end of file
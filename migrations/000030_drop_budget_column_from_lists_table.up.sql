@@ -0,0 +1 @@
+ALTER TABLE `lists` DROP COLUMN `budget`;
\ No newline at end of file
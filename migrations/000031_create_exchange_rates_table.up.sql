@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS `exchange_rates`
+(
+    `currency`   CHAR(3)         PRIMARY KEY NOT NULL COMMENT 'Код валюты, ISO 4217',
+    `rate`       DECIMAL(20, 10) NOT NULL COMMENT 'Сколько единиц валюты стоит одна единица базовой валюты',
+    `updated_at` DATETIME        NOT NULL DEFAULT NOW()
+);
\ No newline at end of file
@@ -0,0 +1 @@
+DELETE FROM permissions WHERE code = 'rates:write';
\ No newline at end of file
@@ -0,0 +1 @@
+INSERT INTO permissions (code) VALUES ('rates:write');
\ No newline at end of file
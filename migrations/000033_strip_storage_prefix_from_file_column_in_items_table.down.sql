@@ -0,0 +1 @@
+UPDATE `items` SET `file` = CONCAT('storage/', `file`) WHERE `file` != '';
\ No newline at end of file
@@ -0,0 +1 @@
+UPDATE `items` SET `file` = SUBSTRING(`file`, 9) WHERE `file` LIKE 'storage/%';
\ No newline at end of file
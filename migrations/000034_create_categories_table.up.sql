@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS `categories`
+(
+    `id`         BIGINT UNSIGNED PRIMARY KEY NOT NULL AUTO_INCREMENT,
+    `user_id`    BIGINT          NOT NULL REFERENCES users ON DELETE CASCADE,
+    `name`       VARCHAR(255)    NOT NULL,
+    `icon`       VARCHAR(255)    NOT NULL DEFAULT 'mdi-tag',
+    `version`    INT             NOT NULL DEFAULT 1,
+    `order`      INT             NOT NULL DEFAULT 1 COMMENT 'Порядок отделов магазина',
+    `created_at` DATETIME        NOT NULL DEFAULT NOW(),
+    `updated_at` DATETIME        NOT NULL DEFAULT NOW(),
+    INDEX `categories_user_id_order_index` (`user_id`, `order`)
+);
@@ -0,0 +1,3 @@
+DROP INDEX `items_category_id_index` ON `items`;
+ALTER TABLE `items`
+    DROP COLUMN `category_id`;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS item_history;
\ No newline at end of file
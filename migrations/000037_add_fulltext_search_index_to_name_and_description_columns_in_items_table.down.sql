@@ -0,0 +1 @@
+ALTER TABLE items DROP INDEX items_name_description_fulltext;
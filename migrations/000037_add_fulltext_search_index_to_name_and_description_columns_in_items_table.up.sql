@@ -0,0 +1,2 @@
+-- Общий поиск ищет товары и по названию, и по описанию.
+ALTER TABLE items ADD FULLTEXT `items_name_description_fulltext` (name, description);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS `sessions`
+(
+    `id`           BIGINT UNSIGNED PRIMARY KEY NOT NULL AUTO_INCREMENT,
+    `user_id`      BIGINT          NOT NULL REFERENCES users ON DELETE CASCADE,
+    `device_name`  VARCHAR(255)    NOT NULL DEFAULT '' COMMENT 'Название устройства, указанное при входе',
+    `ip`           VARCHAR(45)     NOT NULL DEFAULT '' COMMENT 'IP-адрес последнего запроса',
+    `user_agent`   VARCHAR(255)    NOT NULL DEFAULT '' COMMENT 'User-Agent последнего запроса',
+    `created_at`   DATETIME        NOT NULL DEFAULT NOW(),
+    `last_used_at` DATETIME        NOT NULL DEFAULT NOW() COMMENT 'Время последнего запроса с токеном доступа сессии',
+    INDEX `sessions_user_id_index` (`user_id`)
+);
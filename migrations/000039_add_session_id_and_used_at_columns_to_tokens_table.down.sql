@@ -0,0 +1,5 @@
+DROP INDEX `tokens_session_id_index` ON `tokens`;
+ALTER TABLE `tokens`
+    DROP COLUMN `used_at`;
+ALTER TABLE `tokens`
+    DROP COLUMN `session_id`;
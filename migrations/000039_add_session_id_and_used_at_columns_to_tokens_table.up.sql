@@ -0,0 +1,3 @@
+ALTER TABLE `tokens` ADD COLUMN `session_id` BIGINT NULL DEFAULT NULL COMMENT 'Сессия, которой принадлежат токены доступа и обновления';
+ALTER TABLE `tokens` ADD COLUMN `used_at` DATETIME NULL DEFAULT NULL COMMENT 'Время обмена токена обновления, повторный обмен отзывает всю сессию';
+CREATE INDEX `tokens_session_id_index` ON `tokens` (`session_id`);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS `personal_access_tokens`
+(
+    `id`           BIGINT UNSIGNED PRIMARY KEY NOT NULL AUTO_INCREMENT,
+    `token_id`     BIGINT          NOT NULL REFERENCES tokens ON DELETE CASCADE,
+    `user_id`      BIGINT          NOT NULL REFERENCES users ON DELETE CASCADE,
+    `name`         VARCHAR(255)    NOT NULL COMMENT 'Название, под которым пользователь видит токен',
+    `list_id`      BIGINT          NULL DEFAULT NULL COMMENT 'Единственный список, к которому токен даёт доступ',
+    `created_at`   DATETIME        NOT NULL DEFAULT NOW(),
+    `last_used_at` DATETIME        NULL DEFAULT NULL COMMENT 'Время последнего запроса с токеном',
+    UNIQUE INDEX `personal_access_tokens_token_id_unique` (`token_id`),
+    INDEX `personal_access_tokens_user_id_index` (`user_id`)
+);
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS `tokens_permissions`
+(
+    `id`            BIGINT UNSIGNED PRIMARY KEY NOT NULL AUTO_INCREMENT,
+    `token_id`      BIGINT          NOT NULL COMMENT 'Персональный токен доступа, ограниченный этими правами' REFERENCES tokens ON DELETE CASCADE,
+    `permission_id` BIGINT          NOT NULL REFERENCES permissions ON DELETE CASCADE,
+    `created_at`    DATETIME        NOT NULL DEFAULT NOW(),
+    INDEX `tokens_permissions_token_id_index` (`token_id`)
+);
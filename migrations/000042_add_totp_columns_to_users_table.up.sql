@@ -0,0 +1,3 @@
+ALTER TABLE `users` ADD COLUMN `totp_secret` VARCHAR(64) NULL DEFAULT NULL COMMENT 'Секрет TOTP в base32, задаётся при подключении двухфакторной аутентификации';
+ALTER TABLE `users` ADD COLUMN `totp_enabled` BOOL NOT NULL DEFAULT false COMMENT 'Вход требует код TOTP после подтверждения первого кода';
+ALTER TABLE `users` ADD COLUMN `totp_counter` BIGINT NOT NULL DEFAULT 0 COMMENT 'Последний принятый временной шаг, один код нельзя ввести дважды';
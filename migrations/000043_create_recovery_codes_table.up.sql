@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS `recovery_codes`
+(
+    `id`         BIGINT UNSIGNED PRIMARY KEY NOT NULL AUTO_INCREMENT,
+    `user_id`    BIGINT          NOT NULL REFERENCES users ON DELETE CASCADE,
+    `hash`       VARCHAR(100)    NOT NULL COMMENT 'Хэш кода восстановления по алгоритму sha-256',
+    `used_at`    DATETIME        NULL DEFAULT NULL COMMENT 'Время входа с кодом, каждый код действует один раз',
+    `created_at` DATETIME        NOT NULL DEFAULT NOW(),
+    INDEX `recovery_codes_user_id_index` (`user_id`)
+);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS `user_identities`
+(
+    `id`         BIGINT UNSIGNED PRIMARY KEY NOT NULL AUTO_INCREMENT,
+    `user_id`    BIGINT          NOT NULL REFERENCES users ON DELETE CASCADE,
+    `provider`   VARCHAR(100)    NOT NULL COMMENT 'Название провайдера OpenID Connect из конфигурации',
+    `subject`    VARCHAR(255)    NOT NULL COMMENT 'Идентификатор пользователя у провайдера, claim sub',
+    `email`      VARCHAR(255)    NOT NULL COMMENT 'Подтверждённый провайдером email на момент привязки',
+    `created_at` DATETIME        NOT NULL DEFAULT NOW(),
+    UNIQUE INDEX `user_identities_provider_subject_unique` (`provider`, `subject`),
+    INDEX `user_identities_user_id_index` (`user_id`)
+);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS `oidc_authorizations`
+(
+    `id`            BIGINT UNSIGNED PRIMARY KEY NOT NULL AUTO_INCREMENT,
+    `hash`          VARCHAR(100)    NOT NULL COMMENT 'Хэш параметра state по алгоритму sha-256',
+    `provider`      VARCHAR(100)    NOT NULL COMMENT 'Название провайдера OpenID Connect из конфигурации',
+    `code_verifier` VARCHAR(128)    NOT NULL COMMENT 'Секрет PKCE, отправляется провайдеру вместе с кодом',
+    `nonce`         VARCHAR(100)    NOT NULL COMMENT 'Значение, которое провайдер возвращает в id_token',
+    `expired_at`    DATETIME        NOT NULL,
+    UNIQUE INDEX `oidc_authorizations_hash_unique` (`hash`)
+);
@@ -0,0 +1,8 @@
+// Package migrations embeds the SQL migrations into the binary. The MySQL migrations are in the root of the directory,
+// the migrations of the other databases are in the directory named after the dialect.
+package migrations
+
+import "embed"
+
+//go:embed *.sql postgres/*.sql sqlite/*.sql
+var FS embed.FS
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS exchange_rates, template_items, templates, tombstones, list_members, items, lists, folders, users_permissions, permissions, tokens, users;
\ No newline at end of file
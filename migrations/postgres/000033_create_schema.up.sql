@@ -0,0 +1,169 @@
+-- Схема PostgreSQL соответствует схеме MySQL после миграции 000033, дальнейшие миграции добавляются для каждой базы.
+CREATE TABLE IF NOT EXISTS "users"
+(
+    "id"         BIGSERIAL PRIMARY KEY,
+    "name"       VARCHAR(255) NOT NULL,
+    "email"      VARCHAR(255) NOT NULL CONSTRAINT "users_email_key" UNIQUE,
+    "password"   VARCHAR(255) NOT NULL,
+    "created_at" TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    "updated_at" TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    "is_active"  BOOLEAN      NOT NULL DEFAULT false,
+    "version"    INT          NOT NULL DEFAULT 1,
+    "currency"   CHAR(3)      NOT NULL DEFAULT 'USD'
+);
+
+CREATE TABLE IF NOT EXISTS "tokens"
+(
+    "id"         BIGSERIAL PRIMARY KEY,
+    "hash"       VARCHAR(100) NOT NULL,
+    "user_id"    BIGINT       NOT NULL REFERENCES users ON DELETE CASCADE,
+    "expired_at" TIMESTAMPTZ  NOT NULL,
+    "scope"      VARCHAR(255) NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS "permissions"
+(
+    "id"   BIGSERIAL PRIMARY KEY,
+    "code" VARCHAR(255) NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS "users_permissions"
+(
+    "id"            BIGSERIAL PRIMARY KEY,
+    "user_id"       BIGINT      NOT NULL REFERENCES users ON DELETE CASCADE,
+    "permission_id" BIGINT      NOT NULL REFERENCES permissions ON DELETE CASCADE,
+    "created_at"    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO permissions (code)
+VALUES
+    ('folders:read'),
+    ('folders:write'),
+    ('lists:write'),
+    ('lists:read'),
+    ('items:read'),
+    ('items:write'),
+    ('rates:write');
+
+CREATE TABLE IF NOT EXISTS "folders"
+(
+    "id"         BIGSERIAL PRIMARY KEY,
+    "user_id"    BIGINT       REFERENCES users ON DELETE CASCADE,
+    "name"       VARCHAR(255) NOT NULL,
+    "icon"       VARCHAR(255) NOT NULL DEFAULT 'mdi-folder',
+    "version"    INT          NOT NULL DEFAULT 1,
+    "order"      INT          NOT NULL DEFAULT 1,
+    "created_at" TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    "updated_at" TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    "deleted_at" TIMESTAMPTZ  NULL DEFAULT NULL
+);
+
+-- Папка по умолчанию, id задан явно, поэтому последовательность сдвигается вручную.
+INSERT INTO folders (id, user_id, name, icon, version, "order", created_at, updated_at) VALUES (1, null, 'default', 'mdi-folder', 1, 1, NOW(), NOW());
+SELECT setval(pg_get_serial_sequence('folders', 'id'), 1);
+
+CREATE TABLE IF NOT EXISTS "lists"
+(
+    "id"              BIGSERIAL PRIMARY KEY,
+    "user_id"         BIGINT       NOT NULL REFERENCES users ON DELETE CASCADE,
+    "folder_id"       BIGINT       NOT NULL REFERENCES folders ON DELETE CASCADE,
+    "name"            VARCHAR(255) NOT NULL,
+    "icon"            VARCHAR(255) NOT NULL DEFAULT 'mdi-view-list',
+    "version"         INT          NOT NULL DEFAULT 1,
+    "order"           INT          NOT NULL DEFAULT 1,
+    "link"            CHAR(36),
+    "created_at"      TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    "updated_at"      TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    "deleted_at"      TIMESTAMPTZ  NULL DEFAULT NULL,
+    "budget_minor"    BIGINT       NULL DEFAULT NULL,
+    "budget_currency" CHAR(3)      NOT NULL DEFAULT 'USD'
+);
+
+CREATE TABLE IF NOT EXISTS "items"
+(
+    "id"            BIGSERIAL PRIMARY KEY,
+    "user_id"       BIGINT       NOT NULL REFERENCES users ON DELETE CASCADE,
+    "list_id"       BIGINT       NOT NULL REFERENCES lists ON DELETE CASCADE,
+    "name"          VARCHAR(255) NOT NULL,
+    "description"   TEXT,
+    "quantity"      INT          NOT NULL DEFAULT 0,
+    "quantity_type" VARCHAR(255) NOT NULL DEFAULT 'piece',
+    "is_starred"    BOOLEAN      NOT NULL DEFAULT false,
+    "file"          TEXT         NOT NULL,
+    "created_at"    TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    "updated_at"    TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    "version"       INT          NOT NULL DEFAULT 1,
+    "order"         INT          NOT NULL DEFAULT 1,
+    "is_done"       BOOLEAN      NOT NULL DEFAULT false,
+    "deleted_at"    TIMESTAMPTZ  NULL DEFAULT NULL,
+    "done_at"       TIMESTAMPTZ  NULL DEFAULT NULL,
+    "price_minor"   BIGINT       NOT NULL DEFAULT 0,
+    "currency"      CHAR(3)      NOT NULL DEFAULT 'USD'
+);
+
+-- Индексы полнотекстового поиска по названиям, вместо FULLTEXT в MySQL.
+CREATE INDEX "folders_name_search_index" ON folders USING GIN (to_tsvector('simple', name));
+CREATE INDEX "lists_name_search_index" ON lists USING GIN (to_tsvector('simple', name));
+CREATE INDEX "items_name_search_index" ON items USING GIN (to_tsvector('simple', name));
+
+CREATE TABLE IF NOT EXISTS "list_members"
+(
+    "id"          BIGSERIAL PRIMARY KEY,
+    "list_id"     BIGINT      NOT NULL REFERENCES lists ON DELETE CASCADE,
+    "user_id"     BIGINT      NOT NULL REFERENCES users ON DELETE CASCADE,
+    "role"        VARCHAR(20) NOT NULL DEFAULT 'viewer',
+    "is_accepted" BOOLEAN     NOT NULL DEFAULT false,
+    "token_id"    BIGINT      NULL,
+    "created_at"  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    "updated_at"  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT "list_members_list_id_user_id_unique" UNIQUE ("list_id", "user_id")
+);
+
+CREATE TABLE IF NOT EXISTS "tombstones"
+(
+    "id"          BIGSERIAL PRIMARY KEY,
+    "user_id"     BIGINT      NOT NULL,
+    "list_id"     BIGINT      NULL,
+    "record_type" VARCHAR(20) NOT NULL,
+    "record_id"   BIGINT      NOT NULL,
+    "deleted_at"  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX "tombstones_user_id_deleted_at_index" ON tombstones ("user_id", "deleted_at");
+CREATE INDEX "tombstones_list_id_deleted_at_index" ON tombstones ("list_id", "deleted_at");
+
+CREATE TABLE IF NOT EXISTS "templates"
+(
+    "id"          BIGSERIAL PRIMARY KEY,
+    "user_id"     BIGINT       NOT NULL REFERENCES users ON DELETE CASCADE,
+    "folder_id"   BIGINT       NOT NULL DEFAULT 1,
+    "name"        VARCHAR(255) NOT NULL,
+    "icon"        VARCHAR(255) NOT NULL DEFAULT 'mdi-view-list',
+    "schedule"    VARCHAR(100) NOT NULL DEFAULT '',
+    "next_run_at" TIMESTAMPTZ  NULL,
+    "version"     INT          NOT NULL DEFAULT 1,
+    "created_at"  TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    "updated_at"  TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+CREATE INDEX "templates_next_run_at_index" ON templates ("next_run_at");
+
+CREATE TABLE IF NOT EXISTS "template_items"
+(
+    "id"            BIGSERIAL PRIMARY KEY,
+    "template_id"   BIGINT       NOT NULL REFERENCES templates ON DELETE CASCADE,
+    "name"          VARCHAR(255) NOT NULL,
+    "description"   TEXT,
+    "quantity"      INT          NOT NULL DEFAULT 0,
+    "quantity_type" VARCHAR(255) NOT NULL DEFAULT 'piece',
+    "is_starred"    BOOLEAN      NOT NULL DEFAULT false,
+    "order"         INT          NOT NULL DEFAULT 1,
+    "price_minor"   BIGINT       NOT NULL DEFAULT 0,
+    "currency"      CHAR(3)      NOT NULL DEFAULT 'USD'
+);
+CREATE INDEX "template_items_template_id_index" ON template_items ("template_id");
+
+CREATE TABLE IF NOT EXISTS "exchange_rates"
+(
+    "currency"   CHAR(3)         PRIMARY KEY NOT NULL,
+    "rate"       DECIMAL(20, 10) NOT NULL,
+    "updated_at" TIMESTAMPTZ     NOT NULL DEFAULT NOW()
+);
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE "items" ADD COLUMN "category_id" BIGINT NULL DEFAULT NULL REFERENCES categories ON DELETE SET NULL;
+CREATE INDEX "items_category_id_index" ON items ("category_id");
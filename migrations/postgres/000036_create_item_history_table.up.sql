@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS "item_history"
+(
+    "id"             BIGSERIAL PRIMARY KEY,
+    "user_id"        BIGINT       NOT NULL REFERENCES users ON DELETE CASCADE,
+    "name"           VARCHAR(190) NOT NULL,
+    "name_key"       VARCHAR(190) NOT NULL,
+    "quantity"       INT          NOT NULL DEFAULT 0,
+    "quantity_type"  VARCHAR(255) NOT NULL DEFAULT 'piece',
+    "price_minor"    BIGINT       NOT NULL DEFAULT 0,
+    "currency"       CHAR(3)      NOT NULL DEFAULT 'USD',
+    "category_id"    BIGINT       NULL DEFAULT NULL REFERENCES categories ON DELETE SET NULL,
+    "times_bought"   INT          NOT NULL DEFAULT 1,
+    "last_bought_at" TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+-- text_pattern_ops позволяет искать по началу названия с LIKE 'молоко%' по индексу.
+CREATE UNIQUE INDEX "item_history_user_id_name_key_unique" ON item_history ("user_id", "name_key" text_pattern_ops);
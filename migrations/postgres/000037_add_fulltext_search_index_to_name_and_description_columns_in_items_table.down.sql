@@ -0,0 +1 @@
+DROP INDEX IF EXISTS "items_name_description_search_index";
@@ -0,0 +1,2 @@
+-- Выражение должно совпадать с тем, которое строит Dialect.search для названия и описания товара.
+CREATE INDEX "items_name_description_search_index" ON items USING GIN (to_tsvector('simple', name || ' ' || COALESCE(description, '')));
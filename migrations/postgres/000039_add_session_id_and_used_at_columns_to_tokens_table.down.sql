@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS "tokens_session_id_index";
+ALTER TABLE "tokens" DROP COLUMN "used_at";
+ALTER TABLE "tokens" DROP COLUMN "session_id";
@@ -0,0 +1,3 @@
+ALTER TABLE "tokens" ADD COLUMN "session_id" BIGINT NULL DEFAULT NULL REFERENCES sessions ON DELETE CASCADE;
+ALTER TABLE "tokens" ADD COLUMN "used_at" TIMESTAMPTZ NULL DEFAULT NULL;
+CREATE INDEX "tokens_session_id_index" ON tokens ("session_id");
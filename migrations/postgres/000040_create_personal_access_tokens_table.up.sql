@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS "personal_access_tokens"
+(
+    "id"           BIGSERIAL PRIMARY KEY,
+    "token_id"     BIGINT       NOT NULL REFERENCES tokens ON DELETE CASCADE,
+    "user_id"      BIGINT       NOT NULL REFERENCES users ON DELETE CASCADE,
+    "name"         VARCHAR(255) NOT NULL,
+    "list_id"      BIGINT       NULL DEFAULT NULL REFERENCES lists ON DELETE CASCADE,
+    "created_at"   TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    "last_used_at" TIMESTAMPTZ  NULL DEFAULT NULL
+);
+CREATE UNIQUE INDEX "personal_access_tokens_token_id_unique" ON personal_access_tokens ("token_id");
+CREATE INDEX "personal_access_tokens_user_id_index" ON personal_access_tokens ("user_id");
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS "tokens_permissions"
+(
+    "id"            BIGSERIAL PRIMARY KEY,
+    "token_id"      BIGINT      NOT NULL REFERENCES tokens ON DELETE CASCADE,
+    "permission_id" BIGINT      NOT NULL REFERENCES permissions ON DELETE CASCADE,
+    "created_at"    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX "tokens_permissions_token_id_index" ON tokens_permissions ("token_id");
@@ -0,0 +1,3 @@
+ALTER TABLE "users" DROP COLUMN "totp_counter";
+ALTER TABLE "users" DROP COLUMN "totp_enabled";
+ALTER TABLE "users" DROP COLUMN "totp_secret";
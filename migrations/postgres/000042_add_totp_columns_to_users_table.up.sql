@@ -0,0 +1,3 @@
+ALTER TABLE "users" ADD COLUMN "totp_secret" VARCHAR(64) NULL DEFAULT NULL;
+ALTER TABLE "users" ADD COLUMN "totp_enabled" BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE "users" ADD COLUMN "totp_counter" BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS "recovery_codes"
+(
+    "id"         BIGSERIAL PRIMARY KEY,
+    "user_id"    BIGINT       NOT NULL REFERENCES users ON DELETE CASCADE,
+    "hash"       VARCHAR(100) NOT NULL,
+    "used_at"    TIMESTAMPTZ  NULL DEFAULT NULL,
+    "created_at" TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+CREATE INDEX "recovery_codes_user_id_index" ON recovery_codes ("user_id");
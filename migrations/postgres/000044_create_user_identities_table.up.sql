@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS "user_identities"
+(
+    "id"         BIGSERIAL PRIMARY KEY,
+    "user_id"    BIGINT       NOT NULL REFERENCES users ON DELETE CASCADE,
+    "provider"   VARCHAR(100) NOT NULL,
+    "subject"    VARCHAR(255) NOT NULL,
+    "email"      VARCHAR(255) NOT NULL,
+    "created_at" TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+CREATE UNIQUE INDEX "user_identities_provider_subject_unique" ON user_identities ("provider", "subject");
+CREATE INDEX "user_identities_user_id_index" ON user_identities ("user_id");
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS "oidc_authorizations"
+(
+    "id"            BIGSERIAL PRIMARY KEY,
+    "hash"          VARCHAR(100) NOT NULL,
+    "provider"      VARCHAR(100) NOT NULL,
+    "code_verifier" VARCHAR(128) NOT NULL,
+    "nonce"         VARCHAR(100) NOT NULL,
+    "expired_at"    TIMESTAMPTZ  NOT NULL
+);
+CREATE UNIQUE INDEX "oidc_authorizations_hash_unique" ON oidc_authorizations ("hash");
@@ -0,0 +1,12 @@
+DROP TABLE IF EXISTS exchange_rates;
+DROP TABLE IF EXISTS template_items;
+DROP TABLE IF EXISTS templates;
+DROP TABLE IF EXISTS tombstones;
+DROP TABLE IF EXISTS list_members;
+DROP TABLE IF EXISTS items;
+DROP TABLE IF EXISTS lists;
+DROP TABLE IF EXISTS folders;
+DROP TABLE IF EXISTS users_permissions;
+DROP TABLE IF EXISTS permissions;
+DROP TABLE IF EXISTS tokens;
+DROP TABLE IF EXISTS users;
\ No newline at end of file
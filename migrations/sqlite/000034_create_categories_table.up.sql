@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS "categories"
+(
+    "id"         INTEGER PRIMARY KEY AUTOINCREMENT,
+    "user_id"    BIGINT       NOT NULL REFERENCES users ON DELETE CASCADE,
+    "name"       VARCHAR(255) NOT NULL,
+    "icon"       VARCHAR(255) NOT NULL DEFAULT 'mdi-tag',
+    "version"    INT          NOT NULL DEFAULT 1,
+    "order"      INT          NOT NULL DEFAULT 1,
+    "created_at" DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX "categories_user_id_order_index" ON categories ("user_id", "order");
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS "items_category_id_index";
+ALTER TABLE "items" DROP COLUMN "category_id";
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS "item_history"
+(
+    "id"             INTEGER PRIMARY KEY AUTOINCREMENT,
+    "user_id"        BIGINT       NOT NULL REFERENCES users ON DELETE CASCADE,
+    "name"           VARCHAR(190) NOT NULL,
+    "name_key"       VARCHAR(190) NOT NULL,
+    "quantity"       INT          NOT NULL DEFAULT 0,
+    "quantity_type"  VARCHAR(255) NOT NULL DEFAULT 'piece',
+    "price_minor"    BIGINT       NOT NULL DEFAULT 0,
+    "currency"       CHAR(3)      NOT NULL DEFAULT 'USD',
+    "category_id"    BIGINT       NULL DEFAULT NULL,
+    "times_bought"   INT          NOT NULL DEFAULT 1,
+    "last_bought_at" DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX "item_history_user_id_name_key_unique" ON item_history ("user_id", "name_key");
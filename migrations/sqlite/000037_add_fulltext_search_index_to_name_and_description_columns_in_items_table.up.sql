@@ -0,0 +1 @@
+-- SQLite ищет слова через LIKE, индекс для этого не используется.
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS "sessions"
+(
+    "id"           INTEGER PRIMARY KEY AUTOINCREMENT,
+    "user_id"      BIGINT       NOT NULL REFERENCES users ON DELETE CASCADE,
+    "device_name"  VARCHAR(255) NOT NULL DEFAULT '',
+    "ip"           VARCHAR(45)  NOT NULL DEFAULT '',
+    "user_agent"   VARCHAR(255) NOT NULL DEFAULT '',
+    "created_at"   DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "last_used_at" DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX "sessions_user_id_index" ON sessions ("user_id");
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS tokens_permissions;
\ No newline at end of file
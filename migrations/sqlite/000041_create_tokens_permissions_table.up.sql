@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS "tokens_permissions"
+(
+    "id"            INTEGER PRIMARY KEY AUTOINCREMENT,
+    "token_id"      BIGINT      NOT NULL REFERENCES tokens ON DELETE CASCADE,
+    "permission_id" BIGINT      NOT NULL REFERENCES permissions ON DELETE CASCADE,
+    "created_at"    DATETIME    NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX "tokens_permissions_token_id_index" ON tokens_permissions ("token_id");
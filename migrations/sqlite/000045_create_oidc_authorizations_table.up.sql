@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS "oidc_authorizations"
+(
+    "id"            INTEGER PRIMARY KEY AUTOINCREMENT,
+    "hash"          VARCHAR(100) NOT NULL,
+    "provider"      VARCHAR(100) NOT NULL,
+    "code_verifier" VARCHAR(128) NOT NULL,
+    "nonce"         VARCHAR(100) NOT NULL,
+    "expired_at"    DATETIME     NOT NULL
+);
+CREATE UNIQUE INDEX "oidc_authorizations_hash_unique" ON oidc_authorizations ("hash");